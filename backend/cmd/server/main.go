@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -10,8 +11,12 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/smoreg/freezino/backend/internal/config"
 	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/mail"
 	"github.com/smoreg/freezino/backend/internal/middleware"
 	"github.com/smoreg/freezino/backend/internal/router"
+	"github.com/smoreg/freezino/backend/internal/scheduler"
+	"github.com/smoreg/freezino/backend/internal/service"
 )
 
 func main() {
@@ -33,6 +38,47 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Evaluate achievements and challenges in the background as users work, play and shop
+	achievementService, err := service.NewAchievementService()
+	if err != nil {
+		log.Fatalf("Failed to initialize achievements: %v", err)
+	}
+	challengeService, err := service.NewChallengeService()
+	if err != nil {
+		log.Fatalf("Failed to initialize challenges: %v", err)
+	}
+	events.SubscribeAsync("achievements", events.All, achievementService.HandleEvent)
+	events.SubscribeAsync("challenges", events.All, challengeService.HandleEvent)
+
+	// Keep leaderboards current; migrations built the all-time boards from history
+	leaderboardService := service.NewLeaderboardService()
+	events.SubscribeAsyncTx("leaderboards", events.All, leaderboardService.ApplyEvent)
+
+	// Send account emails through the configured transport
+	mailer, err := mail.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+
+	// Push live notifications to connected clients
+	events.SubscribeAsync("notifications", events.All, service.NewNotificationService().HandleEvent)
+
+	// Deliver events on a relay goroutine, so requests don't wait for subscribers
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	events.StartRelay(relayCtx, database.GetDB())
+
+	// Start the periodic background jobs
+	if cfg.SchedulerEnabled {
+		hostname, _ := os.Hostname()
+		sched := scheduler.New(database.GetDB(), fmt.Sprintf("%s-%d", hostname, os.Getpid()))
+		if err := service.RegisterBackgroundJobs(sched); err != nil {
+			log.Fatalf("Failed to register background jobs: %v", err)
+		}
+		sched.Start()
+		defer sched.Stop()
+	}
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      "Freezino API",
@@ -49,7 +95,7 @@ func main() {
 	app.Static("/images", "./static/images")
 
 	// Setup routes
-	router.Setup(app, cfg)
+	router.Setup(app, cfg, mailer)
 
 	// Root endpoint
 	app.Get("/", func(c *fiber.Ctx) error {
@@ -10,22 +10,43 @@ import (
 	"github.com/smoreg/freezino/backend/internal/config"
 	"github.com/smoreg/freezino/backend/internal/database"
 	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/service"
 )
 
 // Handler handles authentication requests
 type Handler struct {
 	config     *config.Config
 	jwtManager *JWTManager
+	sessions   *service.AuthSessionService
 }
 
 // NewHandler creates a new auth handler
 func NewHandler(cfg *config.Config) *Handler {
+	jwtManager := NewJWTManager(cfg)
 	return &Handler{
 		config:     cfg,
-		jwtManager: NewJWTManager(cfg),
+		jwtManager: jwtManager,
+		sessions:   service.NewAuthSessionService(jwtManager.RefreshExpiration()),
 	}
 }
 
+// SetRefreshCookie stores a refresh token in the HTTP-only cookie browsers
+// send back to the auth endpoints. An empty token clears it.
+func SetRefreshCookie(c *fiber.Ctx, cfg *config.Config, token string, expires time.Time) {
+	if token == "" {
+		expires = time.Now().Add(-1 * time.Hour)
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    token,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   cfg.Environment == "production",
+		SameSite: "Lax",
+		Path:     "/api/auth",
+	})
+}
+
 // generateState generates a random state string for OAuth
 func generateState() string {
 	b := make([]byte, 32)
@@ -139,17 +160,25 @@ func (h *Handler) GoogleCallback(c *fiber.Ctx) error {
 	db := database.GetDB()
 	var user model.User
 
+	// Google has already confirmed addresses it marks as verified
+	var verifiedAt *time.Time
+	if userInfo.VerifiedEmail {
+		now := time.Now()
+		verifiedAt = &now
+	}
+
 	result := db.Where("google_id = ?", userInfo.ID).First(&user)
 	isNewUser := result.Error != nil
 	if isNewUser {
 		// User doesn't exist, create new one
 		googleID := userInfo.ID
 		user = model.User{
-			GoogleID: &googleID,
-			Email:    userInfo.Email,
-			Name:     userInfo.Name,
-			Avatar:   userInfo.Picture,
-			Balance:  1000.00, // Initial balance
+			GoogleID:        &googleID,
+			Email:           userInfo.Email,
+			EmailVerifiedAt: verifiedAt,
+			Name:            userInfo.Name,
+			Avatar:          userInfo.Picture,
+			Balance:         1000.00, // Initial balance
 		}
 
 		if err := db.Create(&user).Error; err != nil {
@@ -163,10 +192,20 @@ func (h *Handler) GoogleCallback(c *fiber.Ctx) error {
 			// Log error but don't fail login
 			fmt.Printf("Warning: Failed to give starter items to user %d: %v\n", user.ID, err)
 		}
+	} else if user.EmailVerifiedAt == nil && verifiedAt != nil && user.Email == userInfo.Email {
+		if err := db.Model(&user).Update("email_verified_at", verifiedAt).Error; err != nil {
+			fmt.Printf("Warning: Failed to mark email of user %d verified: %v\n", user.ID, err)
+		}
 	}
 
-	// Generate JWT tokens
-	accessToken, refreshToken, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email)
+	// Start a session and generate its tokens
+	session, refreshToken, err := h.sessions.StartSession(user.ID, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to start session",
+		})
+	}
+	accessToken, err := h.jwtManager.GenerateAccessToken(user.ID, user.Email, session.ID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to generate tokens",
@@ -174,15 +213,7 @@ func (h *Handler) GoogleCallback(c *fiber.Ctx) error {
 	}
 
 	// Set refresh token as HTTP-only cookie
-	c.Cookie(&fiber.Cookie{
-		Name:     "refresh_token",
-		Value:    refreshToken,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-		HTTPOnly: true,
-		Secure:   h.config.Environment == "production",
-		SameSite: "Lax",
-		Path:     "/api/auth",
-	})
+	SetRefreshCookie(c, h.config, refreshToken, session.ExpiresAt)
 
 	// Redirect to frontend with access token
 	redirectURL := h.config.FrontendURL + "/auth/callback?token=" + accessToken
@@ -210,58 +241,3 @@ func (h *Handler) GetMe(c *fiber.Ctx) error {
 		"user": user,
 	})
 }
-
-// Logout logs out the user by clearing tokens
-func (h *Handler) Logout(c *fiber.Ctx) error {
-	// Clear refresh token cookie
-	c.Cookie(&fiber.Cookie{
-		Name:     "refresh_token",
-		Value:    "",
-		Expires:  time.Now().Add(-1 * time.Hour),
-		HTTPOnly: true,
-		Secure:   h.config.Environment == "production",
-		SameSite: "Lax",
-		Path:     "/api/auth",
-	})
-
-	return c.JSON(fiber.Map{
-		"message": "logged out successfully",
-	})
-}
-
-// RefreshToken generates a new access token using refresh token
-func (h *Handler) RefreshToken(c *fiber.Ctx) error {
-	refreshToken := c.Cookies("refresh_token")
-	if refreshToken == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "refresh token not found",
-		})
-	}
-
-	// Validate refresh token
-	claims, err := h.jwtManager.ValidateToken(refreshToken)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "invalid refresh token",
-		})
-	}
-
-	// Check token type
-	if claims.Type != RefreshToken {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "invalid token type",
-		})
-	}
-
-	// Generate new access token
-	accessToken, err := h.jwtManager.GenerateAccessToken(claims.UserID, claims.Email)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to generate access token",
-		})
-	}
-
-	return c.JSON(fiber.Map{
-		"access_token": accessToken,
-	})
-}
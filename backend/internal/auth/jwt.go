@@ -2,6 +2,8 @@ package auth
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -23,9 +25,10 @@ const (
 
 // Claims represents the JWT claims
 type Claims struct {
-	UserID uint      `json:"user_id"`
-	Email  string    `json:"email"`
-	Type   TokenType `json:"type"`
+	UserID    uint      `json:"user_id"`
+	Email     string    `json:"email"`
+	Type      TokenType `json:"type"`
+	SessionID uint      `json:"sid,omitempty"` // The session the token was issued to; revoking it revokes the token
 	jwt.RegisteredClaims
 }
 
@@ -39,17 +42,18 @@ func NewJWTManager(cfg *config.Config) *JWTManager {
 	return &JWTManager{config: cfg}
 }
 
-// GenerateAccessToken generates an access token for a user
-func (jm *JWTManager) GenerateAccessToken(userID uint, email string) (string, error) {
+// GenerateAccessToken generates an access token for a user's session
+func (jm *JWTManager) GenerateAccessToken(userID uint, email string, sessionID uint) (string, error) {
 	duration, err := time.ParseDuration(jm.config.JWTAccessExpiration)
 	if err != nil {
 		duration = 15 * time.Minute // default 15 minutes
 	}
 
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Type:   AccessToken,
+		UserID:    userID,
+		Email:     email,
+		Type:      AccessToken,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -61,26 +65,20 @@ func (jm *JWTManager) GenerateAccessToken(userID uint, email string) (string, er
 	return token.SignedString([]byte(jm.config.JWTSecret))
 }
 
-// GenerateRefreshToken generates a refresh token for a user
-func (jm *JWTManager) GenerateRefreshToken(userID uint, email string) (string, error) {
-	duration, err := time.ParseDuration(jm.config.JWTRefreshExpiration)
-	if err != nil {
-		duration = 7 * 24 * time.Hour // default 7 days
+// RefreshExpiration returns how long a session lasts after its refresh token
+// was last used. Refresh tokens themselves are opaque and kept server-side.
+func (jm *JWTManager) RefreshExpiration() time.Duration {
+	value := jm.config.JWTRefreshExpiration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour
+		}
 	}
-
-	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Type:   RefreshToken,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		duration = 7 * 24 * time.Hour // default 7 days
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jm.config.JWTSecret))
+	return duration
 }
 
 // ValidateToken validates a JWT token and returns the claims
@@ -107,18 +105,3 @@ func (jm *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
-
-// GenerateTokenPair generates both access and refresh tokens
-func (jm *JWTManager) GenerateTokenPair(userID uint, email string) (accessToken, refreshToken string, err error) {
-	accessToken, err = jm.GenerateAccessToken(userID, email)
-	if err != nil {
-		return "", "", err
-	}
-
-	refreshToken, err = jm.GenerateRefreshToken(userID, email)
-	if err != nil {
-		return "", "", err
-	}
-
-	return accessToken, refreshToken, nil
-}
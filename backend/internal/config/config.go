@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -24,6 +25,21 @@ type Config struct {
 
 	// Frontend URL
 	FrontendURL string
+
+	// Admins, by email; their address must be verified
+	AdminEmails []string
+
+	// Mail
+	MailTransport string // smtp, file or memory
+	MailFrom      string
+	MailDir       string // Where the file transport drops messages
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+
+	// Background jobs
+	SchedulerEnabled bool
 }
 
 // Load loads configuration from environment variables
@@ -49,6 +65,21 @@ func Load() *Config {
 
 		// Frontend
 		FrontendURL: getEnv("FRONTEND_URL", "http://localhost:5173"),
+
+		// Admins
+		AdminEmails: splitList(getEnv("ADMIN_EMAILS", "")),
+
+		// Mail
+		MailTransport: getEnv("MAIL_TRANSPORT", "file"),
+		MailFrom:      getEnv("MAIL_FROM", "Freezino <no-reply@freezino.local>"),
+		MailDir:       getEnv("MAIL_DIR", "./data/mail"),
+		SMTPHost:      getEnv("SMTP_HOST", ""),
+		SMTPPort:      getEnv("SMTP_PORT", "587"),
+		SMTPUsername:  getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:  getEnv("SMTP_PASSWORD", ""),
+
+		// Background jobs
+		SchedulerEnabled: getEnv("SCHEDULER_ENABLED", "true") == "true",
 	}
 
 	return cfg
@@ -62,3 +93,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// splitList splits a comma-separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,37 @@
+// Package data bundles the static JSON datasets shipped with the backend.
+package data
+
+import _ "embed"
+
+// JobsJSON is the built-in job catalogue, used when no jobs.json file is
+// found on disk next to the server.
+//
+//go:embed jobs.json
+var JobsJSON []byte
+
+// CareersJSON is the built-in career ladder (levels, titles, pay multipliers).
+//
+//go:embed careers.json
+var CareersJSON []byte
+
+// CollectorsJSON is the built-in set of collector encounter scenarios.
+//
+//go:embed collectors.json
+var CollectorsJSON []byte
+
+// AchievementsJSON is the built-in achievement catalogue.
+//
+//go:embed achievements.json
+var AchievementsJSON []byte
+
+// ChallengesJSON is the built-in set of daily and weekly challenge templates
+// and the schedule they rotate on.
+//
+//go:embed challenges.json
+var ChallengesJSON []byte
+
+// CountriesJSON is the built-in list of countries and their average hourly
+// wages, used when no countries.json file is found on disk next to the server.
+//
+//go:embed countries.json
+var CountriesJSON []byte
@@ -1,12 +1,203 @@
 package database
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"math"
+	"time"
 
 	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
 )
 
+// columnBackfill gives rows that existed before a column was added a value
+// other than the column's zero value, which would mean something else for
+// them. Without a column it fills a new table from data that predates it.
+type columnBackfill struct {
+	model  interface{}
+	column string
+	apply  func(db *gorm.DB) error
+}
+
+// due reports whether the migration about to run adds the backfill's column,
+// or its table when it has no column
+func (b columnBackfill) due(db *gorm.DB) bool {
+	if !db.Migrator().HasTable(b.model) {
+		return b.column == ""
+	}
+	return b.column != "" && !db.Migrator().HasColumn(b.model, b.column)
+}
+
+// name returns what the backfill fills, for logging
+func (b columnBackfill) name() string {
+	if b.column != "" {
+		return b.column
+	}
+	if table, ok := b.model.(interface{ TableName() string }); ok {
+		return table.TableName()
+	}
+	return "table"
+}
+
+// backfills run once, in the migration that adds their column or table
+var backfills = []columnBackfill{
+	{
+		// Cars and houses owned before upkeep existed are billed from now, not
+		// for their whole history
+		model:  &model.UserItem{},
+		column: "last_upkeep_at",
+		apply: func(db *gorm.DB) error {
+			return db.Model(&model.UserItem{}).Where("last_upkeep_at IS NULL").Update("last_upkeep_at", time.Now()).Error
+		},
+	},
+	{
+		// Accounts from before email verification keep the market, auctions and
+		// loans they already had; they count as verified since they signed up
+		model:  &model.User{},
+		column: "email_verified_at",
+		apply: func(db *gorm.DB) error {
+			return db.Model(&model.User{}).Where("email_verified_at IS NULL").
+				Update("email_verified_at", gorm.Expr("created_at")).Error
+		},
+	},
+	{
+		// Limits set before they recorded a timezone keep the one they were
+		// being counted in
+		model:  &model.GamblingLimit{},
+		column: "timezone",
+		apply: func(db *gorm.DB) error {
+			return db.Model(&model.GamblingLimit{}).Where("timezone = ''").
+				Update("timezone", gorm.Expr("COALESCE((?), '')",
+					db.Unscoped().Model(&model.User{}).Select("timezone").Where("users.id = gambling_limits.user_id"))).Error
+		},
+	},
+	{
+		// Shops seeded before stock existed get the catalog's stock and
+		// editions; copies already owned count towards an edition
+		model:  &model.Item{},
+		column: "stock",
+		apply: func(db *gorm.DB) error {
+			for _, item := range shopCatalog() {
+				updates := map[string]interface{}{}
+				if item.Stock != nil {
+					updates["stock"] = *item.Stock
+					updates["max_stock"] = item.MaxStock
+					updates["restock_quantity"] = item.RestockQuantity
+					updates["restock_interval_hours"] = item.RestockIntervalHours
+				}
+				if item.EditionSize != nil {
+					updates["edition_size"] = *item.EditionSize
+					updates["editions_sold"] = gorm.Expr("MIN((?), ?)",
+						db.Model(&model.UserItem{}).Select("COUNT(*)").Where("user_items.item_id = items.id"), *item.EditionSize)
+				}
+				if len(updates) == 0 {
+					continue
+				}
+				if err := db.Model(&model.Item{}).Where("name = ?", item.Name).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Items seeded before effects existed get the catalog's effects
+		model:  &model.Item{},
+		column: "effects",
+		apply: func(db *gorm.DB) error {
+			for _, item := range shopCatalog() {
+				if len(item.Effects) == 0 {
+					continue
+				}
+				if err := db.Model(&model.Item{}).Where("name = ?", item.Name).
+					Select("effects").Updates(&model.Item{Effects: item.Effects}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Events only keep leaderboards current, so the all-time boards start
+		// from the play, work and debt recorded before they existed
+		model: &model.LeaderboardEntry{},
+		apply: rebuildAllTimeLeaderboards,
+	},
+}
+
+// rebuildAllTimeLeaderboards scores everyone's history on the all-time
+// leaderboards. Board names and the all-time period are the leaderboard
+// service's; the highest debt ever reached isn't recorded, so most_debt
+// starts from what users owe now.
+func rebuildAllTimeLeaderboards(db *gorm.DB) error {
+	games := db.Model(&model.GameSession{})
+	boards := map[string]*gorm.DB{
+		"net_profit":   games.Session(&gorm.Session{}).Select("user_id, SUM(win - bet) AS score").Group("user_id"),
+		"biggest_win":  games.Session(&gorm.Session{}).Select("user_id, MAX(win - bet) AS score").Group("user_id").Having("MAX(win - bet) > 0"),
+		"hours_worked": db.Model(&model.WorkSession{}).Select("user_id, SUM(duration_seconds) / 3600.0 AS score").Group("user_id"),
+		"most_debt":    db.Model(&model.Loan{}).Select("user_id, SUM(remaining_amount) AS score").Group("user_id"),
+	}
+
+	for board, query := range boards {
+		var scores []struct {
+			UserID uint
+			Score  float64
+		}
+		if err := query.Scan(&scores).Error; err != nil {
+			return fmt.Errorf("failed to compute %s leaderboard: %w", board, err)
+		}
+		if len(scores) == 0 {
+			continue
+		}
+
+		entries := make([]model.LeaderboardEntry, len(scores))
+		for i, score := range scores {
+			entries[i] = model.LeaderboardEntry{Board: board, Period: "all", UserID: score.UserID, Score: math.Round(score.Score*100) / 100}
+		}
+		if err := db.CreateInBatches(entries, 500).Error; err != nil {
+			return fmt.Errorf("failed to save %s leaderboard: %w", board, err)
+		}
+	}
+
+	// Hidden users keep their scores but stay out of rankings
+	return db.Model(&model.LeaderboardEntry{}).
+		Where("user_id IN (?)", db.Unscoped().Model(&model.User{}).Select("id").
+			Where("leaderboard_visibility = ?", model.LeaderboardVisibilityHidden)).
+		Update("hidden", true).Error
+}
+
+// hashExportTokens moves data exports from storing their download token to
+// storing its hash, keeping the links already handed out working
+func hashExportTokens(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var exports []struct {
+			ID    uint
+			Token string
+		}
+		if err := tx.Table("data_exports").Select("id, token").Scan(&exports).Error; err != nil {
+			return err
+		}
+		if tx.Migrator().HasIndex(&model.DataExport{}, "idx_data_exports_token") {
+			if err := tx.Migrator().DropIndex(&model.DataExport{}, "idx_data_exports_token"); err != nil {
+				return err
+			}
+		}
+		if err := tx.Migrator().RenameColumn(&model.DataExport{}, "token", "token_hash"); err != nil {
+			return err
+		}
+		for _, export := range exports {
+			sum := sha256.Sum256([]byte(export.Token))
+			if err := tx.Table("data_exports").Where("id = ?", export.ID).
+				Update("token_hash", hex.EncodeToString(sum[:])).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // Migrate runs auto-migration for all models
 func Migrate() error {
 	if DB == nil {
@@ -15,6 +206,22 @@ func Migrate() error {
 
 	log.Println("Running database migrations...")
 
+	// Data exports stored their download token itself until they stored its hash
+	if DB.Migrator().HasColumn(&model.DataExport{}, "token") {
+		if err := hashExportTokens(DB); err != nil {
+			return fmt.Errorf("failed to hash data export tokens: %w", err)
+		}
+		log.Println("Hashed download tokens of existing data exports")
+	}
+
+	// Note which backfills are due before their columns are created
+	var pending []columnBackfill
+	for _, backfill := range backfills {
+		if backfill.due(DB) {
+			pending = append(pending, backfill)
+		}
+	}
+
 	// Auto-migrate all models
 	err := DB.AutoMigrate(
 		&model.User{},
@@ -27,12 +234,49 @@ func Migrate() error {
 		&model.RouletteResult{},
 		&model.UserStatus{},
 		&model.Loan{},
+		&model.LoanInstallment{},
+		&model.LoanStatementEntry{},
+		&model.Bankruptcy{},
+		&model.CollectionEvent{},
+		&model.CollectorEncounter{},
+		&model.CollectorEncounterStep{},
+		&model.Career{},
+		&model.MarketListing{},
+		&model.ItemPricePoint{},
+		&model.Auction{},
+		&model.AuctionBid{},
+		&model.SchedulerLease{},
+		&model.UserAchievement{},
+		&model.UserChallenge{},
+		&model.OutboxEvent{},
+		&model.OutboxCursor{},
+		&model.Notification{},
+		&model.LeaderboardEntry{},
+		&model.GamblingLimit{},
+		&model.GamblingSession{},
+		&model.OpenStake{},
+		&model.RealityCheck{},
+		&model.DataExport{},
+		&model.AccountDeletion{},
+		&model.AuthSession{},
+		&model.RefreshToken{},
+		&model.EmailToken{},
+		&model.TwoFactor{},
+		&model.RecoveryCode{},
+		&model.TwoFactorChallenge{},
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	for _, backfill := range pending {
+		if err := backfill.apply(DB); err != nil {
+			return fmt.Errorf("failed to backfill %s: %w", backfill.name(), err)
+		}
+		log.Printf("Backfilled %s for existing rows", backfill.name())
+	}
+
 	log.Println("Database migrations completed successfully")
 
 	// Seed initial data (items) as part of migration
@@ -54,6 +298,36 @@ func DropAllTables() error {
 	log.Println("Dropping all tables...")
 
 	err := DB.Migrator().DropTable(
+		&model.TwoFactorChallenge{},
+		&model.RecoveryCode{},
+		&model.TwoFactor{},
+		&model.EmailToken{},
+		&model.RefreshToken{},
+		&model.AuthSession{},
+		&model.AccountDeletion{},
+		&model.DataExport{},
+		&model.RealityCheck{},
+		&model.OpenStake{},
+		&model.GamblingSession{},
+		&model.GamblingLimit{},
+		&model.LeaderboardEntry{},
+		&model.Notification{},
+		&model.OutboxCursor{},
+		&model.OutboxEvent{},
+		&model.UserChallenge{},
+		&model.UserAchievement{},
+		&model.SchedulerLease{},
+		&model.AuctionBid{},
+		&model.Auction{},
+		&model.ItemPricePoint{},
+		&model.MarketListing{},
+		&model.Career{},
+		&model.CollectorEncounterStep{},
+		&model.CollectorEncounter{},
+		&model.CollectionEvent{},
+		&model.Bankruptcy{},
+		&model.LoanStatementEntry{},
+		&model.LoanInstallment{},
 		&model.UserStatus{},
 		&model.Loan{},
 		&model.RouletteResult{},
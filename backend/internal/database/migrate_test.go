@@ -0,0 +1,214 @@
+package database_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// legacyItem is the items table as it was before stock, editions and effects
+type legacyItem struct {
+	ID          uint `gorm:"primarykey"`
+	Name        string
+	Type        model.ItemType
+	Rarity      model.ItemRarity
+	Price       float64
+	ImageURL    string
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt
+}
+
+func (legacyItem) TableName() string {
+	return "items"
+}
+
+// openTestDB points the database package at a fresh file for the test
+func openTestDB(t *testing.T) *gorm.DB {
+	require.NoError(t, database.Initialize(database.Config{DBPath: filepath.Join(t.TempDir(), "test.db")}))
+	t.Cleanup(func() { database.Close() })
+	return database.GetDB()
+}
+
+func createUser(t *testing.T, db *gorm.DB, balance float64) *model.User {
+	user := &model.User{Username: "buyer", Email: "buyer@test.com", Balance: balance}
+	require.NoError(t, db.Create(user).Error)
+	return user
+}
+
+func findItem(t *testing.T, db *gorm.DB, name string) model.Item {
+	var item model.Item
+	require.NoError(t, db.Where("name = ?", name).First(&item).Error)
+	return item
+}
+
+func TestSeededShopSellsLimitedAndStockedItems(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, database.Migrate())
+	user := createUser(t, db, 100000000)
+	shop := service.NewShopService()
+
+	// Limited editions are numbered until they sell out
+	villa := findItem(t, db, "Island Villa")
+	require.NotNil(t, villa.EditionSize)
+	for serial := 1; serial <= *villa.EditionSize; serial++ {
+		bought, err := shop.BuyItem(user.ID, villa.ID)
+		require.NoError(t, err)
+		require.NotNil(t, bought.UserItem.SerialNumber)
+		assert.Equal(t, serial, *bought.UserItem.SerialNumber)
+	}
+	_, err := shop.BuyItem(user.ID, villa.ID)
+	assert.EqualError(t, err, "limited edition sold out")
+
+	// Stocked items run out and come back on their restock schedule
+	ferrari := findItem(t, db, "Ferrari F8")
+	require.NotNil(t, ferrari.Stock)
+	for i := 0; i < *ferrari.Stock; i++ {
+		_, err := shop.BuyItem(user.ID, ferrari.ID)
+		require.NoError(t, err)
+	}
+	_, err = shop.BuyItem(user.ID, ferrari.ID)
+	assert.EqualError(t, err, "item out of stock")
+
+	restocked, err := shop.RestockItems()
+	require.NoError(t, err)
+	assert.Positive(t, restocked)
+	_, err = shop.BuyItem(user.ID, ferrari.ID)
+	assert.NoError(t, err)
+}
+
+func TestMigrateConfiguresShopSeededBeforeStock(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, db.AutoMigrate(&legacyItem{}, &model.User{}, &model.UserItem{}))
+
+	villa := legacyItem{Name: "Island Villa", Type: model.ItemTypeHouse, Rarity: model.ItemRarityLegendary, Price: 1000000}
+	ferrari := legacyItem{Name: "Ferrari F8", Type: model.ItemTypeCar, Rarity: model.ItemRarityLegendary, Price: 280000}
+	shirt := legacyItem{Name: "Plain T-Shirt", Type: model.ItemTypeClothing, Rarity: model.ItemRarityCommon, Price: 500}
+	require.NoError(t, db.Create(&[]*legacyItem{&villa, &ferrari, &shirt}).Error)
+
+	user := createUser(t, db, 0)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, db.Create(&model.UserItem{UserID: user.ID, ItemID: villa.ID, PurchasedAt: time.Now()}).Error)
+	}
+
+	require.NoError(t, database.Migrate())
+
+	// Copies owned before the migration count towards the edition
+	migrated := findItem(t, db, "Island Villa")
+	require.NotNil(t, migrated.EditionSize)
+	assert.Equal(t, 10, *migrated.EditionSize)
+	assert.Equal(t, 3, migrated.EditionsSold)
+
+	migrated = findItem(t, db, "Ferrari F8")
+	require.NotNil(t, migrated.Stock)
+	assert.Equal(t, 5, *migrated.Stock)
+	assert.Equal(t, 24, migrated.RestockIntervalHours)
+
+	// Regular items stay unlimited
+	migrated = findItem(t, db, "Plain T-Shirt")
+	assert.Nil(t, migrated.Stock)
+	assert.Nil(t, migrated.EditionSize)
+}
+
+func TestMigrateAttachesEffectsToItemsSeededBefore(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, db.AutoMigrate(&legacyItem{}, &model.User{}, &model.UserItem{}))
+
+	home := legacyItem{Name: "Family Home", Type: model.ItemTypeHouse, Rarity: model.ItemRarityRare, Price: 120000}
+	watch := legacyItem{Name: "Luxury Watch", Type: model.ItemTypeAccessories, Rarity: model.ItemRarityRare, Price: 15000}
+	shirt := legacyItem{Name: "Plain T-Shirt", Type: model.ItemTypeClothing, Rarity: model.ItemRarityCommon, Price: 500}
+	require.NoError(t, db.Create(&[]*legacyItem{&home, &watch, &shirt}).Error)
+
+	user := createUser(t, db, 0)
+	owned := &model.UserItem{UserID: user.ID, ItemID: watch.ID, PurchasedAt: time.Now(), IsEquipped: true}
+	require.NoError(t, db.Create(owned).Error)
+
+	require.NoError(t, database.Migrate())
+
+	migrated := findItem(t, db, "Family Home")
+	assert.Equal(t, []model.ItemEffect{{Type: model.ItemEffectLoanRateDiscount, Value: 0.01, LoanType: model.LoanTypeBank}}, migrated.Effects)
+	assert.Empty(t, findItem(t, db, "Plain T-Shirt").Effects)
+
+	// Items owned before the migration now apply their effects
+	modifiers, err := service.LoadItemModifiers(db, user.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.05, modifiers.WorkPay(model.JobTypeOffice, 1), 1e-9)
+}
+
+func TestMigrateBuildsAllTimeLeaderboardsFromHistory(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.GameSession{}, &model.WorkSession{}, &model.Loan{}))
+
+	winner := &model.User{Username: "winner", Email: "winner@test.com"}
+	loser := &model.User{Username: "loser", Email: "loser@test.com"}
+	hidden := &model.User{Username: "hidden", Email: "hidden@test.com", LeaderboardVisibility: model.LeaderboardVisibilityHidden}
+	require.NoError(t, db.Create(&[]*model.User{winner, loser, hidden}).Error)
+	require.NoError(t, db.Create(&[]model.GameSession{
+		{UserID: winner.ID, GameType: model.GameTypeSlots, Bet: 100, Win: 400},
+		{UserID: winner.ID, GameType: model.GameTypeRoulette, Bet: 50, Win: 0},
+		{UserID: loser.ID, GameType: model.GameTypeSlots, Bet: 300, Win: 0},
+		{UserID: hidden.ID, GameType: model.GameTypeSlots, Bet: 10, Win: 1000},
+	}).Error)
+
+	require.NoError(t, database.Migrate())
+
+	// Casino stats rank players played before the boards existed
+	stats, err := service.NewCasinoStatsService().GetCasinoStats()
+	require.NoError(t, err)
+	require.NotEmpty(t, stats.TopWinners)
+	assert.Equal(t, winner.ID, stats.TopWinners[0].UserID)
+	assert.Equal(t, 250.0, stats.TopWinners[0].NetProfit)
+	require.NotEmpty(t, stats.TopLosers)
+	assert.Equal(t, loser.ID, stats.TopLosers[0].UserID)
+	assert.Equal(t, -300.0, stats.TopLosers[0].NetProfit)
+
+	board, err := service.NewLeaderboardService().GetLeaderboard(service.LeaderboardBiggestWin, service.LeaderboardWindowAllTime, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, board.Entries, 1)
+	assert.Equal(t, 300.0, board.Entries[0].Score)
+}
+
+// legacyDataExport is the data_exports table as it was when it stored the
+// download token itself
+type legacyDataExport struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint
+	Status    model.DataExportStatus
+	Token     string `gorm:"size:64;not null;uniqueIndex"`
+	CreatedAt time.Time
+}
+
+func (legacyDataExport) TableName() string {
+	return "data_exports"
+}
+
+func TestMigrateHashesDataExportTokens(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &legacyDataExport{}))
+	user := createUser(t, db, 0)
+	require.NoError(t, db.Create(&legacyDataExport{UserID: user.ID, Status: model.DataExportStatusPending, Token: "handed-out"}).Error)
+
+	require.NoError(t, database.Migrate())
+	assert.False(t, db.Migrator().HasColumn(&model.DataExport{}, "token"))
+
+	// Links already handed out still find their export
+	exports := service.NewDataExportService()
+	_, err := exports.OpenExport("handed-out")
+	assert.EqualError(t, err, "export_not_ready")
+
+	// Asking again replaces it with a new link
+	_, created, err := exports.RequestExport(user.ID)
+	require.NoError(t, err)
+	assert.False(t, created)
+	_, err = exports.OpenExport("handed-out")
+	assert.EqualError(t, err, "export_not_found")
+}
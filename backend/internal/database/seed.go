@@ -71,15 +71,17 @@ func seedTestUser() error {
 			return fmt.Errorf("failed to hash password for %s: %w", userData.username, err)
 		}
 
+		verifiedAt := time.Now() // Test users can use every feature without a mailbox
 		testUser := model.User{
-			Username:     userData.username,
-			Email:        userData.email,
-			Name:         userData.name,
-			PasswordHash: hashedPassword,
-			Avatar:       fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s", userData.username),
-			Balance:      1000.00,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
+			Username:        userData.username,
+			Email:           userData.email,
+			EmailVerifiedAt: &verifiedAt,
+			Name:            userData.name,
+			PasswordHash:    hashedPassword,
+			Avatar:          fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s", userData.username),
+			Balance:         1000.00,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
 		}
 
 		if err := DB.Create(&testUser).Error; err != nil {
@@ -112,7 +114,26 @@ func seedItems() error {
 		return nil
 	}
 
-	items := []model.Item{
+	items := shopCatalog()
+
+	// Create all items
+	for _, item := range items {
+		item.CreatedAt = time.Now()
+		item.UpdatedAt = time.Now()
+
+		if err := DB.Create(&item).Error; err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Created %d shop items", len(items))
+	return nil
+}
+
+// shopCatalog returns the items the shop is seeded with. The rarest pieces
+// are limited editions or come in small, restocking batches.
+func shopCatalog() []model.Item {
+	return []model.Item{
 		// CLOTHING - Common (7 items, $50-$2,000)
 		{
 			Name:        "Worn Homeless Clothes",
@@ -237,6 +258,7 @@ func seedItems() error {
 			Price:       25000.00,
 			ImageURL:    "/images/clothing/haute-couture.jpg",
 			Description: "Exclusive haute couture piece",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectJobLuck, Value: 0.25, JobType: model.JobTypeStreamer}},
 		},
 		{
 			Name:        "Luxury Fur Coat",
@@ -255,6 +277,7 @@ func seedItems() error {
 			Price:       50000.00,
 			ImageURL:    "/images/clothing/limited-edition.jpg",
 			Description: "Rare runway piece from exclusive collection",
+			EditionSize: intPtr(100),
 		},
 
 		// ACCESSORIES - Common (5 items, $500-$3,000)
@@ -323,6 +346,7 @@ func seedItems() error {
 			Price:       5500.00,
 			ImageURL:    "/images/accessories/briefcase.jpg",
 			Description: "Premium leather briefcase",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectWorkPayMultiplier, Value: 0.1, JobType: model.JobTypeOffice}},
 		},
 		{
 			Name:        "Pearl Earrings",
@@ -347,6 +371,7 @@ func seedItems() error {
 			Price:       15000.00,
 			ImageURL:    "/images/accessories/luxury-watch.jpg",
 			Description: "Premium Swiss watch",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectWorkPayMultiplier, Value: 0.05}},
 		},
 
 		// ACCESSORIES - Epic (2 items, $20,000-$25,000)
@@ -375,14 +400,20 @@ func seedItems() error {
 			Price:       85000.00,
 			ImageURL:    "/images/accessories/collectible-watch.jpg",
 			Description: "Limited edition timepiece from prestigious watchmaker",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectWorkPayMultiplier, Value: 0.1}},
+			EditionSize: intPtr(50),
 		},
 		{
-			Name:        "Diamond Necklace Set",
-			Type:        model.ItemTypeAccessories,
-			Rarity:      model.ItemRarityLegendary,
-			Price:       120000.00,
-			ImageURL:    "/images/accessories/diamond-set.jpg",
-			Description: "Exquisite diamond necklace and earring set",
+			Name:                 "Diamond Necklace Set",
+			Type:                 model.ItemTypeAccessories,
+			Rarity:               model.ItemRarityLegendary,
+			Price:                120000.00,
+			ImageURL:             "/images/accessories/diamond-set.jpg",
+			Description:          "Exquisite diamond necklace and earring set",
+			Stock:                intPtr(5),
+			MaxStock:             5,
+			RestockQuantity:      1,
+			RestockIntervalHours: 48,
 		},
 
 		// CARS - Common (2 items, $1,000-$5,000)
@@ -473,20 +504,28 @@ func seedItems() error {
 			Description: "Ultimate luxury sedan",
 		},
 		{
-			Name:        "Porsche 911",
-			Type:        model.ItemTypeCar,
-			Rarity:      model.ItemRarityLegendary,
-			Price:       125000.00,
-			ImageURL:    "/images/cars/porsche-911.jpg",
-			Description: "Iconic sports car",
+			Name:                 "Porsche 911",
+			Type:                 model.ItemTypeCar,
+			Rarity:               model.ItemRarityLegendary,
+			Price:                125000.00,
+			ImageURL:             "/images/cars/porsche-911.jpg",
+			Description:          "Iconic sports car",
+			Stock:                intPtr(10),
+			MaxStock:             10,
+			RestockQuantity:      2,
+			RestockIntervalHours: 24,
 		},
 		{
-			Name:        "Ferrari F8",
-			Type:        model.ItemTypeCar,
-			Rarity:      model.ItemRarityLegendary,
-			Price:       280000.00,
-			ImageURL:    "/images/cars/ferrari-f8.jpg",
-			Description: "Italian supercar",
+			Name:                 "Ferrari F8",
+			Type:                 model.ItemTypeCar,
+			Rarity:               model.ItemRarityLegendary,
+			Price:                280000.00,
+			ImageURL:             "/images/cars/ferrari-f8.jpg",
+			Description:          "Italian supercar",
+			Stock:                intPtr(5),
+			MaxStock:             5,
+			RestockQuantity:      1,
+			RestockIntervalHours: 24,
 		},
 		{
 			Name:        "Lamborghini Aventador",
@@ -495,6 +534,7 @@ func seedItems() error {
 			Price:       500000.00,
 			ImageURL:    "/images/cars/lamborghini.jpg",
 			Description: "Legendary Italian supercar",
+			EditionSize: intPtr(25),
 		},
 
 		// HOUSES - Common (2 items, $2,000-$5,000)
@@ -539,6 +579,7 @@ func seedItems() error {
 			Price:       120000.00,
 			ImageURL:    "/images/houses/family-home.jpg",
 			Description: "Spacious family home",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectLoanRateDiscount, Value: 0.01, LoanType: model.LoanTypeBank}},
 		},
 		{
 			Name:        "Lake House",
@@ -565,6 +606,7 @@ func seedItems() error {
 			Price:       500000.00,
 			ImageURL:    "/images/houses/penthouse.jpg",
 			Description: "Top-floor luxury penthouse",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectLoanRateDiscount, Value: 0.02}},
 		},
 
 		// HOUSES - Legendary (3 items, $750,000-$1,000,000)
@@ -577,12 +619,17 @@ func seedItems() error {
 			Description: "Stunning modern mansion",
 		},
 		{
-			Name:        "Private Estate",
-			Type:        model.ItemTypeHouse,
-			Rarity:      model.ItemRarityLegendary,
-			Price:       1000000.00,
-			ImageURL:    "/images/houses/estate.jpg",
-			Description: "Exclusive private estate",
+			Name:                 "Private Estate",
+			Type:                 model.ItemTypeHouse,
+			Rarity:               model.ItemRarityLegendary,
+			Price:                1000000.00,
+			ImageURL:             "/images/houses/estate.jpg",
+			Description:          "Exclusive private estate",
+			Effects:              []model.ItemEffect{{Type: model.ItemEffectLoanRateDiscount, Value: 0.03}},
+			Stock:                intPtr(3),
+			MaxStock:             3,
+			RestockQuantity:      1,
+			RestockIntervalHours: 72,
 		},
 		{
 			Name:        "Island Villa",
@@ -591,6 +638,7 @@ func seedItems() error {
 			Price:       1000000.00,
 			ImageURL:    "/images/houses/island-villa.jpg",
 			Description: "Private island villa paradise",
+			EditionSize: intPtr(10),
 		},
 
 		// MUTATIONS - Received from Lab Rat job (hidden from shop)
@@ -601,6 +649,7 @@ func seedItems() error {
 			Price:       0.00,
 			ImageURL:    "/images/mutations/third-eye.jpg",
 			Description: "You can see things others can't. Not always pleasant.",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectGamePayoutMultiplier, Value: 0.05, Game: model.GameTypeRoulette}},
 		},
 		{
 			Name:        "Glowing Skin",
@@ -617,6 +666,7 @@ func seedItems() error {
 			Price:       0.00,
 			ImageURL:    "/images/mutations/extra-arm.jpg",
 			Description: "A third arm grew from your back. Very practical!",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectGameLossRebate, Value: 0.1, Game: model.GameTypeBlackjack}},
 		},
 		{
 			Name:        "Tentacle Fingers",
@@ -625,6 +675,7 @@ func seedItems() error {
 			Price:       0.00,
 			ImageURL:    "/images/mutations/tentacle-fingers.jpg",
 			Description: "Your fingers became tentacles. Typing is... difficult.",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectGamePayoutMultiplier, Value: 0.1, Game: model.GameTypeSlots}},
 		},
 		{
 			Name:        "Super Strength",
@@ -633,6 +684,7 @@ func seedItems() error {
 			Price:       0.00,
 			ImageURL:    "/images/mutations/super-strength.jpg",
 			Description: "You're incredibly strong now! Worth the pain.",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectWorkPayMultiplier, Value: 0.25, JobType: model.JobTypeBottleCollector}},
 		},
 		{
 			Name:        "Night Vision",
@@ -641,6 +693,7 @@ func seedItems() error {
 			Price:       0.00,
 			ImageURL:    "/images/mutations/night-vision.jpg",
 			Description: "You can see perfectly in the dark. Your eyes glow red though.",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectGameLossRebate, Value: 0.05, Game: model.GameTypeCrash}},
 		},
 		{
 			Name:        "Scaly Skin",
@@ -725,6 +778,7 @@ func seedItems() error {
 			Price:       2800.00,
 			ImageURL:    "/images/clothing/onesie.jpg",
 			Description: "Magical unicorn pajamas with rainbow tail",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectJobLuck, Value: 0.5, JobType: model.JobTypeStreamer}},
 		},
 		{
 			Name:        "Inflatable T-Rex Costume",
@@ -733,6 +787,7 @@ func seedItems() error {
 			Price:       8500.00,
 			ImageURL:    "/images/clothing/inflatable-dinosaur.jpg",
 			Description: "Battery-powered inflatable dinosaur suit. Pure chaos!",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectJobLuck, Value: 1, JobType: model.JobTypeStreamer}},
 		},
 		{
 			Name:        "Pickle Costume",
@@ -799,6 +854,7 @@ func seedItems() error {
 			Price:       777.00,
 			ImageURL:    "/images/accessories/potato-clock.jpg",
 			Description: "Powered by a potato. Science!",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectWorkPayFlat, Value: 5}},
 		},
 		{
 			Name:        "Finger Hands",
@@ -979,6 +1035,7 @@ func seedItems() error {
 			Price:       15000.00,
 			ImageURL:    "/images/houses/van.jpg",
 			Description: "Living the #VanLife dream! Government cheese optional",
+			Effects:     []model.ItemEffect{{Type: model.ItemEffectLoanRateDiscount, Value: 0.05, LoanType: model.LoanTypeMicrocredit}},
 		},
 		{
 			Name:        "Inflatable Bouncy Castle",
@@ -989,19 +1046,11 @@ func seedItems() error {
 			Description: "Your childhood dream home. Bouncing is mandatory!",
 		},
 	}
+}
 
-	// Create all items
-	for _, item := range items {
-		item.CreatedAt = time.Now()
-		item.UpdatedAt = time.Now()
-
-		if err := DB.Create(&item).Error; err != nil {
-			return err
-		}
-	}
-
-	log.Printf("Created %d shop items", len(items))
-	return nil
+// intPtr returns a pointer to v for optional item fields
+func intPtr(v int) *int {
+	return &v
 }
 
 // ClearData removes all data from the database (keeps schema)
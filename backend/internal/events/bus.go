@@ -0,0 +1,461 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// All subscribes a handler to every event name
+const All = "*"
+
+// Bus parameters
+const (
+	asyncMaxAttempts = 5           // Times an async subscriber tries an event before skipping it
+	flushBatchSize   = 500         // Outbox rows handled per query while flushing
+	relayInterval    = time.Minute // How often the relay flushes without a signal, to retry failed events
+)
+
+// Handler reacts to an event
+type Handler func(event Event) error
+
+// TxHandler reacts to an event by writing to the database inside tx
+type TxHandler func(tx *gorm.DB, event Event) error
+
+type subscriber struct {
+	name   string
+	handle Handler
+
+	// Asynchronous subscribers read the outbox themselves, from a cursor
+	// stored under id
+	id       string
+	handleTx TxHandler
+	wake     chan *gorm.DB // Nil for synchronous subscribers
+	ready    bool          // Cursor exists; guarded by the bus's flushMu
+	failedID uint          // Event that last failed, and how many times in a row
+	failures int
+}
+
+// Bus delivers committed outbox events to subscribers
+type Bus struct {
+	mu      sync.RWMutex
+	subs    []*subscriber
+	flushMu sync.Mutex    // Keeps this process's flushes in outbox order
+	wake    chan struct{} // Set while a relay is running
+}
+
+// New creates a bus without subscribers
+func New() *Bus {
+	return &Bus{}
+}
+
+// defaultBus carries events for every service in the process
+var defaultBus = New()
+
+// Default returns the process-wide bus
+func Default() *Bus {
+	return defaultBus
+}
+
+// Subscribe registers a handler that runs in the flushing goroutine, before
+// Flush returns. Handlers must be quick; their errors are logged.
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, &subscriber{name: name, handle: handler})
+}
+
+// SubscribeAsync registers a handler that runs on its own worker goroutine.
+// The worker reads the outbox from a cursor stored under id, so events it
+// has not handled yet survive a full backlog, a failure or a restart. An
+// event whose handler fails is retried on later flushes, up to
+// asyncMaxAttempts times, and may then be handled more than once.
+func (b *Bus) SubscribeAsync(id, name string, handler Handler) {
+	b.subscribeAsync(&subscriber{id: id, name: name, handle: handler})
+}
+
+// SubscribeAsyncTx is SubscribeAsync for handlers that only write to the
+// database. Their writes commit together with the cursor, so each event
+// takes effect exactly once.
+func (b *Bus) SubscribeAsyncTx(id, name string, handler TxHandler) {
+	b.subscribeAsync(&subscriber{id: id, name: name, handleTx: handler})
+}
+
+func (b *Bus) subscribeAsync(sub *subscriber) {
+	sub.wake = make(chan *gorm.DB, 1)
+	go func() {
+		for db := range sub.wake {
+			if err := sub.catchUp(db); err != nil {
+				log.Printf("events: %s subscriber will retry: %v", sub.id, err)
+			}
+		}
+	}()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, sub)
+}
+
+// matches reports whether the subscriber wants the event
+func (s *subscriber) matches(event Event) bool {
+	return s.name == All || s.name == event.EventName()
+}
+
+// call runs the handler, turning a panic into an error
+func (s *subscriber) call(tx *gorm.DB, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	if s.handleTx != nil {
+		return s.handleTx(tx, event)
+	}
+	return s.handle(event)
+}
+
+// catchUp hands the subscriber every outbox event past its cursor, oldest
+// first. It stops at the first event that fails, leaving it for the next flush.
+func (s *subscriber) catchUp(db *gorm.DB) error {
+	for {
+		var cursor model.OutboxCursor
+		if err := db.First(&cursor, "subscriber = ?", s.id).Error; err != nil {
+			return fmt.Errorf("failed to read cursor: %w", err)
+		}
+
+		var pending []model.OutboxEvent
+		if err := db.Where("id > ?", cursor.LastEventID).
+			Order("id").Limit(flushBatchSize).Find(&pending).Error; err != nil {
+			return fmt.Errorf("failed to read outbox: %w", err)
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		for _, row := range pending {
+			moved, err := s.deliver(db, cursor.LastEventID, row)
+			if err != nil {
+				return err
+			}
+			if !moved {
+				break // Another worker moved the cursor; read it again
+			}
+			cursor.LastEventID = row.ID
+		}
+	}
+}
+
+// errCursorMoved rolls back a handler's writes when another worker handled the event first
+var errCursorMoved = errors.New("cursor moved")
+
+// deliver hands one outbox row to the subscriber and moves its cursor from
+// from to the row. It reports false if the cursor was no longer at from.
+func (s *subscriber) deliver(db *gorm.DB, from uint, row model.OutboxEvent) (bool, error) {
+	event, ok := decodeRow(row)
+	if !ok || !s.matches(event) {
+		return advanceCursor(db, s.id, from, row.ID)
+	}
+
+	var err error
+	moved := false
+	if s.handleTx != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := s.call(tx, event); err != nil {
+				return err
+			}
+			var err error
+			if moved, err = advanceCursor(tx, s.id, from, row.ID); err == nil && !moved {
+				return errCursorMoved
+			}
+			return err
+		})
+		if errors.Is(err, errCursorMoved) {
+			return false, nil
+		}
+	} else if err = s.call(db, event); err == nil {
+		moved, err = advanceCursor(db, s.id, from, row.ID)
+	}
+	if err == nil {
+		s.failures = 0
+		return moved, nil
+	}
+
+	if s.failedID != row.ID {
+		s.failedID, s.failures = row.ID, 0
+	}
+	s.failures++
+	if s.failures < asyncMaxAttempts {
+		return false, fmt.Errorf("%s event %d failed (attempt %d): %w", row.Name, row.ID, s.failures, err)
+	}
+	log.Printf("events: %s subscriber gave up on %s event %d for user %d after %d attempts: %v",
+		s.id, row.Name, row.ID, row.UserID, s.failures, err)
+	s.failures = 0
+	return advanceCursor(db, s.id, from, row.ID)
+}
+
+// advanceCursor moves a subscriber's cursor from one event to the next. It
+// reports false if the cursor had already moved.
+func advanceCursor(db *gorm.DB, id string, from, to uint) (bool, error) {
+	result := db.Model(&model.OutboxCursor{}).
+		Where("subscriber = ? AND last_event_id = ?", id, from).
+		Updates(map[string]interface{}{"last_event_id": to, "updated_at": time.Now()})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to move cursor: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// decodeRow turns an outbox row back into its event. Rows that cannot be
+// decoded are logged and skipped.
+func decodeRow(row model.OutboxEvent) (Event, bool) {
+	decode, ok := decoders[row.Name]
+	if !ok {
+		log.Printf("events: skipping outbox event %d with unknown name %q", row.ID, row.Name)
+		return nil, false
+	}
+	event, err := decode([]byte(row.Payload))
+	if err != nil {
+		log.Printf("events: skipping outbox event %d with bad payload: %v", row.ID, err)
+		return nil, false
+	}
+	return event, true
+}
+
+// subscribers returns the current subscribers
+func (b *Bus) subscribers() []*subscriber {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.subs
+}
+
+// dispatch hands an event to every matching synchronous subscriber
+func (b *Bus) dispatch(event Event) {
+	for _, sub := range b.subscribers() {
+		if sub.wake != nil || !sub.matches(event) {
+			continue
+		}
+		if err := sub.call(nil, event); err != nil {
+			log.Printf("events: %s handler failed for user %d: %v", event.EventName(), event.EventUserID(), err)
+		}
+	}
+}
+
+// openCursors creates the cursors of new asynchronous subscribers. They start
+// after the events already published, which earlier processes delivered.
+func (b *Bus) openCursors(db *gorm.DB) error {
+	for _, sub := range b.subscribers() {
+		if sub.wake == nil || sub.ready {
+			continue
+		}
+		var published uint
+		if err := db.Model(&model.OutboxEvent{}).Where("published_at IS NOT NULL").
+			Select("COALESCE(MAX(id), 0)").Scan(&published).Error; err != nil {
+			return fmt.Errorf("failed to read outbox: %w", err)
+		}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.OutboxCursor{Subscriber: sub.id, LastEventID: published}).Error; err != nil {
+			return fmt.Errorf("failed to create cursor for %s: %w", sub.id, err)
+		}
+		sub.ready = true
+	}
+	return nil
+}
+
+// wakeAsync asks every asynchronous subscriber to catch up with the outbox
+func (b *Bus) wakeAsync(db *gorm.DB) {
+	for _, sub := range b.subscribers() {
+		if sub.wake == nil || !sub.ready {
+			continue
+		}
+		select {
+		case sub.wake <- db:
+		default: // A catch-up is already due
+		}
+	}
+}
+
+// Record adds an event to the outbox as part of tx. It reaches subscribers
+// only when a Flush runs after tx has committed.
+func Record(tx *gorm.DB, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.EventName(), err)
+	}
+
+	if err := tx.Create(&model.OutboxEvent{
+		Name:    event.EventName(),
+		UserID:  event.EventUserID(),
+		Payload: string(payload),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record %s event: %w", event.EventName(), err)
+	}
+	return nil
+}
+
+// Flush publishes every committed outbox event that has not been published
+// yet, oldest first, to the synchronous subscribers. Each row is claimed
+// before it is dispatched, so with several flushers an event still reaches
+// them only once. Asynchronous subscribers are then woken to catch up from
+// their cursors.
+func (b *Bus) Flush(db *gorm.DB) (int, error) {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	if err := b.openCursors(db); err != nil {
+		return 0, err
+	}
+	defer b.wakeAsync(db)
+
+	published := 0
+	lastID := uint(0)
+	for {
+		var pending []model.OutboxEvent
+		if err := db.Where("published_at IS NULL AND id > ?", lastID).
+			Order("id").Limit(flushBatchSize).Find(&pending).Error; err != nil {
+			return published, fmt.Errorf("failed to read outbox: %w", err)
+		}
+		if len(pending) == 0 {
+			return published, nil
+		}
+
+		for _, row := range pending {
+			lastID = row.ID
+
+			result := db.Model(&model.OutboxEvent{}).
+				Where("id = ? AND published_at IS NULL", row.ID).
+				Update("published_at", time.Now())
+			if result.Error != nil {
+				return published, fmt.Errorf("failed to claim outbox event %d: %w", row.ID, result.Error)
+			}
+			if result.RowsAffected == 0 {
+				continue // Another flusher got it first
+			}
+
+			event, ok := decodeRow(row)
+			if !ok {
+				continue
+			}
+			b.dispatch(event)
+			published++
+		}
+	}
+}
+
+// StartRelay flushes the outbox on its own goroutine whenever Signal is
+// called, so requests that raise events don't wait for their delivery. It
+// also flushes on start, to deliver what was left before a restart, and
+// every relayInterval, to retry failed events. It runs until ctx is done.
+func (b *Bus) StartRelay(ctx context.Context, db *gorm.DB) {
+	wake := make(chan struct{}, 1)
+	wake <- struct{}{}
+	b.mu.Lock()
+	b.wake = wake
+	b.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(relayInterval)
+		defer ticker.Stop()
+		defer func() {
+			b.mu.Lock()
+			b.wake = nil
+			b.mu.Unlock()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			case <-ticker.C:
+			}
+			if _, err := b.Flush(db); err != nil {
+				log.Printf("events: relay failed to flush outbox: %v", err)
+			}
+		}
+	}()
+}
+
+// Signal tells the relay that events have been committed. Signals that
+// arrive while a flush is due are merged into it. Without a running relay it
+// flushes db in the calling goroutine. A failed flush is logged and left to
+// the next one.
+func (b *Bus) Signal(db *gorm.DB) {
+	b.mu.RLock()
+	wake := b.wake
+	b.mu.RUnlock()
+
+	if wake == nil {
+		if _, err := b.Flush(db); err != nil {
+			log.Printf("events: failed to flush outbox: %v", err)
+		}
+		return
+	}
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Publish records and immediately flushes an event, for changes that are
+// not made inside a transaction
+func (b *Bus) Publish(db *gorm.DB, event Event) error {
+	if err := Record(db, event); err != nil {
+		return err
+	}
+	_, err := b.Flush(db)
+	return err
+}
+
+// Prune deletes published outbox events older than the retention period
+// that every asynchronous subscriber has handled
+func Prune(db *gorm.DB, retention time.Duration) (int64, error) {
+	result := db.Where("published_at IS NOT NULL AND published_at < ?", time.Now().Add(-retention)).
+		Where("id <= COALESCE((?), id)", db.Model(&model.OutboxCursor{}).Select("MIN(last_event_id)")).
+		Delete(&model.OutboxEvent{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune outbox: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Subscribe registers a synchronous handler on the default bus
+func Subscribe(name string, handler Handler) {
+	defaultBus.Subscribe(name, handler)
+}
+
+// SubscribeAsync registers an asynchronous handler on the default bus
+func SubscribeAsync(id, name string, handler Handler) {
+	defaultBus.SubscribeAsync(id, name, handler)
+}
+
+// SubscribeAsyncTx registers an asynchronous database handler on the default bus
+func SubscribeAsyncTx(id, name string, handler TxHandler) {
+	defaultBus.SubscribeAsyncTx(id, name, handler)
+}
+
+// Flush publishes pending outbox events on the default bus
+func Flush(db *gorm.DB) (int, error) {
+	return defaultBus.Flush(db)
+}
+
+// StartRelay starts relaying outbox events on the default bus
+func StartRelay(ctx context.Context, db *gorm.DB) {
+	defaultBus.StartRelay(ctx, db)
+}
+
+// Signal tells the default bus's relay that events have been committed
+func Signal(db *gorm.DB) {
+	defaultBus.Signal(db)
+}
+
+// Publish records and flushes an event on the default bus
+func Publish(db *gorm.DB, event Event) error {
+	return defaultBus.Publish(db, event)
+}
@@ -0,0 +1,241 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	dbName := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dbName), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.OutboxEvent{}, &model.OutboxCursor{}))
+	return db
+}
+
+func TestFlushSkipsRolledBackEvents(t *testing.T) {
+	db := setupTestDB(t)
+	bus := New()
+	var seen []Event
+	bus.Subscribe(All, func(event Event) error {
+		seen = append(seen, event)
+		return nil
+	})
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, Record(tx, BetSettled{UserID: 1, Bet: 10}))
+		return errors.New("bet failed")
+	})
+	require.Error(t, err)
+
+	count, err := bus.Flush(db)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Empty(t, seen)
+}
+
+func TestFlushDeliversOnce(t *testing.T) {
+	db := setupTestDB(t)
+	bus := New()
+
+	var bets, all []Event
+	bus.Subscribe(NameBetSettled, func(event Event) error {
+		bets = append(bets, event)
+		return nil
+	})
+	bus.Subscribe(All, func(event Event) error {
+		all = append(all, event)
+		return errors.New("handler errors don't stop delivery")
+	})
+	async := make(chan Event, 4)
+	bus.SubscribeAsync("work", NameWorkCompleted, func(event Event) error {
+		async <- event
+		return nil
+	})
+
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, Record(tx, BetSettled{UserID: 1, GameType: model.GameTypeSlots, Bet: 10, Payout: 25}))
+		return Record(tx, WorkCompleted{UserID: 1, Earned: 500})
+	}))
+
+	count, err := bus.Flush(db)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	require.Len(t, bets, 1)
+	bet := bets[0].(BetSettled)
+	assert.Equal(t, model.GameTypeSlots, bet.GameType)
+	assert.Equal(t, 15.0, bet.Net())
+	assert.Len(t, all, 2)
+
+	select {
+	case event := <-async:
+		assert.Equal(t, WorkCompleted{UserID: 1, Earned: 500}, event)
+	case <-time.After(time.Second):
+		t.Fatal("async subscriber never received the event")
+	}
+
+	// Already published events are not delivered again
+	count, err = bus.Flush(db)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Len(t, all, 2)
+}
+
+// receive waits for the next event an async subscriber handled
+func receive(t *testing.T, handled <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-handled:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("async subscriber never handled the event")
+		return nil
+	}
+}
+
+func TestAsyncSubscriberRetriesFailedEvents(t *testing.T) {
+	db := setupTestDB(t)
+	bus := New()
+
+	// The first attempt's writes are rolled back along with the cursor
+	type handledEvent struct {
+		ID     uint `gorm:"primarykey"`
+		UserID uint
+	}
+	require.NoError(t, db.AutoMigrate(&handledEvent{}))
+	attempts := 0
+	failed := make(chan struct{}, 1)
+	handled := make(chan Event, 4)
+	bus.SubscribeAsyncTx("flaky", All, func(tx *gorm.DB, event Event) error {
+		if err := tx.Create(&handledEvent{UserID: event.EventUserID()}).Error; err != nil {
+			return err
+		}
+		attempts++
+		if attempts == 1 {
+			failed <- struct{}{}
+			return errors.New("database is busy")
+		}
+		handled <- event
+		return nil
+	})
+
+	require.NoError(t, bus.Publish(db, Bankruptcy{UserID: 7}))
+	select {
+	case <-failed:
+	case <-time.After(time.Second):
+		t.Fatal("async subscriber never tried the event")
+	}
+
+	// The next flush retries it
+	_, err := bus.Flush(db)
+	require.NoError(t, err)
+	assert.Equal(t, Bankruptcy{UserID: 7}, receive(t, handled))
+
+	var rows int64
+	require.NoError(t, db.Model(&handledEvent{}).Count(&rows).Error)
+	assert.Equal(t, int64(1), rows)
+
+	var cursor model.OutboxCursor
+	require.NoError(t, db.First(&cursor, "subscriber = ?", "flaky").Error)
+	assert.NotZero(t, cursor.LastEventID)
+}
+
+func TestAsyncSubscriberResumesFromCursor(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Events published before a subscriber existed were delivered by earlier processes
+	require.NoError(t, New().Publish(db, LoanTaken{UserID: 1, Amount: 100}))
+
+	first := New()
+	handled := make(chan Event, 4)
+	first.SubscribeAsync("loans", NameLoanTaken, func(event Event) error {
+		handled <- event
+		return nil
+	})
+	require.NoError(t, first.Publish(db, LoanTaken{UserID: 1, Amount: 200}))
+	assert.Equal(t, LoanTaken{UserID: 1, Amount: 200}, receive(t, handled))
+
+	// After a restart the subscriber picks up what was published while it was down
+	require.NoError(t, Record(db, LoanTaken{UserID: 1, Amount: 300}))
+	_, err := New().Flush(db)
+	require.NoError(t, err)
+
+	restarted := New()
+	restarted.SubscribeAsync("loans", NameLoanTaken, func(event Event) error {
+		handled <- event
+		return nil
+	})
+	_, err = restarted.Flush(db)
+	require.NoError(t, err)
+	assert.Equal(t, LoanTaken{UserID: 1, Amount: 300}, receive(t, handled))
+	assert.Empty(t, handled)
+}
+
+func TestSignalWakesRelay(t *testing.T) {
+	db := setupTestDB(t)
+	bus := New()
+	delivered := make(chan Event, 4)
+	bus.Subscribe(All, func(event Event) error {
+		delivered <- event
+		return nil
+	})
+
+	// Without a relay, signalling flushes at once
+	require.NoError(t, Record(db, Bankruptcy{UserID: 1}))
+	bus.Signal(db)
+	require.Len(t, delivered, 1)
+	<-delivered
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus.StartRelay(ctx, db)
+
+	require.NoError(t, Record(db, LoanTaken{UserID: 1, Amount: 1000}))
+	bus.Signal(db)
+	select {
+	case event := <-delivered:
+		assert.Equal(t, LoanTaken{UserID: 1, Amount: 1000}, event)
+	case <-time.After(time.Second):
+		t.Fatal("relay never delivered the event")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	db := setupTestDB(t)
+	bus := New()
+
+	require.NoError(t, bus.Publish(db, LoanTaken{UserID: 1, Amount: 1000}))
+	require.NoError(t, Record(db, Bankruptcy{UserID: 1}))
+	require.NoError(t, db.Model(&model.OutboxEvent{}).Where("published_at IS NOT NULL").
+		Update("published_at", time.Now().Add(-48*time.Hour)).Error)
+
+	// Events an async subscriber has yet to handle are kept
+	cursor := model.OutboxCursor{Subscriber: "slow"}
+	require.NoError(t, db.Create(&cursor).Error)
+	pruned, err := Prune(db, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, pruned)
+
+	require.NoError(t, db.Model(&cursor).Update("last_event_id", 1).Error)
+	pruned, err = Prune(db, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+
+	// Unpublished events are kept however old they are
+	var remaining int64
+	require.NoError(t, db.Model(&model.OutboxEvent{}).Count(&remaining).Error)
+	assert.Equal(t, int64(1), remaining)
+}
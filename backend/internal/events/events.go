@@ -0,0 +1,232 @@
+// Package events is the in-process domain event bus.
+//
+// Services record typed events in the outbox_events table inside the same
+// gorm transaction as the change they describe, then flush the outbox once
+// that transaction has committed, so an event is never seen for a change that
+// rolled back. Flushing hands each event to synchronous subscribers in the
+// flushing goroutine. Asynchronous subscribers read the outbox on their own
+// workers from a stored cursor, so they catch up after a failure or a restart.
+// A background relay flushes anything a crashed or failed caller left behind.
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+)
+
+// Event names
+const (
+	NameBetSettled    = "bet_settled"
+	NameWorkCompleted = "work_completed"
+	NameItemPurchased = "item_purchased"
+	NameItemSold      = "item_sold"
+	NameLoanTaken     = "loan_taken"
+	NameBankruptcy    = "bankruptcy"
+
+	NameBalanceChanged      = "balance_changed"
+	NameLoanAccrued         = "loan_accrued"
+	NameCollectionAction    = "collection_action"
+	NameStatusChanged       = "status_changed"
+	NameAchievementUnlocked = "achievement_unlocked"
+	NameRealityCheckDue     = "reality_check_due"
+	NameDataExportReady     = "data_export_ready"
+)
+
+// Event is something that happened to a user
+type Event interface {
+	EventName() string
+	EventUserID() uint
+}
+
+// BetSettled is published when a game round is settled
+type BetSettled struct {
+	UserID   uint           `json:"user_id"`
+	GameType model.GameType `json:"game_type"`
+	Bet      float64        `json:"bet"`
+	Payout   float64        `json:"payout"` // Total returned to the player, stake included
+}
+
+func (e BetSettled) EventName() string { return NameBetSettled }
+func (e BetSettled) EventUserID() uint { return e.UserID }
+
+// Net returns the player's net result for the round
+func (e BetSettled) Net() float64 { return e.Payout - e.Bet }
+
+// WorkCompleted is published when a work shift is paid
+type WorkCompleted struct {
+	UserID          uint          `json:"user_id"`
+	JobType         model.JobType `json:"job_type"`
+	Earned          float64       `json:"earned"`
+	DurationSeconds int           `json:"duration_seconds"`
+}
+
+func (e WorkCompleted) EventName() string { return NameWorkCompleted }
+func (e WorkCompleted) EventUserID() uint { return e.UserID }
+
+// Item purchase and sale sources
+const (
+	SourceShop   = "shop"
+	SourceMarket = "market"
+)
+
+// ItemPurchased is published when a user buys an item from the shop or the market
+type ItemPurchased struct {
+	UserID     uint    `json:"user_id"`
+	ItemID     uint    `json:"item_id"`
+	UserItemID uint    `json:"user_item_id"`
+	Price      float64 `json:"price"`
+	Source     string  `json:"source"`
+}
+
+func (e ItemPurchased) EventName() string { return NameItemPurchased }
+func (e ItemPurchased) EventUserID() uint { return e.UserID }
+
+// ItemSold is published when a user sells an item to the shop or on the market
+type ItemSold struct {
+	UserID uint    `json:"user_id"`
+	ItemID uint    `json:"item_id"`
+	Price  float64 `json:"price"`
+	Source string  `json:"source"`
+}
+
+func (e ItemSold) EventName() string { return NameItemSold }
+func (e ItemSold) EventUserID() uint { return e.UserID }
+
+// LoanTaken is published when a loan is disbursed
+type LoanTaken struct {
+	UserID   uint           `json:"user_id"`
+	LoanID   uint           `json:"loan_id"`
+	LoanType model.LoanType `json:"loan_type"`
+	Amount   float64        `json:"amount"`
+	APR      float64        `json:"apr"`
+}
+
+func (e LoanTaken) EventName() string { return NameLoanTaken }
+func (e LoanTaken) EventUserID() uint { return e.UserID }
+
+// Bankruptcy is published when collectors declare a user bankrupt
+type Bankruptcy struct {
+	UserID         uint    `json:"user_id"`
+	DebtWrittenOff float64 `json:"debt_written_off"`
+	ItemsSeized    int     `json:"items_seized"`
+}
+
+func (e Bankruptcy) EventName() string { return NameBankruptcy }
+func (e Bankruptcy) EventUserID() uint { return e.UserID }
+
+// BalanceChanged is published for balance changes that no more specific event
+// describes, such as loan repayments and challenge rewards
+type BalanceChanged struct {
+	UserID  uint    `json:"user_id"`
+	Balance float64 `json:"balance"`
+	Change  float64 `json:"change"`
+	Reason  string  `json:"reason"`
+}
+
+func (e BalanceChanged) EventName() string { return NameBalanceChanged }
+func (e BalanceChanged) EventUserID() uint { return e.UserID }
+
+// LoanAccrued is published when interest is added to a loan
+type LoanAccrued struct {
+	UserID    uint    `json:"user_id"`
+	LoanID    uint    `json:"loan_id"`
+	Interest  float64 `json:"interest"`  // Accrued by this tick
+	Remaining float64 `json:"remaining"` // Total now owed on the loan
+}
+
+func (e LoanAccrued) EventName() string { return NameLoanAccrued }
+func (e LoanAccrued) EventUserID() uint { return e.UserID }
+
+// CollectionAction is published for every step collectors take against a user
+type CollectionAction struct {
+	UserID      uint                      `json:"user_id"`
+	LoanID      *uint                     `json:"loan_id,omitempty"`
+	Action      model.CollectionEventType `json:"action"`
+	Amount      float64                   `json:"amount"`
+	ItemName    string                    `json:"item_name,omitempty"`
+	Description string                    `json:"description"`
+}
+
+func (e CollectionAction) EventName() string { return NameCollectionAction }
+func (e CollectionAction) EventUserID() uint { return e.UserID }
+
+// StatusChanged is published when a timed status such as in_jail is granted or expires
+type StatusChanged struct {
+	UserID    uint       `json:"user_id"`
+	Status    string     `json:"status"`
+	Active    bool       `json:"active"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (e StatusChanged) EventName() string { return NameStatusChanged }
+func (e StatusChanged) EventUserID() uint { return e.UserID }
+
+// AchievementUnlocked is published when a user unlocks an achievement
+type AchievementUnlocked struct {
+	UserID uint   `json:"user_id"`
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+}
+
+func (e AchievementUnlocked) EventName() string { return NameAchievementUnlocked }
+func (e AchievementUnlocked) EventUserID() uint { return e.UserID }
+
+// RealityCheckDue is published when a gambling session has run long enough
+// that the user must acknowledge a reality check before betting again
+type RealityCheckDue struct {
+	UserID        uint    `json:"user_id"`
+	CheckID       uint    `json:"check_id"`
+	PlayedMinutes int     `json:"played_minutes"`
+	Wagered       float64 `json:"wagered"`
+	NetResult     float64 `json:"net_result"` // Negative when the session is down
+	Country       string  `json:"country"`
+	WorkHours     float64 `json:"work_hours"` // The loss in hours of work at the country's average wage
+}
+
+func (e RealityCheckDue) EventName() string { return NameRealityCheckDue }
+func (e RealityCheckDue) EventUserID() uint { return e.UserID }
+
+// DataExportReady is published when a user's data export can be downloaded
+type DataExportReady struct {
+	UserID    uint      `json:"user_id"`
+	ExportID  uint      `json:"export_id"`
+	Size      int64     `json:"size"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (e DataExportReady) EventName() string { return NameDataExportReady }
+func (e DataExportReady) EventUserID() uint { return e.UserID }
+
+// decoders unmarshal outbox payloads into typed events by name
+var decoders = map[string]func(payload []byte) (Event, error){
+	NameBetSettled:    decode[BetSettled],
+	NameWorkCompleted: decode[WorkCompleted],
+	NameItemPurchased: decode[ItemPurchased],
+	NameItemSold:      decode[ItemSold],
+	NameLoanTaken:     decode[LoanTaken],
+	NameBankruptcy:    decode[Bankruptcy],
+
+	NameBalanceChanged:      decode[BalanceChanged],
+	NameLoanAccrued:         decode[LoanAccrued],
+	NameCollectionAction:    decode[CollectionAction],
+	NameStatusChanged:       decode[StatusChanged],
+	NameAchievementUnlocked: decode[AchievementUnlocked],
+	NameRealityCheckDue:     decode[RealityCheckDue],
+	NameDataExportReady:     decode[DataExportReady],
+}
+
+func decode[T Event](payload []byte) (Event, error) {
+	var event T
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// Known reports whether an event name is one the bus can carry
+func Known(name string) bool {
+	_, ok := decoders[name]
+	return ok
+}
@@ -27,7 +27,7 @@ type BlackjackGame struct {
 	Deck       []Card  `json:"-"` // Don't send deck to client
 	Bet        float64 `json:"bet"`
 	GameOver   bool    `json:"game_over"`
-	Result     string  `json:"result"`     // "player_win", "dealer_win", "push", "blackjack"
+	Result     string  `json:"result"`     // "player_win", "dealer_win", "push", "blackjack", "forfeit"
 	Multiplier float64 `json:"multiplier"` // Win multiplier (1.0, 1.5, 2.0)
 	CanDouble  bool    `json:"can_double"`
 	CanSplit   bool    `json:"can_split"`
@@ -260,6 +260,13 @@ func (g *BlackjackGame) Split() error {
 	return fmt.Errorf("split is not yet fully implemented")
 }
 
+// Forfeit ends an unfinished game as a loss, as when the player leaves mid-hand
+func (g *BlackjackGame) Forfeit() {
+	g.GameOver = true
+	g.Result = "forfeit"
+	g.Multiplier = 0
+}
+
 // determineWinner determines the winner of the game
 func (g *BlackjackGame) determineWinner() {
 	if g.DealerHand.Value > 21 {
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// AccountDeletionHandler handles account deletion HTTP requests
+type AccountDeletionHandler struct {
+	accountDeletionService *service.AccountDeletionService
+}
+
+// NewAccountDeletionHandler creates a new account deletion handler instance
+func NewAccountDeletionHandler() *AccountDeletionHandler {
+	return &AccountDeletionHandler{
+		accountDeletionService: service.NewAccountDeletionService(),
+	}
+}
+
+// GetDeletion handles GET /api/user/deletion
+// @Summary Get pending account deletion
+// @Description Get the user's pending account deletion request; data is null if there is none.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.AccountDeletion
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/user/deletion [get]
+func (h *AccountDeletionHandler) GetDeletion(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	deletion, err := h.accountDeletionService.GetDeletion(userID)
+	if err != nil {
+		return h.respondError(c, err, "failed to get account deletion")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    deletion,
+	})
+}
+
+// RequestDeletion handles POST /api/user/deletion
+// @Summary Delete account
+// @Description Schedule the user's account for deletion in 14 days. Until then it works as usual and the request can be cancelled;
+// @Description afterwards the name, email, login and avatar are erased and game and transaction history is kept under a pseudonym.
+// @Description Loans must be repaid and market listings and auction bids closed first; if new ones are open when the grace period ends, deletion waits for them.
+// @Description With dry_run=true nothing is scheduled and the report of what deletion would touch is returned.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Only report what would be deleted"
+// @Success 202 {object} model.AccountDeletion
+// @Success 200 {object} service.AccountDeletionReport
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/user/deletion [post]
+func (h *AccountDeletionHandler) RequestDeletion(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	if c.QueryBool("dry_run") {
+		report, err := h.accountDeletionService.PreviewDeletion(userID)
+		if err != nil {
+			return h.respondError(c, err, "failed to preview account deletion")
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"success": true,
+			"data":    report,
+		})
+	}
+
+	deletion, _, err := h.accountDeletionService.RequestDeletion(userID)
+	if err != nil {
+		return h.respondError(c, err, "failed to request account deletion")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"data":    deletion,
+		"message": "account scheduled for deletion",
+	})
+}
+
+// CancelDeletion handles DELETE /api/user/deletion
+// @Summary Cancel account deletion
+// @Description Cancel the user's pending account deletion request.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.AccountDeletion
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/user/deletion [delete]
+func (h *AccountDeletionHandler) CancelDeletion(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	deletion, err := h.accountDeletionService.CancelDeletion(userID)
+	if err != nil {
+		return h.respondError(c, err, "failed to cancel account deletion")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    deletion,
+		"message": "account deletion cancelled",
+	})
+}
+
+// respondError maps account deletion service errors to HTTP responses
+func (h *AccountDeletionHandler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "user_not_found", "deletion_not_found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	case "account_has_open_loans", "account_has_open_listings", "account_has_held_bids":
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   true,
+		"message": fallback,
+	})
+}
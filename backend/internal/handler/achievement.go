@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// AchievementHandler handles achievement-related HTTP requests
+type AchievementHandler struct {
+	achievementService *service.AchievementService
+}
+
+// NewAchievementHandler creates a new achievement handler instance
+func NewAchievementHandler() (*AchievementHandler, error) {
+	achievementService, err := service.NewAchievementService()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AchievementHandler{
+		achievementService: achievementService,
+	}, nil
+}
+
+// GetAchievements handles GET /api/achievements
+// @Summary Get achievements
+// @Description List every achievement with the user's progress. Unlocked achievements include a real-world statistic in hours of work in the chosen country.
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param country query string false "Country code for work-time statistics (default: the profile's country, else US)"
+// @Success 200 {object} service.AchievementsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/achievements [get]
+func (h *AchievementHandler) GetAchievements(c *fiber.Ctx) error {
+	return h.respond(c, false)
+}
+
+// GetUnlockedAchievements handles GET /api/achievements/unlocked
+// @Summary Get unlocked achievements
+// @Description List only the achievements the user has unlocked
+// @Tags achievements
+// @Accept json
+// @Produce json
+// @Param country query string false "Country code for work-time statistics (default: the profile's country, else US)"
+// @Success 200 {object} service.AchievementsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/achievements/unlocked [get]
+func (h *AchievementHandler) GetUnlockedAchievements(c *fiber.Ctx) error {
+	return h.respond(c, true)
+}
+
+func (h *AchievementHandler) respond(c *fiber.Ctx, unlockedOnly bool) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	get := h.achievementService.GetAchievements
+	if unlockedOnly {
+		get = h.achievementService.GetUnlocked
+	}
+
+	achievements, err := get(userID, c.Query("country"))
+	if err != nil {
+		if err.Error() == "country_not_found" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "country_not_found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get achievements",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    achievements,
+	})
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// AdminHandler handles HTTP requests of the admins listed in ADMIN_EMAILS
+type AdminHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+// NewAdminHandler creates a new admin handler instance
+func NewAdminHandler() *AdminHandler {
+	return &AdminHandler{
+		twoFactorService: service.NewTwoFactorService(),
+	}
+}
+
+// GetTwoFactorOverview handles GET /api/admin/two-factor
+// @Summary Two-factor adoption
+// @Description Count the password accounts, and how many of them have two-factor authentication enabled or pending. Admins only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.TwoFactorOverview
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/admin/two-factor [get]
+func (h *AdminHandler) GetTwoFactorOverview(c *fiber.Ctx) error {
+	overview, err := h.twoFactorService.GetOverview()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get two-factor overview",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    overview,
+	})
+}
+
+// GetUserTwoFactor handles GET /api/admin/users/:userId/two-factor
+// @Summary Get a user's two-factor status
+// @Description Whether a user has two-factor authentication and how many recovery codes they have left, without any secrets. Admins only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} service.TwoFactorStatus
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/admin/users/{userId}/two-factor [get]
+func (h *AdminHandler) GetUserTwoFactor(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid user id",
+		})
+	}
+
+	status, err := h.twoFactorService.GetStatus(uint(userID))
+	if err != nil {
+		if err.Error() == "user_not_found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get two-factor status",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    status,
+	})
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// AnalyticsHandler handles personal gambling analytics HTTP requests
+type AnalyticsHandler struct {
+	analyticsService *service.AnalyticsService
+}
+
+// NewAnalyticsHandler creates a new analytics handler instance
+func NewAnalyticsHandler() *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: service.NewAnalyticsService(),
+	}
+}
+
+// GetOverview handles GET /api/analytics
+// @Summary Get gambling analytics
+// @Description Get the user's totals and per-game breakdown, longest and current win and loss streaks, biggest
+// @Description rounds, largest drawdown and run-up, and gambling losses against work earnings over a range (default all time).
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param from query string false "Start, RFC 3339 or YYYY-MM-DD in the user's timezone"
+// @Param to query string false "End (exclusive), RFC 3339 or YYYY-MM-DD in the user's timezone (default now)"
+// @Success 200 {object} service.AnalyticsOverview
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/analytics [get]
+func (h *AnalyticsHandler) GetOverview(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	overview, err := h.analyticsService.GetOverview(userID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		return h.respondError(c, err, "failed to get analytics")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    overview,
+	})
+}
+
+// GetTimeSeries handles GET /api/analytics/timeseries
+// @Summary Get profit and loss over time
+// @Description Get wagered, won, net and cumulative net per day or hour in the user's timezone, overall and per game.
+// @Description Defaults to the last 30 days for daily buckets and the last 48 hours for hourly ones; at most 744 buckets.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param bucket query string false "day (default) or hour"
+// @Param from query string false "Start, RFC 3339 or YYYY-MM-DD in the user's timezone"
+// @Param to query string false "End (exclusive), RFC 3339 or YYYY-MM-DD in the user's timezone (default now)"
+// @Success 200 {object} service.TimeSeriesResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/analytics/timeseries [get]
+func (h *AnalyticsHandler) GetTimeSeries(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	series, err := h.analyticsService.GetTimeSeries(userID, c.Query("bucket"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		return h.respondError(c, err, "failed to get time series")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    series,
+	})
+}
+
+// GetSessions handles GET /api/analytics/sessions
+// @Summary Get play sessions
+// @Description Split the user's rounds into sessions wherever they took a break longer than the gap,
+// @Description and return a summary of all sessions with the most recent ones.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param gap query int false "Break in minutes that ends a session (default 30, max 1440)"
+// @Param limit query int false "Sessions to list (default 20, max 100)"
+// @Param from query string false "Start, RFC 3339 or YYYY-MM-DD in the user's timezone"
+// @Param to query string false "End (exclusive), RFC 3339 or YYYY-MM-DD in the user's timezone (default now)"
+// @Success 200 {object} service.SessionsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/analytics/sessions [get]
+func (h *AnalyticsHandler) GetSessions(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	gap := time.Duration(c.QueryInt("gap", 0)) * time.Minute
+	sessions, err := h.analyticsService.GetSessions(userID, gap, c.QueryInt("limit", 0), c.Query("from"), c.Query("to"))
+	if err != nil {
+		return h.respondError(c, err, "failed to get sessions")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    sessions,
+	})
+}
+
+// GetHeatmap handles GET /api/analytics/heatmap
+// @Summary Get play heatmap
+// @Description Get rounds, stakes and net per weekday (0 is Sunday) and hour of day in the user's timezone.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param from query string false "Start, RFC 3339 or YYYY-MM-DD in the user's timezone"
+// @Param to query string false "End (exclusive), RFC 3339 or YYYY-MM-DD in the user's timezone (default now)"
+// @Success 200 {object} service.HeatmapResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/analytics/heatmap [get]
+func (h *AnalyticsHandler) GetHeatmap(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	heatmap, err := h.analyticsService.GetHeatmap(userID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		return h.respondError(c, err, "failed to get heatmap")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    heatmap,
+	})
+}
+
+// respondError maps analytics service errors to HTTP responses
+func (h *AnalyticsHandler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "invalid_bucket", "invalid_range", "range_too_large", "invalid_gap":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	case "user_not_found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   true,
+		"message": fallback,
+	})
+}
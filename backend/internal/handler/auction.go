@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// AuctionHandler handles auction house HTTP and WebSocket requests
+type AuctionHandler struct {
+	auctionService *service.AuctionService
+	feed           *service.AuctionFeed
+}
+
+// NewAuctionHandler creates a new auction handler instance
+func NewAuctionHandler() *AuctionHandler {
+	return &AuctionHandler{
+		auctionService: service.NewAuctionService(),
+		feed:           service.DefaultAuctionFeed(),
+	}
+}
+
+// GetAuctions handles GET /api/auctions
+// @Summary List auctions
+// @Description Get active auctions, ending soonest first
+// @Tags auctions
+// @Accept json
+// @Produce json
+// @Param limit query int false "Limit number of auctions" default(50)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auctions [get]
+func (h *AuctionHandler) GetAuctions(c *fiber.Ctx) error {
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	// Signed-in viewers see which auctions they lead
+	viewerID, _ := c.Locals("userID").(uint)
+
+	auctions, total, err := h.auctionService.GetAuctions(viewerID, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get auctions",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"auctions": auctions,
+			"total":    total,
+			"limit":    limit,
+			"offset":   offset,
+		},
+	})
+}
+
+// GetAuction handles GET /api/auctions/:auctionId
+// @Summary Get auction
+// @Description Get an auction with its bid history
+// @Tags auctions
+// @Accept json
+// @Produce json
+// @Param auctionId path int true "Auction ID"
+// @Success 200 {object} service.AuctionView
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auctions/{auctionId} [get]
+func (h *AuctionHandler) GetAuction(c *fiber.Ctx) error {
+	auctionID, err := strconv.ParseUint(c.Params("auctionId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid auction ID",
+		})
+	}
+
+	viewerID, _ := c.Locals("userID").(uint)
+
+	auction, err := h.auctionService.GetAuction(viewerID, uint(auctionID))
+	if err != nil {
+		if err.Error() == "auction_not_found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "auction_not_found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get auction",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    auction,
+	})
+}
+
+// PlaceBid handles POST /api/auctions/:auctionId/bids
+// @Summary Place a bid
+// @Description Bid on an auction. The amount is held from the balance until the bid is outbid or the auction settles.
+// @Tags auctions
+// @Accept json
+// @Produce json
+// @Param auctionId path int true "Auction ID"
+// @Param body body service.PlaceBidRequest true "Bid"
+// @Success 200 {object} service.PlaceBidResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auctions/{auctionId}/bids [post]
+func (h *AuctionHandler) PlaceBid(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	auctionID, err := strconv.ParseUint(c.Params("auctionId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid auction ID",
+		})
+	}
+
+	var req service.PlaceBidRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	result, err := h.auctionService.PlaceBid(userID, uint(auctionID), req)
+	if err != nil {
+		switch err.Error() {
+		case "auction_not_found":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "auction_not_found",
+			})
+		case "auction_closed", "already_high_bidder":
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		case "bid_too_low":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "bid_too_low",
+				"details": "Bids must beat the current bid by at least 5% ($1 minimum)",
+			})
+		case "invalid_amount", "insufficient_balance", "auction_not_started", "cannot_bid_on_own_item":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "failed to place bid",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetMyBids handles GET /api/auctions/my-bids
+// @Summary Get my bids
+// @Description Get the authenticated user's bids and the state of their holds
+// @Tags auctions
+// @Accept json
+// @Produce json
+// @Param limit query int false "Limit number of bids" default(50)
+// @Success 200 {array} model.AuctionBid
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auctions/my-bids [get]
+func (h *AuctionHandler) GetMyBids(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+
+	bids, err := h.auctionService.GetUserBids(userID, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get bids",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    bids,
+	})
+}
+
+// AuctionFeedWebSocket streams live auction events. Pass ?auction_id= to
+// follow a single auction; without it every auction is streamed.
+func (h *AuctionHandler) AuctionFeedWebSocket(c *websocket.Conn) {
+	var auctionID uint
+	if idStr := c.Query("auction_id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			_ = c.WriteJSON(fiber.Map{"type": MsgTypeError, "message": "invalid auction ID"})
+			c.Close()
+			return
+		}
+		auctionID = uint(id)
+	}
+
+	events, unsubscribe := h.feed.Subscribe(auctionID)
+	defer func() {
+		unsubscribe()
+		c.Close()
+	}()
+
+	// The feed is read-only; reading only detects the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := c.WriteJSON(event); err != nil {
+				log.Printf("Auction feed write error: %v", err)
+				return
+			}
+		}
+	}
+}
@@ -1,32 +1,54 @@
 package handler
 
 import (
+	"log"
+	"strconv"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/smoreg/freezino/backend/internal/auth"
 	"github.com/smoreg/freezino/backend/internal/config"
+	"github.com/smoreg/freezino/backend/internal/mail"
+	"github.com/smoreg/freezino/backend/internal/model"
 	"github.com/smoreg/freezino/backend/internal/service"
 )
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
 	authService *service.AuthService
+	sessions    *service.AuthSessionService
+	emails      *service.AccountEmailService
+	twoFactor   *service.TwoFactorService
 	jwtManager  *auth.JWTManager
 	cfg         *config.Config
 }
 
-// NewAuthHandler creates a new auth handler
-func NewAuthHandler(authService *service.AuthService, cfg *config.Config) *AuthHandler {
+// NewAuthHandler creates a new auth handler that sends account emails through mailer
+func NewAuthHandler(authService *service.AuthService, mailer mail.Mailer, cfg *config.Config) *AuthHandler {
+	jwtManager := auth.NewJWTManager(cfg)
 	return &AuthHandler{
 		authService: authService,
-		jwtManager:  auth.NewJWTManager(cfg),
+		sessions:    service.NewAuthSessionService(jwtManager.RefreshExpiration()),
+		emails:      service.NewAccountEmailService(mailer, cfg.JWTSecret, cfg.FrontendURL),
+		twoFactor:   service.NewTwoFactorService(),
+		jwtManager:  jwtManager,
 		cfg:         cfg,
 	}
 }
 
-// generateTokens generates access and refresh JWT tokens using JWTManager
-func (h *AuthHandler) generateTokens(userID uint, email string) (string, string, error) {
-	return h.jwtManager.GenerateTokenPair(userID, email)
+// generateTokens starts a session for the user on the requesting device and
+// returns its access and refresh tokens
+func (h *AuthHandler) generateTokens(c *fiber.Ctx, user *model.User) (string, string, error) {
+	session, refreshToken, err := h.sessions.StartSession(user.ID, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return "", "", err
+	}
+	accessToken, err := h.jwtManager.GenerateAccessToken(user.ID, user.Email, session.ID)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
 }
 
 // Register handles user registration
@@ -48,8 +70,13 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
+	// Ask the user to confirm their address; they can request another email later
+	if err := h.emails.SendVerification(user.ID, c.Get(fiber.HeaderAcceptLanguage)); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
 	// Generate tokens
-	accessToken, refreshToken, err := h.generateTokens(user.ID, user.Email)
+	accessToken, refreshToken, err := h.generateTokens(c, user)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
@@ -78,7 +105,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	}
 
 	// Authenticate user
-	user, err := h.authService.Login(req)
+	user, challenge, err := h.authService.Login(req)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"success": false,
@@ -86,8 +113,20 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
+	// Accounts with two-factor authentication finish at /auth/2fa/verify
+	if challenge != nil {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data": fiber.Map{
+				"two_factor_required": true,
+				"challenge_token":     challenge.Token,
+				"expires_at":          challenge.ExpiresAt,
+			},
+		})
+	}
+
 	// Generate tokens
-	accessToken, refreshToken, err := h.generateTokens(user.ID, user.Email)
+	accessToken, refreshToken, err := h.generateTokens(c, user)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
@@ -105,47 +144,482 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	})
 }
 
-// RefreshToken handles token refresh
-func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
+// VerifyTwoFactor handles POST /api/auth/2fa/verify
+// @Summary Complete two-factor login
+// @Description Exchange the challenge token from /auth/login and a code from the authenticator app, or a recovery code, for session tokens.
+// @Description A challenge lasts 5 minutes and allows 5 wrong codes, after which the password must be entered again.
+// @Description After 10 wrong codes in a row, across challenges, logins are locked for 15 minutes, doubling with every further wrong code up to a day.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body object true "Challenge token and code" example({"challenge_token":"...","code":"123456"})
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactor(c *fiber.Ctx) error {
 	var req struct {
-		RefreshToken string `json:"refresh_token"`
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
 	}
-
-	if err := c.BodyParser(&req); err != nil {
+	if err := c.BodyParser(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Invalid request body",
+			"error":   true,
+			"message": "challenge_token and code are required",
 		})
 	}
 
-	// Validate refresh token
-	claims, err := h.jwtManager.ValidateToken(req.RefreshToken)
+	user, err := h.twoFactor.CompleteChallenge(req.ChallengeToken, req.Code)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		switch err.Error() {
+		case "invalid_challenge", "challenge_expired", "invalid_code":
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		case "too_many_attempts", "two_factor_locked":
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to verify code",
+		})
+	}
+
+	// Generate tokens
+	accessToken, refreshToken, err := h.generateTokens(c, user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
-			"error":   "Invalid or expired refresh token",
+			"error":   "Failed to generate tokens",
 		})
 	}
 
-	// Check token type
-	if claims.Type != auth.RefreshToken {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"user":          user,
+			"access_token":  accessToken,
+			"refresh_token": refreshToken,
+		},
+	})
+}
+
+// RefreshToken handles POST /api/auth/refresh
+// @Summary Refresh tokens
+// @Description Exchange a refresh token, from the body or the refresh_token cookie, for a new access token and a new refresh token.
+// @Description Each refresh token works once: presenting one that was already exchanged logs its session out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/refresh [post]
+func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Invalid request body",
+			})
+		}
+	}
+
+	// Browsers signed in through Google carry the token in a cookie instead
+	fromCookie := false
+	if req.RefreshToken == "" {
+		req.RefreshToken = c.Cookies("refresh_token")
+		fromCookie = true
+	}
+	if req.RefreshToken == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"success": false,
-			"error":   "Invalid token type",
+			"error":   "Refresh token not found",
+		})
+	}
+
+	session, refreshToken, err := h.sessions.Refresh(req.RefreshToken, c.IP())
+	if err != nil {
+		switch err.Error() {
+		case "invalid_refresh_token", "refresh_token_reused", "session_revoked", "session_expired":
+			if fromCookie {
+				auth.SetRefreshCookie(c, h.cfg, "", time.Time{})
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"success": false,
+				"error":   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   "Failed to refresh session",
 		})
 	}
 
-	// Generate new access token
-	accessToken, err := h.jwtManager.GenerateAccessToken(claims.UserID, claims.Email)
+	accessToken, err := h.jwtManager.GenerateAccessToken(session.UserID, session.User.Email, session.ID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
 			"error":   "Failed to generate access token",
 		})
 	}
+	if fromCookie {
+		auth.SetRefreshCookie(c, h.cfg, refreshToken, session.ExpiresAt)
+	}
+
+	return c.JSON(fiber.Map{
+		"success":       true,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+// Logout handles POST /api/auth/logout
+// @Summary Log out
+// @Description End the current session, or with all=true every session of the user ("log out everywhere").
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param all query bool false "Log out on every device"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/logout [post]
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+	sessionID, _ := c.Locals("sessionID").(uint)
+
+	if c.QueryBool("all") {
+		if _, err := h.sessions.RevokeAllSessions(userID, 0); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "failed to log out",
+			})
+		}
+	} else if sessionID != 0 {
+		if err := h.sessions.RevokeSession(userID, sessionID, service.SessionRevokedLogout); err != nil && err.Error() != "session_not_found" {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "failed to log out",
+			})
+		}
+	}
+
+	// Clear refresh token cookie
+	auth.SetRefreshCookie(c, h.cfg, "", time.Time{})
 
 	return c.JSON(fiber.Map{
-		"success":      true,
-		"access_token": accessToken,
+		"message": "logged out successfully",
+	})
+}
+
+// GetSessions handles GET /api/auth/sessions
+// @Summary List sessions
+// @Description List the devices the user is signed in on, most recently used first. current marks the one making the request.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.AuthSession
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/sessions [get]
+func (h *AuthHandler) GetSessions(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+	sessionID, _ := c.Locals("sessionID").(uint)
+
+	sessions, err := h.sessions.ListSessions(userID, sessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get sessions",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    sessions,
+	})
+}
+
+// RevokeSession handles DELETE /api/auth/sessions/:id
+// @Summary Revoke a session
+// @Description Sign a device out. Its refresh token stops working at once and so do its access tokens.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid session id",
+		})
+	}
+
+	if err := h.sessions.RevokeSession(userID, uint(id), service.SessionRevokedByUser); err != nil {
+		if err.Error() == "session_not_found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to revoke session",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"message": "session revoked",
+	})
+}
+
+// RevokeOtherSessions handles DELETE /api/auth/sessions
+// @Summary Sign out other devices
+// @Description Revoke every session of the user except the one making the request.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/sessions [delete]
+func (h *AuthHandler) RevokeOtherSessions(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+	sessionID, _ := c.Locals("sessionID").(uint)
+
+	count, err := h.sessions.RevokeAllSessions(userID, sessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to revoke sessions",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    fiber.Map{"revoked": count},
+	})
+}
+
+// mailLanguage returns the language to write emails to the requester in: the
+// one asked for, or else the ones their browser accepts
+func mailLanguage(c *fiber.Ctx, requested string) string {
+	if requested != "" {
+		return requested
+	}
+	return c.Get(fiber.HeaderAcceptLanguage)
+}
+
+// SendVerificationEmail handles POST /api/auth/verify-email/send
+// @Summary Resend verification email
+// @Description Email the user a new link to confirm their address, replacing earlier links. Until the address is confirmed the market, auction bids and loans are locked.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param lang query string false "Email language (en, es or ru); defaults to Accept-Language"
+// @Success 202 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/verify-email/send [post]
+func (h *AuthHandler) SendVerificationEmail(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	if err := h.emails.SendVerification(userID, mailLanguage(c, c.Query("lang"))); err != nil {
+		return h.respondEmailError(c, err, "failed to send verification email")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"message": "verification email sent",
+	})
+}
+
+// VerifyEmail handles POST /api/auth/verify-email
+// @Summary Verify email address
+// @Description Confirm the user's email address with the token from a verification email. Each token works once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body object true "Verification token" example({"token":"..."})
+// @Success 200 {object} model.User
+// @Failure 400 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	user, err := h.emails.VerifyEmail(req.Token)
+	if err != nil {
+		return h.respondEmailError(c, err, "failed to verify email")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    user,
+		"message": "email verified",
+	})
+}
+
+// ForgotPassword handles POST /api/auth/forgot-password
+// @Summary Request password reset
+// @Description Email a password reset link to the account with this address. The response is the same whether or not there is one.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body object true "Account email and optional email language" example({"email":"user@example.com","language":"es"})
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req struct {
+		Email    string `json:"email"`
+		Language string `json:"language"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "email is required",
+		})
+	}
+
+	if err := h.emails.RequestPasswordReset(req.Email, mailLanguage(c, req.Language)); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to send password reset email",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"success": true,
+		"message": "if an account uses this email, a reset link has been sent to it",
+	})
+}
+
+// ResetPassword handles POST /api/auth/reset-password
+// @Summary Reset password
+// @Description Set a new password with the token from a password reset email. Each token works once, and every session of the user is signed out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body object true "Reset token and new password" example({"token":"...","password":"new-password"})
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	if _, err := h.emails.ResetPassword(req.Token, req.Password); err != nil {
+		return h.respondEmailError(c, err, "failed to reset password")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"message": "password reset, please log in again",
+	})
+}
+
+// respondEmailError maps account email service errors to HTTP responses
+func (h *AuthHandler) respondEmailError(c *fiber.Ctx, err error, fallback string) error {
+	status := fiber.StatusInternalServerError
+	switch err.Error() {
+	case "invalid_token", "password_too_short":
+		status = fiber.StatusBadRequest
+	case "token_expired":
+		status = fiber.StatusGone
+	case "user_not_found":
+		status = fiber.StatusNotFound
+	case "email_already_verified":
+		status = fiber.StatusConflict
+	case "email_recently_sent":
+		status = fiber.StatusTooManyRequests
+	default:
+		log.Printf("%s: %v", fallback, err)
+		return c.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": fallback,
+		})
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   true,
+		"message": err.Error(),
 	})
 }
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// ChallengeHandler handles daily and weekly challenge HTTP requests
+type ChallengeHandler struct {
+	challengeService *service.ChallengeService
+}
+
+// NewChallengeHandler creates a new challenge handler instance
+func NewChallengeHandler() (*ChallengeHandler, error) {
+	challengeService, err := service.NewChallengeService()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChallengeHandler{
+		challengeService: challengeService,
+	}, nil
+}
+
+// GetChallenges handles GET /api/challenges
+// @Summary Get challenges
+// @Description Get the user's daily and weekly challenges with their progress. Rewards for challenges completed since the last update are paid out.
+// @Tags challenges
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.ChallengesResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/challenges [get]
+func (h *ChallengeHandler) GetChallenges(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	challenges, err := h.challengeService.GetChallenges(userID)
+	if err != nil {
+		if err.Error() == "user_not_found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "user_not_found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get challenges",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    challenges,
+	})
+}
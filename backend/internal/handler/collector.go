@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// CollectorHandler handles collector encounter HTTP requests
+type CollectorHandler struct {
+	collectorService *service.CollectorService
+}
+
+// NewCollectorHandler creates a new collector handler instance
+func NewCollectorHandler() *CollectorHandler {
+	return &CollectorHandler{
+		collectorService: service.NewCollectorService(),
+	}
+}
+
+// GetEncounter handles GET /api/collectors/encounter
+// @Summary Get collector encounter
+// @Description Get the active collector encounter, starting one if the user has loans in collection
+// @Tags collectors
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.EncounterView
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/collectors/encounter [get]
+func (h *CollectorHandler) GetEncounter(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	encounter, err := h.collectorService.GetEncounter(userID)
+	if err != nil {
+		if err.Error() == "no_encounter" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "no_encounter",
+				"details": "No collectors are looking for you right now",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get encounter",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    encounter,
+	})
+}
+
+// ChooseEncounter handles POST /api/collectors/encounter/:encounterId/choice
+// @Summary Make a choice in a collector encounter
+// @Description Resolve the current step of an encounter with one of the offered choices
+// @Tags collectors
+// @Accept json
+// @Produce json
+// @Param encounterId path int true "Encounter ID"
+// @Param body body service.EncounterChoiceRequest true "Choice"
+// @Success 200 {object} service.EncounterChoiceResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/collectors/encounter/{encounterId}/choice [post]
+func (h *CollectorHandler) ChooseEncounter(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	encounterID, err := strconv.ParseUint(c.Params("encounterId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid encounter ID",
+		})
+	}
+
+	var req service.EncounterChoiceRequest
+	if err := c.BodyParser(&req); err != nil || req.Choice == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	result, err := h.collectorService.Choose(userID, uint(encounterID), req)
+	if err != nil {
+		switch err.Error() {
+		case "encounter_not_found":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "encounter_not_found",
+			})
+		case "encounter_resolved", "invalid_choice":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "failed to resolve choice",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetEncounterHistory handles GET /api/collectors/history
+// @Summary Get collector encounter history
+// @Description Get past collector encounters with every recorded choice and outcome
+// @Tags collectors
+// @Accept json
+// @Produce json
+// @Param limit query int false "Number of encounters (default 20, max 50)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/collectors/history [get]
+func (h *CollectorHandler) GetEncounterHistory(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+
+	encounters, err := h.collectorService.GetEncounterHistory(userID, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get encounter history",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"encounters": encounters,
+			"count":      len(encounters),
+		},
+	})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// CreditHandler handles credit score HTTP requests
+type CreditHandler struct {
+	creditService *service.CreditService
+}
+
+// NewCreditHandler creates a new credit handler instance
+func NewCreditHandler() *CreditHandler {
+	return &CreditHandler{
+		creditService: service.NewCreditService(),
+	}
+}
+
+// GetCreditReport handles GET /api/user/credit-report
+// @Summary Get credit report
+// @Description Get the current user's credit score, the factors behind it and the loans it unlocks
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.CreditReport
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/user/credit-report [get]
+func (h *CreditHandler) GetCreditReport(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	report, err := h.creditService.GetCreditReport(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get credit report",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    report,
+	})
+}
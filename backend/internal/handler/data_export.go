@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// DataExportHandler handles personal data export HTTP requests
+type DataExportHandler struct {
+	dataExportService *service.DataExportService
+}
+
+// NewDataExportHandler creates a new data export handler instance
+func NewDataExportHandler() *DataExportHandler {
+	return &DataExportHandler{
+		dataExportService: service.NewDataExportService(),
+	}
+}
+
+// RequestExport handles POST /api/user/export
+// @Summary Request a data export
+// @Description Queue a zip of everything held about the user: data.json plus a CSV file per table. It is built in the
+// @Description background; a data_export_ready notification is sent when its download link is ready, which expires after 24 hours.
+// @Description The download link is only shown in this response. If an export is already queued, that one is returned with
+// @Description 200 and a new link that replaces its old one.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 202 {object} service.DataExportResponse
+// @Success 200 {object} service.DataExportResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/user/export [post]
+func (h *DataExportHandler) RequestExport(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	export, created, err := h.dataExportService.RequestExport(userID)
+	if err != nil {
+		return h.respondError(c, err, "failed to request data export")
+	}
+
+	status := fiber.StatusOK
+	if created {
+		status = fiber.StatusAccepted
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"success": true,
+		"data":    export,
+	})
+}
+
+// GetExports handles GET /api/user/export
+// @Summary List data exports
+// @Description List the user's recent data exports, newest first. Download links are not shown again.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 200 {array} service.DataExportResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/user/export [get]
+func (h *DataExportHandler) GetExports(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	exports, err := h.dataExportService.GetExports(userID)
+	if err != nil {
+		return h.respondError(c, err, "failed to get data exports")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    exports,
+	})
+}
+
+// Download handles GET /api/exports/:token
+// @Summary Download a data export
+// @Description Download a ready data export. The token in the link is the only credential, so the link can be
+// @Description opened directly in a browser until it expires.
+// @Tags user
+// @Produce application/zip
+// @Param token path string true "Download token"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/exports/{token} [get]
+func (h *DataExportHandler) Download(c *fiber.Ctx) error {
+	export, err := h.dataExportService.OpenExport(c.Params("token"))
+	if err != nil {
+		return h.respondError(c, err, "failed to get data export")
+	}
+
+	return c.Download(export.FilePath, fmt.Sprintf("freezino-export-%d.zip", export.ID))
+}
+
+// respondError maps data export service errors to HTTP responses
+func (h *DataExportHandler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	status := fiber.StatusInternalServerError
+	switch err.Error() {
+	case "user_not_found", "export_not_found":
+		status = fiber.StatusNotFound
+	case "export_not_ready", "export_failed":
+		status = fiber.StatusConflict
+	case "export_expired":
+		status = fiber.StatusGone
+	default:
+		return c.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": fallback,
+		})
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   true,
+		"message": err.Error(),
+	})
+}
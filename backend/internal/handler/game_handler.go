@@ -2,14 +2,20 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/events"
 	"github.com/smoreg/freezino/backend/internal/game"
 	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/service"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GameHandler manages game WebSocket connections
@@ -60,7 +66,8 @@ type NewGamePayload struct {
 
 // ErrorPayload represents an error message
 type ErrorPayload struct {
-	Message string `json:"message"`
+	Message string     `json:"message"`
+	Until   *time.Time `json:"until,omitempty"` // When a bet blocked by a gambling limit is possible again
 }
 
 // BalanceUpdatePayload represents balance update
@@ -72,10 +79,16 @@ type BalanceUpdatePayload struct {
 func (h *GameHandler) BlackjackWebSocket(c *websocket.Conn) {
 	var (
 		currentGame *game.BlackjackGame
+		stake       *model.OpenStake
 		userID      uint
 	)
 
 	defer func() {
+		// A hand left unfinished is lost, which settles its open stake
+		if currentGame != nil && !currentGame.GameOver {
+			currentGame.Forfeit()
+			h.handleGameEnd(currentGame, stake)
+		}
 		h.games.Delete(c)
 		c.Close()
 	}()
@@ -95,25 +108,21 @@ func (h *GameHandler) BlackjackWebSocket(c *websocket.Conn) {
 				continue
 			}
 
-			userID = payload.UserID
-
-			// Get user balance
-			var user model.User
-			if err := h.db.First(&user, userID).Error; err != nil {
-				h.sendError(c, "User not found")
-				continue
+			// Starting over gives up the hand in play
+			if currentGame != nil && !currentGame.GameOver {
+				currentGame.Forfeit()
+				h.handleGameEnd(currentGame, stake)
 			}
+			currentGame = nil
 
-			// Check if user has enough balance
-			if user.Balance < payload.Bet {
-				h.sendError(c, "Insufficient balance")
-				continue
-			}
+			userID = payload.UserID
 
-			// Deduct bet from user balance
-			user.Balance -= payload.Bet
-			if err := h.db.Save(&user).Error; err != nil {
-				h.sendError(c, "Failed to update balance")
+			// Take the stake, checked against the balance and responsible-gaming limits
+			var balance float64
+			var err error
+			stake, balance, err = service.PlaceOpenStake(h.db, userID, model.GameTypeBlackjack, payload.Bet)
+			if err != nil {
+				h.sendStakeError(c, err, "Insufficient balance")
 				continue
 			}
 
@@ -125,11 +134,11 @@ func (h *GameHandler) BlackjackWebSocket(c *websocket.Conn) {
 			h.sendGameState(c, currentGame)
 
 			// Send balance update
-			h.sendBalanceUpdate(c, user.Balance)
+			h.sendBalanceUpdate(c, balance)
 
 			// If game is already over (blackjack), handle payout
 			if currentGame.GameOver {
-				h.handleGameEnd(currentGame, userID)
+				h.handleGameEnd(currentGame, stake)
 			}
 
 		case MsgTypeHit:
@@ -146,7 +155,7 @@ func (h *GameHandler) BlackjackWebSocket(c *websocket.Conn) {
 			h.sendGameState(c, currentGame)
 
 			if currentGame.GameOver {
-				h.handleGameEnd(currentGame, userID)
+				h.handleGameEnd(currentGame, stake)
 			}
 
 		case MsgTypeStand:
@@ -163,7 +172,7 @@ func (h *GameHandler) BlackjackWebSocket(c *websocket.Conn) {
 			h.sendGameState(c, currentGame)
 
 			if currentGame.GameOver {
-				h.handleGameEnd(currentGame, userID)
+				h.handleGameEnd(currentGame, stake)
 			}
 
 		case MsgTypeDouble:
@@ -172,38 +181,28 @@ func (h *GameHandler) BlackjackWebSocket(c *websocket.Conn) {
 				continue
 			}
 
-			// Check if user has enough balance to double
-			var user model.User
-			if err := h.db.First(&user, userID).Error; err != nil {
-				h.sendError(c, "User not found")
+			if currentGame.GameOver || !currentGame.CanDouble {
+				h.sendError(c, "Cannot double at this point")
 				continue
 			}
 
-			if user.Balance < currentGame.Bet {
-				h.sendError(c, "Insufficient balance to double")
-				continue
-			}
-
-			// Deduct additional bet
-			user.Balance -= currentGame.Bet
-			if err := h.db.Save(&user).Error; err != nil {
-				h.sendError(c, "Failed to update balance")
+			// Doubling is a second stake, so it is checked too
+			balance, err := service.RaiseOpenStake(h.db, stake, currentGame.Bet)
+			if err != nil {
+				h.sendStakeError(c, err, "Insufficient balance to double")
 				continue
 			}
 
+			// Checked above, so doubling can't fail once the stake is taken
 			if err := currentGame.Double(); err != nil {
-				h.sendError(c, err.Error())
-				// Refund the additional bet
-				user.Balance += currentGame.Bet / 2
-				h.db.Save(&user)
-				continue
+				log.Printf("Error doubling blackjack game: %v", err)
 			}
 
 			h.sendGameState(c, currentGame)
-			h.sendBalanceUpdate(c, user.Balance)
+			h.sendBalanceUpdate(c, balance)
 
 			if currentGame.GameOver {
-				h.handleGameEnd(currentGame, userID)
+				h.handleGameEnd(currentGame, stake)
 			}
 
 		case MsgTypeSplit:
@@ -226,46 +225,66 @@ func (h *GameHandler) BlackjackWebSocket(c *websocket.Conn) {
 }
 
 // handleGameEnd handles the end of a game (update balance, save session)
-func (h *GameHandler) handleGameEnd(g *game.BlackjackGame, userID uint) {
-	// Update user balance
-	var user model.User
-	if err := h.db.First(&user, userID).Error; err != nil {
-		log.Printf("Error fetching user: %v", err)
-		return
-	}
+func (h *GameHandler) handleGameEnd(g *game.BlackjackGame, stake *model.OpenStake) {
+	userID := stake.UserID
+
+	// Settle the game in one transaction with its event
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// The stake is no longer open once the game has a result
+		if err := tx.Delete(stake).Error; err != nil {
+			return fmt.Errorf("failed to close open stake: %w", err)
+		}
 
-	payout := g.GetPayout()
-	user.Balance += payout
+		// Update user balance
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return fmt.Errorf("failed to fetch user: %w", err)
+		}
 
-	if err := h.db.Save(&user).Error; err != nil {
-		log.Printf("Error updating user balance: %v", err)
-		return
-	}
+		payout := service.GamePayout(tx, userID, model.GameTypeBlackjack, g.Bet, g.GetPayout())
+		user.Balance += payout
 
-	// Save game session
-	gameSession := model.GameSession{
-		UserID:   userID,
-		GameType: model.GameTypeBlackjack,
-		Bet:      g.Bet,
-		Win:      payout - g.Bet, // Net win/loss
-	}
+		if err := tx.Save(&user).Error; err != nil {
+			return fmt.Errorf("failed to update user balance: %w", err)
+		}
 
-	if err := h.db.Create(&gameSession).Error; err != nil {
-		log.Printf("Error saving game session: %v", err)
-	}
+		// Save game session
+		gameSession := model.GameSession{
+			UserID:   userID,
+			GameType: model.GameTypeBlackjack,
+			Bet:      g.Bet,
+			Win:      payout - g.Bet, // Net win/loss
+		}
 
-	// Create transaction record
-	transaction := model.Transaction{
-		UserID:      userID,
-		Type:        model.TransactionTypeGame,
-		Amount:      payout - g.Bet,
-		BalanceAfter: user.Balance,
-		Description: "Blackjack - " + g.Result,
-	}
+		if err := tx.Create(&gameSession).Error; err != nil {
+			return fmt.Errorf("failed to save game session: %w", err)
+		}
+
+		// Create transaction record
+		transaction := model.Transaction{
+			UserID:       userID,
+			Type:         model.TransactionTypeGame,
+			Amount:       payout - g.Bet,
+			BalanceAfter: user.Balance,
+			Description:  "Blackjack - " + g.Result,
+		}
+
+		if err := tx.Create(&transaction).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
 
-	if err := h.db.Create(&transaction).Error; err != nil {
-		log.Printf("Error creating transaction: %v", err)
+		return events.Record(tx, events.BetSettled{
+			UserID:   userID,
+			GameType: model.GameTypeBlackjack,
+			Bet:      g.Bet,
+			Payout:   payout,
+		})
+	})
+	if err != nil {
+		log.Printf("Error settling blackjack game: %v", err)
+		return
 	}
+	events.Signal(h.db)
 }
 
 // sendGameState sends the current game state to the client
@@ -302,6 +321,38 @@ func (h *GameHandler) sendError(c *websocket.Conn, message string) {
 	}
 }
 
+// sendStakeError sends why a stake couldn't be taken, using insufficient as
+// the message when the balance doesn't cover it
+func (h *GameHandler) sendStakeError(c *websocket.Conn, err error, insufficient string) {
+	switch err.Error() {
+	case "user_not_found":
+		h.sendError(c, "User not found")
+	case "insufficient_balance":
+		h.sendError(c, insufficient)
+	default:
+		h.sendBetError(c, err)
+	}
+}
+
+// sendBetError sends why a bet was refused, with the limit's code and when
+// betting is possible again if a responsible-gaming limit refused it
+func (h *GameHandler) sendBetError(c *websocket.Conn, err error) {
+	var limitErr *service.BetLimitError
+	if !errors.As(err, &limitErr) {
+		log.Printf("Error checking gambling limits: %v", err)
+		h.sendError(c, "Failed to check gambling limits")
+		return
+	}
+
+	msg := WebSocketMessage{
+		Type:    MsgTypeError,
+		Payload: mustMarshal(ErrorPayload{Message: limitErr.Code, Until: limitErr.Until}),
+	}
+	if err := c.WriteJSON(msg); err != nil {
+		log.Printf("Error sending error message: %v", err)
+	}
+}
+
 // mustMarshal marshals data to JSON or panics
 func mustMarshal(v interface{}) json.RawMessage {
 	data, err := json.Marshal(v)
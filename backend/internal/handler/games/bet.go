@@ -0,0 +1,54 @@
+package games
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/service"
+	"gorm.io/gorm"
+)
+
+// takeStake locks the user and checks the stake against their balance and
+// gambling limits, as the first step of a bet's transaction
+func takeStake(tx *gorm.DB, userID uint, bet float64) (*model.User, error) {
+	user, err := service.TakeStake(tx, userID, bet)
+	if err == nil {
+		return user, nil
+	}
+
+	var limitErr *service.BetLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		return nil, err
+	case err.Error() == "user_not_found":
+		return nil, fiber.NewError(fiber.StatusNotFound, "user not found")
+	case err.Error() == "insufficient_balance":
+		return nil, fiber.NewError(fiber.StatusBadRequest, "insufficient balance")
+	}
+	return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to place bet")
+}
+
+// betError writes the response for a bet that was not settled
+func betError(c *fiber.Ctx, err error) error {
+	var limitErr *service.BetLimitError
+	if errors.As(err, &limitErr) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   true,
+			"message": limitErr.Code,
+			"until":   limitErr.Until,
+		})
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error":   true,
+			"message": fiberErr.Message,
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   true,
+		"message": "failed to place bet",
+	})
+}
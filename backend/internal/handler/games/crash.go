@@ -8,7 +8,10 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
 	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/service"
+	"gorm.io/gorm"
 )
 
 // CrashHandler handles crash game HTTP requests
@@ -46,6 +49,7 @@ type BetResponse struct {
 // @Param request body BetRequest true "Bet request"
 // @Success 200 {object} BetResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/games/crash/bet [post]
@@ -74,24 +78,6 @@ func (h *CrashHandler) PlaceBet(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get user
-	db := database.GetDB()
-	var user model.User
-	if err := db.First(&user, req.UserID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error":   true,
-			"message": "user not found",
-		})
-	}
-
-	// Check if user has enough balance
-	if user.Balance < req.BetAmount {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   true,
-			"message": "insufficient balance",
-		})
-	}
-
 	// Generate crash point (house edge: ~3%)
 	crashPoint := generateCrashPoint()
 
@@ -103,53 +89,66 @@ func (h *CrashHandler) PlaceBet(c *fiber.Ctx) error {
 		winAmount = req.BetAmount * req.CashoutAt
 	}
 
-	// Calculate net change
-	netChange := winAmount - req.BetAmount
-
-	// Update user balance
-	newBalance := user.Balance + netChange
-	if err := db.Model(&user).Update("balance", newBalance).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   true,
-			"message": "failed to update balance",
-		})
-	}
-
-	// Create game session record
-	gameSession := model.GameSession{
-		UserID:   req.UserID,
-		GameType: model.GameTypeCrash,
-		Bet:      req.BetAmount,
-		Win:      winAmount,
-	}
-
-	if err := db.Create(&gameSession).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   true,
-			"message": "failed to create game session",
-		})
-	}
-
-	// Create transaction record
-	transactionType := model.TransactionType("game_loss")
-	if won {
-		transactionType = model.TransactionTypeGameWin
-	}
-
-	transaction := model.Transaction{
-		UserID:       req.UserID,
-		Type:         transactionType,
-		Amount:       math.Abs(netChange),
-		BalanceAfter: newBalance,
-		Description:  "Crash game - " + strconv.FormatFloat(crashPoint, 'f', 2, 64) + "x",
-	}
-
-	if err := db.Create(&transaction).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   true,
-			"message": "failed to create transaction",
+	// Settle the bet in one transaction with its event
+	db := database.GetDB()
+	var newBalance float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user, err := takeStake(tx, req.UserID, req.BetAmount)
+		if err != nil {
+			return err
+		}
+		winAmount = service.GamePayout(tx, req.UserID, model.GameTypeCrash, req.BetAmount, winAmount)
+
+		// Calculate net change
+		netChange := winAmount - req.BetAmount
+
+		// Update user balance
+		newBalance = user.Balance + netChange
+		if err := tx.Model(user).Update("balance", newBalance).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to update balance")
+		}
+
+		// Create game session record
+		gameSession := model.GameSession{
+			UserID:   req.UserID,
+			GameType: model.GameTypeCrash,
+			Bet:      req.BetAmount,
+			Win:      winAmount,
+		}
+
+		if err := tx.Create(&gameSession).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to create game session")
+		}
+
+		// Create transaction record
+		transactionType := model.TransactionType("game_loss")
+		if won {
+			transactionType = model.TransactionTypeGameWin
+		}
+
+		transaction := model.Transaction{
+			UserID:       req.UserID,
+			Type:         transactionType,
+			Amount:       math.Abs(netChange),
+			BalanceAfter: newBalance,
+			Description:  "Crash game - " + strconv.FormatFloat(crashPoint, 'f', 2, 64) + "x",
+		}
+
+		if err := tx.Create(&transaction).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to create transaction")
+		}
+
+		return events.Record(tx, events.BetSettled{
+			UserID:   req.UserID,
+			GameType: model.GameTypeCrash,
+			Bet:      req.BetAmount,
+			Payout:   winAmount,
 		})
+	})
+	if err != nil {
+		return betError(c, err)
 	}
+	events.Signal(db)
 
 	return c.Status(fiber.StatusOK).JSON(BetResponse{
 		Success:       true,
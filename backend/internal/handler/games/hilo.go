@@ -8,7 +8,10 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
 	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/service"
+	"gorm.io/gorm"
 )
 
 // HiLoHandler handles hi-lo game HTTP requests
@@ -48,6 +51,7 @@ type HiLoBetResponse struct {
 // @Param request body HiLoBetRequest true "Bet request"
 // @Success 200 {object} HiLoBetResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/games/hilo/bet [post]
@@ -76,24 +80,6 @@ func (h *HiLoHandler) PlaceBet(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get user
-	db := database.GetDB()
-	var user model.User
-	if err := db.First(&user, req.UserID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error":   true,
-			"message": "user not found",
-		})
-	}
-
-	// Check if user has enough balance
-	if user.Balance < req.BetAmount {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   true,
-			"message": "insufficient balance",
-		})
-	}
-
 	// Generate cards
 	rand.Seed(time.Now().UnixNano())
 	currentCard := rand.Intn(13) + 1 // 1-13 (Ace to King)
@@ -114,69 +100,82 @@ func (h *HiLoHandler) PlaceBet(c *fiber.Ctx) error {
 	isPush := currentCard == nextCard
 
 	// Calculate winnings (2x multiplier for correct guess)
-	winAmount := 0.0
-	netChange := -req.BetAmount // Default to loss
+	winAmount := 0.0 // Default to loss
 
 	if isPush {
 		// Push - return the bet
 		winAmount = req.BetAmount
-		netChange = 0
 	} else if won {
 		// Win - 2x payout
 		winAmount = req.BetAmount * 2.0
-		netChange = winAmount - req.BetAmount
-	}
-
-	// Update user balance
-	newBalance := user.Balance + netChange
-	if err := db.Model(&user).Update("balance", newBalance).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   true,
-			"message": "failed to update balance",
-		})
-	}
-
-	// Create game session record
-	gameSession := model.GameSession{
-		UserID:   req.UserID,
-		GameType: model.GameTypeHiLo,
-		Bet:      req.BetAmount,
-		Win:      winAmount,
-	}
-
-	if err := db.Create(&gameSession).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   true,
-			"message": "failed to create game session",
-		})
 	}
 
-	// Create transaction record
-	transactionType := model.TransactionType("game_loss")
-	description := "Hi-Lo game - Loss"
-
-	if isPush {
-		transactionType = model.TransactionType("game_push")
-		description = "Hi-Lo game - Push (tie)"
-	} else if won {
-		transactionType = model.TransactionTypeGameWin
-		description = "Hi-Lo game - Win"
-	}
-
-	transaction := model.Transaction{
-		UserID:       req.UserID,
-		Type:         transactionType,
-		Amount:       math.Abs(netChange),
-		BalanceAfter: newBalance,
-		Description:  description + " (" + strconv.Itoa(currentCard) + " vs " + strconv.Itoa(nextCard) + ")",
-	}
-
-	if err := db.Create(&transaction).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   true,
-			"message": "failed to create transaction",
+	// Settle the bet in one transaction with its event
+	db := database.GetDB()
+	var newBalance float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user, err := takeStake(tx, req.UserID, req.BetAmount)
+		if err != nil {
+			return err
+		}
+
+		// Equipped items can boost winnings or soften losses
+		winAmount = service.GamePayout(tx, req.UserID, model.GameTypeHiLo, req.BetAmount, winAmount)
+		netChange := winAmount - req.BetAmount
+
+		// Update user balance
+		newBalance = user.Balance + netChange
+		if err := tx.Model(user).Update("balance", newBalance).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to update balance")
+		}
+
+		// Create game session record
+		gameSession := model.GameSession{
+			UserID:   req.UserID,
+			GameType: model.GameTypeHiLo,
+			Bet:      req.BetAmount,
+			Win:      winAmount,
+		}
+
+		if err := tx.Create(&gameSession).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to create game session")
+		}
+
+		// Create transaction record
+		transactionType := model.TransactionType("game_loss")
+		description := "Hi-Lo game - Loss"
+
+		if isPush {
+			transactionType = model.TransactionType("game_push")
+			description = "Hi-Lo game - Push (tie)"
+		} else if won {
+			transactionType = model.TransactionTypeGameWin
+			description = "Hi-Lo game - Win"
+		}
+
+		transaction := model.Transaction{
+			UserID:       req.UserID,
+			Type:         transactionType,
+			Amount:       math.Abs(netChange),
+			BalanceAfter: newBalance,
+			Description:  description + " (" + strconv.Itoa(currentCard) + " vs " + strconv.Itoa(nextCard) + ")",
+		}
+
+		if err := tx.Create(&transaction).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to create transaction")
+		}
+
+		return events.Record(tx, events.BetSettled{
+			UserID:   req.UserID,
+			GameType: model.GameTypeHiLo,
+			Bet:      req.BetAmount,
+			Payout:   winAmount,
 		})
+	})
+	if err != nil {
+		return betError(c, err)
 	}
+	events.Signal(db)
 
 	return c.Status(fiber.StatusOK).JSON(HiLoBetResponse{
 		Success:     true,
@@ -8,7 +8,10 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
 	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/service"
+	"gorm.io/gorm"
 )
 
 // WheelHandler handles wheel game HTTP requests
@@ -66,6 +69,7 @@ var wheelSegments = []WheelSegment{
 // @Param request body WheelSpinRequest true "Spin request"
 // @Success 200 {object} WheelSpinResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/games/wheel/spin [post]
@@ -86,86 +90,81 @@ func (h *WheelHandler) Spin(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get user
-	db := database.GetDB()
-	var user model.User
-	if err := db.First(&user, req.UserID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error":   true,
-			"message": "user not found",
-		})
-	}
-
-	// Check if user has enough balance
-	if user.Balance < req.BetAmount {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   true,
-			"message": "insufficient balance",
-		})
-	}
-
 	// Spin the wheel (weighted random)
 	winningSegmentIndex := spinWheel()
 	winningSegment := wheelSegments[winningSegmentIndex]
 
-	// Calculate winnings
-	winAmount := req.BetAmount * winningSegment.Multiplier
-	netChange := winAmount - req.BetAmount
+	// Settle the bet in one transaction with its event
+	db := database.GetDB()
+	var winAmount, newBalance float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user, err := takeStake(tx, req.UserID, req.BetAmount)
+		if err != nil {
+			return err
+		}
 
-	// Update user balance
-	newBalance := user.Balance + netChange
-	if err := db.Model(&user).Update("balance", newBalance).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   true,
-			"message": "failed to update balance",
-		})
-	}
+		// Calculate winnings
+		winAmount = req.BetAmount * winningSegment.Multiplier
+		winAmount = service.GamePayout(tx, req.UserID, model.GameTypeWheel, req.BetAmount, winAmount)
+		netChange := winAmount - req.BetAmount
 
-	// Create game session record
-	gameSession := model.GameSession{
-		UserID:   req.UserID,
-		GameType: model.GameTypeWheel,
-		Bet:      req.BetAmount,
-		Win:      winAmount,
-	}
+		// Update user balance
+		newBalance = user.Balance + netChange
+		if err := tx.Model(user).Update("balance", newBalance).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to update balance")
+		}
 
-	if err := db.Create(&gameSession).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   true,
-			"message": "failed to create game session",
-		})
-	}
+		// Create game session record
+		gameSession := model.GameSession{
+			UserID:   req.UserID,
+			GameType: model.GameTypeWheel,
+			Bet:      req.BetAmount,
+			Win:      winAmount,
+		}
 
-	// Create transaction record
-	transactionType := model.TransactionType("game_loss")
-	description := "Wheel of Fortune - "
-
-	if winningSegment.Multiplier == 0 {
-		description += "Lost all"
-	} else if netChange > 0 {
-		transactionType = model.TransactionTypeGameWin
-		description += strconv.FormatFloat(winningSegment.Multiplier, 'f', 1, 64) + "x win"
-	} else if netChange == 0 {
-		transactionType = model.TransactionType("game_push")
-		description += "Break even"
-	} else {
-		description += strconv.FormatFloat(winningSegment.Multiplier, 'f', 1, 64) + "x (loss)"
-	}
+		if err := tx.Create(&gameSession).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to create game session")
+		}
 
-	transaction := model.Transaction{
-		UserID:       req.UserID,
-		Type:         transactionType,
-		Amount:       math.Abs(netChange),
-		BalanceAfter: newBalance,
-		Description:  description,
-	}
+		// Create transaction record
+		transactionType := model.TransactionType("game_loss")
+		description := "Wheel of Fortune - "
+
+		if winningSegment.Multiplier == 0 {
+			description += "Lost all"
+		} else if netChange > 0 {
+			transactionType = model.TransactionTypeGameWin
+			description += strconv.FormatFloat(winningSegment.Multiplier, 'f', 1, 64) + "x win"
+		} else if netChange == 0 {
+			transactionType = model.TransactionType("game_push")
+			description += "Break even"
+		} else {
+			description += strconv.FormatFloat(winningSegment.Multiplier, 'f', 1, 64) + "x (loss)"
+		}
 
-	if err := db.Create(&transaction).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   true,
-			"message": "failed to create transaction",
+		transaction := model.Transaction{
+			UserID:       req.UserID,
+			Type:         transactionType,
+			Amount:       math.Abs(netChange),
+			BalanceAfter: newBalance,
+			Description:  description,
+		}
+
+		if err := tx.Create(&transaction).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to create transaction")
+		}
+
+		return events.Record(tx, events.BetSettled{
+			UserID:   req.UserID,
+			GameType: model.GameTypeWheel,
+			Bet:      req.BetAmount,
+			Payout:   winAmount,
 		})
+	})
+	if err != nil {
+		return betError(c, err)
 	}
+	events.Signal(db)
 
 	return c.Status(fiber.StatusOK).JSON(WheelSpinResponse{
 		Success:    true,
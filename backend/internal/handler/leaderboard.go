@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// LeaderboardHandler handles leaderboard HTTP requests
+type LeaderboardHandler struct {
+	leaderboardService *service.LeaderboardService
+}
+
+// NewLeaderboardHandler creates a new leaderboard handler instance
+func NewLeaderboardHandler() *LeaderboardHandler {
+	return &LeaderboardHandler{
+		leaderboardService: service.NewLeaderboardService(),
+	}
+}
+
+// GetLeaderboard handles GET /api/leaderboards/:board
+// @Summary Get a leaderboard
+// @Description Page through a leaderboard, highest score first. Boards are net_profit, biggest_win, hours_worked and most_debt.
+// @Description Players who opted out are left out and anonymous players are shown without name or ID.
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param board path string true "Leaderboard"
+// @Param window query string false "daily, weekly or all_time" default(all_time)
+// @Param limit query int false "Limit number of entries" default(50)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} service.LeaderboardResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/leaderboards/{board} [get]
+func (h *LeaderboardHandler) GetLeaderboard(c *fiber.Ctx) error {
+	leaderboard, err := h.leaderboardService.GetLeaderboard(
+		c.Params("board"), c.Query("window"), c.QueryInt("limit", 50), c.QueryInt("offset", 0))
+	if err != nil {
+		return h.respondError(c, err, "failed to get leaderboard")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    leaderboard,
+	})
+}
+
+// GetMyRank handles GET /api/leaderboards/:board/me
+// @Summary Get my rank
+// @Description Get the authenticated user's rank and score on a leaderboard. Users who opted out see where they would rank.
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param board path string true "Leaderboard"
+// @Param window query string false "daily, weekly or all_time" default(all_time)
+// @Success 200 {object} service.LeaderboardRankResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/leaderboards/{board}/me [get]
+func (h *LeaderboardHandler) GetMyRank(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	rank, err := h.leaderboardService.GetUserRank(userID, c.Params("board"), c.Query("window"))
+	if err != nil {
+		return h.respondError(c, err, "failed to get rank")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    rank,
+	})
+}
+
+// respondError maps leaderboard service errors to HTTP responses
+func (h *LeaderboardHandler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "unknown_board", "unknown_window":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	case "user_not_found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   true,
+		"message": fallback,
+	})
+}
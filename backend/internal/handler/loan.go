@@ -166,12 +166,41 @@ func (h *LoanHandler) TakeLoan(c *fiber.Ctx) error {
 				"message": "item_already_collateral",
 				"details": "This item is already used as collateral",
 			})
+		case "item_listed":
+			return c.Status(status).JSON(fiber.Map{
+				"error":   true,
+				"message": "item_listed",
+				"details": "Listed items cannot be used as collateral",
+			})
 		case "collateral_must_be_car_or_house":
 			return c.Status(status).JSON(fiber.Map{
 				"error":   true,
 				"message": "collateral_must_be_car_or_house",
 				"details": "Only cars and houses can be used as collateral",
 			})
+		case "credit_score_too_low":
+			return c.Status(status).JSON(fiber.Map{
+				"error":   true,
+				"message": "credit_score_too_low",
+				"details": "Your credit score is too low for this loan type",
+			})
+		case "credit_limit_exceeded":
+			return c.Status(status).JSON(fiber.Map{
+				"error":   true,
+				"message": "credit_limit_exceeded",
+				"details": "The amount is above the limit your credit score allows",
+			})
+		case "repayment_plan_not_allowed":
+			return c.Status(status).JSON(fiber.Map{
+				"error":   true,
+				"message": "repayment_plan_not_allowed",
+				"details": "This loan type does not offer the requested repayment plan",
+			})
+		case "invalid_installment_count":
+			return c.Status(status).JSON(fiber.Map{
+				"error":   true,
+				"message": "invalid_installment_count",
+			})
 		case "collateral_insufficient":
 			return c.Status(status).JSON(fiber.Map{
 				"error":   true,
@@ -239,7 +268,7 @@ func (h *LoanHandler) RepayLoan(c *fiber.Ctx) error {
 	req.LoanID = uint(loanID)
 
 	// Process repayment
-	err = h.loanService.RepayLoan(userID, req)
+	result, err := h.loanService.RepayLoan(userID, req)
 	if err != nil {
 		errMsg := err.Error()
 
@@ -262,6 +291,11 @@ func (h *LoanHandler) RepayLoan(c *fiber.Ctx) error {
 				"error":   true,
 				"message": "insufficient_balance",
 			})
+		case "loan_already_repaid":
+			return c.Status(status).JSON(fiber.Map{
+				"error":   true,
+				"message": "loan_already_repaid",
+			})
 		default:
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error":   true,
@@ -274,12 +308,91 @@ func (h *LoanHandler) RepayLoan(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"success": true,
 		"message": "repayment processed successfully",
+		"data":    result,
+	})
+}
+
+// GetLoanSchedule handles GET /api/loans/:loanId/schedule
+// @Summary Get loan schedule
+// @Description Get the installment schedule of a loan
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Param loanId path int true "Loan ID"
+// @Success 200 {object} service.LoanScheduleResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/loans/{loanId}/schedule [get]
+func (h *LoanHandler) GetLoanSchedule(c *fiber.Ctx) error {
+	return h.loanDetail(c, func(userID, loanID uint) (interface{}, error) {
+		return h.loanService.GetLoanSchedule(userID, loanID)
+	})
+}
+
+// GetLoanStatement handles GET /api/loans/:loanId/statement
+// @Summary Get loan statement
+// @Description Get disbursement, fees and payments of a loan with their allocation
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Param loanId path int true "Loan ID"
+// @Success 200 {object} service.LoanStatementResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/loans/{loanId}/statement [get]
+func (h *LoanHandler) GetLoanStatement(c *fiber.Ctx) error {
+	return h.loanDetail(c, func(userID, loanID uint) (interface{}, error) {
+		return h.loanService.GetLoanStatement(userID, loanID)
+	})
+}
+
+// loanDetail parses the loan ID and maps ownership errors for per-loan endpoints
+func (h *LoanHandler) loanDetail(c *fiber.Ctx, fetch func(userID, loanID uint) (interface{}, error)) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	loanID, err := strconv.ParseUint(c.Params("loanId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid loan ID",
+		})
+	}
+
+	data, err := fetch(userID, uint(loanID))
+	if err != nil {
+		switch err.Error() {
+		case "loan_not_found", "not_your_loan":
+			// Don't reveal whether someone else's loan exists
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "loan_not_found",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "failed to get loan",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    data,
 	})
 }
 
 // CheckBankruptcy handles GET /api/loans/bankruptcy-check
 // @Summary Check bankruptcy status
-// @Description Check if user is bankrupt and handle debt collection
+// @Description Run collections on loans whose notice period has ended and report whether the user went bankrupt
 // @Tags loans
 // @Accept json
 // @Produce json
@@ -310,3 +423,43 @@ func (h *LoanHandler) CheckBankruptcy(c *fiber.Ctx) error {
 		"is_bankrupt": isBankrupt,
 	})
 }
+
+// GetCollectionEvents handles GET /api/loans/collections
+// @Summary Get collections log
+// @Description Get notices, seizures, auctions and bankruptcies recorded by collectors
+// @Tags loans
+// @Accept json
+// @Produce json
+// @Param limit query int false "Number of events (default 50, max 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/loans/collections [get]
+func (h *LoanHandler) GetCollectionEvents(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+
+	events, err := h.loanService.GetCollectionEvents(userID, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get collection events",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"events": events,
+			"count":  len(events),
+		},
+	})
+}
@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// MarketHandler handles player-to-player marketplace HTTP requests
+type MarketHandler struct {
+	marketService *service.MarketService
+}
+
+// NewMarketHandler creates a new market handler instance
+func NewMarketHandler() *MarketHandler {
+	return &MarketHandler{
+		marketService: service.NewMarketService(),
+	}
+}
+
+// GetListings handles GET /api/market
+// @Summary Browse the market
+// @Description Get active listings, cheapest first, optionally filtered by item type or item
+// @Tags market
+// @Accept json
+// @Produce json
+// @Param type query string false "Item type"
+// @Param item_id query int false "Item ID"
+// @Param limit query int false "Limit number of listings" default(50)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/market [get]
+func (h *MarketHandler) GetListings(c *fiber.Ctx) error {
+	filter := service.MarketFilter{
+		ItemType: c.Query("type"),
+		Limit:    50,
+	}
+	if itemIDStr := c.Query("item_id"); itemIDStr != "" {
+		if id, err := strconv.ParseUint(itemIDStr, 10, 32); err == nil {
+			filter.ItemID = uint(id)
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			filter.Limit = l
+		}
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			filter.Offset = o
+		}
+	}
+
+	listings, total, err := h.marketService.GetListings(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get listings",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"listings": listings,
+			"total":    total,
+			"limit":    filter.Limit,
+			"offset":   filter.Offset,
+		},
+	})
+}
+
+// CreateListing handles POST /api/market/listings
+// @Summary List an item for sale
+// @Description Put an owned item up for sale. A listing fee is charged and the item is held in escrow.
+// @Tags market
+// @Accept json
+// @Produce json
+// @Param body body service.CreateListingRequest true "Listing"
+// @Success 201 {object} service.ListingResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/market/listings [post]
+func (h *MarketHandler) CreateListing(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	var req service.CreateListingRequest
+	if err := c.BodyParser(&req); err != nil || req.UserItemID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	result, err := h.marketService.CreateListing(userID, req)
+	if err != nil {
+		switch err.Error() {
+		case "item_not_found":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "item_not_found",
+			})
+		case "invalid_price":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "invalid_price",
+				"details": "Price must be positive and at most $10,000,000",
+			})
+		case "item_equipped":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "item_equipped",
+				"details": "Unequip the item before listing it",
+			})
+		case "item_is_collateral":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "item_is_collateral",
+				"details": "Items pledged as loan collateral cannot be sold",
+			})
+		case "item_already_listed", "insufficient_balance":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "failed to create listing",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// BuyListing handles POST /api/market/listings/:listingId/buy
+// @Summary Buy a listing
+// @Description Pay the seller and receive the listed item
+// @Tags market
+// @Accept json
+// @Produce json
+// @Param listingId path int true "Listing ID"
+// @Success 200 {object} service.BuyListingResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/market/listings/{listingId}/buy [post]
+func (h *MarketHandler) BuyListing(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	listingID, err := strconv.ParseUint(c.Params("listingId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid listing ID",
+		})
+	}
+
+	result, err := h.marketService.BuyListing(userID, uint(listingID))
+	if err != nil {
+		switch err.Error() {
+		case "listing_not_found":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "listing_not_found",
+			})
+		case "listing_not_active":
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   true,
+				"message": "listing_not_active",
+				"details": "This listing was already sold or cancelled",
+			})
+		case "cannot_buy_own_listing", "insufficient_balance":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "failed to buy listing",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// CancelListing handles DELETE /api/market/listings/:listingId
+// @Summary Cancel a listing
+// @Description Take a listing off the market and return the item from escrow. The listing fee is not refunded.
+// @Tags market
+// @Accept json
+// @Produce json
+// @Param listingId path int true "Listing ID"
+// @Success 200 {object} service.ListingResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/market/listings/{listingId} [delete]
+func (h *MarketHandler) CancelListing(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	listingID, err := strconv.ParseUint(c.Params("listingId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid listing ID",
+		})
+	}
+
+	result, err := h.marketService.CancelListing(userID, uint(listingID))
+	if err != nil {
+		switch err.Error() {
+		case "listing_not_found", "not_your_listing":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "listing_not_found",
+			})
+		case "listing_not_active":
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   true,
+				"message": "listing_not_active",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "failed to cancel listing",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetMyListings handles GET /api/market/listings/mine
+// @Summary Get my listings
+// @Description Get every listing created by the authenticated user
+// @Tags market
+// @Accept json
+// @Produce json
+// @Success 200 {array} model.MarketListing
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/market/listings/mine [get]
+func (h *MarketHandler) GetMyListings(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	listings, err := h.marketService.GetMyListings(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get listings",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    listings,
+	})
+}
+
+// GetPriceHistory handles GET /api/market/items/:itemId/history
+// @Summary Get item price history
+// @Description Get recent market trades of an item with average, minimum, maximum and last price
+// @Tags market
+// @Accept json
+// @Produce json
+// @Param itemId path int true "Item ID"
+// @Param limit query int false "Limit number of trades" default(100)
+// @Success 200 {object} service.PriceHistoryResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/market/items/{itemId}/history [get]
+func (h *MarketHandler) GetPriceHistory(c *fiber.Ctx) error {
+	itemID, err := strconv.ParseUint(c.Params("itemId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid item ID",
+		})
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "100"))
+
+	history, err := h.marketService.GetPriceHistory(uint(itemID), limit)
+	if err != nil {
+		if err.Error() == "item_not_found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "item_not_found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get price history",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    history,
+	})
+}
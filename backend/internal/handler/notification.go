@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+	"github.com/valyala/fasthttp"
+)
+
+// notificationKeepAlive is how often an idle stream is pinged, so dead
+// connections are noticed and proxies don't time the stream out
+const notificationKeepAlive = 25 * time.Second
+
+// NotificationHandler streams live notifications to users
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+	upgrade             fiber.Handler
+}
+
+// NewNotificationHandler creates a new notification handler instance
+func NewNotificationHandler() *NotificationHandler {
+	h := &NotificationHandler{
+		notificationService: service.NewNotificationService(),
+	}
+	h.upgrade = websocket.New(h.streamWebSocket)
+	return h
+}
+
+// Stream handles GET /ws/events
+// @Summary Live notification stream
+// @Description Push balance changes, loan interest ticks, collections, status changes and achievement unlocks.
+// @Description WebSocket clients are upgraded; other clients get server-sent events. To resume after a reconnect,
+// @Description send the last notification ID received as the Last-Event-ID header or the last_event_id query parameter.
+// @Description Browsers may pass the access token as the token query parameter.
+// @Tags notifications
+// @Produce text/event-stream
+// @Param last_event_id query int false "Resume after this notification ID"
+// @Param token query string false "Access token, for clients that can't set headers"
+// @Success 200 {object} service.NotificationMessage
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /ws/events [get]
+func (h *NotificationHandler) Stream(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	lastID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	var lastEventID uint
+	if lastID != "" {
+		id, err := strconv.ParseUint(lastID, 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "invalid last event ID",
+			})
+		}
+		lastEventID = uint(id)
+	}
+
+	if websocket.IsWebSocketUpgrade(c) {
+		c.Locals("lastEventID", lastEventID)
+		return h.upgrade(c)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		// Clients retry after 3s and send Last-Event-ID themselves
+		fmt.Fprint(w, "retry: 3000\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		h.stream(userID, lastEventID, nil, func(message *service.NotificationMessage) error {
+			if message == nil {
+				fmt.Fprint(w, ": keep-alive\n\n")
+				return w.Flush()
+			}
+			data, err := json.Marshal(message)
+			if err != nil {
+				return err
+			}
+			if message.ID != 0 {
+				fmt.Fprintf(w, "id: %d\n", message.ID)
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", message.Type, data)
+			return w.Flush()
+		})
+	}))
+	return nil
+}
+
+// streamWebSocket sends notifications as JSON messages over a WebSocket
+func (h *NotificationHandler) streamWebSocket(c *websocket.Conn) {
+	userID, _ := c.Locals("userID").(uint)
+	lastEventID, _ := c.Locals("lastEventID").(uint)
+	defer c.Close()
+
+	// The stream is read-only; reading only detects the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	h.stream(userID, lastEventID, closed, func(message *service.NotificationMessage) error {
+		if message == nil {
+			return c.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+		}
+		return c.WriteJSON(message)
+	})
+}
+
+// stream replays what the user missed since lastEventID and then follows
+// their live notifications until closed fires or send fails. send is
+// called with nil when the stream has been idle for a while.
+func (h *NotificationHandler) stream(userID, lastEventID uint, closed <-chan struct{}, send func(*service.NotificationMessage) error) {
+	// Subscribe before replaying so nothing created in between is missed
+	live, unsubscribe := h.notificationService.Subscribe(userID)
+	defer unsubscribe()
+
+	missed, resync, err := h.notificationService.Replay(userID, lastEventID)
+	if err != nil {
+		log.Printf("Notification replay error: %v", err)
+		resync = true
+	}
+	if resync {
+		if err := send(&service.NotificationMessage{Type: service.NotificationResync, CreatedAt: time.Now()}); err != nil {
+			return
+		}
+	}
+	sent := lastEventID
+	for i := range missed {
+		if err := send(&missed[i]); err != nil {
+			return
+		}
+		sent = missed[i].ID
+	}
+
+	keepAlive := time.NewTicker(notificationKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-keepAlive.C:
+			if err := send(nil); err != nil {
+				return
+			}
+		case message, ok := <-live:
+			if !ok {
+				return
+			}
+			if message.ID <= sent {
+				continue // Already replayed
+			}
+			if err := send(&message); err != nil {
+				return
+			}
+			sent = message.ID
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// ResponsibleGamingHandler handles gambling limit and cooldown HTTP requests
+type ResponsibleGamingHandler struct {
+	responsibleGamingService *service.ResponsibleGamingService
+}
+
+// NewResponsibleGamingHandler creates a new responsible gaming handler instance
+func NewResponsibleGamingHandler() *ResponsibleGamingHandler {
+	return &ResponsibleGamingHandler{
+		responsibleGamingService: service.NewResponsibleGamingService(),
+	}
+}
+
+// GetLimits handles GET /api/responsible-gaming
+// @Summary Get gambling limits
+// @Description Get the user's loss, session wager and session duration limits with how much of each is used,
+// @Description any pending loosening, the cooling-off or self-exclusion period in force and the current gambling session.
+// @Tags responsible-gaming
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.ResponsibleGamingResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/responsible-gaming [get]
+func (h *ResponsibleGamingHandler) GetLimits(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	limits, err := h.responsibleGamingService.GetLimits(userID)
+	if err != nil {
+		return h.respondError(c, err, "failed to get gambling limits")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    limits,
+	})
+}
+
+// SetLimit handles PUT /api/responsible-gaming/limits/:type
+// @Summary Set a gambling limit
+// @Description Set or remove (amount null) a daily_loss, weekly_loss, session_wager or session_duration (minutes) limit.
+// @Description Tightening a limit applies at once; loosening or removing it applies after 24 hours.
+// @Tags responsible-gaming
+// @Accept json
+// @Produce json
+// @Param type path string true "Limit type"
+// @Param request body service.SetGamblingLimitRequest true "New limit"
+// @Success 200 {object} service.ResponsibleGamingResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/responsible-gaming/limits/{type} [put]
+func (h *ResponsibleGamingHandler) SetLimit(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	// Parse request body
+	var req service.SetGamblingLimitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	limits, err := h.responsibleGamingService.SetLimit(userID, model.GamblingLimitType(c.Params("type")), req.Amount)
+	if err != nil {
+		return h.respondError(c, err, "failed to set gambling limit")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    limits,
+	})
+}
+
+// StartCooldown handles POST /api/responsible-gaming/cooldown
+// @Summary Take a break from gambling
+// @Description Start a cooling-off (1-42 days) or self-exclusion (180-1825 days) period. All bets are refused until it ends.
+// @Description It can't be cancelled or shortened.
+// @Tags responsible-gaming
+// @Accept json
+// @Produce json
+// @Param request body service.StartCooldownRequest true "Cooldown"
+// @Success 200 {object} service.ResponsibleGamingResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/responsible-gaming/cooldown [post]
+func (h *ResponsibleGamingHandler) StartCooldown(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	// Parse request body
+	var req service.StartCooldownRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	limits, err := h.responsibleGamingService.StartCooldown(userID, req.Kind, req.Days)
+	if err != nil {
+		return h.respondError(c, err, "failed to start cooldown")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    limits,
+	})
+}
+
+// GetRealityCheck handles GET /api/responsible-gaming/reality-check
+// @Summary Get the pending reality check
+// @Description Every 30 minutes of play, betting stops until the user acknowledges a reality check showing how long
+// @Description they have played and what they have lost, in hours of work in their profile's country. Data is null if none is due.
+// @Tags responsible-gaming
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.RealityCheckResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/responsible-gaming/reality-check [get]
+func (h *ResponsibleGamingHandler) GetRealityCheck(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	check, err := h.responsibleGamingService.GetRealityCheck(userID)
+	if err != nil {
+		return h.respondError(c, err, "failed to get reality check")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    check,
+	})
+}
+
+// AcknowledgeRealityCheck handles POST /api/responsible-gaming/reality-check/:id/ack
+// @Summary Acknowledge a reality check
+// @Description Confirm the user has seen a reality check so betting can continue until the next one
+// @Tags responsible-gaming
+// @Accept json
+// @Produce json
+// @Param id path int true "Reality check ID"
+// @Success 200 {object} service.RealityCheckResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/responsible-gaming/reality-check/{id}/ack [post]
+func (h *ResponsibleGamingHandler) AcknowledgeRealityCheck(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	checkID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid reality check ID",
+		})
+	}
+
+	check, err := h.responsibleGamingService.AcknowledgeRealityCheck(userID, uint(checkID))
+	if err != nil {
+		return h.respondError(c, err, "failed to acknowledge reality check")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    check,
+	})
+}
+
+// respondError maps responsible gaming service errors to HTTP responses
+func (h *ResponsibleGamingHandler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	switch err.Error() {
+	case "unknown_limit", "invalid_limit", "unknown_cooldown", "invalid_cooldown_length":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	case "user_not_found", "reality_check_not_found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":   true,
+		"message": fallback,
+	})
+}
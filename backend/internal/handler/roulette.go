@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -29,6 +30,7 @@ func NewRouletteHandler() *RouletteHandler {
 // @Param request body service.PlaceBetRequest true "Bet request"
 // @Success 200 {object} service.PlaceBetResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/games/roulette/bet [post]
@@ -65,6 +67,16 @@ func (h *RouletteHandler) PlaceBet(c *fiber.Ctx) error {
 	// Place bet
 	result, err := h.rouletteService.PlaceBet(req)
 	if err != nil {
+		// Check for responsible-gaming limits
+		var limitErr *service.BetLimitError
+		if errors.As(err, &limitErr) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   true,
+				"message": limitErr.Code,
+				"until":   limitErr.Until,
+			})
+		}
+
 		switch err.Error() {
 		case "user not found":
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -63,6 +63,7 @@ func (h *ShopHandler) GetItems(c *fiber.Ctx) error {
 // @Success 200 {object} service.BuyItemResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/shop/buy/{itemId} [post]
 func (h *ShopHandler) BuyItem(c *fiber.Ctx) error {
@@ -102,6 +103,12 @@ func (h *ShopHandler) BuyItem(c *fiber.Ctx) error {
 				"message": errMsg,
 			})
 		}
+		if errMsg == "item out of stock" || errMsg == "limited edition sold out" {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   true,
+				"message": errMsg,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "failed to purchase item",
@@ -115,9 +122,53 @@ func (h *ShopHandler) BuyItem(c *fiber.Ctx) error {
 	})
 }
 
+// GetPriceHistory handles GET /api/shop/items/:itemId/price-history
+// @Summary Get shop price history
+// @Description Get recent shop purchase prices of an item along with its base and current price
+// @Tags shop
+// @Accept json
+// @Produce json
+// @Param itemId path int true "Item ID"
+// @Param limit query int false "Limit number of price points" default(100)
+// @Success 200 {object} service.ShopPriceHistoryResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/shop/items/{itemId}/price-history [get]
+func (h *ShopHandler) GetPriceHistory(c *fiber.Ctx) error {
+	itemID, err := strconv.ParseUint(c.Params("itemId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid item ID",
+		})
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "100"))
+
+	history, err := h.shopService.GetPriceHistory(uint(itemID), limit)
+	if err != nil {
+		if err.Error() == "item not found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": "item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get price history",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    history,
+	})
+}
+
 // SellItem handles POST /api/shop/sell/:userItemId
 // @Summary Sell an item
-// @Description Sell a user's item for 50% of its original price
+// @Description Sell a user's item for its resale value, which depends on its type, age and condition
 // @Tags shop
 // @Accept json
 // @Produce json
@@ -159,6 +210,12 @@ func (h *ShopHandler) SellItem(c *fiber.Ctx) error {
 				"message": errMsg,
 			})
 		}
+		if errMsg == "item is listed on the market" || errMsg == "item is pledged as collateral" {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   true,
+				"message": errMsg,
+			})
+		}
 		// Log the actual error for debugging
 		c.Context().Logger().Printf("Error selling item: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -263,6 +320,12 @@ func (h *ShopHandler) EquipItem(c *fiber.Ctx) error {
 				"message": errMsg,
 			})
 		}
+		if errMsg == "item is listed on the market" || errMsg == "item is broken" {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   true,
+				"message": errMsg,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "failed to equip item",
@@ -275,3 +338,69 @@ func (h *ShopHandler) EquipItem(c *fiber.Ctx) error {
 		"message": "item equipped successfully",
 	})
 }
+
+// RepairItem handles POST /api/shop/repair/:userItemId
+// @Summary Repair an item
+// @Description Restore a worn or broken item to mint condition. The cost grows with the damage.
+// @Tags shop
+// @Accept json
+// @Produce json
+// @Param userItemId path int true "User Item ID"
+// @Success 200 {object} service.RepairItemResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/shop/repair/{userItemId} [post]
+func (h *ShopHandler) RepairItem(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	userItemID, err := strconv.ParseUint(c.Params("userItemId"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid user item ID",
+		})
+	}
+
+	result, err := h.shopService.RepairItem(userID, uint(userItemID))
+	if err != nil {
+		errMsg := err.Error()
+		switch errMsg {
+		case "user not found", "user item not found or does not belong to user":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   true,
+				"message": errMsg,
+			})
+		case "item is listed on the market", "item is not damaged":
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   true,
+				"message": errMsg,
+			})
+		case "insufficient balance":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": errMsg,
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "failed to repair item",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+		"message": "item repaired successfully",
+	})
+}
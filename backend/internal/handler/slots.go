@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/smoreg/freezino/backend/internal/service"
 )
@@ -27,6 +29,7 @@ func NewSlotsHandler() *SlotsHandler {
 // @Param bet body number true "Bet amount"
 // @Success 200 {object} service.SpinResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/games/slots/spin [post]
@@ -74,6 +77,16 @@ func (h *SlotsHandler) Spin(c *fiber.Ctx) error {
 			})
 		}
 
+		// Check for responsible-gaming limits
+		var limitErr *service.BetLimitError
+		if errors.As(err, &limitErr) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   true,
+				"message": limitErr.Code,
+				"until":   limitErr.Until,
+			})
+		}
+
 		// Check for insufficient balance error
 		if len(err.Error()) > 20 && err.Error()[:20] == "insufficient balance" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/smoreg/freezino/backend/internal/service"
+)
+
+// TwoFactorHandler handles two-factor authentication setup HTTP requests
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+// NewTwoFactorHandler creates a new two-factor handler instance
+func NewTwoFactorHandler() *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: service.NewTwoFactorService(),
+	}
+}
+
+// twoFactorCodeRequest carries a code from the authenticator app or a recovery code
+type twoFactorCodeRequest struct {
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+// GetStatus handles GET /api/auth/2fa
+// @Summary Get two-factor status
+// @Description Whether two-factor authentication is on, and how many recovery codes are left.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.TwoFactorStatus
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/2fa [get]
+func (h *TwoFactorHandler) GetStatus(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	status, err := h.twoFactorService.GetStatus(userID)
+	if err != nil {
+		return h.respondError(c, err, "failed to get two-factor status")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// Enroll handles POST /api/auth/2fa/enroll
+// @Summary Start two-factor setup
+// @Description Generate a TOTP secret and its otpauth:// provisioning URI to show as a QR code. Nothing changes until the first code is confirmed at /auth/2fa/enable.
+// @Description Only accounts with a password can enroll.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.TwoFactorEnrollment
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/2fa/enroll [post]
+func (h *TwoFactorHandler) Enroll(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	enrollment, err := h.twoFactorService.Enroll(userID)
+	if err != nil {
+		return h.respondError(c, err, "failed to start two-factor setup")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    enrollment,
+	})
+}
+
+// Enable handles POST /api/auth/2fa/enable
+// @Summary Enable two-factor authentication
+// @Description Confirm setup with a first code from the authenticator app. Returns 10 recovery codes, which are shown only this once.
+// @Description Every other session is signed out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body object true "Code from the authenticator app" example({"code":"123456"})
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/2fa/enable [post]
+func (h *TwoFactorHandler) Enable(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	sessionID, _ := c.Locals("sessionID").(uint)
+
+	codes, err := h.twoFactorService.Enable(userID, sessionID, req.Code)
+	if err != nil {
+		return h.respondError(c, err, "failed to enable two-factor authentication")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    fiber.Map{"recovery_codes": codes},
+		"message": "two-factor authentication enabled",
+	})
+}
+
+// Disable handles POST /api/auth/2fa/disable
+// @Summary Disable two-factor authentication
+// @Description Turn two-factor authentication off with the account password and a code from the authenticator app or a recovery code.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body object true "Password and code" example({"password":"...","code":"123456"})
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/2fa/disable [post]
+func (h *TwoFactorHandler) Disable(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	if err := h.twoFactorService.Disable(userID, req.Password, req.Code); err != nil {
+		return h.respondError(c, err, "failed to disable two-factor authentication")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"message": "two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes handles POST /api/auth/2fa/recovery-codes
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes with 10 new ones, given a code from the authenticator app.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body object true "Code from the authenticator app" example({"code":"123456"})
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/auth/2fa/recovery-codes [post]
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	var req twoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "invalid request body",
+		})
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		return h.respondError(c, err, "failed to regenerate recovery codes")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    fiber.Map{"recovery_codes": codes},
+	})
+}
+
+// respondError maps two-factor service errors to HTTP responses
+func (h *TwoFactorHandler) respondError(c *fiber.Ctx, err error, fallback string) error {
+	status := fiber.StatusInternalServerError
+	switch err.Error() {
+	case "invalid_code", "invalid_password":
+		status = fiber.StatusBadRequest
+	case "user_not_found":
+		status = fiber.StatusNotFound
+	case "two_factor_requires_password", "two_factor_already_enabled", "two_factor_not_enrolled", "two_factor_not_enabled":
+		status = fiber.StatusConflict
+	default:
+		return c.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": fallback,
+		})
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":   true,
+		"message": err.Error(),
+	})
+}
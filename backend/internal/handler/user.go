@@ -94,10 +94,10 @@ func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
 	}
 
 	// Validate that at least one field is provided
-	if req.Name == nil && req.Avatar == nil {
+	if req.Name == nil && req.Avatar == nil && req.Timezone == nil && req.Country == nil && req.LeaderboardVisibility == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
-			"message": "at least one field (name or avatar) must be provided",
+			"message": "at least one field (name, avatar, timezone, country or leaderboard_visibility) must be provided",
 		})
 	}
 
@@ -109,6 +109,12 @@ func (h *UserHandler) UpdateProfile(c *fiber.Ctx) error {
 				"message": "user not found",
 			})
 		}
+		if err.Error() == "invalid timezone" || err.Error() == "invalid country" || err.Error() == "invalid leaderboard visibility" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
 			"message": "failed to update profile",
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -10,13 +11,16 @@ import (
 
 // WorkHandler handles work-related HTTP requests
 type WorkHandler struct {
-	workService *service.WorkService
+	workService   *service.WorkService
+	careerService *service.CareerService
 }
 
 // NewWorkHandler creates a new work handler instance
 func NewWorkHandler() *WorkHandler {
+	workService := service.NewWorkService()
 	return &WorkHandler{
-		workService: service.NewWorkService(),
+		workService:   workService,
+		careerService: service.NewCareerService(workService.Jobs()),
 	}
 }
 
@@ -76,23 +80,18 @@ func (h *WorkHandler) StartWork(c *fiber.Ctx) error {
 				"message": "work session already in progress",
 			})
 		}
-		// Check for job requirement errors
-		if err.Error() == "office_no_clothes" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   true,
-				"message": "office_no_clothes",
-			})
-		}
-		if err.Error() == "courier_no_uniform" {
+		if err.Error() == "unknown job type" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error":   true,
-				"message": "courier_no_uniform",
+				"message": "unknown job type",
 			})
 		}
-		if err.Error() == "stunt_driver_no_car" {
+		// Check for job requirement errors
+		var reqErr *service.JobRequirementError
+		if errors.As(err, &reqErr) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error":   true,
-				"message": "stunt_driver_no_car",
+				"message": reqErr.Code,
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -265,65 +264,14 @@ func (h *WorkHandler) GetHistory(c *fiber.Ctx) error {
 
 // GetAvailableJobs handles GET /api/work/jobs
 // @Summary Get available job types
-// @Description Get list of available job types with requirements
+// @Description Get list of available job types with requirements, generated from the job catalogue
 // @Tags work
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Router /api/work/jobs [get]
 func (h *WorkHandler) GetAvailableJobs(c *fiber.Ctx) error {
-	jobs := []map[string]interface{}{
-		{
-			"type":        "office",
-			"name":        "Office Worker",
-			"base_reward": 500,
-			"requires":    "clothing",
-			"description": "Standard office job. Requires at least one clothing item.",
-		},
-		{
-			"type":        "courier",
-			"name":        "Courier",
-			"base_reward": 500,
-			"requires":    "courier_uniform",
-			"bonus":       "own_car",
-			"description": "Delivery work. Requires Courier Uniform. +$250 with own car.",
-		},
-		{
-			"type":        "lab_rat",
-			"name":        "Lab Test Subject",
-			"base_reward": 500,
-			"reward":      "random_mutation",
-			"description": "Be a test subject for mad scientist. Receive random mutation.",
-		},
-		{
-			"type":        "stunt_driver",
-			"name":        "Stunt Driver",
-			"base_reward": 1500,
-			"requires":    "car",
-			"penalty":     "car_broken",
-			"description": "High-risk stunts. Requires car. Earn $1500 but car gets broken.",
-		},
-		{
-			"type":        "drug_dealer",
-			"name":        "Surprise Delivery",
-			"base_reward": 2000,
-			"penalty":     "jail_8years",
-			"description": "Risky business. Earn $2000 but you'll be caught (8 year sentence, skippable).",
-		},
-		{
-			"type":        "streamer",
-			"name":        "Streamer",
-			"base_reward": 0,
-			"variable":    "lottery",
-			"description": "Stream online. 70% = $0, 29% = $1, 1% = $10,000 + go viral (all future streams $10k).",
-		},
-		{
-			"type":        "bottle_collector",
-			"name":        "Bottle Collector",
-			"base_reward": 100,
-			"description": "Collect bottles and cans. Always $100. Available to everyone.",
-		},
-	}
+	jobs := h.workService.GetAvailableJobs()
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"success": true,
@@ -331,6 +279,40 @@ func (h *WorkHandler) GetAvailableJobs(c *fiber.Ctx) error {
 	})
 }
 
+// GetCareer handles GET /api/work/career
+// @Summary Get career progression
+// @Description Get experience, level and pay multiplier per job, plus jobs still locked behind career levels
+// @Tags work
+// @Accept json
+// @Produce json
+// @Success 200 {object} service.CareerOverviewResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/work/career [get]
+func (h *WorkHandler) GetCareer(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   true,
+			"message": "unauthorized",
+		})
+	}
+
+	overview, err := h.careerService.GetCareers(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "failed to get career",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"success": true,
+		"data":    overview,
+	})
+}
+
 // SkipJailTime handles POST /api/work/skip-jail
 // @Summary Skip jail time
 // @Description Skip the jail sentence time (instant release)
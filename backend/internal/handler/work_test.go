@@ -31,6 +31,7 @@ func setupTestApp(t *testing.T) (*fiber.App, *gorm.DB) {
 		&model.User{},
 		&model.WorkSession{},
 		&model.Transaction{},
+		&model.OutboxEvent{},
 	)
 	require.NoError(t, err)
 
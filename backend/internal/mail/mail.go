@@ -0,0 +1,52 @@
+// Package mail sends the emails the backend writes to users, through SMTP in
+// production, as files on disk in development, or into memory in tests.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+
+	"github.com/smoreg/freezino/backend/internal/config"
+)
+
+// Transports a Mailer can be built for
+const (
+	TransportSMTP   = "smtp"
+	TransportFile   = "file"
+	TransportMemory = "memory"
+)
+
+// Message is an email to one recipient, with a plain text and an HTML body
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New builds the mailer cfg.MailTransport names
+func New(cfg *config.Config) (Mailer, error) {
+	from, err := mail.ParseAddress(cfg.MailFrom)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM %q: %w", cfg.MailFrom, err)
+	}
+
+	switch cfg.MailTransport {
+	case TransportSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail transport")
+		}
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, from), nil
+	case TransportFile:
+		return NewFileMailer(cfg.MailDir, from), nil
+	case TransportMemory:
+		return NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mail transport %q", cfg.MailTransport)
+}
@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"context"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                          "en",
+		"ru":                        "ru",
+		"es-ES":                     "es",
+		"ru-RU,ru;q=0.9,en;q=0.8":   "ru",
+		"de-DE,de;q=0.9,es;q=0.8":   "es",
+		"en;q=0.5, ES;q=0.9":        "es",
+		"fr, ru;q=0":                "en",
+		"zh-CN,zh;q=0.9":            "en",
+		"en-GB,en-US;q=0.9,en;q=.8": "en",
+	}
+	for accept, want := range tests {
+		assert.Equal(t, want, Language(accept), accept)
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := struct {
+		Name  string
+		Link  string
+		Hours int
+	}{Name: "<Ann>", Link: "http://app.test/page?token=a.b", Hours: 48}
+
+	for _, lang := range Languages {
+		for _, name := range []string{TemplateVerifyEmail, TemplateResetPassword} {
+			msg, err := Render(name, lang, data)
+			require.NoError(t, err, "%s/%s", lang, name)
+			assert.NotEmpty(t, msg.Subject)
+			assert.NotContains(t, msg.Subject, "\n")
+			assert.Contains(t, msg.Text, data.Link)
+			assert.Contains(t, msg.Text, "<Ann>")
+			assert.Contains(t, msg.HTML, "&lt;Ann&gt;")
+		}
+	}
+
+	// Unknown languages fall back to English
+	msg, err := Render(TemplateResetPassword, "fr", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Reset your Freezino password", msg.Subject)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	from, err := mail.ParseAddress("Freezino <no-reply@freezino.test>")
+	require.NoError(t, err)
+
+	msg := Message{To: "ann@example.com", Subject: "Сброс пароля", Text: "Hello\n", HTML: "<p>Hello</p>"}
+	require.NoError(t, NewFileMailer(dir, from).Send(context.Background(), msg))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, "ann@example.com", strings.Trim(parsed.Header.Get("To"), "<>"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Сброс пароля", subject)
+	assert.Contains(t, parsed.Header.Get("Content-Type"), "multipart/alternative")
+}
@@ -0,0 +1,96 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Emails there are templates for
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+)
+
+// DefaultLanguage is used when none of the reader's languages is available
+const DefaultLanguage = "en"
+
+// Languages the templates are written in, matching the frontend's
+var Languages = []string{"en", "es", "ru"}
+
+// Each template is templates/<language>/<name>.txt, the plain text body that
+// also defines "<name>.subject", and templates/<language>/<name>.html
+//
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	textTemplates = map[string]*texttemplate.Template{}
+	htmlTemplates = map[string]*htmltemplate.Template{}
+)
+
+func init() {
+	for _, lang := range Languages {
+		textTemplates[lang] = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/"+lang+"/*.txt"))
+		htmlTemplates[lang] = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/"+lang+"/*.html"))
+	}
+}
+
+// Render fills in a template in the given language, falling back to
+// DefaultLanguage, and returns the message without a recipient
+func Render(name, lang string, data interface{}) (Message, error) {
+	if _, ok := textTemplates[lang]; !ok {
+		lang = DefaultLanguage
+	}
+
+	var subject, body, html bytes.Buffer
+	if err := textTemplates[lang].ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := textTemplates[lang].ExecuteTemplate(&body, name+".txt", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	if err := htmlTemplates[lang].ExecuteTemplate(&html, name+".html", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// Language picks the template language for an Accept-Language header or a
+// plain language tag, such as "ru-RU,ru;q=0.9,en;q=0.8" or "es"
+func Language(accept string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(accept, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		primary, _, _ := strings.Cut(tag, "-")
+		choices = append(choices, choice{lang: strings.ToLower(primary), q: q})
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+
+	for _, c := range choices {
+		if _, ok := textTemplates[c.lang]; ok && c.q > 0 {
+			return c.lang
+		}
+	}
+	return DefaultLanguage
+}
@@ -9,11 +9,13 @@ import (
 	"github.com/smoreg/freezino/backend/internal/config"
 	"github.com/smoreg/freezino/backend/internal/database"
 	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/service"
 )
 
 // AuthMiddleware creates an authentication middleware
 func AuthMiddleware(cfg *config.Config) fiber.Handler {
 	jwtManager := auth.NewJWTManager(cfg)
+	sessions := service.NewAuthSessionService(jwtManager.RefreshExpiration())
 
 	return func(c *fiber.Ctx) error {
 		// Get token from Authorization header
@@ -54,6 +56,15 @@ func AuthMiddleware(cfg *config.Config) fiber.Handler {
 			})
 		}
 
+		// Tokens from a revoked session stop working before they expire
+		if claims.SessionID != 0 {
+			if active, err := sessions.SessionActive(claims.SessionID); err != nil || !active {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "session revoked",
+				})
+			}
+		}
+
 		// Get user from database
 		db := database.GetDB()
 		var user model.User
@@ -67,14 +78,62 @@ func AuthMiddleware(cfg *config.Config) fiber.Handler {
 		// Store user in context
 		c.Locals("user", &user)
 		c.Locals("userID", user.ID)
+		c.Locals("sessionID", claims.SessionID)
 
 		return c.Next()
 	}
 }
 
+// RequireVerifiedEmail lets through only users who confirmed their email
+// address. It must run after AuthMiddleware.
+func RequireVerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*model.User)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "unauthorized",
+			})
+		}
+		if user.EmailVerifiedAt == nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "email not verified",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireAdmin lets through only users whose verified email is in
+// ADMIN_EMAILS. It must run after AuthMiddleware.
+func RequireAdmin(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*model.User)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "unauthorized",
+			})
+		}
+
+		// Unverified, anyone could have registered the address
+		if user.EmailVerifiedAt != nil {
+			for _, email := range cfg.AdminEmails {
+				if strings.EqualFold(email, user.Email) {
+					return c.Next()
+				}
+			}
+		}
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "admin access required",
+		})
+	}
+}
+
 // OptionalAuth is a middleware that adds user info if authenticated but doesn't require it
 func OptionalAuth(cfg *config.Config) fiber.Handler {
 	jwtManager := auth.NewJWTManager(cfg)
+	sessions := service.NewAuthSessionService(jwtManager.RefreshExpiration())
 
 	return func(c *fiber.Ctx) error {
 		// Get token from Authorization header
@@ -101,6 +160,11 @@ func OptionalAuth(cfg *config.Config) fiber.Handler {
 		if claims.Type != auth.AccessToken {
 			return c.Next()
 		}
+		if claims.SessionID != 0 {
+			if active, err := sessions.SessionActive(claims.SessionID); err != nil || !active {
+				return c.Next()
+			}
+		}
 
 		// Get user from database
 		db := database.GetDB()
@@ -113,7 +177,25 @@ func OptionalAuth(cfg *config.Config) fiber.Handler {
 		// Store user in context
 		c.Locals("user", &user)
 		c.Locals("userID", user.ID)
+		c.Locals("sessionID", claims.SessionID)
 
 		return c.Next()
 	}
 }
+
+// StreamAuthMiddleware authenticates like AuthMiddleware but also accepts the
+// access token as a ?token= query parameter, since browsers can't set headers
+// on WebSocket and EventSource requests
+func StreamAuthMiddleware(cfg *config.Config) fiber.Handler {
+	authenticate := AuthMiddleware(cfg)
+
+	return func(c *fiber.Ctx) error {
+		if c.Get("Authorization") == "" {
+			if token := c.Query("token"); token != "" {
+				c.Request().Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+
+		return authenticate(c)
+	}
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+)
+
+// AccountDeletionStatus represents where an account deletion request is
+type AccountDeletionStatus string
+
+const (
+	AccountDeletionPending   AccountDeletionStatus = "pending"   // Waiting out the grace period
+	AccountDeletionCancelled AccountDeletionStatus = "cancelled" // The user changed their mind
+	AccountDeletionCompleted AccountDeletionStatus = "completed" // Personal data has been erased
+)
+
+// AccountDeletion is a user's request to delete their account. Once the grace
+// period is over their personal data is erased and what remains is kept under
+// a pseudonym.
+type AccountDeletion struct {
+	ID           uint                  `gorm:"primarykey" json:"id"`
+	UserID       uint                  `gorm:"not null;index" json:"user_id"`
+	Status       AccountDeletionStatus `gorm:"size:20;not null;index:idx_account_deletion_due,priority:1" json:"status"`
+	RequestedAt  time.Time             `gorm:"not null" json:"requested_at"`
+	ScheduledFor time.Time             `gorm:"not null;index:idx_account_deletion_due,priority:2" json:"scheduled_for"`
+	CancelledAt  *time.Time            `json:"cancelled_at,omitempty"`
+	CompletedAt  *time.Time            `json:"completed_at,omitempty"`
+	Pseudonym    string                `gorm:"size:64" json:"pseudonym,omitempty"` // What the user's name and email were replaced with
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// TableName specifies the table name for AccountDeletion model
+func (AccountDeletion) TableName() string {
+	return "account_deletions"
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"time"
+)
+
+// AuctionStatus represents the state of an auction
+type AuctionStatus string
+
+const (
+	AuctionStatusActive AuctionStatus = "active"
+	AuctionStatusSold   AuctionStatus = "sold"
+	AuctionStatusUnsold AuctionStatus = "unsold" // Closed without a bid meeting the reserve
+)
+
+// AuctionSource represents where the auctioned item came from
+type AuctionSource string
+
+const (
+	AuctionSourceHouse   AuctionSource = "house"   // Rare item offered by the casino
+	AuctionSourceSeizure AuctionSource = "seizure" // Item seized by loan collectors
+)
+
+// Auction is a timed English auction for a single item
+type Auction struct {
+	ID            uint          `gorm:"primarykey" json:"id"`
+	ItemID        uint          `gorm:"not null;index" json:"item_id"`
+	Source        AuctionSource `gorm:"size:20;not null" json:"source"`
+	FormerOwnerID *uint         `gorm:"index" json:"-"`          // Debtor the item was seized from
+	LoanID        *uint         `json:"-"`                       // Loan the seizure was made for
+	SerialNumber  *int          `json:"serial_number,omitempty"` // Copy of a limited edition on sale
+	StartingPrice float64       `gorm:"type:decimal(15,2);not null" json:"starting_price"`
+	ReservePrice  float64       `gorm:"type:decimal(15,2);not null" json:"-"` // Hidden from bidders
+	CurrentBid    float64       `gorm:"type:decimal(15,2);default:0.00" json:"current_bid"`
+	HighBidderID  *uint         `gorm:"index" json:"-"` // Bidders are anonymous; views say whether the viewer leads
+	BidCount      int           `gorm:"default:0" json:"bid_count"`
+	Extensions    int           `gorm:"default:0" json:"extensions"` // Anti-sniping extensions applied
+	StartsAt      time.Time     `gorm:"not null" json:"starts_at"`
+	EndsAt        time.Time     `gorm:"not null;index:idx_auction_status_ends" json:"ends_at"`
+	Status        AuctionStatus `gorm:"size:20;not null;default:'active';index:idx_auction_status_ends" json:"status"`
+	SettledAt     *time.Time    `json:"settled_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+
+	// Relations
+	Item Item `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+}
+
+// TableName specifies the table name for Auction model
+func (Auction) TableName() string {
+	return "auctions"
+}
+
+// ReserveMet reports whether the current bid is high enough to sell the item
+func (a *Auction) ReserveMet() bool {
+	return a.BidCount > 0 && a.CurrentBid >= a.ReservePrice
+}
+
+// BidHoldStatus represents what happened to the funds locked by a bid
+type BidHoldStatus string
+
+const (
+	BidHoldHeld     BidHoldStatus = "held"     // Funds are locked while the bid leads
+	BidHoldReleased BidHoldStatus = "released" // Outbid or auction unsold, funds returned
+	BidHoldCaptured BidHoldStatus = "captured" // Auction won, funds paid for the item
+)
+
+// AuctionBid is a bid on an auction. The bid amount is held from the
+// bidder's balance until the bid is outbid or the auction settles.
+type AuctionBid struct {
+	ID         uint          `gorm:"primarykey" json:"id"`
+	AuctionID  uint          `gorm:"not null;index" json:"auction_id"`
+	UserID     uint          `gorm:"not null;index:idx_bid_user_hold" json:"-"`
+	Amount     float64       `gorm:"type:decimal(15,2);not null" json:"amount"`
+	HoldStatus BidHoldStatus `gorm:"size:20;not null;default:'held';index:idx_bid_user_hold" json:"hold_status"`
+	ReleasedAt *time.Time    `json:"released_at,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// TableName specifies the table name for AuctionBid model
+func (AuctionBid) TableName() string {
+	return "auction_bids"
+}
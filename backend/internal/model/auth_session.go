@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+)
+
+// AuthSession is a signed-in device: one login and every refresh token
+// rotated from it. Its ID is the family ID of those tokens.
+type AuthSession struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	UserAgent     string     `gorm:"size:255" json:"user_agent"`
+	IP            string     `gorm:"size:64" json:"ip"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
+	ExpiresAt     time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `gorm:"size:32" json:"revoked_reason,omitempty"` // logout, logout_all, revoked, token_reused, password_reset or two_factor_enabled
+
+	Current bool `gorm:"-" json:"current"` // Whether this is the session making the request
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// TableName specifies the table name for AuthSession model
+func (AuthSession) TableName() string {
+	return "auth_sessions"
+}
+
+// RefreshToken is a refresh token of a session, stored as a SHA-256 hash. Each
+// token can be used once; using one again means it has been stolen.
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	FamilyID  uint       `gorm:"not null;index" json:"family_id"` // The AuthSession it was issued to
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"` // When it was rotated
+}
+
+// TableName specifies the table name for RefreshToken model
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
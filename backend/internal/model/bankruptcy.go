@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// Bankruptcy records a user going bankrupt and losing everything to collectors
+type Bankruptcy struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	UserID         uint      `gorm:"not null;index" json:"user_id"`
+	DebtWrittenOff float64   `gorm:"type:decimal(15,2);default:0.00" json:"debt_written_off"`
+	ItemsSeized    int       `gorm:"default:0" json:"items_seized"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName specifies the table name for Bankruptcy model
+func (Bankruptcy) TableName() string {
+	return "bankruptcies"
+}
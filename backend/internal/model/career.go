@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// Career tracks a user's experience and level in a single job type
+type Career struct {
+	ID               uint       `gorm:"primarykey" json:"id"`
+	UserID           uint       `gorm:"not null;uniqueIndex:idx_user_job_career" json:"user_id"`
+	JobType          JobType    `gorm:"size:50;not null;uniqueIndex:idx_user_job_career" json:"job_type"`
+	Experience       int        `gorm:"not null;default:0" json:"experience"`
+	Level            int        `gorm:"not null;default:1" json:"level"`
+	ShiftsCompleted  int        `gorm:"not null;default:0" json:"shifts_completed"`
+	TotalEarned      float64    `gorm:"type:decimal(15,2);default:0.00" json:"total_earned"`
+	PromotionBlocked bool       `gorm:"default:false" json:"promotion_blocked"` // Level-up withheld because of a status (e.g. jail record)
+	PromotedAt       *time.Time `json:"promoted_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName specifies the table name for Career model
+func (Career) TableName() string {
+	return "careers"
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+)
+
+// CollectionEventType represents a step of the collections process
+type CollectionEventType string
+
+const (
+	CollectionEventNotice           CollectionEventType = "notice"
+	CollectionEventCollateralSeized CollectionEventType = "collateral_seized"
+	CollectionEventItemSeized       CollectionEventType = "item_seized"
+	CollectionEventItemAuctioned    CollectionEventType = "item_auctioned"
+	CollectionEventBalanceApplied   CollectionEventType = "balance_applied"
+	CollectionEventSurplusReturned  CollectionEventType = "surplus_returned"
+	CollectionEventLoanSettled      CollectionEventType = "loan_settled"
+	CollectionEventBankruptcy       CollectionEventType = "bankruptcy"
+)
+
+// CollectionEvent logs one step taken by collectors so the user can see what happened
+type CollectionEvent struct {
+	ID          uint                `gorm:"primarykey" json:"id"`
+	UserID      uint                `gorm:"not null;index:idx_user_collection_events" json:"user_id"`
+	LoanID      *uint               `gorm:"index" json:"loan_id,omitempty"`
+	Type        CollectionEventType `gorm:"size:30;not null" json:"type"`
+	Amount      float64             `gorm:"type:decimal(15,2);default:0.00" json:"amount"`
+	ItemName    string              `gorm:"size:255" json:"item_name,omitempty"`
+	Description string              `gorm:"size:512" json:"description"`
+	CreatedAt   time.Time           `gorm:"index:idx_user_collection_events" json:"created_at"`
+}
+
+// TableName specifies the table name for CollectionEvent model
+func (CollectionEvent) TableName() string {
+	return "collection_events"
+}
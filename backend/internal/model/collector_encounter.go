@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// EncounterStatus represents whether a collector encounter still awaits a choice
+type EncounterStatus string
+
+const (
+	EncounterStatusActive   EncounterStatus = "active"
+	EncounterStatusResolved EncounterStatus = "resolved"
+)
+
+// CollectorEncounter is one run of a collector scenario for a user
+type CollectorEncounter struct {
+	ID         uint            `gorm:"primarykey" json:"id"`
+	UserID     uint            `gorm:"not null;index:idx_user_encounter_status" json:"user_id"`
+	ScenarioID string          `gorm:"size:100;not null" json:"scenario_id"`
+	State      string          `gorm:"size:100;not null" json:"state"`
+	Status     EncounterStatus `gorm:"size:20;not null;default:'active';index:idx_user_encounter_status" json:"status"`
+	ResolvedAt *time.Time      `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+
+	// Relations
+	Steps []CollectorEncounterStep `gorm:"foreignKey:EncounterID" json:"steps,omitempty"`
+}
+
+// TableName specifies the table name for CollectorEncounter model
+func (CollectorEncounter) TableName() string {
+	return "collector_encounters"
+}
+
+// CollectorEncounterStep records a choice made in an encounter and what came of it
+type CollectorEncounterStep struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	EncounterID   uint      `gorm:"not null;index" json:"encounter_id"`
+	FromState     string    `gorm:"size:100;not null" json:"from_state"`
+	Choice        string    `gorm:"size:100;not null" json:"choice"`
+	ToState       string    `gorm:"size:100;not null" json:"to_state"`
+	OutcomeText   string    `gorm:"size:1024" json:"outcome_text"`
+	BalanceChange float64   `gorm:"type:decimal(15,2);default:0.00" json:"balance_change"`
+	DebtChange    float64   `gorm:"type:decimal(15,2);default:0.00" json:"debt_change"`
+	ItemsLost     int       `gorm:"default:0" json:"items_lost"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for CollectorEncounterStep model
+func (CollectorEncounterStep) TableName() string {
+	return "collector_encounter_steps"
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+)
+
+// DataExportStatus represents where a data export is in its lifecycle
+type DataExportStatus string
+
+const (
+	DataExportStatusPending  DataExportStatus = "pending"
+	DataExportStatusBuilding DataExportStatus = "building"
+	DataExportStatusReady    DataExportStatus = "ready"
+	DataExportStatusFailed   DataExportStatus = "failed"
+	DataExportStatusExpired  DataExportStatus = "expired" // The archive has been deleted
+)
+
+// DataExport is a user's request for an archive of everything held about them.
+// The archive is built in the background and can be downloaded with the token
+// until it expires; only the token's hash is stored.
+type DataExport struct {
+	ID          uint             `gorm:"primarykey" json:"id"`
+	UserID      uint             `gorm:"not null;index" json:"user_id"`
+	Status      DataExportStatus `gorm:"size:20;not null;index" json:"status"`
+	TokenHash   string           `gorm:"size:64;not null;uniqueIndex" json:"-"` // SHA-256 of the download token
+	FilePath    string           `gorm:"size:512" json:"-"`
+	Size        int64            `json:"size"` // Archive size in bytes
+	Error       string           `gorm:"size:255" json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	StartedAt   *time.Time       `json:"started_at,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time       `gorm:"index" json:"expires_at,omitempty"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// TableName specifies the table name for DataExport model
+func (DataExport) TableName() string {
+	return "data_exports"
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+)
+
+// EmailTokenPurpose is what an email token may be used for
+type EmailTokenPurpose string
+
+const (
+	EmailTokenVerifyEmail   EmailTokenPurpose = "verify_email"
+	EmailTokenResetPassword EmailTokenPurpose = "reset_password"
+)
+
+// EmailToken is a single-use token mailed to a user to prove they read the
+// address. The token itself is signed and only its SHA-256 hash is stored.
+type EmailToken struct {
+	ID        uint              `gorm:"primarykey" json:"id"`
+	UserID    uint              `gorm:"not null;index" json:"user_id"`
+	Purpose   EmailTokenPurpose `gorm:"size:20;not null" json:"purpose"`
+	Email     string            `gorm:"size:255;not null" json:"email"` // The address it was sent to
+	TokenHash string            `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time        `json:"used_at,omitempty"` // When it was used, or replaced by a newer token
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// TableName specifies the table name for EmailToken model
+func (EmailToken) TableName() string {
+	return "email_tokens"
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+)
+
+// GamblingLimitType is the kind of responsible-gaming limit a user has set
+type GamblingLimitType string
+
+const (
+	GamblingLimitDailyLoss       GamblingLimitType = "daily_loss"       // Net loss per calendar day
+	GamblingLimitWeeklyLoss      GamblingLimitType = "weekly_loss"      // Net loss per calendar week
+	GamblingLimitSessionWager    GamblingLimitType = "session_wager"    // Total stakes per gambling session
+	GamblingLimitSessionDuration GamblingLimitType = "session_duration" // Minutes per gambling session
+)
+
+// GamblingLimit is a limit a user has put on their own gambling. Tighter limits
+// apply at once; looser ones wait in Pending* until PendingAt.
+type GamblingLimit struct {
+	ID            uint              `gorm:"primarykey" json:"id"`
+	UserID        uint              `gorm:"not null;uniqueIndex:idx_gambling_limit_user" json:"user_id"`
+	Type          GamblingLimitType `gorm:"size:20;not null;uniqueIndex:idx_gambling_limit_user" json:"type"`
+	Amount        *float64          `gorm:"type:decimal(15,2)" json:"amount"`         // Nil when no limit is in force yet
+	PendingAmount *float64          `gorm:"type:decimal(15,2)" json:"pending_amount"` // Nil with PendingAt set removes the limit
+	PendingAt     *time.Time        `json:"pending_at"`
+
+	// The timezone the limit's days and weeks are counted in, fixed when the
+	// limit is set so changing timezone can't restart a window. A change waits
+	// in PendingTimezone like a looser limit does.
+	Timezone        string `gorm:"size:64" json:"timezone"`
+	PendingTimezone string `gorm:"size:64" json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GamblingLimit model
+func (GamblingLimit) TableName() string {
+	return "gambling_limits"
+}
+
+// Effective returns the limit in force at now, or nil if there is none
+func (l *GamblingLimit) Effective(now time.Time) *float64 {
+	if l == nil {
+		return nil
+	}
+	if l.PendingAt != nil && !now.Before(*l.PendingAt) {
+		return l.PendingAmount
+	}
+	return l.Amount
+}
+
+// EffectiveTimezone returns the timezone the limit is counted in at now. It is
+// empty for limits set before timezones were recorded.
+func (l *GamblingLimit) EffectiveTimezone(now time.Time) string {
+	if l == nil {
+		return ""
+	}
+	if l.PendingAt != nil && !now.Before(*l.PendingAt) && l.PendingTimezone != "" {
+		return l.PendingTimezone
+	}
+	return l.Timezone
+}
+
+// GamblingSession is a run of bets with no long break in between. Session
+// limits and reality checks are measured against it.
+type GamblingSession struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;index:idx_gambling_session_user" json:"user_id"`
+	StartedAt time.Time `gorm:"not null" json:"started_at"`
+	LastBetAt time.Time `gorm:"not null;index:idx_gambling_session_user" json:"last_bet_at"`
+	Wagered   float64   `gorm:"type:decimal(15,2);not null;default:0" json:"wagered"`
+	Bets      int       `gorm:"not null;default:0" json:"bets"`
+
+	RealityCheckAt *time.Time `gorm:"index" json:"reality_check_at"` // When the next reality check is due
+}
+
+// TableName specifies the table name for GamblingSession model
+func (GamblingSession) TableName() string {
+	return "gambling_sessions"
+}
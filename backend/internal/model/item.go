@@ -40,6 +40,24 @@ type Item struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 
+	// Stock (nil means unlimited) and its restock schedule
+	Stock                *int       `json:"stock,omitempty"`
+	MaxStock             int        `gorm:"default:0" json:"max_stock,omitempty"` // Restocks stop at this level (0 means no cap)
+	RestockQuantity      int        `gorm:"default:0" json:"restock_quantity,omitempty"`
+	RestockIntervalHours int        `gorm:"default:0" json:"restock_interval_hours,omitempty"`
+	LastRestockAt        *time.Time `json:"last_restock_at,omitempty"`
+
+	// Limited editions get serial numbers 1..EditionSize and never restock
+	EditionSize  *int `json:"edition_size,omitempty"`
+	EditionsSold int  `gorm:"default:0" json:"editions_sold"`
+
+	// Demand pricing: purchases add a premium on top of Price that decays over time
+	DemandPremium   float64    `gorm:"default:0" json:"-"`
+	DemandUpdatedAt *time.Time `json:"-"`
+
+	// Modifiers applied to work, loans and games while the item is equipped
+	Effects []ItemEffect `gorm:"serializer:json" json:"effects,omitempty"`
+
 	// Relations
 	UserItems []UserItem `gorm:"foreignKey:ItemID" json:"user_items,omitempty"`
 }
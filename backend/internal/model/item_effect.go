@@ -0,0 +1,36 @@
+package model
+
+// ItemEffectType identifies what an item effect modifies
+type ItemEffectType string
+
+const (
+	ItemEffectWorkPayMultiplier    ItemEffectType = "work_pay_multiplier"    // Adds Value (0.1 = +10%) to shift pay
+	ItemEffectWorkPayFlat          ItemEffectType = "work_pay_flat"          // Adds Value dollars to shift pay
+	ItemEffectJobLuck              ItemEffectType = "job_luck"               // Scales the weight of outcomes paying above base pay by 1+Value
+	ItemEffectLoanRateDiscount     ItemEffectType = "loan_rate_discount"     // Subtracts Value from the APR of new loans
+	ItemEffectGamePayoutMultiplier ItemEffectType = "game_payout_multiplier" // Adds Value (0.05 = +5%) to game winnings above the stake
+	ItemEffectGameLossRebate       ItemEffectType = "game_loss_rebate"       // Refunds Value (0.1 = 10%) of a lost stake
+)
+
+// ItemEffect is a typed modifier carried by an item definition. Effects only
+// apply while the item is equipped. JobType, LoanType and Game narrow the
+// effect to one job, loan type or game; empty means every one.
+type ItemEffect struct {
+	Type     ItemEffectType `json:"type"`
+	Value    float64        `json:"value"`
+	JobType  JobType        `json:"job_type,omitempty"`
+	LoanType LoanType       `json:"loan_type,omitempty"`
+	Game     GameType       `json:"game,omitempty"`
+}
+
+// Valid reports whether the effect has a known type and a sensible value
+func (e ItemEffect) Valid() bool {
+	switch e.Type {
+	case ItemEffectWorkPayMultiplier, ItemEffectWorkPayFlat, ItemEffectJobLuck,
+		ItemEffectLoanRateDiscount, ItemEffectGamePayoutMultiplier:
+		return e.Value > 0
+	case ItemEffectGameLossRebate:
+		return e.Value > 0 && e.Value < 1
+	}
+	return false
+}
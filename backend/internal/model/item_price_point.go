@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+)
+
+// PriceSource represents where an item changed hands
+type PriceSource string
+
+const (
+	PriceSourceMarket PriceSource = "market"
+	PriceSourceShop   PriceSource = "shop"
+)
+
+// ItemPricePoint records the price an item traded at
+type ItemPricePoint struct {
+	ID        uint        `gorm:"primarykey" json:"id"`
+	ItemID    uint        `gorm:"not null;index:idx_item_price_history" json:"item_id"`
+	Price     float64     `gorm:"type:decimal(15,2);not null" json:"price"`
+	Source    PriceSource `gorm:"size:20;not null" json:"source"`
+	CreatedAt time.Time   `gorm:"index:idx_item_price_history" json:"created_at"`
+}
+
+// TableName specifies the table name for ItemPricePoint model
+func (ItemPricePoint) TableName() string {
+	return "item_price_points"
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// LeaderboardEntry is a user's score on one leaderboard for one period. Scores
+// are updated incrementally as events come in rather than recomputed.
+type LeaderboardEntry struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Board     string    `gorm:"size:32;not null;uniqueIndex:idx_leaderboard_user,priority:1;index:idx_leaderboard_rank,priority:1" json:"board"`
+	Period    string    `gorm:"size:16;not null;uniqueIndex:idx_leaderboard_user,priority:2;index:idx_leaderboard_rank,priority:2" json:"period"` // "all", "d2026-10-18" or "w2026-10-12"
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_leaderboard_user,priority:3;index" json:"user_id"`
+	Hidden    bool      `gorm:"not null;default:false;index:idx_leaderboard_rank,priority:3" json:"hidden"` // Copied from the user's visibility so ranks are a single indexed count
+	Score     float64   `gorm:"not null;index:idx_leaderboard_rank,priority:4" json:"score"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for LeaderboardEntry model
+func (LeaderboardEntry) TableName() string {
+	return "leaderboard_entries"
+}
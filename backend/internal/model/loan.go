@@ -15,24 +15,69 @@ const (
 	LoanTypeMicrocredit LoanType = "microcredit"
 )
 
+// LoanStatus represents where a loan is in its lifecycle
+type LoanStatus string
+
+const (
+	LoanStatusActive       LoanStatus = "active"
+	LoanStatusOverdue      LoanStatus = "overdue"       // Past its due date
+	LoanStatusInCollection LoanStatus = "in_collection" // Overdue past the grace period, handed to collectors
+)
+
+// CollectionStage represents how far collections on a loan have progressed
+type CollectionStage string
+
+const (
+	CollectionStageNone        CollectionStage = ""
+	CollectionStageNotice      CollectionStage = "notice"      // Notice sent, waiting out the grace period
+	CollectionStageCollateral  CollectionStage = "collateral"  // Pledged collateral seized and auctioned
+	CollectionStageLiquidation CollectionStage = "liquidation" // Other assets being liquidated
+)
+
+// LoanRepaymentPlan represents how a loan is paid back
+type LoanRepaymentPlan string
+
+const (
+	LoanPlanAnnuity LoanRepaymentPlan = "annuity" // Equal installments of principal + interest
+	LoanPlanBullet  LoanRepaymentPlan = "bullet"  // Everything due in one payment at the end of the term
+)
+
 // Loan represents a user's loan/credit
 type Loan struct {
-	ID                uint           `gorm:"primarykey" json:"id"`
-	UserID            uint           `gorm:"not null;index" json:"user_id"`
-	Type              LoanType       `gorm:"size:50;not null" json:"type"`
-	PrincipalAmount   float64        `gorm:"type:decimal(15,2);not null" json:"principal_amount"`    // Original borrowed amount
-	RemainingAmount   float64        `gorm:"type:decimal(15,2);not null" json:"remaining_amount"`    // Current debt with interest
-	InterestRate      float64        `gorm:"type:decimal(10,6);not null" json:"interest_rate"`       // Interest rate (e.g., 0.05 for 5%)
-	InterestPerSecond float64        `gorm:"type:decimal(15,8);not null" json:"interest_per_second"` // How much interest accrues per second
-	CollateralItemID  *uint          `gorm:"index" json:"collateral_item_id,omitempty"`              // For bank loans - item held as collateral
-	LastInterestAt    time.Time      `gorm:"not null" json:"last_interest_at"`                       // Last time interest was calculated
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
-	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uint       `gorm:"primarykey" json:"id"`
+	UserID            uint       `gorm:"not null;index" json:"user_id"`
+	Type              LoanType   `gorm:"size:50;not null" json:"type"`
+	PrincipalAmount   float64    `gorm:"type:decimal(15,2);not null" json:"principal_amount"`    // Original borrowed amount
+	RemainingAmount   float64    `gorm:"type:decimal(15,2);not null" json:"remaining_amount"`    // Current debt with interest
+	InterestRate      float64    `gorm:"type:decimal(10,6);not null" json:"interest_rate"`       // Annual interest rate / APR (e.g., 0.05 for 5%)
+	InterestPerSecond float64    `gorm:"type:decimal(15,8);not null" json:"interest_per_second"` // How much interest accrues per second
+	CollateralItemID  *uint      `gorm:"index" json:"collateral_item_id,omitempty"`              // For bank loans - item held as collateral
+	LastInterestAt    time.Time  `gorm:"not null" json:"last_interest_at"`                       // Last time interest was calculated
+	Status            LoanStatus `gorm:"size:50;not null;default:'active';index" json:"status"`
+	DueAt             *time.Time `gorm:"index" json:"due_at,omitempty"` // When the loan must be repaid in full
+	OverdueAt         *time.Time `json:"overdue_at,omitempty"`          // When the loan was marked overdue
+
+	// Collections progress, set once the loan is handed to collectors
+	CollectionStage    CollectionStage `gorm:"size:20;default:''" json:"collection_stage,omitempty"`
+	CollectionNoticeAt *time.Time      `json:"collection_notice_at,omitempty"`
+
+	// Terms and balance breakdown. RemainingAmount is always the sum of the three buckets.
+	RepaymentPlan        LoanRepaymentPlan `gorm:"size:20;not null;default:'bullet'" json:"repayment_plan"`
+	TermDays             int               `gorm:"not null;default:0" json:"term_days"`
+	InstallmentCount     int               `gorm:"not null;default:1" json:"installment_count"`
+	LateFee              float64           `gorm:"type:decimal(15,2);default:0.00" json:"late_fee"`  // Charged for each missed installment
+	PenaltyRate          float64           `gorm:"type:decimal(10,6);default:0" json:"penalty_rate"` // APR applied while the loan is overdue
+	OutstandingPrincipal float64           `gorm:"type:decimal(15,2);default:0.00" json:"outstanding_principal"`
+	AccruedInterest      float64           `gorm:"type:decimal(15,8);default:0" json:"accrued_interest"`
+	OutstandingFees      float64           `gorm:"type:decimal(15,2);default:0.00" json:"outstanding_fees"`
+	CreatedAt            time.Time         `json:"created_at"`
+	UpdatedAt            time.Time         `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt    `gorm:"index" json:"-"`
 
 	// Relations
-	User           User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	CollateralItem *UserItem `gorm:"foreignKey:CollateralItemID" json:"collateral_item,omitempty"`
+	User           User              `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	CollateralItem *UserItem         `gorm:"foreignKey:CollateralItemID" json:"collateral_item,omitempty"`
+	Installments   []LoanInstallment `gorm:"foreignKey:LoanID" json:"installments,omitempty"`
 }
 
 // TableName specifies the table name for Loan model
@@ -47,4 +92,5 @@ type LoanSummary struct {
 	FriendsLoanCount   int     `json:"friends_loan_count"`
 	TotalFriendsLoaned float64 `json:"total_friends_loaned"` // Total ever borrowed from friends
 	ActiveLoans        int     `json:"active_loans"`
+	OverdueLoans       int     `json:"overdue_loans"`
 }
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+)
+
+// InstallmentStatus represents the payment state of a loan installment
+type InstallmentStatus string
+
+const (
+	InstallmentStatusPending InstallmentStatus = "pending"
+	InstallmentStatusPaid    InstallmentStatus = "paid"
+	InstallmentStatusMissed  InstallmentStatus = "missed" // Past due and not fully paid; a late fee was charged
+	// Never paid because collectors forgave the loan
+	InstallmentStatusWrittenOff InstallmentStatus = "written_off"
+)
+
+// LoanInstallment is one scheduled payment of a loan
+type LoanInstallment struct {
+	ID           uint              `gorm:"primarykey" json:"id"`
+	LoanID       uint              `gorm:"not null;index:idx_loan_installment" json:"loan_id"`
+	Number       int               `gorm:"not null;index:idx_loan_installment" json:"number"`
+	DueAt        time.Time         `gorm:"not null;index" json:"due_at"`
+	PrincipalDue float64           `gorm:"type:decimal(15,2);not null" json:"principal_due"`
+	InterestDue  float64           `gorm:"type:decimal(15,2);not null" json:"interest_due"`
+	AmountDue    float64           `gorm:"type:decimal(15,2);not null" json:"amount_due"`
+	AmountPaid   float64           `gorm:"type:decimal(15,2);default:0.00" json:"amount_paid"`
+	Status       InstallmentStatus `gorm:"size:20;not null;default:'pending';index" json:"status"`
+	PaidAt       *time.Time        `json:"paid_at,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// TableName specifies the table name for LoanInstallment model
+func (LoanInstallment) TableName() string {
+	return "loan_installments"
+}
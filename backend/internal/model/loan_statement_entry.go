@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+)
+
+// LoanEntryType represents the kind of loan statement entry
+type LoanEntryType string
+
+const (
+	LoanEntryDisbursement LoanEntryType = "disbursement"
+	LoanEntryLateFee      LoanEntryType = "late_fee"
+	LoanEntryPayment      LoanEntryType = "payment"
+	LoanEntryCollection   LoanEntryType = "collection" // Proceeds from seized assets
+	LoanEntryWriteOff     LoanEntryType = "write_off"  // Debt discharged in bankruptcy
+)
+
+// LoanStatementEntry records a money movement on a loan for statements
+type LoanStatementEntry struct {
+	ID            uint          `gorm:"primarykey" json:"id"`
+	LoanID        uint          `gorm:"not null;index:idx_loan_entries" json:"loan_id"`
+	UserID        uint          `gorm:"not null;index" json:"user_id"`
+	Type          LoanEntryType `gorm:"size:20;not null" json:"type"`
+	Amount        float64       `gorm:"type:decimal(15,2);not null" json:"amount"`
+	FeesPaid      float64       `gorm:"type:decimal(15,2);default:0.00" json:"fees_paid"`
+	InterestPaid  float64       `gorm:"type:decimal(15,2);default:0.00" json:"interest_paid"`
+	PrincipalPaid float64       `gorm:"type:decimal(15,2);default:0.00" json:"principal_paid"`
+	BalanceAfter  float64       `gorm:"type:decimal(15,2);default:0.00" json:"balance_after"` // Loan balance after the entry
+	Description   string        `gorm:"size:512" json:"description"`
+	CreatedAt     time.Time     `gorm:"index:idx_loan_entries" json:"created_at"`
+}
+
+// TableName specifies the table name for LoanStatementEntry model
+func (LoanStatementEntry) TableName() string {
+	return "loan_statement_entries"
+}
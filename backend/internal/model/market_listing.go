@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+)
+
+// ListingStatus represents the state of a marketplace listing
+type ListingStatus string
+
+const (
+	ListingStatusActive    ListingStatus = "active"
+	ListingStatusSold      ListingStatus = "sold"
+	ListingStatusCancelled ListingStatus = "cancelled"
+)
+
+// MarketListing is a user item offered for sale to other users.
+// While active the item is held in escrow (UserItem.IsListed).
+type MarketListing struct {
+	ID          uint          `gorm:"primarykey" json:"id"`
+	SellerID    uint          `gorm:"not null;index" json:"seller_id"`
+	UserItemID  uint          `gorm:"not null;index" json:"user_item_id"`
+	ItemID      uint          `gorm:"not null;index:idx_listing_item_status" json:"item_id"`
+	Price       float64       `gorm:"type:decimal(15,2);not null" json:"price"`
+	ListingFee  float64       `gorm:"type:decimal(15,2);default:0.00" json:"listing_fee"` // House cut charged when listing
+	Status      ListingStatus `gorm:"size:20;not null;default:'active';index:idx_listing_item_status" json:"status"`
+	BuyerID     *uint         `gorm:"index" json:"buyer_id,omitempty"`
+	SoldAt      *time.Time    `json:"sold_at,omitempty"`
+	CancelledAt *time.Time    `json:"cancelled_at,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+
+	// Relations
+	Item Item `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+}
+
+// TableName specifies the table name for MarketListing model
+func (MarketListing) TableName() string {
+	return "market_listings"
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// Notification is a live update pushed to a user over the event stream. It
+// is kept for a while so a client that reconnects can resume from the last
+// notification it saw.
+type Notification struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;index:idx_notification_user" json:"user_id"`
+	Type      string    `gorm:"size:50;not null" json:"type"`
+	Payload   string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for Notification model
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// OpenStake is a stake on a game that is still being played, such as a
+// blackjack hand. It counts as lost towards the user's loss limits until the
+// game settles and deletes it.
+type OpenStake struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	GameType  GameType  `gorm:"size:50;not null" json:"game_type"`
+	Amount    float64   `gorm:"type:decimal(15,2);not null" json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for OpenStake model
+func (OpenStake) TableName() string {
+	return "open_stakes"
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// OutboxCursor is the last outbox event an asynchronous subscriber has
+// handled. Subscribers resume from it after a failure or a restart.
+type OutboxCursor struct {
+	Subscriber  string    `gorm:"primaryKey;size:100" json:"subscriber"`
+	LastEventID uint      `gorm:"not null;default:0" json:"last_event_id"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for OutboxCursor model
+func (OutboxCursor) TableName() string {
+	return "outbox_cursors"
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// OutboxEvent is a domain event recorded in the same transaction as the change
+// it describes. It is handed to subscribers only after that transaction commits.
+type OutboxEvent struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	Name        string     `gorm:"size:100;not null;index" json:"name"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Payload     string     `gorm:"type:text;not null" json:"payload"`
+	PublishedAt *time.Time `gorm:"index" json:"published_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for OutboxEvent model
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
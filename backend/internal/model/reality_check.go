@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// RealityCheck is a summary of a gambling session shown to the user at
+// intervals. Betting stays blocked until they acknowledge it.
+type RealityCheck struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	UserID         uint       `gorm:"not null;index" json:"user_id"`
+	SessionID      uint       `gorm:"not null;index" json:"session_id"`
+	PlayedMinutes  int        `gorm:"not null" json:"played_minutes"`
+	Wagered        float64    `gorm:"type:decimal(15,2);not null" json:"wagered"`
+	NetResult      float64    `gorm:"type:decimal(15,2);not null" json:"net_result"` // Negative when the session is down
+	Country        string     `gorm:"size:2;not null" json:"country"`
+	WorkHours      float64    `gorm:"not null" json:"work_hours"` // The loss in hours of work at the country's average wage
+	CreatedAt      time.Time  `json:"created_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+}
+
+// TableName specifies the table name for RealityCheck model
+func (RealityCheck) TableName() string {
+	return "reality_checks"
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// SchedulerLease records which server instance currently owns a background job.
+// Only the lease holder runs the job, so several instances can share a database.
+type SchedulerLease struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	Name      string     `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Owner     string     `gorm:"size:255;not null" json:"owner"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastError string     `gorm:"size:512" json:"last_error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for SchedulerLease model
+func (SchedulerLease) TableName() string {
+	return "scheduler_leases"
+}
@@ -15,6 +15,18 @@ const (
 	TransactionTypePurchase TransactionType = "purchase"
 	TransactionTypeSale     TransactionType = "sale"
 	TransactionTypeInitial  TransactionType = "initial"
+
+	TransactionTypeMarketFee      TransactionType = "market_fee"
+	TransactionTypeMarketSale     TransactionType = "market_sale"
+	TransactionTypeMarketPurchase TransactionType = "market_purchase"
+
+	TransactionTypeAuctionHold    TransactionType = "auction_hold"
+	TransactionTypeAuctionRelease TransactionType = "auction_release"
+
+	TransactionTypeRepair TransactionType = "repair"
+	TransactionTypeUpkeep TransactionType = "upkeep"
+
+	TransactionTypeChallengeReward TransactionType = "challenge_reward"
 )
 
 // Transaction represents a financial transaction
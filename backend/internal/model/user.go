@@ -6,19 +6,34 @@ import (
 	"gorm.io/gorm"
 )
 
+// LeaderboardVisibility controls how a user appears on leaderboards
+type LeaderboardVisibility string
+
+const (
+	LeaderboardVisibilityPublic    LeaderboardVisibility = "public"
+	LeaderboardVisibilityAnonymous LeaderboardVisibility = "anonymous" // Ranked, but without name or avatar
+	LeaderboardVisibilityHidden    LeaderboardVisibility = "hidden"    // Left out of rankings entirely
+)
+
 // User represents a user in the system
 type User struct {
-	ID           uint           `gorm:"primarykey" json:"id"`
-	GoogleID     *string        `gorm:"uniqueIndex;size:255" json:"google_id,omitempty"`
-	Username     string         `gorm:"uniqueIndex;size:255" json:"username,omitempty"`
-	Email        string         `gorm:"uniqueIndex;size:255;not null" json:"email"`
-	PasswordHash string         `gorm:"size:255" json:"-"`
-	Name         string         `gorm:"size:255;not null" json:"name"`
-	Avatar       string         `gorm:"size:512" json:"avatar"`
-	Balance      float64        `gorm:"type:decimal(15,2);default:1000.00;not null" json:"balance"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uint           `gorm:"primarykey" json:"id"`
+	GoogleID        *string        `gorm:"uniqueIndex;size:255" json:"google_id,omitempty"`
+	Username        string         `gorm:"uniqueIndex;size:255" json:"username,omitempty"`
+	Email           string         `gorm:"uniqueIndex;size:255;not null" json:"email"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at"` // Unverified accounts cannot trade, bid or borrow
+	PasswordHash    string         `gorm:"size:255" json:"-"`
+	Name            string         `gorm:"size:255;not null" json:"name"`
+	Avatar          string         `gorm:"size:512" json:"avatar"`
+	Balance         float64        `gorm:"type:decimal(15,2);default:1000.00;not null" json:"balance"`
+	Timezone        string         `gorm:"size:64" json:"timezone,omitempty"` // IANA name; daily and weekly resets follow it
+	Country         string         `gorm:"size:2" json:"country,omitempty"`   // countries.json code; reality checks convert losses into work hours there
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Privacy
+	LeaderboardVisibility LeaderboardVisibility `gorm:"size:20;not null;default:'public'" json:"leaderboard_visibility"`
 
 	// Relations
 	Transactions []Transaction `gorm:"foreignKey:UserID" json:"transactions,omitempty"`
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// UserAchievement records an achievement a user has unlocked
+type UserAchievement struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_user_achievement" json:"user_id"`
+	Code       string    `gorm:"size:100;not null;uniqueIndex:idx_user_achievement" json:"code"`
+	StatAmount float64   `gorm:"type:decimal(15,2);default:0.00" json:"stat_amount"` // Value of the achievement's stat metric at unlock
+	UnlockedAt time.Time `gorm:"not null" json:"unlocked_at"`
+}
+
+// TableName specifies the table name for UserAchievement model
+func (UserAchievement) TableName() string {
+	return "user_achievements"
+}
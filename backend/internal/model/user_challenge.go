@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+)
+
+// ChallengePeriod is how long a challenge runs before it resets
+type ChallengePeriod string
+
+const (
+	ChallengePeriodDaily  ChallengePeriod = "daily"
+	ChallengePeriodWeekly ChallengePeriod = "weekly"
+)
+
+// UserChallenge tracks a user's progress on one challenge in one period
+type UserChallenge struct {
+	ID          uint            `gorm:"primarykey" json:"id"`
+	UserID      uint            `gorm:"not null;uniqueIndex:idx_user_challenge_period" json:"user_id"`
+	Code        string          `gorm:"size:100;not null;uniqueIndex:idx_user_challenge_period" json:"code"`
+	Period      ChallengePeriod `gorm:"size:20;not null" json:"period"`
+	PeriodStart time.Time       `gorm:"not null;uniqueIndex:idx_user_challenge_period" json:"period_start"`
+	PeriodEnd   time.Time       `gorm:"not null" json:"period_end"`
+	Target      float64         `gorm:"type:decimal(15,2);not null" json:"target"`
+	Reward      float64         `gorm:"type:decimal(15,2);not null" json:"reward"`
+	Progress    float64         `gorm:"type:decimal(15,2);default:0.00" json:"progress"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"` // Set once, when the reward is paid
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// TableName specifies the table name for UserChallenge model
+func (UserChallenge) TableName() string {
+	return "user_challenges"
+}
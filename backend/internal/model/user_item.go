@@ -4,6 +4,9 @@ import (
 	"time"
 )
 
+// ItemDurabilityMax is the durability of an item in mint condition
+const ItemDurabilityMax = 100.0
+
 // UserItem represents a user's purchased item
 type UserItem struct {
 	ID           uint      `gorm:"primarykey" json:"id"`
@@ -12,9 +15,15 @@ type UserItem struct {
 	PurchasedAt  time.Time `gorm:"not null;index:idx_user_purchased" json:"purchased_at"`
 	IsEquipped   bool      `gorm:"default:false;index:idx_user_equipped" json:"is_equipped"`
 	IsCollateral bool      `gorm:"default:false;index" json:"is_collateral"` // Item is used as loan collateral
+	IsListed     bool      `gorm:"default:false;index" json:"is_listed"`     // Item is held in escrow by a market listing
+	SerialNumber *int      `json:"serial_number,omitempty"`                  // Copy number of a limited edition item
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 
+	// Condition wears down with use; broken items (0) cannot be equipped until repaired
+	Durability   float64    `gorm:"default:100" json:"durability"`
+	LastUpkeepAt *time.Time `json:"last_upkeep_at,omitempty"` // Upkeep is charged from here (or PurchasedAt)
+
 	// Relations
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Item Item `gorm:"foreignKey:ItemID" json:"item,omitempty"`
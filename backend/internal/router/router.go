@@ -10,12 +10,13 @@ import (
 	"github.com/smoreg/freezino/backend/internal/database"
 	"github.com/smoreg/freezino/backend/internal/handler"
 	games "github.com/smoreg/freezino/backend/internal/handler/games"
+	"github.com/smoreg/freezino/backend/internal/mail"
 	"github.com/smoreg/freezino/backend/internal/middleware"
 	"github.com/smoreg/freezino/backend/internal/service"
 )
 
-// Setup configures all application routes
-func Setup(app *fiber.App, cfg *config.Config) {
+// Setup configures all application routes; account emails go out through mailer
+func Setup(app *fiber.App, cfg *config.Config, mailer mail.Mailer) {
 	// API group
 	api := app.Group("/api")
 
@@ -28,7 +29,7 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	// Local auth (username/password)
 	db := database.GetDB()
 	authService := service.NewAuthService(db)
-	localAuthHandler := handler.NewAuthHandler(authService, cfg)
+	localAuthHandler := handler.NewAuthHandler(authService, mailer, cfg)
 
 	authGroup := api.Group("/auth")
 	{
@@ -41,11 +42,31 @@ func Setup(app *fiber.App, cfg *config.Config) {
 		authGroup.Get("/google/callback", authHandler.GoogleCallback)
 
 		// Token refresh
-		authGroup.Post("/refresh", authHandler.RefreshToken)
+		authGroup.Post("/refresh", localAuthHandler.RefreshToken)
 
 		// Protected routes (require authentication)
 		authGroup.Get("/me", middleware.AuthMiddleware(cfg), authHandler.GetMe)
-		authGroup.Post("/logout", middleware.AuthMiddleware(cfg), authHandler.Logout)
+		authGroup.Post("/logout", middleware.AuthMiddleware(cfg), localAuthHandler.Logout)
+
+		// Signed-in devices
+		authGroup.Get("/sessions", middleware.AuthMiddleware(cfg), localAuthHandler.GetSessions)
+		authGroup.Delete("/sessions", middleware.AuthMiddleware(cfg), localAuthHandler.RevokeOtherSessions)
+		authGroup.Delete("/sessions/:id", middleware.AuthMiddleware(cfg), localAuthHandler.RevokeSession)
+
+		// Two-factor authentication
+		twoFactorHandler := handler.NewTwoFactorHandler()
+		authGroup.Post("/2fa/verify", localAuthHandler.VerifyTwoFactor) // Second step of login, with the challenge token
+		authGroup.Get("/2fa", middleware.AuthMiddleware(cfg), twoFactorHandler.GetStatus)
+		authGroup.Post("/2fa/enroll", middleware.AuthMiddleware(cfg), twoFactorHandler.Enroll)
+		authGroup.Post("/2fa/enable", middleware.AuthMiddleware(cfg), twoFactorHandler.Enable)
+		authGroup.Post("/2fa/disable", middleware.AuthMiddleware(cfg), twoFactorHandler.Disable)
+		authGroup.Post("/2fa/recovery-codes", middleware.AuthMiddleware(cfg), twoFactorHandler.RegenerateRecoveryCodes)
+
+		// Email verification and password reset, through links mailed to the user
+		authGroup.Post("/verify-email/send", middleware.AuthMiddleware(cfg), localAuthHandler.SendVerificationEmail)
+		authGroup.Post("/verify-email", localAuthHandler.VerifyEmail)
+		authGroup.Post("/forgot-password", localAuthHandler.ForgotPassword)
+		authGroup.Post("/reset-password", localAuthHandler.ResetPassword)
 	}
 
 	// User routes (protected)
@@ -58,6 +79,21 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	user.Get("/transactions", userHandler.GetTransactions)
 	user.Get("/items", userHandler.GetUserItems)
 
+	creditHandler := handler.NewCreditHandler()
+	user.Get("/credit-report", creditHandler.GetCreditReport)
+
+	// Personal data export; the download link carries its own token instead of auth
+	dataExportHandler := handler.NewDataExportHandler()
+	user.Post("/export", dataExportHandler.RequestExport)
+	user.Get("/export", dataExportHandler.GetExports)
+	api.Get("/exports/:token", dataExportHandler.Download)
+
+	// Account deletion
+	accountDeletionHandler := handler.NewAccountDeletionHandler()
+	user.Get("/deletion", accountDeletionHandler.GetDeletion)
+	user.Post("/deletion", accountDeletionHandler.RequestDeletion)
+	user.Delete("/deletion", accountDeletionHandler.CancelDeletion)
+
 	// Work routes (protected)
 	workHandler := handler.NewWorkHandler()
 	work := api.Group("/work", middleware.AuthMiddleware(cfg))
@@ -66,6 +102,7 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	work.Post("/complete", workHandler.CompleteWork)
 	work.Get("/history", workHandler.GetHistory)
 	work.Get("/jobs", workHandler.GetAvailableJobs)
+	work.Get("/career", workHandler.GetCareer)
 	work.Post("/skip-jail", workHandler.SkipJailTime)
 
 	// Stats routes
@@ -100,12 +137,14 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	shopHandler := handler.NewShopHandler()
 	shop := api.Group("/shop")
 	shop.Get("/items", shopHandler.GetItems) // Public - can browse items without auth
+	shop.Get("/items/:itemId/price-history", shopHandler.GetPriceHistory)
 	// Protected shop routes (require auth)
 	shopProtected := shop.Group("", middleware.AuthMiddleware(cfg))
 	shopProtected.Post("/buy/:itemId", shopHandler.BuyItem)
 	shopProtected.Post("/sell/:userItemId", shopHandler.SellItem)
 	shopProtected.Get("/my-items", shopHandler.GetMyItems)
 	shopProtected.Post("/equip/:userItemId", shopHandler.EquipItem)
+	shopProtected.Post("/repair/:userItemId", shopHandler.RepairItem)
 
 	// Game routes (protected)
 	gamesGroup := api.Group("/games", middleware.AuthMiddleware(cfg))
@@ -143,9 +182,22 @@ func Setup(app *fiber.App, cfg *config.Config) {
 	gamesGroup.Get("/history", gameHistoryHandler.GetHistory)
 	gamesGroup.Get("/stats", gameHistoryHandler.GetStats)
 
+	// Gambling analytics routes (protected)
+	analyticsHandler := handler.NewAnalyticsHandler()
+	analytics := api.Group("/analytics", middleware.AuthMiddleware(cfg))
+	analytics.Get("", analyticsHandler.GetOverview)
+	analytics.Get("/timeseries", analyticsHandler.GetTimeSeries)
+	analytics.Get("/sessions", analyticsHandler.GetSessions)
+	analytics.Get("/heatmap", analyticsHandler.GetHeatmap)
+
 	// Game WebSocket routes
 	gameHandler := handler.NewGameHandler(db)
 
+	// Live notifications (WebSocket, or server-sent events for plain requests).
+	// Registered ahead of the upgrade-only middleware below so SSE gets through.
+	notificationHandler := handler.NewNotificationHandler()
+	app.Get("/ws/events", middleware.StreamAuthMiddleware(cfg), notificationHandler.Stream)
+
 	// WebSocket upgrade middleware and routes
 	app.Use("/ws", func(c *fiber.Ctx) error {
 		if websocket.IsWebSocketUpgrade(c) {
@@ -156,14 +208,80 @@ func Setup(app *fiber.App, cfg *config.Config) {
 
 	app.Get("/ws/blackjack", websocket.New(gameHandler.BlackjackWebSocket))
 
+	// Auction house routes
+	auctionHandler := handler.NewAuctionHandler()
+	auctions := api.Group("/auctions")
+	auctions.Get("", middleware.OptionalAuth(cfg), auctionHandler.GetAuctions) // Public - anyone can browse auctions, bidders stay anonymous
+	auctions.Get("/my-bids", middleware.AuthMiddleware(cfg), auctionHandler.GetMyBids)
+	auctions.Get("/:auctionId", middleware.OptionalAuth(cfg), auctionHandler.GetAuction)
+	auctions.Post("/:auctionId/bids", middleware.AuthMiddleware(cfg), middleware.RequireVerifiedEmail(), auctionHandler.PlaceBid)
+	app.Get("/ws/auctions", websocket.New(auctionHandler.AuctionFeedWebSocket))
+
 	// Loan routes (protected)
 	loanHandler := handler.NewLoanHandler()
 	loans := api.Group("/loans", middleware.AuthMiddleware(cfg))
 	loans.Get("/summary", loanHandler.GetLoanSummary)
 	loans.Get("", loanHandler.GetUserLoans)
-	loans.Post("/take", loanHandler.TakeLoan)
+	loans.Post("/take", middleware.RequireVerifiedEmail(), loanHandler.TakeLoan)
 	loans.Post("/repay/:loanId", loanHandler.RepayLoan)
 	loans.Get("/bankruptcy-check", loanHandler.CheckBankruptcy)
+	loans.Get("/collections", loanHandler.GetCollectionEvents)
+	loans.Get("/:loanId/schedule", loanHandler.GetLoanSchedule)
+	loans.Get("/:loanId/statement", loanHandler.GetLoanStatement)
+
+	// Collector encounter routes (protected)
+	collectorHandler := handler.NewCollectorHandler()
+	collectors := api.Group("/collectors", middleware.AuthMiddleware(cfg))
+	collectors.Get("/encounter", collectorHandler.GetEncounter)
+	collectors.Post("/encounter/:encounterId/choice", collectorHandler.ChooseEncounter)
+	collectors.Get("/history", collectorHandler.GetEncounterHistory)
+
+	// Achievement routes (protected)
+	achievementHandler, err := handler.NewAchievementHandler()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize achievement handler: %v", err))
+	}
+	achievements := api.Group("/achievements", middleware.AuthMiddleware(cfg))
+	achievements.Get("", achievementHandler.GetAchievements)
+	achievements.Get("/unlocked", achievementHandler.GetUnlockedAchievements)
+
+	// Challenge routes (protected)
+	challengeHandler, err := handler.NewChallengeHandler()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize challenge handler: %v", err))
+	}
+	api.Get("/challenges", middleware.AuthMiddleware(cfg), challengeHandler.GetChallenges)
+
+	// Leaderboard routes
+	leaderboardHandler := handler.NewLeaderboardHandler()
+	leaderboards := api.Group("/leaderboards")
+	leaderboards.Get("/:board", leaderboardHandler.GetLeaderboard) // Public - opted-out players are left out
+	leaderboards.Get("/:board/me", middleware.AuthMiddleware(cfg), leaderboardHandler.GetMyRank)
+
+	// Responsible gaming routes (protected)
+	responsibleGamingHandler := handler.NewResponsibleGamingHandler()
+	responsibleGaming := api.Group("/responsible-gaming", middleware.AuthMiddleware(cfg))
+	responsibleGaming.Get("", responsibleGamingHandler.GetLimits)
+	responsibleGaming.Put("/limits/:type", responsibleGamingHandler.SetLimit)
+	responsibleGaming.Post("/cooldown", responsibleGamingHandler.StartCooldown)
+	responsibleGaming.Get("/reality-check", responsibleGamingHandler.GetRealityCheck)
+	responsibleGaming.Post("/reality-check/:id/ack", responsibleGamingHandler.AcknowledgeRealityCheck)
+
+	// Marketplace routes (protected)
+	marketHandler := handler.NewMarketHandler()
+	market := api.Group("/market", middleware.AuthMiddleware(cfg))
+	market.Get("", marketHandler.GetListings)
+	market.Post("/listings", middleware.RequireVerifiedEmail(), marketHandler.CreateListing)
+	market.Get("/listings/mine", marketHandler.GetMyListings)
+	market.Post("/listings/:listingId/buy", middleware.RequireVerifiedEmail(), marketHandler.BuyListing)
+	market.Delete("/listings/:listingId", marketHandler.CancelListing)
+	market.Get("/items/:itemId/history", marketHandler.GetPriceHistory)
+
+	// Admin routes (protected, admins listed in ADMIN_EMAILS only)
+	adminHandler := handler.NewAdminHandler()
+	admin := api.Group("/admin", middleware.AuthMiddleware(cfg), middleware.RequireAdmin(cfg))
+	admin.Get("/two-factor", adminHandler.GetTwoFactorOverview)
+	admin.Get("/users/:userId/two-factor", adminHandler.GetUserTwoFactor)
 
 	// Future routes will be added here
 }
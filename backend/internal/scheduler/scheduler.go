@@ -0,0 +1,248 @@
+// Package scheduler runs periodic background jobs inside the API process.
+//
+// Jobs are registered before Start with an interval and optional jitter.
+// Before each run the scheduler takes a lease row in scheduler_leases, so when
+// several instances share a database only one of them executes a given job.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// ErrNotLeader is returned by RunNow when another instance holds the job lease
+var ErrNotLeader = errors.New("job lease held by another instance")
+
+// Job is a periodic background task
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Jitter   time.Duration // Random extra delay added to every interval
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs registered jobs on their intervals
+type Scheduler struct {
+	db    *gorm.DB
+	owner string
+
+	mu      sync.Mutex
+	jobs    []*Job
+	names   map[string]bool
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New creates a scheduler; owner identifies this instance in lease rows
+func New(db *gorm.DB, owner string) *Scheduler {
+	return &Scheduler{
+		db:    db,
+		owner: owner,
+		names: make(map[string]bool),
+	}
+}
+
+// Register adds a job. Jobs must be registered before Start and names must be unique.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" {
+		return errors.New("job name is required")
+	}
+	if job.Interval <= 0 {
+		return fmt.Errorf("job %s: interval must be positive", job.Name)
+	}
+	if job.Jitter < 0 {
+		return fmt.Errorf("job %s: jitter must not be negative", job.Name)
+	}
+	if job.Run == nil {
+		return fmt.Errorf("job %s: run function is required", job.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return fmt.Errorf("job %s: scheduler already started", job.Name)
+	}
+	if s.names[job.Name] {
+		return fmt.Errorf("job %s already registered", job.Name)
+	}
+
+	s.names[job.Name] = true
+	s.jobs = append(s.jobs, &job)
+	return nil
+}
+
+// Start launches one goroutine per registered job
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+
+	log.Printf("Scheduler started with %d jobs (owner %s)", len(s.jobs), s.owner)
+}
+
+// Stop cancels all jobs and waits for running ones to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	s.wg.Wait()
+	log.Println("Scheduler stopped")
+}
+
+// RunNow runs a registered job immediately if this instance can take its lease
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	var job *Job
+	for _, j := range s.jobs {
+		if j.Name == name {
+			job = j
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if job == nil {
+		return fmt.Errorf("job %s not registered", name)
+	}
+	return s.runOnce(ctx, job)
+}
+
+// loop waits interval+jitter between runs until the context is cancelled
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	defer s.wg.Done()
+
+	for {
+		timer := time.NewTimer(s.nextDelay(job))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.runOnce(ctx, job); err != nil && !errors.Is(err, ErrNotLeader) {
+			log.Printf("Scheduler job %s failed: %v", job.Name, err)
+		}
+	}
+}
+
+// nextDelay returns the job interval plus a random jitter
+func (s *Scheduler) nextDelay(job *Job) time.Duration {
+	if job.Jitter <= 0 {
+		return job.Interval
+	}
+	return job.Interval + time.Duration(rand.Int63n(int64(job.Jitter)))
+}
+
+// runOnce takes the lease, runs the job and records the result
+func (s *Scheduler) runOnce(ctx context.Context, job *Job) (err error) {
+	acquired, err := s.acquireLease(job)
+	if err != nil {
+		return fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	if !acquired {
+		return ErrNotLeader
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+		s.recordRun(job, err)
+	}()
+
+	return job.Run(ctx)
+}
+
+// acquireLease claims or renews the job lease for this instance.
+// The lease lasts one interval plus jitter, so a crashed leader is replaced
+// after at most one missed run.
+func (s *Scheduler) acquireLease(job *Job) (bool, error) {
+	now := time.Now()
+	expiresAt := now.Add(job.Interval + job.Jitter)
+
+	result := s.db.Model(&model.SchedulerLease{}).
+		Where("name = ? AND (owner = ? OR expires_at < ?)", job.Name, s.owner, now).
+		Updates(map[string]interface{}{
+			"owner":      s.owner,
+			"expires_at": expiresAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	var count int64
+	if err := s.db.Model(&model.SchedulerLease{}).Where("name = ?", job.Name).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		// Someone else holds a live lease
+		return false, nil
+	}
+
+	lease := model.SchedulerLease{
+		Name:      job.Name,
+		Owner:     s.owner,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.Create(&lease).Error; err != nil {
+		// Lost the race to create the lease row, if it exists now. Anything
+		// else is a real failure and must not look like a held lease.
+		if err := s.db.Model(&model.SchedulerLease{}).Where("name = ?", job.Name).Count(&count).Error; err == nil && count > 0 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// recordRun stores the last run time and error on the lease row
+func (s *Scheduler) recordRun(job *Job, runErr error) {
+	now := time.Now()
+	lastError := ""
+	if runErr != nil {
+		lastError = runErr.Error()
+		if len(lastError) > 512 {
+			lastError = lastError[:512]
+		}
+	}
+
+	if err := s.db.Model(&model.SchedulerLease{}).
+		Where("name = ? AND owner = ?", job.Name, s.owner).
+		Updates(map[string]interface{}{
+			"last_run_at": now,
+			"last_error":  lastError,
+		}).Error; err != nil {
+		log.Printf("Scheduler failed to record run of %s: %v", job.Name, err)
+	}
+}
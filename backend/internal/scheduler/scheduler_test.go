@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	dbName := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dbName), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.SchedulerLease{}))
+	return db
+}
+
+func TestRegisterValidation(t *testing.T) {
+	s := New(setupTestDB(t), "a")
+	noop := func(ctx context.Context) error { return nil }
+
+	assert.Error(t, s.Register(Job{Interval: time.Second, Run: noop}))
+	assert.Error(t, s.Register(Job{Name: "x", Run: noop}))
+	assert.Error(t, s.Register(Job{Name: "x", Interval: time.Second}))
+	require.NoError(t, s.Register(Job{Name: "x", Interval: time.Second, Run: noop}))
+	assert.Error(t, s.Register(Job{Name: "x", Interval: time.Second, Run: noop}), "duplicate names are rejected")
+
+	s.Start()
+	defer s.Stop()
+	assert.Error(t, s.Register(Job{Name: "y", Interval: time.Second, Run: noop}), "no registration after start")
+}
+
+func TestLeaseIsExclusive(t *testing.T) {
+	db := setupTestDB(t)
+	var runs int32
+	job := Job{
+		Name:     "accrue",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}
+
+	leader := New(db, "instance-a")
+	follower := New(db, "instance-b")
+	require.NoError(t, leader.Register(job))
+	require.NoError(t, follower.Register(job))
+
+	require.NoError(t, leader.RunNow(context.Background(), "accrue"))
+	assert.ErrorIs(t, follower.RunNow(context.Background(), "accrue"), ErrNotLeader)
+	require.NoError(t, leader.RunNow(context.Background(), "accrue"), "the holder renews its own lease")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+
+	// Once the lease expires another instance takes over
+	require.NoError(t, db.Model(&model.SchedulerLease{}).Where("name = ?", "accrue").
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	require.NoError(t, follower.RunNow(context.Background(), "accrue"))
+
+	var lease model.SchedulerLease
+	require.NoError(t, db.Where("name = ?", "accrue").First(&lease).Error)
+	assert.Equal(t, "instance-b", lease.Owner)
+	assert.NotNil(t, lease.LastRunAt)
+}
+
+func TestLeaseCreateFailureIsNotMistakenForAHeldLease(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+		_ = tx.AddError(errors.New("disk I/O error"))
+	}))
+	s := New(db, "a")
+	require.NoError(t, s.Register(Job{Name: "accrue", Interval: time.Hour, Run: func(ctx context.Context) error { return nil }}))
+
+	err := s.RunNow(context.Background(), "accrue")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotLeader)
+	assert.Contains(t, err.Error(), "disk I/O error")
+}
+
+func TestRunRecordsErrorsAndPanics(t *testing.T) {
+	db := setupTestDB(t)
+	s := New(db, "a")
+	require.NoError(t, s.Register(Job{Name: "fails", Interval: time.Hour, Run: func(ctx context.Context) error {
+		return errors.New("boom")
+	}}))
+	require.NoError(t, s.Register(Job{Name: "panics", Interval: time.Hour, Run: func(ctx context.Context) error {
+		panic("oops")
+	}}))
+
+	assert.EqualError(t, s.RunNow(context.Background(), "fails"), "boom")
+	assert.Error(t, s.RunNow(context.Background(), "panics"))
+
+	var lease model.SchedulerLease
+	require.NoError(t, db.Where("name = ?", "fails").First(&lease).Error)
+	assert.Equal(t, "boom", lease.LastError)
+}
+
+func TestStartRunsJobsOnInterval(t *testing.T) {
+	s := New(setupTestDB(t), "a")
+	ran := make(chan struct{}, 1)
+	require.NoError(t, s.Register(Job{Name: "tick", Interval: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	}}))
+
+	s.Start()
+	defer s.Stop()
+
+	select {
+	case <-ran:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not run")
+	}
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// Account deletion parameters
+const (
+	AccountDeletionGracePeriod = 14 * 24 * time.Hour // Time to change one's mind before data is erased
+	accountDeletionName        = "Deleted user"      // Replaces the user's and their contact messages' names
+	accountDeletionMessage     = "[deleted]"         // Replaces the text of their contact messages
+	accountDeletionEmailDomain = "deleted.invalid"   // Pseudonymous emails can never be delivered
+)
+
+// accountDeletionTable is a table with rows that belong to a user
+type accountDeletionTable struct {
+	name  string
+	query func(db *gorm.DB, user *model.User) *gorm.DB
+}
+
+// accountDeletionKept are kept under the user's ID once the account is
+// anonymised, so casino statistics, leaderboards and loan books still add up
+var accountDeletionKept = []accountDeletionTable{
+	{name: "transactions", query: byUserID("transactions")},
+	{name: "game_sessions", query: byUserID("game_sessions")},
+	{name: "roulette_results", query: byUserID("roulette_results")},
+	{name: "work_sessions", query: byUserID("work_sessions")},
+	{name: "user_items", query: byUserID("user_items")},
+	{name: "loans", query: byUserID("loans")},
+	{name: "loan_statement_entries", query: byUserID("loan_statement_entries")},
+	{name: "bankruptcies", query: byUserID("bankruptcies")},
+	{name: "user_achievements", query: byUserID("user_achievements")},
+	{name: "leaderboard_entries", query: byUserID("leaderboard_entries")},
+}
+
+// accountDeletionDeleted are personal and of no use once the account is gone
+var accountDeletionDeleted = []accountDeletionTable{
+	{name: "notifications", query: byUserID("notifications")},
+	{name: "data_exports", query: byUserID("data_exports")},
+	{name: "email_tokens", query: byUserID("email_tokens")},
+	{name: "two_factor_settings", query: byUserID("two_factor_settings")},
+	{name: "recovery_codes", query: byUserID("recovery_codes")},
+	{name: "two_factor_challenges", query: byUserID("two_factor_challenges")},
+	{name: "refresh_tokens", query: func(db *gorm.DB, user *model.User) *gorm.DB {
+		return db.Table("refresh_tokens").Where("family_id IN (?)", db.Table("auth_sessions").Select("id").Where("user_id = ?", user.ID))
+	}},
+	{name: "auth_sessions", query: byUserID("auth_sessions")}, // After their tokens, which are found through them
+}
+
+// AccountDeletionService handles account deletion requests and erases
+// personal data when their grace period is over
+type AccountDeletionService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewAccountDeletionService creates a new account deletion service instance
+func NewAccountDeletionService() *AccountDeletionService {
+	return &AccountDeletionService{
+		db: database.GetDB(),
+	}
+}
+
+// clock returns the current time
+func (s *AccountDeletionService) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// AccountDeletionReport lists the rows deleting an account touches, by table
+type AccountDeletionReport struct {
+	UserID     uint             `json:"user_id"`
+	DryRun     bool             `json:"dry_run"`
+	Anonymised map[string]int64 `json:"anonymised"` // Personal data overwritten
+	Deleted    map[string]int64 `json:"deleted"`
+	Kept       map[string]int64 `json:"kept"` // Kept under the pseudonymous account
+}
+
+// findUser loads a user; pass an unscoped db to include closed accounts
+func (s *AccountDeletionService) findUser(db *gorm.DB, userID uint) (*model.User, error) {
+	var user model.User
+	if err := db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user_not_found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return &user, nil
+}
+
+// pendingDeletion returns the user's deletion request in its grace period, or nil
+func (s *AccountDeletionService) pendingDeletion(db *gorm.DB, userID uint) (*model.AccountDeletion, error) {
+	var deletion model.AccountDeletion
+	err := db.Where("user_id = ? AND status = ?", userID, model.AccountDeletionPending).First(&deletion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account deletion: %w", err)
+	}
+	return &deletion, nil
+}
+
+// openObligation returns the reason the account can't be erased yet, or "" if
+// it can. An erased account could not repay a loan or settle a sale or bid.
+func (s *AccountDeletionService) openObligation(db *gorm.DB, userID uint) (string, error) {
+	for _, check := range []struct {
+		query *gorm.DB
+		code  string
+	}{
+		{db.Model(&model.Loan{}).Where("user_id = ?", userID), "account_has_open_loans"},
+		{db.Model(&model.MarketListing{}).Where("seller_id = ? AND status = ?", userID, model.ListingStatusActive), "account_has_open_listings"},
+		{db.Model(&model.AuctionBid{}).Where("user_id = ? AND hold_status = ?", userID, model.BidHoldHeld), "account_has_held_bids"},
+	} {
+		var n int64
+		if err := check.query.Count(&n).Error; err != nil {
+			return "", fmt.Errorf("failed to check open obligations: %w", err)
+		}
+		if n > 0 {
+			return check.code, nil
+		}
+	}
+	return "", nil
+}
+
+// RequestDeletion schedules the user's account for deletion at the end of the
+// grace period. Loans must be repaid, and listings and bids closed, first. A
+// request already pending is returned as is; created reports which.
+func (s *AccountDeletionService) RequestDeletion(userID uint) (*model.AccountDeletion, bool, error) {
+	if _, err := s.findUser(s.db, userID); err != nil {
+		return nil, false, err
+	}
+	reason, err := s.openObligation(s.db, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if reason != "" {
+		return nil, false, errors.New(reason)
+	}
+
+	deletion, err := s.pendingDeletion(s.db, userID)
+	if err != nil || deletion != nil {
+		return deletion, false, err
+	}
+
+	now := s.clock()
+	deletion = &model.AccountDeletion{
+		UserID:       userID,
+		Status:       model.AccountDeletionPending,
+		RequestedAt:  now,
+		ScheduledFor: now.Add(AccountDeletionGracePeriod),
+	}
+	if err := s.db.Create(deletion).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to create account deletion: %w", err)
+	}
+	return deletion, true, nil
+}
+
+// GetDeletion returns the user's pending deletion request, or nil if there is none
+func (s *AccountDeletionService) GetDeletion(userID uint) (*model.AccountDeletion, error) {
+	return s.pendingDeletion(s.db, userID)
+}
+
+// CancelDeletion cancels the user's pending deletion request
+func (s *AccountDeletionService) CancelDeletion(userID uint) (*model.AccountDeletion, error) {
+	now := s.clock()
+
+	deletion, err := s.pendingDeletion(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if deletion == nil {
+		return nil, errors.New("deletion_not_found")
+	}
+
+	result := s.db.Model(deletion).Where("status = ?", model.AccountDeletionPending).
+		Updates(map[string]interface{}{"status": model.AccountDeletionCancelled, "cancelled_at": now})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel account deletion: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("deletion_not_found") // Completed in the meantime
+	}
+	deletion.Status = model.AccountDeletionCancelled
+	deletion.CancelledAt = &now
+	return deletion, nil
+}
+
+// PreviewDeletion reports what deleting the user's account would do without doing it
+func (s *AccountDeletionService) PreviewDeletion(userID uint) (*AccountDeletionReport, error) {
+	user, err := s.findUser(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.anonymise(s.db, user, "", true)
+}
+
+// DeleteDueAccounts anonymises every account whose grace period is over,
+// returning how many
+func (s *AccountDeletionService) DeleteDueAccounts() (int, error) {
+	var deletions []model.AccountDeletion
+	if err := s.db.Where("status = ? AND scheduled_for <= ?", model.AccountDeletionPending, s.clock().Local()).
+		Order("scheduled_for").Find(&deletions).Error; err != nil {
+		return 0, fmt.Errorf("failed to find due account deletions: %w", err)
+	}
+
+	count := 0
+	for i := range deletions {
+		report, err := s.completeDeletion(&deletions[i])
+		if err != nil {
+			return count, err
+		}
+		if report != nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// newPseudonym returns a random name to keep a deleted user's data under
+func newPseudonym() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate pseudonym: %w", err)
+	}
+	return "deleted-" + hex.EncodeToString(b), nil
+}
+
+// completeDeletion erases the personal data of a pending deletion's user. The
+// report is nil if the request was cancelled in the meantime, or is postponed
+// because the user took a loan, listed an item or bid during the grace period.
+func (s *AccountDeletionService) completeDeletion(deletion *model.AccountDeletion) (*AccountDeletionReport, error) {
+	pseudonym, err := newPseudonym()
+	if err != nil {
+		return nil, err
+	}
+
+	var report *AccountDeletionReport
+	var files []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		reason, err := s.openObligation(tx, deletion.UserID)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			log.Printf("Postponed deletion of account %d: %s", deletion.UserID, reason)
+			return nil
+		}
+
+		// The request may have been cancelled since it was loaded
+		result := tx.Model(deletion).Where("status = ?", model.AccountDeletionPending).
+			Updates(map[string]interface{}{
+				"status":       model.AccountDeletionCompleted,
+				"completed_at": s.clock(),
+				"pseudonym":    pseudonym,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to complete account deletion: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		user, err := s.findUser(tx.Unscoped(), deletion.UserID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&model.DataExport{}).Where("user_id = ? AND file_path <> ''", user.ID).
+			Pluck("file_path", &files).Error; err != nil {
+			return fmt.Errorf("failed to get data exports: %w", err)
+		}
+		report, err = s.anonymise(tx, user, pseudonym, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to delete export archive %s: %v", file, err)
+		}
+	}
+	return report, nil
+}
+
+// anonymise counts the rows deleting the user's account touches and, unless
+// dryRun is set, replaces their personal data with the pseudonym, deletes
+// what is only of use to them and closes the account
+func (s *AccountDeletionService) anonymise(tx *gorm.DB, user *model.User, pseudonym string, dryRun bool) (*AccountDeletionReport, error) {
+	report := &AccountDeletionReport{
+		UserID:     user.ID,
+		DryRun:     dryRun,
+		Anonymised: map[string]int64{"users": 1},
+		Deleted:    make(map[string]int64, len(accountDeletionDeleted)),
+		Kept:       make(map[string]int64, len(accountDeletionKept)),
+	}
+
+	count := func(table accountDeletionTable) (int64, error) {
+		var n int64
+		if err := table.query(tx, user).Count(&n).Error; err != nil {
+			return 0, fmt.Errorf("failed to count %s: %w", table.name, err)
+		}
+		return n, nil
+	}
+	contactMessages := func(db *gorm.DB, user *model.User) *gorm.DB {
+		return db.Table("contact_messages").Where("email = ?", user.Email)
+	}
+
+	var err error
+	if report.Anonymised["contact_messages"], err = count(accountDeletionTable{"contact_messages", contactMessages}); err != nil {
+		return nil, err
+	}
+	for _, table := range accountDeletionKept {
+		if report.Kept[table.name], err = count(table); err != nil {
+			return nil, err
+		}
+	}
+	for _, table := range accountDeletionDeleted {
+		if report.Deleted[table.name], err = count(table); err != nil {
+			return nil, err
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	if err := contactMessages(tx, user).Updates(map[string]interface{}{
+		"name":    accountDeletionName,
+		"email":   pseudonym + "@" + accountDeletionEmailDomain,
+		"message": accountDeletionMessage,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to anonymise contact messages: %w", err)
+	}
+	for _, table := range accountDeletionDeleted {
+		if err := table.query(tx, user).Delete(map[string]interface{}{}).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", table.name, err)
+		}
+	}
+
+	// Leaderboards keep the scores but no longer show who they were
+	visibility := model.LeaderboardVisibilityAnonymous
+	if user.LeaderboardVisibility == model.LeaderboardVisibilityHidden {
+		visibility = model.LeaderboardVisibilityHidden
+	}
+	if err := tx.Unscoped().Model(user).Updates(map[string]interface{}{
+		"google_id":              nil,
+		"username":               pseudonym,
+		"email":                  pseudonym + "@" + accountDeletionEmailDomain,
+		"email_verified_at":      nil,
+		"password_hash":          "",
+		"name":                   accountDeletionName,
+		"avatar":                 "",
+		"timezone":               "",
+		"country":                "",
+		"leaderboard_visibility": visibility,
+		"deleted_at":             s.clock(),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to anonymise user: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountDeletion(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	addTransactions(t, db, user.ID, model.TransactionTypeGameLoss, 10, 20)
+	require.NoError(t, db.Create(&model.GameSession{UserID: user.ID, GameType: model.GameTypeSlots, Bet: 10}).Error)
+	require.NoError(t, db.Create(&model.ContactMessage{Name: "Test User", Email: user.Email, Message: "Call me on 555-0100"}).Error)
+	require.NoError(t, db.Create(&model.Notification{UserID: user.ID, Type: NotificationBalanceUpdate, Payload: "{}"}).Error)
+	require.NoError(t, db.Create(&model.LeaderboardEntry{Board: "net_profit", Period: "all", UserID: user.ID, Score: -30}).Error)
+
+	now := time.Now()
+	service := &AccountDeletionService{db: db, now: func() time.Time { return now }}
+
+	report, err := service.PreviewDeletion(user.ID)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, int64(1), report.Anonymised["contact_messages"])
+	assert.Equal(t, int64(2), report.Kept["transactions"])
+	assert.Equal(t, int64(1), report.Kept["leaderboard_entries"])
+	assert.Equal(t, int64(1), report.Deleted["notifications"])
+
+	// A cancelled request is never carried out
+	deletion, created, err := service.RequestDeletion(user.ID)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.WithinDuration(t, now.Add(AccountDeletionGracePeriod), deletion.ScheduledFor, time.Second)
+	_, err = service.CancelDeletion(user.ID)
+	require.NoError(t, err)
+	_, err = service.CancelDeletion(user.ID)
+	assert.EqualError(t, err, "deletion_not_found")
+
+	deletion, _, err = service.RequestDeletion(user.ID)
+	require.NoError(t, err)
+	again, created, err := service.RequestDeletion(user.ID)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, deletion.ID, again.ID)
+
+	// Nothing happens during the grace period
+	count, err := service.DeleteDueAccounts()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	now = now.Add(AccountDeletionGracePeriod + time.Minute)
+	count, err = service.DeleteDueAccounts()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var completed model.AccountDeletion
+	require.NoError(t, db.First(&completed, deletion.ID).Error)
+	assert.Equal(t, model.AccountDeletionCompleted, completed.Status)
+	require.NotEmpty(t, completed.Pseudonym)
+
+	// The account is closed and holds nothing personal
+	_, err = service.GetDeletion(user.ID)
+	require.NoError(t, err)
+	assert.Error(t, db.First(&model.User{}, user.ID).Error)
+	var anonymised model.User
+	require.NoError(t, db.Unscoped().First(&anonymised, user.ID).Error)
+	assert.Equal(t, completed.Pseudonym, anonymised.Username)
+	assert.Equal(t, completed.Pseudonym+"@deleted.invalid", anonymised.Email)
+	assert.Equal(t, "Deleted user", anonymised.Name)
+	assert.Nil(t, anonymised.GoogleID)
+	assert.Equal(t, model.LeaderboardVisibilityAnonymous, anonymised.LeaderboardVisibility)
+
+	var message model.ContactMessage
+	require.NoError(t, db.First(&message).Error)
+	assert.Equal(t, anonymised.Email, message.Email)
+	assert.Equal(t, "[deleted]", message.Message)
+
+	// History stays under the pseudonymous account
+	var transactions, sessions, notifications int64
+	db.Model(&model.Transaction{}).Where("user_id = ?", user.ID).Count(&transactions)
+	db.Model(&model.GameSession{}).Where("user_id = ?", user.ID).Count(&sessions)
+	db.Model(&model.Notification{}).Where("user_id = ?", user.ID).Count(&notifications)
+	assert.Equal(t, int64(2), transactions)
+	assert.Equal(t, int64(1), sessions)
+	assert.Zero(t, notifications)
+
+	_, _, err = service.RequestDeletion(user.ID)
+	assert.EqualError(t, err, "user_not_found")
+}
+
+func TestAccountDeletionWaitsForOpenObligations(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	now := time.Now()
+	service := &AccountDeletionService{db: db, now: func() time.Time { return now }}
+
+	loan := model.Loan{UserID: user.ID, Type: model.LoanTypeFriends, PrincipalAmount: 100, RemainingAmount: 100, LastInterestAt: now}
+	require.NoError(t, db.Create(&loan).Error)
+	_, _, err := service.RequestDeletion(user.ID)
+	assert.EqualError(t, err, "account_has_open_loans")
+	require.NoError(t, db.Delete(&loan).Error) // Repaid
+
+	deletion, _, err := service.RequestDeletion(user.ID)
+	require.NoError(t, err)
+
+	// A bid placed during the grace period holds the deletion back until it is released
+	bid := model.AuctionBid{AuctionID: 1, UserID: user.ID, Amount: 50, HoldStatus: model.BidHoldHeld}
+	require.NoError(t, db.Create(&bid).Error)
+	now = now.Add(AccountDeletionGracePeriod + time.Minute)
+	count, err := service.DeleteDueAccounts()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	pending, err := service.GetDeletion(user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	assert.Equal(t, deletion.ID, pending.ID)
+
+	require.NoError(t, db.Model(&bid).Update("hold_status", model.BidHoldReleased).Error)
+	count, err = service.DeleteDueAccounts()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestUpkeepSkipsDeletedAccounts(t *testing.T) {
+	db := setupTestDB(t)
+	deleted := createTestUser(t, db, 1000)
+	owner := createTestUser(t, db, 1000)
+	car := createTestItem(t, db, "Hatchback", model.ItemTypeCar, 1000)
+	purchased := time.Now().Add(-UpkeepPeriod - time.Hour)
+	for _, userID := range []uint{deleted.ID, owner.ID} {
+		require.NoError(t, db.Create(&model.UserItem{UserID: userID, ItemID: car.ID, PurchasedAt: purchased}).Error)
+	}
+
+	now := time.Now()
+	service := &AccountDeletionService{db: db, now: func() time.Time { return now }}
+	_, _, err := service.RequestDeletion(deleted.ID)
+	require.NoError(t, err)
+	now = now.Add(AccountDeletionGracePeriod + time.Minute)
+	count, err := service.DeleteDueAccounts()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// The deleted account's car stays on record but nobody is billed for it,
+	// and the other owners still are
+	shop := &ShopService{db: db}
+	count, err = shop.ChargeUpkeep()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 997.0, userBalance(t, db, owner.ID))
+	var anonymised model.User
+	require.NoError(t, db.Unscoped().First(&anonymised, deleted.ID).Error)
+	assert.Equal(t, 1000.0, anonymised.Balance)
+}
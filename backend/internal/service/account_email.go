@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/mail"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Account email parameters
+const (
+	EmailVerificationExpiry = 48 * time.Hour
+	PasswordResetExpiry     = time.Hour
+	AccountEmailCooldown    = time.Minute // Minimum time between two emails of a kind to a user
+	MinPasswordLength       = 6           // Same as registration
+	emailTokenBytes         = 32
+	accountEmailSendTimeout = 30 * time.Second
+)
+
+// AccountEmailService verifies email addresses and resets forgotten
+// passwords through single-use links it mails to users. The tokens in the
+// links are signed, so forged ones are turned away before the database is
+// asked, and only their hash is stored.
+type AccountEmailService struct {
+	db          *gorm.DB
+	mailer      mail.Mailer
+	secret      []byte
+	frontendURL string
+	now         func() time.Time
+}
+
+// NewAccountEmailService creates a new account email service that signs
+// tokens with secret and links to pages of the frontend at frontendURL
+func NewAccountEmailService(mailer mail.Mailer, secret, frontendURL string) *AccountEmailService {
+	return &AccountEmailService{
+		db:          database.GetDB(),
+		mailer:      mailer,
+		secret:      []byte(secret),
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// clock returns the current time
+func (s *AccountEmailService) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// accountEmailData is what the mail templates are filled in with
+type accountEmailData struct {
+	Name  string
+	Link  string
+	Hours int // How long the link works
+}
+
+// signEmailToken returns the signature of a token's random part for a purpose,
+// so a token for one purpose can't be used for another
+func (s *AccountEmailService) signEmailToken(purpose model.EmailTokenPurpose, random string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(string(purpose) + "." + random))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issueEmailToken replaces the user's unused tokens for a purpose with a new
+// one and returns it
+func (s *AccountEmailService) issueEmailToken(tx *gorm.DB, user *model.User, purpose model.EmailTokenPurpose, ttl time.Duration) (*model.EmailToken, string, error) {
+	now := s.clock()
+	b := make([]byte, emailTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	random := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := tx.Model(&model.EmailToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+		Update("used_at", now).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to replace tokens: %w", err)
+	}
+	token := &model.EmailToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		TokenHash: hashToken(random),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := tx.Create(token).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to store token: %w", err)
+	}
+	return token, random + "." + s.signEmailToken(purpose, random), nil
+}
+
+// recentlySent reports whether the user was sent an email for a purpose
+// within AccountEmailCooldown
+func (s *AccountEmailService) recentlySent(userID uint, purpose model.EmailTokenPurpose) (bool, error) {
+	var count int64
+	if err := s.db.Model(&model.EmailToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, s.clock().Add(-AccountEmailCooldown).Local()).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to get tokens: %w", err)
+	}
+	return count > 0, nil
+}
+
+// sendEmailToken issues a token and mails its link to the user. The token is
+// withdrawn if the email can't be sent, so the cooldown doesn't block a retry.
+func (s *AccountEmailService) sendEmailToken(user *model.User, purpose model.EmailTokenPurpose, ttl time.Duration, template, page, lang string) error {
+	stored, token, err := s.issueEmailToken(s.db, user, purpose, ttl)
+	if err != nil {
+		return err
+	}
+
+	name := user.Name
+	if name == "" {
+		name = user.Username
+	}
+	msg, err := mail.Render(template, mail.Language(lang), accountEmailData{
+		Name:  name,
+		Link:  s.frontendURL + page + "?token=" + url.QueryEscape(token),
+		Hours: int(ttl / time.Hour),
+	})
+	if err == nil {
+		msg.To = user.Email
+		ctx, cancel := context.WithTimeout(context.Background(), accountEmailSendTimeout)
+		defer cancel()
+		err = s.mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		s.db.Delete(stored)
+		return fmt.Errorf("failed to send %s email: %w", template, err)
+	}
+	return nil
+}
+
+// consumeEmailToken checks a token, marks it used and returns its user.
+// Tokens that are forged, unknown, used, for another purpose or for an
+// address the user no longer has are all invalid_token.
+func (s *AccountEmailService) consumeEmailToken(tx *gorm.DB, token string, purpose model.EmailTokenPurpose) (*model.User, error) {
+	random, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signEmailToken(purpose, random))) {
+		return nil, errors.New("invalid_token")
+	}
+
+	now := s.clock()
+	var stored model.EmailToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").
+		Where("token_hash = ? AND purpose = ?", hashToken(random), purpose).First(&stored).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid_token")
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	if stored.UsedAt != nil || stored.User.ID == 0 || stored.User.Email != stored.Email {
+		return nil, errors.New("invalid_token")
+	}
+	if !now.Before(stored.ExpiresAt) {
+		return nil, errors.New("token_expired")
+	}
+
+	result := tx.Model(&stored).Where("used_at IS NULL").Update("used_at", now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to use token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("invalid_token") // Used by a concurrent request
+	}
+	return &stored.User, nil
+}
+
+// SendVerification mails the user a link to confirm their email address, in
+// the language lang names (an Accept-Language value or a language code)
+func (s *AccountEmailService) SendVerification(userID uint, lang string) error {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user_not_found")
+		}
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user.EmailVerifiedAt != nil {
+		return errors.New("email_already_verified")
+	}
+
+	recent, err := s.recentlySent(user.ID, model.EmailTokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+	if recent {
+		return errors.New("email_recently_sent")
+	}
+
+	return s.sendEmailToken(&user, model.EmailTokenVerifyEmail, EmailVerificationExpiry, mail.TemplateVerifyEmail, "/verify-email", lang)
+}
+
+// VerifyEmail confirms the address a verification token was sent to
+func (s *AccountEmailService) VerifyEmail(token string) (*model.User, error) {
+	var user *model.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if user, err = s.consumeEmailToken(tx, token, model.EmailTokenVerifyEmail); err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+
+		now := s.clock()
+		if err := tx.Model(user).Update("email_verified_at", now).Error; err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+		user.EmailVerifiedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RequestPasswordReset mails a password reset link to the account with the
+// given email. To not reveal which addresses have accounts, it succeeds
+// without sending anything when there is none, when the account signs in with
+// Google only, or when a link was sent moments ago.
+func (s *AccountEmailService) RequestPasswordReset(email, lang string) error {
+	var user model.User
+	if err := s.db.Where("LOWER(email) = LOWER(?)", strings.TrimSpace(email)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user.PasswordHash == "" {
+		return nil
+	}
+
+	recent, err := s.recentlySent(user.ID, model.EmailTokenResetPassword)
+	if err != nil || recent {
+		return err
+	}
+
+	return s.sendEmailToken(&user, model.EmailTokenResetPassword, PasswordResetExpiry, mail.TemplateResetPassword, "/reset-password", lang)
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out
+// everywhere. The reset link proves they read the address, so it is verified too.
+func (s *AccountEmailService) ResetPassword(token, password string) (*model.User, error) {
+	if len(password) < MinPasswordLength {
+		return nil, errors.New("password_too_short")
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var user *model.User
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if user, err = s.consumeEmailToken(tx, token, model.EmailTokenResetPassword); err != nil {
+			return err
+		}
+
+		now := s.clock()
+		updates := map[string]interface{}{"password_hash": string(hashedPassword)}
+		if user.EmailVerifiedAt == nil {
+			updates["email_verified_at"] = now
+			user.EmailVerifiedAt = &now
+		}
+		if err := tx.Model(user).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to reset password: %w", err)
+		}
+
+		if err := tx.Model(&model.AuthSession{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Updates(map[string]interface{}{"revoked_at": now, "revoked_reason": SessionRevokedPasswordReset}).Error; err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/mail"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mailedToken returns the token in the link of the last email sent to an address
+func mailedToken(t *testing.T, mailer *mail.MemoryMailer, to string) string {
+	msg, ok := mailer.Last(to)
+	require.True(t, ok, "no email sent to %s", to)
+	match := regexp.MustCompile(`\?token=(\S+)`).FindStringSubmatch(msg.Text)
+	require.Len(t, match, 2, "no link in %q", msg.Text)
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+	return token
+}
+
+func TestEmailVerification(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	mailer := mail.NewMemoryMailer()
+
+	now := time.Now()
+	service := &AccountEmailService{db: db, mailer: mailer, secret: []byte("secret"), frontendURL: "http://app.test", now: func() time.Time { return now }}
+
+	require.NoError(t, service.SendVerification(user.ID, "ru-RU,ru;q=0.9,en;q=0.8"))
+	msg, _ := mailer.Last(user.Email)
+	assert.Equal(t, "Подтвердите адрес почты во Freezino", msg.Subject)
+	assert.Contains(t, msg.HTML, "http://app.test/verify-email?token=")
+	first := mailedToken(t, mailer, user.Email)
+
+	assert.EqualError(t, service.SendVerification(user.ID, "en"), "email_recently_sent")
+
+	// A new email replaces the link in the previous one
+	now = now.Add(AccountEmailCooldown + time.Second)
+	require.NoError(t, service.SendVerification(user.ID, "en"))
+	token := mailedToken(t, mailer, user.Email)
+	_, err := service.VerifyEmail(first)
+	assert.EqualError(t, err, "invalid_token")
+
+	// Tokens are signed for one purpose
+	_, err = service.VerifyEmail(token + "x")
+	assert.EqualError(t, err, "invalid_token")
+	_, err = service.ResetPassword(token, "new-password")
+	assert.EqualError(t, err, "invalid_token")
+
+	verified, err := service.VerifyEmail(token)
+	require.NoError(t, err)
+	require.NotNil(t, verified.EmailVerifiedAt)
+	_, err = service.VerifyEmail(token)
+	assert.EqualError(t, err, "invalid_token")
+	assert.EqualError(t, service.SendVerification(user.ID, "en"), "email_already_verified")
+
+	// Links stop working when they expire
+	other := createTestUser(t, db, 1000)
+	require.NoError(t, service.SendVerification(other.ID, ""))
+	token = mailedToken(t, mailer, other.Email)
+	now = now.Add(EmailVerificationExpiry)
+	_, err = service.VerifyEmail(token)
+	assert.EqualError(t, err, "token_expired")
+}
+
+func TestPasswordReset(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(user).Update("password_hash", string(hash)).Error)
+	google := createTestUser(t, db, 1000) // Signs in with Google only
+	mailer := mail.NewMemoryMailer()
+
+	now := time.Now()
+	service := &AccountEmailService{db: db, mailer: mailer, secret: []byte("secret"), frontendURL: "http://app.test", now: func() time.Time { return now }}
+	sessions := &AuthSessionService{db: db, ttl: time.Hour, now: func() time.Time { return now }}
+	session, _, err := sessions.StartSession(user.ID, "Firefox", "10.0.0.1")
+	require.NoError(t, err)
+
+	// Nothing tells apart addresses without a password account
+	require.NoError(t, service.RequestPasswordReset("nobody@example.com", "en"))
+	require.NoError(t, service.RequestPasswordReset(google.Email, "en"))
+	assert.Empty(t, mailer.Messages())
+
+	require.NoError(t, service.RequestPasswordReset(user.Email, "es"))
+	msg, _ := mailer.Last(user.Email)
+	assert.Equal(t, "Restablece tu contraseña de Freezino", msg.Subject)
+	assert.Contains(t, msg.Text, "1 hora")
+	token := mailedToken(t, mailer, user.Email)
+
+	// Asking again right away sends nothing, but doesn't say so
+	require.NoError(t, service.RequestPasswordReset(user.Email, "es"))
+	assert.Len(t, mailer.Messages(), 1)
+
+	_, err = service.ResetPassword(token, "short")
+	assert.EqualError(t, err, "password_too_short")
+
+	reset, err := service.ResetPassword(token, "new-password")
+	require.NoError(t, err)
+	assert.NotNil(t, reset.EmailVerifiedAt)
+	_, err = service.ResetPassword(token, "another-password")
+	assert.EqualError(t, err, "invalid_token")
+
+	var updated model.User
+	require.NoError(t, db.First(&updated, user.ID).Error)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.PasswordHash), []byte("new-password")))
+
+	// Everyone signed in with the old password is signed out
+	active, err := sessions.SessionActive(session.ID)
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	now = now.Add(AccountEmailCooldown + time.Second)
+	require.NoError(t, service.RequestPasswordReset(user.Email, "en"))
+	token = mailedToken(t, mailer, user.Email)
+	now = now.Add(PasswordResetExpiry)
+	_, err = service.ResetPassword(token, "new-password")
+	assert.EqualError(t, err, "token_expired")
+}
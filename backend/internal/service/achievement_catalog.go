@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/smoreg/freezino/backend/internal/data"
+	"github.com/smoreg/freezino/backend/internal/events"
+)
+
+// Achievement categories
+const (
+	AchievementCategoryNormal = "normal"
+	AchievementCategoryIronic = "ironic" // Dark-humour achievements with an educational twist
+)
+
+// AchievementCondition compares one user metric against bounds
+type AchievementCondition struct {
+	Metric string   `json:"metric"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+}
+
+// AchievementStat attaches a real-world statistic to an achievement. The
+// metric's value at unlock time is converted to hours of work in a country and
+// substituted into Text ({amount}, {hours}, {days} and {country}).
+type AchievementStat struct {
+	Metric string `json:"metric"`
+	Text   string `json:"text"`
+}
+
+// AchievementDefinition declares an achievement in the catalogue
+type AchievementDefinition struct {
+	Code        string                 `json:"code"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Category    string                 `json:"category"`
+	Requires    []string               `json:"requires,omitempty"` // Achievements that must be unlocked first
+	Triggers    []string               `json:"triggers"`           // Event names that evaluate this achievement
+	Conditions  []AchievementCondition `json:"conditions"`
+	Stat        AchievementStat        `json:"stat"`
+}
+
+// triggeredBy reports whether the event evaluates this achievement
+func (a *AchievementDefinition) triggeredBy(eventName string) bool {
+	for _, trigger := range a.Triggers {
+		if trigger == eventName {
+			return true
+		}
+	}
+	return false
+}
+
+// AchievementCatalog holds all achievement definitions in declaration order
+type AchievementCatalog struct {
+	achievements []AchievementDefinition
+	byCode       map[string]*AchievementDefinition
+}
+
+var (
+	defaultAchievementCatalog     *AchievementCatalog
+	defaultAchievementCatalogOnce sync.Once
+)
+
+// ParseAchievementCatalog parses and validates a JSON achievement catalogue
+func ParseAchievementCatalog(raw []byte) (*AchievementCatalog, error) {
+	var achievements []AchievementDefinition
+	if err := json.Unmarshal(raw, &achievements); err != nil {
+		return nil, fmt.Errorf("failed to parse achievement catalogue: %w", err)
+	}
+
+	catalog := &AchievementCatalog{
+		achievements: achievements,
+		byCode:       make(map[string]*AchievementDefinition, len(achievements)),
+	}
+
+	for i := range catalog.achievements {
+		achievement := &catalog.achievements[i]
+		if achievement.Code == "" {
+			return nil, fmt.Errorf("achievement #%d has no code", i)
+		}
+		if _, exists := catalog.byCode[achievement.Code]; exists {
+			return nil, fmt.Errorf("duplicate achievement %q", achievement.Code)
+		}
+		if achievement.Category != AchievementCategoryNormal && achievement.Category != AchievementCategoryIronic {
+			return nil, fmt.Errorf("achievement %q has unknown category %q", achievement.Code, achievement.Category)
+		}
+		if len(achievement.Triggers) == 0 {
+			return nil, fmt.Errorf("achievement %q has no triggers", achievement.Code)
+		}
+		for _, trigger := range achievement.Triggers {
+			if !events.Known(trigger) {
+				return nil, fmt.Errorf("achievement %q has unknown trigger %q", achievement.Code, trigger)
+			}
+		}
+		if len(achievement.Conditions) == 0 {
+			return nil, fmt.Errorf("achievement %q has no conditions", achievement.Code)
+		}
+		for _, condition := range achievement.Conditions {
+			if !validAchievementMetric(condition.Metric) {
+				return nil, fmt.Errorf("achievement %q uses unknown metric %q", achievement.Code, condition.Metric)
+			}
+			if condition.Min == nil && condition.Max == nil {
+				return nil, fmt.Errorf("achievement %q has a condition without min or max", achievement.Code)
+			}
+		}
+		if !validAchievementMetric(achievement.Stat.Metric) || achievement.Stat.Text == "" {
+			return nil, fmt.Errorf("achievement %q needs a stat with a known metric and text", achievement.Code)
+		}
+		catalog.byCode[achievement.Code] = achievement
+	}
+
+	for _, achievement := range catalog.achievements {
+		for _, code := range achievement.Requires {
+			if _, ok := catalog.byCode[code]; !ok {
+				return nil, fmt.Errorf("achievement %q requires unknown achievement %q", achievement.Code, code)
+			}
+		}
+	}
+
+	return catalog, nil
+}
+
+// LoadAchievementCatalog loads achievements.json from disk, falling back to the built-in copy
+func LoadAchievementCatalog() (*AchievementCatalog, error) {
+	dataPath := filepath.Join("backend", "internal", "data", "achievements.json")
+	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
+		dataPath = filepath.Join("internal", "data", "achievements.json")
+	}
+
+	raw, err := os.ReadFile(dataPath)
+	if err != nil {
+		return DefaultAchievementCatalog(), nil
+	}
+
+	return ParseAchievementCatalog(raw)
+}
+
+// DefaultAchievementCatalog returns the catalogue embedded in the binary
+func DefaultAchievementCatalog() *AchievementCatalog {
+	defaultAchievementCatalogOnce.Do(func() {
+		catalog, err := ParseAchievementCatalog(data.AchievementsJSON)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in achievement catalogue: %v", err))
+		}
+		defaultAchievementCatalog = catalog
+	})
+	return defaultAchievementCatalog
+}
+
+// Get returns the achievement with the given code
+func (c *AchievementCatalog) Get(code string) (*AchievementDefinition, bool) {
+	achievement, ok := c.byCode[code]
+	return achievement, ok
+}
+
+// All returns every achievement in catalogue order
+func (c *AchievementCatalog) All() []AchievementDefinition {
+	return c.achievements
+}
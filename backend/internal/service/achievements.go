@@ -0,0 +1,450 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Metrics achievement conditions and stats can refer to
+const (
+	AchievementMetricBalance        = "balance"
+	AchievementMetricWorkEarned     = "work_earned"
+	AchievementMetricWorkHours      = "work_hours"
+	AchievementMetricGamesPlayed    = "games_played"
+	AchievementMetricGamesWon       = "games_won"
+	AchievementMetricTotalWagered   = "total_wagered"
+	AchievementMetricGamblingLosses = "gambling_losses" // Net amount lost across all games, never negative
+	AchievementMetricGameWinStreak  = "game_win_streak"
+	AchievementMetricGameLossStreak = "game_loss_streak"
+	AchievementMetricItemsPurchased = "items_purchased" // Shop and marketplace purchases
+	AchievementMetricHousesOwned    = "houses_owned"
+	AchievementMetricMaxHouseValue  = "max_house_value"
+	AchievementMetricLoansTaken     = "loans_taken"
+	AchievementMetricTotalBorrowed  = "total_borrowed"
+	AchievementMetricBankruptcies   = "bankruptcies"
+	AchievementMetricDebtWrittenOff = "debt_written_off"
+)
+
+// Achievement parameters
+const (
+	achievementStreakWindow = 100 // Game results inspected when measuring streaks
+)
+
+// gameTransactionTypes are the transaction types that record a game's result
+var gameTransactionTypes = []model.TransactionType{
+	model.TransactionTypeGame,
+	model.TransactionTypeGameWin,
+	model.TransactionTypeGameLoss,
+	model.TransactionType("game_push"),
+}
+
+// gameNetAmountSQL is a game transaction's signed net result. Some games record
+// losses as positive amounts, so the sign of a loss comes from its type.
+const gameNetAmountSQL = "CASE WHEN type = 'game_loss' THEN -ABS(amount) WHEN type = 'game_push' THEN 0 ELSE amount END"
+
+// validAchievementMetric reports whether a metric can be computed
+func validAchievementMetric(metric string) bool {
+	switch metric {
+	case AchievementMetricBalance, AchievementMetricWorkEarned, AchievementMetricWorkHours,
+		AchievementMetricGamesPlayed, AchievementMetricGamesWon, AchievementMetricTotalWagered,
+		AchievementMetricGamblingLosses, AchievementMetricGameWinStreak, AchievementMetricGameLossStreak,
+		AchievementMetricItemsPurchased, AchievementMetricHousesOwned,
+		AchievementMetricMaxHouseValue, AchievementMetricLoansTaken, AchievementMetricTotalBorrowed,
+		AchievementMetricBankruptcies, AchievementMetricDebtWrittenOff:
+		return true
+	}
+	return false
+}
+
+// achievementMetrics computes a user's metrics on first use and caches them
+// for the rest of one evaluation. When since is set, activity metrics only
+// count what happened from then on.
+type achievementMetrics struct {
+	db     *gorm.DB
+	userID uint
+	since  time.Time
+	values map[string]float64
+}
+
+func newAchievementMetrics(db *gorm.DB, userID uint) *achievementMetrics {
+	return &achievementMetrics{db: db, userID: userID, values: make(map[string]float64)}
+}
+
+// newWindowedMetrics returns metrics that only count activity since a time
+func newWindowedMetrics(db *gorm.DB, userID uint, since time.Time) *achievementMetrics {
+	metrics := newAchievementMetrics(db, userID)
+	// Timestamps are stored as text in the server's zone, so compare in it too
+	metrics.since = since.Local()
+	return metrics
+}
+
+// windowedMetric reports whether a metric counts activity and can be limited to a time window
+func windowedMetric(metric string) bool {
+	switch metric {
+	case AchievementMetricBalance, AchievementMetricHousesOwned, AchievementMetricMaxHouseValue,
+		AchievementMetricGameWinStreak, AchievementMetricGameLossStreak:
+		return false
+	}
+	return validAchievementMetric(metric)
+}
+
+// get returns the value of a metric
+func (m *achievementMetrics) get(metric string) (float64, error) {
+	if value, ok := m.values[metric]; ok {
+		return value, nil
+	}
+
+	value, err := m.compute(metric)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute %s: %w", metric, err)
+	}
+	m.values[metric] = value
+	return value, nil
+}
+
+// activity scopes a query to the user's rows inside the metrics window
+func (m *achievementMetrics) activity(db *gorm.DB, value interface{}) *gorm.DB {
+	query := db.Model(value).Where("user_id = ?", m.userID)
+	if !m.since.IsZero() {
+		query = query.Where("created_at >= ?", m.since)
+	}
+	return query
+}
+
+func (m *achievementMetrics) compute(metric string) (float64, error) {
+	var value float64
+	var err error
+
+	switch metric {
+	case AchievementMetricBalance:
+		err = m.db.Model(&model.User{}).Where("id = ?", m.userID).Select("balance").Scan(&value).Error
+	case AchievementMetricWorkEarned:
+		err = m.activity(m.db, &model.Transaction{}).Where("type = ?", model.TransactionTypeWork).
+			Select("COALESCE(SUM(amount), 0)").Scan(&value).Error
+	case AchievementMetricWorkHours:
+		err = m.activity(m.db, &model.WorkSession{}).
+			Select("COALESCE(SUM(duration_seconds), 0) / 3600.0").Scan(&value).Error
+	case AchievementMetricGamesPlayed:
+		err = m.activity(m.db, &model.GameSession{}).Select("COUNT(*)").Scan(&value).Error
+	case AchievementMetricGamesWon:
+		err = m.activity(m.db, &model.Transaction{}).Where("type IN ?", gameTransactionTypes).
+			Where(gameNetAmountSQL + " > 0").Select("COUNT(*)").Scan(&value).Error
+	case AchievementMetricTotalWagered:
+		err = m.activity(m.db, &model.GameSession{}).Select("COALESCE(SUM(bet), 0)").Scan(&value).Error
+	case AchievementMetricGamblingLosses:
+		err = m.activity(m.db, &model.Transaction{}).Where("type IN ?", gameTransactionTypes).
+			Select("COALESCE(SUM(" + gameNetAmountSQL + "), 0)").Scan(&value).Error
+		value = max(-value, 0)
+	case AchievementMetricGameWinStreak:
+		value, err = m.streak(true)
+	case AchievementMetricGameLossStreak:
+		value, err = m.streak(false)
+	case AchievementMetricItemsPurchased:
+		err = m.activity(m.db, &model.Transaction{}).
+			Where("type IN ?", []model.TransactionType{model.TransactionTypePurchase, model.TransactionTypeMarketPurchase}).
+			Select("COUNT(*)").Scan(&value).Error
+	case AchievementMetricHousesOwned:
+		err = m.ownedHouses().Select("COUNT(*)").Scan(&value).Error
+	case AchievementMetricMaxHouseValue:
+		err = m.ownedHouses().Select("COALESCE(MAX(items.price), 0)").Scan(&value).Error
+	case AchievementMetricLoansTaken:
+		// Repaid and discharged loans are soft-deleted but still count
+		err = m.activity(m.db.Unscoped(), &model.Loan{}).Select("COUNT(*)").Scan(&value).Error
+	case AchievementMetricTotalBorrowed:
+		err = m.activity(m.db.Unscoped(), &model.Loan{}).
+			Select("COALESCE(SUM(principal_amount), 0)").Scan(&value).Error
+	case AchievementMetricBankruptcies:
+		err = m.activity(m.db, &model.Bankruptcy{}).Select("COUNT(*)").Scan(&value).Error
+	case AchievementMetricDebtWrittenOff:
+		err = m.activity(m.db, &model.Bankruptcy{}).
+			Select("COALESCE(SUM(debt_written_off), 0)").Scan(&value).Error
+	default:
+		return 0, fmt.Errorf("unknown metric")
+	}
+
+	return value, err
+}
+
+// ownedHouses scopes a query to the houses the user currently owns
+func (m *achievementMetrics) ownedHouses() *gorm.DB {
+	return m.db.Model(&model.UserItem{}).
+		Joins("JOIN items ON items.id = user_items.item_id").
+		Where("user_items.user_id = ? AND items.type = ?", m.userID, model.ItemTypeHouse)
+}
+
+// streak counts the user's most recent consecutive game wins or losses
+func (m *achievementMetrics) streak(wins bool) (float64, error) {
+	var amounts []float64
+	if err := m.db.Model(&model.Transaction{}).
+		Where("user_id = ? AND type IN ?", m.userID, gameTransactionTypes).
+		Order("id DESC").Limit(achievementStreakWindow).
+		Pluck(gameNetAmountSQL, &amounts).Error; err != nil {
+		return 0, err
+	}
+
+	streak := 0
+	for _, amount := range amounts {
+		if (wins && amount <= 0) || (!wins && amount >= 0) {
+			break
+		}
+		streak++
+	}
+	return float64(streak), nil
+}
+
+// AchievementService evaluates and reports user achievements
+type AchievementService struct {
+	db      *gorm.DB
+	catalog *AchievementCatalog
+	stats   *StatsService
+}
+
+// NewAchievementService creates a new achievement service instance
+func NewAchievementService() (*AchievementService, error) {
+	catalog, err := LoadAchievementCatalog()
+	if err != nil {
+		return nil, err
+	}
+	stats, err := NewStatsService()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AchievementService{
+		db:      database.GetDB(),
+		catalog: catalog,
+		stats:   stats,
+	}, nil
+}
+
+// getCatalog returns the service's catalogue, defaulting to the built-in one
+func (s *AchievementService) getCatalog() *AchievementCatalog {
+	if s.catalog == nil {
+		return DefaultAchievementCatalog()
+	}
+	return s.catalog
+}
+
+// Evaluate checks every achievement the event can trigger and unlocks those
+// whose prerequisites and conditions are met. Returns the newly unlocked codes.
+func (s *AchievementService) Evaluate(event events.Event) ([]string, error) {
+	userID := event.EventUserID()
+	var unlockedCodes []string
+	if err := s.db.Model(&model.UserAchievement{}).
+		Where("user_id = ?", userID).
+		Pluck("code", &unlockedCodes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get unlocked achievements: %w", err)
+	}
+	unlocked := make(map[string]bool, len(unlockedCodes))
+	for _, code := range unlockedCodes {
+		unlocked[code] = true
+	}
+
+	metrics := newAchievementMetrics(s.db, userID)
+	var newlyUnlocked []string
+	for _, achievement := range s.getCatalog().All() {
+		if unlocked[achievement.Code] || !achievement.triggeredBy(event.EventName()) {
+			continue
+		}
+
+		met, err := s.qualifies(&achievement, unlocked, metrics)
+		if err != nil {
+			return newlyUnlocked, fmt.Errorf("failed to evaluate %s: %w", achievement.Code, err)
+		}
+		if !met {
+			continue
+		}
+
+		statAmount, err := metrics.get(achievement.Stat.Metric)
+		if err != nil {
+			return newlyUnlocked, err
+		}
+
+		// A concurrent evaluation may have unlocked it first; the unique index keeps one row
+		created := false
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserAchievement{
+				UserID:     userID,
+				Code:       achievement.Code,
+				StatAmount: roundMoney(statAmount),
+				UnlockedAt: time.Now(),
+			})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			created = true
+			return events.Record(tx, events.AchievementUnlocked{
+				UserID: userID,
+				Code:   achievement.Code,
+				Name:   achievement.Name,
+			})
+		})
+		if err != nil {
+			return newlyUnlocked, fmt.Errorf("failed to unlock %s: %w", achievement.Code, err)
+		}
+		if !created {
+			continue
+		}
+
+		// Later achievements in the same pass may require this one
+		unlocked[achievement.Code] = true
+		newlyUnlocked = append(newlyUnlocked, achievement.Code)
+	}
+
+	if len(newlyUnlocked) > 0 {
+		flushEvents(s.db)
+	}
+	return newlyUnlocked, nil
+}
+
+// HandleEvent evaluates an event from the bus and logs what it unlocked
+func (s *AchievementService) HandleEvent(event events.Event) error {
+	codes, err := s.Evaluate(event)
+	for _, code := range codes {
+		log.Printf("achievements: user %d unlocked %s", event.EventUserID(), code)
+	}
+	return err
+}
+
+// qualifies reports whether a user meets an achievement's prerequisites and conditions
+func (s *AchievementService) qualifies(achievement *AchievementDefinition, unlocked map[string]bool, metrics *achievementMetrics) (bool, error) {
+	for _, code := range achievement.Requires {
+		if !unlocked[code] {
+			return false, nil
+		}
+	}
+
+	for _, condition := range achievement.Conditions {
+		value, err := metrics.get(condition.Metric)
+		if err != nil {
+			return false, err
+		}
+		if condition.Min != nil && value < *condition.Min {
+			return false, nil
+		}
+		if condition.Max != nil && value > *condition.Max {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// AchievementView is an achievement as shown to a user
+type AchievementView struct {
+	Code        string     `json:"code"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Category    string     `json:"category"`
+	Requires    []string   `json:"requires,omitempty"`
+	Unlocked    bool       `json:"unlocked"`
+	UnlockedAt  *time.Time `json:"unlocked_at,omitempty"`
+	Stat        string     `json:"stat,omitempty"`      // Real-world statistic, shown once unlocked
+	WorkTime    *WorkTime  `json:"work_time,omitempty"` // Stat amount in hours of work in the chosen country
+}
+
+// AchievementsResponse lists every achievement with the user's progress
+type AchievementsResponse struct {
+	Achievements []AchievementView `json:"achievements"`
+	Unlocked     int               `json:"unlocked"`
+	Total        int               `json:"total"`
+	Country      string            `json:"country"`
+}
+
+// GetAchievements returns every achievement and which ones the user has
+// unlocked. Stats are expressed in hours of work in the given country.
+func (s *AchievementService) GetAchievements(userID uint, country string) (*AchievementsResponse, error) {
+	return s.getAchievements(userID, country, false)
+}
+
+// GetUnlocked returns only the achievements the user has unlocked
+func (s *AchievementService) GetUnlocked(userID uint, country string) (*AchievementsResponse, error) {
+	return s.getAchievements(userID, country, true)
+}
+
+func (s *AchievementService) getAchievements(userID uint, country string, unlockedOnly bool) (*AchievementsResponse, error) {
+	if country == "" {
+		// Default to the country in the user's profile
+		var user model.User
+		if err := s.db.Select("country").First(&user, userID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+		country = userCountry(&user)
+	}
+	country = strings.ToUpper(country)
+	if s.stats != nil {
+		if _, err := s.stats.GetCountryByCode(country); err != nil {
+			return nil, errors.New("country_not_found")
+		}
+	}
+
+	var records []model.UserAchievement
+	if err := s.db.Where("user_id = ?", userID).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to get achievements: %w", err)
+	}
+	byCode := make(map[string]*model.UserAchievement, len(records))
+	for i := range records {
+		byCode[records[i].Code] = &records[i]
+	}
+
+	catalog := s.getCatalog()
+	response := &AchievementsResponse{
+		Achievements: make([]AchievementView, 0, len(catalog.All())),
+		Total:        len(catalog.All()),
+		Country:      country,
+	}
+
+	for _, achievement := range catalog.All() {
+		record, unlocked := byCode[achievement.Code]
+		if unlocked {
+			response.Unlocked++
+		} else if unlockedOnly {
+			continue
+		}
+
+		view := AchievementView{
+			Code:        achievement.Code,
+			Name:        achievement.Name,
+			Description: achievement.Description,
+			Category:    achievement.Category,
+			Requires:    achievement.Requires,
+			Unlocked:    unlocked,
+		}
+		if unlocked {
+			view.UnlockedAt = &record.UnlockedAt
+			view.Stat, view.WorkTime = s.renderStat(&achievement, record.StatAmount, country)
+		}
+		response.Achievements = append(response.Achievements, view)
+	}
+
+	return response, nil
+}
+
+// renderStat fills an achievement's stat text in for an amount and country
+func (s *AchievementService) renderStat(achievement *AchievementDefinition, amount float64, country string) (string, *WorkTime) {
+	if s.stats == nil {
+		return "", nil
+	}
+	workTime, err := s.stats.GetWorkTime(country, amount)
+	if err != nil {
+		return "", nil
+	}
+
+	replacer := strings.NewReplacer(
+		"{amount}", "$"+strconv.FormatFloat(workTime.Amount, 'f', 2, 64),
+		"{hours}", strconv.FormatFloat(workTime.Hours, 'f', 1, 64),
+		"{days}", strconv.FormatFloat(workTime.Days, 'f', 1, 64),
+		"{country}", workTime.Country,
+	)
+	return replacer.Replace(achievement.Stat.Text), workTime
+}
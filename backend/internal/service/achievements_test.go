@@ -0,0 +1,134 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func addTransactions(t *testing.T, db *gorm.DB, userID uint, txType model.TransactionType, amounts ...float64) {
+	for _, amount := range amounts {
+		require.NoError(t, db.Create(&model.Transaction{UserID: userID, Type: txType, Amount: amount}).Error)
+	}
+}
+
+func TestDefaultAchievementCatalog(t *testing.T) {
+	catalog := DefaultAchievementCatalog()
+	require.NotEmpty(t, catalog.All())
+
+	mansion, ok := catalog.Get("mansion_to_cardboard_box")
+	require.True(t, ok)
+	assert.Equal(t, AchievementCategoryIronic, mansion.Category)
+	assert.Equal(t, []string{"lord_of_the_manor"}, mansion.Requires)
+
+	_, err := ParseAchievementCatalog([]byte(`[{"code":"x","category":"normal","triggers":["bet_settled"],
+		"conditions":[{"metric":"luck","min":1}],"stat":{"metric":"balance","text":"t"}}]`))
+	assert.EqualError(t, err, `achievement "x" uses unknown metric "luck"`)
+
+	_, err = ParseAchievementCatalog([]byte(`[{"code":"x","category":"normal","requires":["y"],"triggers":["bet_settled"],
+		"conditions":[{"metric":"balance","min":1}],"stat":{"metric":"balance","text":"t"}}]`))
+	assert.EqualError(t, err, `achievement "x" requires unknown achievement "y"`)
+}
+
+func TestAchievementUnlocksOnce(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &AchievementService{db: db}
+
+	addTransactions(t, db, user.ID, model.TransactionTypeWork, 300)
+	unlocked, err := service.Evaluate(events.WorkCompleted{UserID: user.ID})
+	require.NoError(t, err)
+	assert.Empty(t, unlocked)
+
+	addTransactions(t, db, user.ID, model.TransactionTypeWork, 250)
+
+	// Events that don't trigger an achievement never unlock it
+	unlocked, err = service.Evaluate(events.BetSettled{UserID: user.ID})
+	require.NoError(t, err)
+	assert.Empty(t, unlocked)
+
+	unlocked, err = service.Evaluate(events.WorkCompleted{UserID: user.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first_paycheck"}, unlocked)
+
+	unlocked, err = service.Evaluate(events.WorkCompleted{UserID: user.ID})
+	require.NoError(t, err)
+	assert.Empty(t, unlocked)
+
+	var records []model.UserAchievement
+	require.NoError(t, db.Where("user_id = ?", user.ID).Find(&records).Error)
+	require.Len(t, records, 1)
+	assert.Equal(t, 550.0, records[0].StatAmount)
+}
+
+func TestAchievementRequiresPrerequisite(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 50)
+	service := &AchievementService{db: db}
+	event := events.ItemSold{UserID: user.ID}
+
+	// Broke and homeless, but never owned a mansion
+	unlocked, err := service.Evaluate(event)
+	require.NoError(t, err)
+	assert.NotContains(t, unlocked, "mansion_to_cardboard_box")
+
+	require.NoError(t, db.Create(&model.UserAchievement{UserID: user.ID, Code: "lord_of_the_manor"}).Error)
+	unlocked, err = service.Evaluate(event)
+	require.NoError(t, err)
+	assert.Contains(t, unlocked, "mansion_to_cardboard_box")
+}
+
+func TestAchievementStreaks(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+
+	addTransactions(t, db, user.ID, model.TransactionTypeGameLoss, -10)
+	addTransactions(t, db, user.ID, model.TransactionTypeGameWin, 5, 5, 5, 5, 5)
+
+	metrics := newAchievementMetrics(db, user.ID)
+	streak, err := metrics.get(AchievementMetricGameWinStreak)
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, streak)
+
+	// Some games record losses as positive amounts
+	addTransactions(t, db, user.ID, model.TransactionTypeGameLoss, 40)
+	metrics = newAchievementMetrics(db, user.ID)
+	streak, err = metrics.get(AchievementMetricGameWinStreak)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, streak)
+	streak, err = metrics.get(AchievementMetricGameLossStreak)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, streak)
+	losses, err := metrics.get(AchievementMetricGamblingLosses)
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, losses)
+}
+
+func TestGetAchievementsRendersStat(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &AchievementService{
+		db:    db,
+		stats: &StatsService{countries: []Country{{Code: "US", Name: "United States", AvgHourlyWage: 25}}},
+	}
+	require.NoError(t, db.Create(&model.UserAchievement{UserID: user.ID, Code: "first_paycheck", StatAmount: 500}).Error)
+
+	response, err := service.GetAchievements(user.ID, "us")
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.Unlocked)
+	assert.Equal(t, len(DefaultAchievementCatalog().All()), response.Total)
+	assert.Len(t, response.Achievements, response.Total)
+	assert.Equal(t, "Earning $500.00 takes 20.0 hours of real work in United States.", response.Achievements[0].Stat)
+
+	unlocked, err := service.GetUnlocked(user.ID, "")
+	require.NoError(t, err)
+	require.Len(t, unlocked.Achievements, 1)
+	assert.Equal(t, 20.0, unlocked.Achievements[0].WorkTime.Hours)
+
+	_, err = service.GetAchievements(user.ID, "XX")
+	assert.EqualError(t, err, "country_not_found")
+}
@@ -0,0 +1,628 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// Analytics time series bucket sizes
+const (
+	AnalyticsBucketHour = "hour"
+	AnalyticsBucketDay  = "day"
+)
+
+// Analytics parameters
+const (
+	analyticsMaxBuckets      = 24 * 31        // Points one time series may return
+	analyticsDefaultDays     = 30             // Span of a daily series when no range is given
+	analyticsDefaultHours    = 48             // Span of an hourly series when no range is given
+	analyticsDefaultSessions = 20             // Sessions listed when no limit is given
+	analyticsMaxSessions     = 100            // Most sessions listed at once
+	analyticsMaxGap          = 24 * time.Hour // Longest break a session may span
+	analyticsEpochThursday   = 4              // 1970-01-01 was a Thursday
+	analyticsDateLayout      = "2006-01-02"   // Dates in from/to are read in the user's timezone
+	analyticsSecondsPerHour  = int64(time.Hour / time.Second)
+	analyticsSecondsPerDay   = 24 * analyticsSecondsPerHour
+)
+
+// gameSessionPayoutSQL is what a game session returned to the player, stake
+// included. Blackjack records its net result in win, every other game the payout.
+const gameSessionPayoutSQL = "CASE WHEN game_type = 'blackjack' THEN bet + win ELSE win END"
+
+// analyticsRoundsSQL selects a user's game rounds in a time range with their
+// payout and Unix time. Everything below aggregates over it in SQL.
+const analyticsRoundsSQL = `SELECT id, game_type, bet, ` + gameSessionPayoutSQL + ` AS payout,
+	CAST(strftime('%s', created_at) AS INTEGER) AS at
+	FROM game_sessions WHERE user_id = @user AND created_at >= @from AND created_at < @to`
+
+// AnalyticsService computes a user's gambling analytics with SQL aggregations
+// over their game sessions
+type AnalyticsService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewAnalyticsService creates a new analytics service instance
+func NewAnalyticsService() *AnalyticsService {
+	return &AnalyticsService{
+		db: database.GetDB(),
+	}
+}
+
+// clock returns the current time
+func (s *AnalyticsService) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// analyticsScope is the user and time range an analytics query covers
+type analyticsScope struct {
+	user   model.User
+	loc    *time.Location
+	offset int64 // The timezone's current UTC offset in seconds, used to bucket by local hour and day
+	from   time.Time
+	to     time.Time
+}
+
+// args returns the named arguments of analyticsRoundsSQL
+func (sc *analyticsScope) args() map[string]interface{} {
+	return map[string]interface{}{
+		"user":   sc.user.ID,
+		"from":   sc.from.Local(),
+		"to":     sc.to.Local(),
+		"offset": sc.offset,
+	}
+}
+
+// parseAnalyticsTime reads an RFC 3339 time or a date in loc
+func parseAnalyticsTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(analyticsDateLayout, value, loc)
+}
+
+// scope loads the user and resolves a from/to range. An empty to means now and
+// an empty from means span before to, or all time if span is zero.
+func (s *AnalyticsService) scope(userID uint, from, to string, span time.Duration) (*analyticsScope, error) {
+	sc := &analyticsScope{}
+	if err := s.db.First(&sc.user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user_not_found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	now := s.clock()
+	sc.loc = userTimezone(&sc.user)
+	_, offset := now.In(sc.loc).Zone()
+	sc.offset = int64(offset)
+
+	sc.to = now
+	if to != "" {
+		t, err := parseAnalyticsTime(to, sc.loc)
+		if err != nil {
+			return nil, errors.New("invalid_range")
+		}
+		sc.to = t
+	}
+	if from != "" {
+		t, err := parseAnalyticsTime(from, sc.loc)
+		if err != nil {
+			return nil, errors.New("invalid_range")
+		}
+		sc.from = t
+	} else if span > 0 {
+		sc.from = sc.to.Add(-span)
+	}
+	if !sc.from.Before(sc.to) {
+		return nil, errors.New("invalid_range")
+	}
+
+	return sc, nil
+}
+
+// AnalyticsTotals sums up a set of game rounds
+type AnalyticsTotals struct {
+	Rounds  int     `json:"rounds"`
+	Wagered float64 `json:"wagered"`
+	Won     float64 `json:"won"` // Paid back, stakes included
+	Net     float64 `json:"net"`
+}
+
+// add adds a row of aggregates
+func (t *AnalyticsTotals) add(rounds int, wagered, won float64) {
+	t.Rounds += rounds
+	t.Wagered = roundMoney(t.Wagered + wagered)
+	t.Won = roundMoney(t.Won + won)
+	t.Net = roundMoney(t.Won - t.Wagered)
+}
+
+// AnalyticsPoint is one bucket of a time series
+type AnalyticsPoint struct {
+	Start time.Time `json:"start"`
+	AnalyticsTotals
+	Cumulative float64                            `json:"cumulative"` // Net since the start of the series
+	Games      map[model.GameType]AnalyticsTotals `json:"games,omitempty"`
+}
+
+// TimeSeriesResponse represents wagered, won and net per hour or day
+type TimeSeriesResponse struct {
+	Bucket   string           `json:"bucket"`
+	Timezone string           `json:"timezone"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Totals   AnalyticsTotals  `json:"totals"`
+	Points   []AnalyticsPoint `json:"points"`
+}
+
+// GetTimeSeries returns the user's wagered, won and net per hour or day in
+// their timezone, overall and per game. Empty buckets are included.
+func (s *AnalyticsService) GetTimeSeries(userID uint, bucket, from, to string) (*TimeSeriesResponse, error) {
+	var size int64
+	var span time.Duration
+	switch bucket {
+	case AnalyticsBucketDay, "":
+		bucket, size, span = AnalyticsBucketDay, analyticsSecondsPerDay, analyticsDefaultDays*24*time.Hour
+	case AnalyticsBucketHour:
+		size, span = analyticsSecondsPerHour, analyticsDefaultHours*time.Hour
+	default:
+		return nil, errors.New("invalid_bucket")
+	}
+
+	sc, err := s.scope(userID, from, to, span)
+	if err != nil {
+		return nil, err
+	}
+	if sc.from.IsZero() {
+		return nil, errors.New("invalid_range")
+	}
+
+	// Bucket numbers count whole local hours or days since the epoch
+	first := (sc.from.Unix() + sc.offset) / size
+	last := (sc.to.Unix() - 1 + sc.offset) / size
+	if last-first+1 > analyticsMaxBuckets {
+		return nil, errors.New("range_too_large")
+	}
+
+	var rows []struct {
+		Bucket   int64
+		GameType model.GameType
+		Rounds   int
+		Wagered  float64
+		Won      float64
+	}
+	args := sc.args()
+	args["size"] = size
+	if err := s.db.Raw(`SELECT (at + @offset) / @size AS bucket, game_type, COUNT(*) AS rounds,
+		COALESCE(SUM(bet), 0) AS wagered, COALESCE(SUM(payout), 0) AS won
+		FROM (`+analyticsRoundsSQL+`) GROUP BY bucket, game_type ORDER BY bucket`, args).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get time series: %w", err)
+	}
+
+	response := &TimeSeriesResponse{
+		Bucket:   bucket,
+		Timezone: sc.loc.String(),
+		From:     sc.from,
+		To:       sc.to,
+		Points:   make([]AnalyticsPoint, last-first+1),
+	}
+	for i := range response.Points {
+		response.Points[i].Start = time.Unix((first+int64(i))*size-sc.offset, 0).In(sc.loc)
+	}
+	for _, row := range rows {
+		point := &response.Points[row.Bucket-first]
+		point.add(row.Rounds, row.Wagered, row.Won)
+		if point.Games == nil {
+			point.Games = make(map[model.GameType]AnalyticsTotals)
+		}
+		game := point.Games[row.GameType]
+		game.add(row.Rounds, row.Wagered, row.Won)
+		point.Games[row.GameType] = game
+		response.Totals.add(row.Rounds, row.Wagered, row.Won)
+	}
+
+	cumulative := 0.0
+	for i := range response.Points {
+		cumulative = roundMoney(cumulative + response.Points[i].Net)
+		response.Points[i].Cumulative = cumulative
+	}
+
+	return response, nil
+}
+
+// GameAnalytics represents a user's results in one game
+type GameAnalytics struct {
+	GameType model.GameType `json:"game_type"`
+	AnalyticsTotals
+	Wins       int     `json:"wins"`
+	Losses     int     `json:"losses"`
+	WinRate    float64 `json:"win_rate"`    // Percentage of rounds won
+	ReturnRate float64 `json:"return_rate"` // Percentage of stakes paid back
+	BiggestWin float64 `json:"biggest_win"`
+}
+
+// StreakStats represents the user's longest and current runs of wins and losses.
+// A push ends a run.
+type StreakStats struct {
+	LongestWin    int    `json:"longest_win"`
+	LongestLoss   int    `json:"longest_loss"`
+	Current       int    `json:"current"`
+	CurrentResult string `json:"current_result,omitempty"` // "win", "loss" or "push"
+}
+
+// SwingStats represents the largest moves in the user's results
+type SwingStats struct {
+	BiggestWin  float64 `json:"biggest_win"`  // Best single round
+	BiggestLoss float64 `json:"biggest_loss"` // Worst single round
+	MaxDrawdown float64 `json:"max_drawdown"` // Largest fall from a running high
+	MaxRunUp    float64 `json:"max_run_up"`   // Largest climb from a running low
+}
+
+// WorkRatio compares gambling losses with what the user earned working
+type WorkRatio struct {
+	WorkEarned   float64  `json:"work_earned"`
+	WorkHours    float64  `json:"work_hours"`
+	GamblingLoss float64  `json:"gambling_loss"`        // Net loss, 0 if ahead
+	Ratio        *float64 `json:"ratio,omitempty"`      // Loss per coin earned working
+	HoursLost    *float64 `json:"hours_lost,omitempty"` // The loss in hours of work at the user's average pay
+}
+
+// AnalyticsOverview represents a user's gambling analytics over a range
+type AnalyticsOverview struct {
+	From      *time.Time      `json:"from,omitempty"` // Nil for all time
+	To        time.Time       `json:"to"`
+	Totals    AnalyticsTotals `json:"totals"`
+	Games     []GameAnalytics `json:"games"`
+	Streaks   StreakStats     `json:"streaks"`
+	Swings    SwingStats      `json:"swings"`
+	WorkRatio WorkRatio       `json:"work_ratio"`
+}
+
+// GetOverview returns the user's per-game breakdown, streaks, swings and the
+// ratio of their gambling losses to their work earnings
+func (s *AnalyticsService) GetOverview(userID uint, from, to string) (*AnalyticsOverview, error) {
+	sc, err := s.scope(userID, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &AnalyticsOverview{To: sc.to, Games: []GameAnalytics{}}
+	if !sc.from.IsZero() {
+		overview.From = &sc.from
+	}
+
+	var games []struct {
+		GameType   model.GameType
+		Rounds     int
+		Wins       int
+		Losses     int
+		Wagered    float64
+		Won        float64
+		BiggestWin float64
+	}
+	if err := s.db.Raw(`SELECT game_type, COUNT(*) AS rounds,
+		SUM(CASE WHEN payout > bet THEN 1 ELSE 0 END) AS wins,
+		SUM(CASE WHEN payout < bet THEN 1 ELSE 0 END) AS losses,
+		COALESCE(SUM(bet), 0) AS wagered, COALESCE(SUM(payout), 0) AS won,
+		MAX(MAX(payout - bet), 0) AS biggest_win
+		FROM (`+analyticsRoundsSQL+`) GROUP BY game_type ORDER BY rounds DESC`, sc.args()).
+		Scan(&games).Error; err != nil {
+		return nil, fmt.Errorf("failed to get game breakdown: %w", err)
+	}
+	for _, row := range games {
+		game := GameAnalytics{
+			GameType:   row.GameType,
+			Wins:       row.Wins,
+			Losses:     row.Losses,
+			BiggestWin: roundMoney(row.BiggestWin),
+		}
+		game.add(row.Rounds, row.Wagered, row.Won)
+		if row.Rounds > 0 {
+			game.WinRate = roundMoney(float64(row.Wins) / float64(row.Rounds) * 100)
+		}
+		if row.Wagered > 0 {
+			game.ReturnRate = roundMoney(row.Won / row.Wagered * 100)
+		}
+		overview.Games = append(overview.Games, game)
+		overview.Totals.add(row.Rounds, row.Wagered, row.Won)
+	}
+
+	if overview.Streaks, err = s.streaks(sc); err != nil {
+		return nil, err
+	}
+	if overview.Swings, err = s.swings(sc); err != nil {
+		return nil, err
+	}
+	if overview.WorkRatio, err = s.workRatio(sc, overview.Totals.Net); err != nil {
+		return nil, err
+	}
+
+	return overview, nil
+}
+
+// analyticsRunsSQL numbers the runs of equal results (1 win, -1 loss, 0 push)
+// in the rounds: a round's run is its position overall minus its position
+// among rounds with the same result, which stays constant along a run
+const analyticsRunsSQL = `SELECT id, result,
+	ROW_NUMBER() OVER (ORDER BY id) - ROW_NUMBER() OVER (PARTITION BY result ORDER BY id) AS run
+	FROM (SELECT id, CASE WHEN payout > bet THEN 1 WHEN payout < bet THEN -1 ELSE 0 END AS result
+	FROM (` + analyticsRoundsSQL + `))`
+
+// streaks finds the longest runs of wins and losses and the run the user is on
+func (s *AnalyticsService) streaks(sc *analyticsScope) (StreakStats, error) {
+	var stats StreakStats
+
+	var longest []struct {
+		Result int
+		Length int
+	}
+	if err := s.db.Raw(`SELECT result, MAX(length) AS length FROM (
+		SELECT result, COUNT(*) AS length FROM (`+analyticsRunsSQL+`) GROUP BY result, run
+		) GROUP BY result`, sc.args()).Scan(&longest).Error; err != nil {
+		return stats, fmt.Errorf("failed to get streaks: %w", err)
+	}
+	for _, row := range longest {
+		switch row.Result {
+		case 1:
+			stats.LongestWin = row.Length
+		case -1:
+			stats.LongestLoss = row.Length
+		}
+	}
+
+	var current struct {
+		Result int
+		Length int
+	}
+	if err := s.db.Raw(`WITH runs AS (`+analyticsRunsSQL+`),
+		latest AS (SELECT result, run FROM runs ORDER BY id DESC LIMIT 1)
+		SELECT runs.result, COUNT(*) AS length FROM runs
+		JOIN latest ON runs.result = latest.result AND runs.run = latest.run
+		GROUP BY runs.result`, sc.args()).Scan(&current).Error; err != nil {
+		return stats, fmt.Errorf("failed to get current streak: %w", err)
+	}
+	stats.Current = current.Length
+	if current.Length > 0 {
+		stats.CurrentResult = map[int]string{1: "win", -1: "loss", 0: "push"}[current.Result]
+	}
+
+	return stats, nil
+}
+
+// swings finds the best and worst rounds and the largest drawdown and run-up
+// of the user's running net, which starts at zero
+func (s *AnalyticsService) swings(sc *analyticsScope) (SwingStats, error) {
+	var row struct {
+		Best     float64
+		Worst    float64
+		Drawdown float64
+		RunUp    float64
+	}
+	if err := s.db.Raw(`WITH running AS (
+		SELECT id, payout - bet AS net, SUM(payout - bet) OVER (ORDER BY id) AS total FROM (`+analyticsRoundsSQL+`)
+		), marks AS (
+		SELECT net, total, MAX(total) OVER (ORDER BY id) AS peak, MIN(total) OVER (ORDER BY id) AS trough FROM running
+		)
+		SELECT COALESCE(MAX(net), 0) AS best, COALESCE(MIN(net), 0) AS worst,
+			COALESCE(MAX(MAX(peak, 0) - total), 0) AS drawdown, COALESCE(MAX(total - MIN(trough, 0)), 0) AS run_up
+		FROM marks`, sc.args()).Scan(&row).Error; err != nil {
+		return SwingStats{}, fmt.Errorf("failed to get swings: %w", err)
+	}
+
+	return SwingStats{
+		BiggestWin:  roundMoney(max(row.Best, 0)),
+		BiggestLoss: roundMoney(max(-row.Worst, 0)),
+		MaxDrawdown: roundMoney(row.Drawdown),
+		MaxRunUp:    roundMoney(row.RunUp),
+	}, nil
+}
+
+// workRatio compares a gambling net result with the user's work earnings in the same range
+func (s *AnalyticsService) workRatio(sc *analyticsScope, net float64) (WorkRatio, error) {
+	var work struct {
+		Earned  float64
+		Seconds int64
+	}
+	if err := s.db.Model(&model.WorkSession{}).
+		Where("user_id = ? AND completed_at >= ? AND completed_at < ?", sc.user.ID, sc.from.Local(), sc.to.Local()).
+		Select("COALESCE(SUM(earned), 0) AS earned, COALESCE(SUM(duration_seconds), 0) AS seconds").
+		Scan(&work).Error; err != nil {
+		return WorkRatio{}, fmt.Errorf("failed to get work earnings: %w", err)
+	}
+
+	ratio := WorkRatio{
+		WorkEarned:   roundMoney(work.Earned),
+		WorkHours:    roundMoney(float64(work.Seconds) / 3600),
+		GamblingLoss: roundMoney(max(-net, 0)),
+	}
+	if work.Earned > 0 {
+		value := roundMoney(ratio.GamblingLoss / work.Earned)
+		ratio.Ratio = &value
+		if work.Seconds > 0 {
+			hours := roundMoney(ratio.GamblingLoss / (work.Earned / (float64(work.Seconds) / 3600)))
+			ratio.HoursLost = &hours
+		}
+	}
+	return ratio, nil
+}
+
+// AnalyticsSession is a run of rounds without a long break between them
+type AnalyticsSession struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"` // Time of the last round
+	Minutes  float64   `json:"minutes"`
+	Rounds   int       `json:"rounds"`
+	Wagered  float64   `json:"wagered"`
+	Won      float64   `json:"won"`
+	Net      float64   `json:"net"`
+	MainGame string    `json:"main_game"` // Most played game
+}
+
+// SessionSummary sums up every session in the range
+type SessionSummary struct {
+	Sessions        int     `json:"sessions"`
+	LosingSessions  int     `json:"losing_sessions"`
+	AverageMinutes  float64 `json:"average_minutes"`
+	LongestMinutes  float64 `json:"longest_minutes"`
+	AverageRounds   float64 `json:"average_rounds"`
+	AverageNet      float64 `json:"average_net"`
+	WorstSessionNet float64 `json:"worst_session_net"`
+}
+
+// SessionsResponse represents the sessions detected in a user's play
+type SessionsResponse struct {
+	GapMinutes int                `json:"gap_minutes"`
+	Summary    SessionSummary     `json:"summary"`
+	Sessions   []AnalyticsSession `json:"sessions"` // Most recent first
+}
+
+// analyticsSessionsSQL groups rounds into sessions: a round more than @gap
+// seconds after the one before it starts a new session
+const analyticsSessionsSQL = `WITH marked AS (
+	SELECT *, CASE WHEN at - LAG(at) OVER (ORDER BY at, id) <= @gap THEN 0 ELSE 1 END AS starts
+	FROM (` + analyticsRoundsSQL + `)
+	), numbered AS (
+	SELECT *, SUM(starts) OVER (ORDER BY at, id) AS session FROM marked
+	), sessions AS (
+	SELECT session, MIN(at) AS start, MAX(at) AS end, COUNT(*) AS rounds,
+		SUM(bet) AS wagered, SUM(payout) AS won, SUM(payout) - SUM(bet) AS net
+	FROM numbered GROUP BY session
+	)`
+
+// GetSessions detects the user's play sessions from the gaps between their
+// bets and returns a summary with the most recent sessions
+func (s *AnalyticsService) GetSessions(userID uint, gap time.Duration, limit int, from, to string) (*SessionsResponse, error) {
+	if gap <= 0 {
+		gap = GamblingSessionIdleGap
+	}
+	if gap > analyticsMaxGap {
+		return nil, errors.New("invalid_gap")
+	}
+	if limit <= 0 || limit > analyticsMaxSessions {
+		limit = analyticsDefaultSessions
+	}
+
+	sc, err := s.scope(userID, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	args := sc.args()
+	args["gap"] = int64(gap / time.Second)
+	args["limit"] = limit
+
+	var summary struct {
+		Sessions        int
+		LosingSessions  int
+		AverageSeconds  float64
+		LongestSeconds  float64
+		AverageRounds   float64
+		AverageNet      float64
+		WorstSessionNet float64
+	}
+	if err := s.db.Raw(analyticsSessionsSQL+`
+		SELECT COUNT(*) AS sessions, COALESCE(SUM(CASE WHEN net < 0 THEN 1 ELSE 0 END), 0) AS losing_sessions,
+			COALESCE(AVG(end - start), 0) AS average_seconds, COALESCE(MAX(end - start), 0) AS longest_seconds,
+			COALESCE(AVG(rounds), 0) AS average_rounds, COALESCE(AVG(net), 0) AS average_net,
+			COALESCE(MIN(net), 0) AS worst_session_net
+		FROM sessions`, args).Scan(&summary).Error; err != nil {
+		return nil, fmt.Errorf("failed to summarise sessions: %w", err)
+	}
+
+	var rows []struct {
+		Start    int64
+		End      int64
+		Rounds   int
+		Wagered  float64
+		Won      float64
+		MainGame string
+	}
+	if err := s.db.Raw(analyticsSessionsSQL+`
+		SELECT start, end, rounds, wagered, won,
+			(SELECT game_type FROM numbered WHERE numbered.session = sessions.session
+				GROUP BY game_type ORDER BY COUNT(*) DESC LIMIT 1) AS main_game
+		FROM sessions ORDER BY session DESC LIMIT @limit`, args).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	response := &SessionsResponse{
+		GapMinutes: int(gap / time.Minute),
+		Summary: SessionSummary{
+			Sessions:        summary.Sessions,
+			LosingSessions:  summary.LosingSessions,
+			AverageMinutes:  roundMoney(summary.AverageSeconds / 60),
+			LongestMinutes:  roundMoney(summary.LongestSeconds / 60),
+			AverageRounds:   roundMoney(summary.AverageRounds),
+			AverageNet:      roundMoney(summary.AverageNet),
+			WorstSessionNet: roundMoney(summary.WorstSessionNet),
+		},
+		Sessions: make([]AnalyticsSession, len(rows)),
+	}
+	for i, row := range rows {
+		response.Sessions[i] = AnalyticsSession{
+			Start:    time.Unix(row.Start, 0).In(sc.loc),
+			End:      time.Unix(row.End, 0).In(sc.loc),
+			Minutes:  roundMoney(float64(row.End-row.Start) / 60),
+			Rounds:   row.Rounds,
+			Wagered:  roundMoney(row.Wagered),
+			Won:      roundMoney(row.Won),
+			Net:      roundMoney(row.Won - row.Wagered),
+			MainGame: row.MainGame,
+		}
+	}
+
+	return response, nil
+}
+
+// HeatmapCell is the play in one hour of one weekday
+type HeatmapCell struct {
+	Weekday int     `json:"weekday"` // 0 is Sunday
+	Hour    int     `json:"hour"`
+	Rounds  int     `json:"rounds"`
+	Wagered float64 `json:"wagered"`
+	Net     float64 `json:"net"`
+}
+
+// HeatmapResponse represents when in the week a user plays
+type HeatmapResponse struct {
+	Timezone string        `json:"timezone"`
+	Cells    []HeatmapCell `json:"cells"` // Only hours with play
+}
+
+// GetHeatmap returns the user's rounds, stakes and net per weekday and hour of
+// day in their timezone
+func (s *AnalyticsService) GetHeatmap(userID uint, from, to string) (*HeatmapResponse, error) {
+	sc, err := s.scope(userID, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	args := sc.args()
+	args["day"] = analyticsSecondsPerDay
+	args["hour"] = analyticsSecondsPerHour
+	args["thursday"] = analyticsEpochThursday
+
+	var cells []HeatmapCell
+	if err := s.db.Raw(`SELECT ((at + @offset) / @day + @thursday) % 7 AS weekday, ((at + @offset) % @day) / @hour AS hour,
+		COUNT(*) AS rounds, COALESCE(SUM(bet), 0) AS wagered, COALESCE(SUM(payout - bet), 0) AS net
+		FROM (`+analyticsRoundsSQL+`) GROUP BY weekday, hour ORDER BY weekday, hour`, args).
+		Scan(&cells).Error; err != nil {
+		return nil, fmt.Errorf("failed to get heatmap: %w", err)
+	}
+	for i := range cells {
+		cells[i].Wagered = roundMoney(cells[i].Wagered)
+		cells[i].Net = roundMoney(cells[i].Net)
+	}
+	if cells == nil {
+		cells = []HeatmapCell{}
+	}
+
+	return &HeatmapResponse{Timezone: sc.loc.String(), Cells: cells}, nil
+}
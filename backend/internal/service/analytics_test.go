@@ -0,0 +1,164 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// seedAnalyticsRounds plays a few rounds from Monday 2026-10-12 10:00 UTC: a
+// 20 minute session that loses three and wins two, a second session two hours
+// later with a push and a loss, and a last loss the next day
+func seedAnalyticsRounds(t *testing.T, db *gorm.DB, userID uint) time.Time {
+	base := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	rounds := []struct {
+		after    time.Duration
+		gameType model.GameType
+		bet, win float64
+	}{
+		{0, model.GameTypeSlots, 10, 0},
+		{5 * time.Minute, model.GameTypeSlots, 10, 0},
+		{10 * time.Minute, model.GameTypeSlots, 10, 0},
+		{15 * time.Minute, model.GameTypeRoulette, 10, 50},
+		{20 * time.Minute, model.GameTypeBlackjack, 10, 10}, // Blackjack records the net
+		{2 * time.Hour, model.GameTypeRoulette, 20, 20},
+		{2*time.Hour + 5*time.Minute, model.GameTypeSlots, 20, 0},
+		{26 * time.Hour, model.GameTypeSlots, 5, 0},
+	}
+	for _, round := range rounds {
+		require.NoError(t, db.Create(&model.GameSession{
+			UserID:    userID,
+			GameType:  round.gameType,
+			Bet:       round.bet,
+			Win:       round.win,
+			CreatedAt: base.Add(round.after),
+		}).Error)
+	}
+	return base
+}
+
+func TestAnalyticsOverview(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	base := seedAnalyticsRounds(t, db, user.ID)
+	service := &AnalyticsService{db: db, now: func() time.Time { return base.Add(72 * time.Hour) }}
+	require.NoError(t, db.Create(&model.WorkSession{
+		UserID: user.ID, DurationSeconds: 3600, Earned: 10, CompletedAt: base.Add(time.Hour),
+	}).Error)
+
+	overview, err := service.GetOverview(user.ID, "", "")
+	require.NoError(t, err)
+	assert.Nil(t, overview.From)
+	assert.Equal(t, AnalyticsTotals{Rounds: 8, Wagered: 95, Won: 90, Net: -5}, overview.Totals)
+
+	require.Len(t, overview.Games, 3)
+	slots := overview.Games[0]
+	assert.Equal(t, model.GameTypeSlots, slots.GameType)
+	assert.Equal(t, AnalyticsTotals{Rounds: 5, Wagered: 55, Won: 0, Net: -55}, slots.AnalyticsTotals)
+	assert.Equal(t, 5, slots.Losses)
+	roulette := overview.Games[1]
+	assert.Equal(t, 1, roulette.Wins)
+	assert.Equal(t, 50.0, roulette.WinRate)
+	assert.Equal(t, 40.0, roulette.BiggestWin)
+	blackjack := overview.Games[2]
+	assert.Equal(t, 20.0, blackjack.Won)
+	assert.Equal(t, 200.0, blackjack.ReturnRate)
+
+	// The push between the two sessions breaks the run of losses
+	assert.Equal(t, StreakStats{LongestWin: 2, LongestLoss: 3, Current: 2, CurrentResult: "loss"}, overview.Streaks)
+	// The running net goes -30, +20, -5
+	assert.Equal(t, SwingStats{BiggestWin: 40, BiggestLoss: 20, MaxDrawdown: 30, MaxRunUp: 50}, overview.Swings)
+
+	require.NotNil(t, overview.WorkRatio.Ratio)
+	assert.Equal(t, 5.0, overview.WorkRatio.GamblingLoss)
+	assert.Equal(t, 0.5, *overview.WorkRatio.Ratio)
+	assert.Equal(t, 0.5, *overview.WorkRatio.HoursLost)
+
+	// The first session alone came out ahead
+	overview, err = service.GetOverview(user.ID, base.Format(time.RFC3339), base.Add(time.Hour).Format(time.RFC3339))
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, overview.Totals.Net)
+	assert.Equal(t, 0.0, overview.WorkRatio.GamblingLoss)
+	assert.Nil(t, overview.WorkRatio.Ratio)
+
+	_, err = service.GetOverview(user.ID, "2026-10-14", "2026-10-13")
+	assert.EqualError(t, err, "invalid_range")
+	_, err = service.GetOverview(user.ID+100, "", "")
+	assert.EqualError(t, err, "user_not_found")
+}
+
+func TestAnalyticsTimeSeries(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	base := seedAnalyticsRounds(t, db, user.ID)
+	service := &AnalyticsService{db: db, now: func() time.Time { return base.Add(72 * time.Hour) }}
+
+	series, err := service.GetTimeSeries(user.ID, AnalyticsBucketDay, "2026-10-12", "2026-10-15")
+	require.NoError(t, err)
+	require.Len(t, series.Points, 3)
+	assert.Equal(t, AnalyticsTotals{Rounds: 7, Wagered: 90, Won: 90, Net: 0}, series.Points[0].AnalyticsTotals)
+	assert.Equal(t, -50.0, series.Points[0].Games[model.GameTypeSlots].Net)
+	assert.Equal(t, -5.0, series.Points[1].Net)
+	assert.Equal(t, 0, series.Points[2].Rounds, "empty days are included")
+	assert.Equal(t, -5.0, series.Points[2].Cumulative)
+	assert.Equal(t, -5.0, series.Totals.Net)
+
+	// Hourly buckets default to the last two days
+	series, err = service.GetTimeSeries(user.ID, AnalyticsBucketHour, "", "")
+	require.NoError(t, err)
+	assert.Len(t, series.Points, analyticsDefaultHours)
+	assert.Equal(t, 1, series.Totals.Rounds)
+
+	_, err = service.GetTimeSeries(user.ID, "minute", "", "")
+	assert.EqualError(t, err, "invalid_bucket")
+	_, err = service.GetTimeSeries(user.ID, AnalyticsBucketHour, "2026-01-01", "")
+	assert.EqualError(t, err, "range_too_large")
+}
+
+func TestAnalyticsSessionsAndHeatmap(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	base := seedAnalyticsRounds(t, db, user.ID)
+	service := &AnalyticsService{db: db, now: func() time.Time { return base.Add(72 * time.Hour) }}
+
+	sessions, err := service.GetSessions(user.ID, 0, 0, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 30, sessions.GapMinutes)
+	assert.Equal(t, 3, sessions.Summary.Sessions)
+	assert.Equal(t, 2, sessions.Summary.LosingSessions)
+	assert.Equal(t, 20.0, sessions.Summary.LongestMinutes)
+	assert.Equal(t, -20.0, sessions.Summary.WorstSessionNet)
+	require.Len(t, sessions.Sessions, 3)
+	assert.Equal(t, 1, sessions.Sessions[0].Rounds, "most recent first")
+	first := sessions.Sessions[2]
+	assert.Equal(t, 5, first.Rounds)
+	assert.Equal(t, 20.0, first.Net)
+	assert.Equal(t, "slots", first.MainGame)
+
+	// With a three hour gap the first two sessions merge
+	sessions, err = service.GetSessions(user.ID, 3*time.Hour, 0, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, sessions.Summary.Sessions)
+	_, err = service.GetSessions(user.ID, 48*time.Hour, 0, "", "")
+	assert.EqualError(t, err, "invalid_gap")
+
+	heatmap, err := service.GetHeatmap(user.ID, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, []HeatmapCell{
+		{Weekday: 1, Hour: 10, Rounds: 5, Wagered: 50, Net: 20},
+		{Weekday: 1, Hour: 12, Rounds: 2, Wagered: 40, Net: -20},
+		{Weekday: 2, Hour: 12, Rounds: 1, Wagered: 5, Net: -5},
+	}, heatmap.Cells)
+
+	// Hours are the user's own
+	require.NoError(t, db.Model(user).Update("timezone", "Asia/Tokyo").Error)
+	heatmap, err = service.GetHeatmap(user.ID, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", heatmap.Timezone)
+	assert.Equal(t, 19, heatmap.Cells[0].Hour)
+	assert.Equal(t, 1, heatmap.Cells[0].Weekday)
+}
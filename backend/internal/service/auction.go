@@ -0,0 +1,611 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AuctionService provides business logic for the auction house
+type AuctionService struct {
+	db   *gorm.DB
+	feed *AuctionFeed
+}
+
+// NewAuctionService creates a new auction service instance
+func NewAuctionService() *AuctionService {
+	return &AuctionService{
+		db:   database.GetDB(),
+		feed: DefaultAuctionFeed(),
+	}
+}
+
+// Auction house rules
+const (
+	AuctionMinIncrementRate = 0.05 // Each bid must beat the current one by 5%
+	AuctionMinIncrement     = 1.0  // ... and by at least $1
+
+	AuctionSnipeWindow    = 2 * time.Minute // Bids this close to the end extend the auction
+	AuctionSnipeExtension = 2 * time.Minute // New time left after a late bid
+
+	AuctionHouseDuration    = 24 * time.Hour
+	AuctionHouseStartRate   = 0.5 // Opening bid as a share of the shop price
+	AuctionHouseReserveRate = 0.8 // Reserve as a share of the shop price
+
+	AuctionSeizureDuration = 24 * time.Hour
+)
+
+// auctionHouseRarities are the shop items offered through house auctions
+var auctionHouseRarities = []model.ItemRarity{model.ItemRarityEpic, model.ItemRarityLegendary}
+
+// PlaceBidRequest represents a bid on an auction
+type PlaceBidRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+// AuctionView is an auction as shown to bidders. Bidders stay anonymous:
+// a signed-in viewer only learns which bids are their own.
+type AuctionView struct {
+	model.Auction
+	ReserveMet bool             `json:"reserve_met"`
+	MinimumBid float64          `json:"minimum_bid"`
+	Leading    bool             `json:"leading"` // The viewer holds the highest bid
+	Bids       []AuctionBidView `json:"bids,omitempty"`
+}
+
+// AuctionBidView is a bid in an auction's history
+type AuctionBidView struct {
+	model.AuctionBid
+	Mine bool `json:"mine"`
+}
+
+// PlaceBidResponse represents the result of a bid
+type PlaceBidResponse struct {
+	Auction    AuctionView      `json:"auction"`
+	Bid        model.AuctionBid `json:"bid"`
+	Extended   bool             `json:"extended"`
+	NewBalance float64          `json:"new_balance"`
+}
+
+// SettlementResult summarises a settlement run
+type SettlementResult struct {
+	Sold   int `json:"sold"`
+	Unsold int `json:"unsold"`
+}
+
+// minimumBid returns the lowest bid the auction accepts next
+func minimumBid(auction *model.Auction) float64 {
+	if auction.BidCount == 0 {
+		return auction.StartingPrice
+	}
+	increment := math.Max(auction.CurrentBid*AuctionMinIncrementRate, AuctionMinIncrement)
+	return roundMoney(auction.CurrentBid + increment)
+}
+
+// viewAuction builds the bidder-facing view of an auction for a viewer, who
+// is 0 when not signed in
+func viewAuction(auction model.Auction, viewerID uint) AuctionView {
+	return AuctionView{
+		Auction:    auction,
+		ReserveMet: auction.ReserveMet(),
+		MinimumBid: minimumBid(&auction),
+		Leading:    viewerID != 0 && auction.HighBidderID != nil && *auction.HighBidderID == viewerID,
+	}
+}
+
+// auctionEvent builds a feed event describing the auction's current state
+func auctionEvent(eventType string, auction *model.Auction) AuctionEvent {
+	return AuctionEvent{
+		Type:       eventType,
+		AuctionID:  auction.ID,
+		Amount:     auction.CurrentBid,
+		BidCount:   auction.BidCount,
+		EndsAt:     auction.EndsAt,
+		Status:     string(auction.Status),
+		ReserveMet: auction.ReserveMet(),
+	}
+}
+
+// GetAuctions returns active auctions ending soonest first, as viewerID sees
+// them (0 when not signed in)
+func (s *AuctionService) GetAuctions(viewerID uint, limit, offset int) ([]AuctionView, int64, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := s.db.Model(&model.Auction{}).Where("status = ?", model.AuctionStatusActive)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count auctions: %w", err)
+	}
+
+	var auctions []model.Auction
+	if err := query.Preload("Item").
+		Order("ends_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&auctions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get auctions: %w", err)
+	}
+
+	views := make([]AuctionView, len(auctions))
+	for i := range auctions {
+		views[i] = viewAuction(auctions[i], viewerID)
+	}
+	return views, total, nil
+}
+
+// GetAuction returns an auction with its bid history, highest first, as
+// viewerID sees it (0 when not signed in)
+func (s *AuctionService) GetAuction(viewerID, auctionID uint) (*AuctionView, error) {
+	var auction model.Auction
+	if err := s.db.Preload("Item").First(&auction, auctionID).Error; err != nil {
+		return nil, errors.New("auction_not_found")
+	}
+
+	var bids []model.AuctionBid
+	if err := s.db.Where("auction_id = ?", auctionID).
+		Order("amount DESC, id DESC").
+		Find(&bids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get bids: %w", err)
+	}
+
+	view := viewAuction(auction, viewerID)
+	view.Bids = make([]AuctionBidView, len(bids))
+	for i := range bids {
+		view.Bids[i] = AuctionBidView{AuctionBid: bids[i], Mine: viewerID != 0 && bids[i].UserID == viewerID}
+	}
+	return &view, nil
+}
+
+// GetUserBids returns the user's bids, newest first
+func (s *AuctionService) GetUserBids(userID uint, limit int) ([]model.AuctionBid, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	var bids []model.AuctionBid
+	if err := s.db.Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&bids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get bids: %w", err)
+	}
+	return bids, nil
+}
+
+// PlaceBid holds the bid amount from the bidder's balance, releases the
+// previous leader's hold and extends the auction if the bid came in late
+func (s *AuctionService) PlaceBid(userID, auctionID uint, req PlaceBidRequest) (*PlaceBidResponse, error) {
+	amount := roundMoney(req.Amount)
+	if amount <= 0 {
+		return nil, errors.New("invalid_amount")
+	}
+
+	var response *PlaceBidResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var auction model.Auction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Item").First(&auction, auctionID).Error; err != nil {
+			return errors.New("auction_not_found")
+		}
+
+		now := time.Now()
+		if auction.Status != model.AuctionStatusActive || !now.Before(auction.EndsAt) {
+			return errors.New("auction_closed")
+		}
+		if now.Before(auction.StartsAt) {
+			return errors.New("auction_not_started")
+		}
+		if auction.FormerOwnerID != nil && *auction.FormerOwnerID == userID {
+			return errors.New("cannot_bid_on_own_item")
+		}
+		if auction.HighBidderID != nil && *auction.HighBidderID == userID {
+			return errors.New("already_high_bidder")
+		}
+		if amount < minimumBid(&auction) {
+			return errors.New("bid_too_low")
+		}
+
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return errors.New("user_not_found")
+		}
+		if user.Balance < amount {
+			return errors.New("insufficient_balance")
+		}
+
+		// Return the funds held for the bid being beaten
+		if err := releaseLeadingHold(tx, &auction, "Outbid on"); err != nil {
+			return err
+		}
+
+		user.Balance = roundMoney(user.Balance - amount)
+		if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			return fmt.Errorf("failed to hold funds: %w", err)
+		}
+
+		bid := model.AuctionBid{
+			AuctionID:  auction.ID,
+			UserID:     userID,
+			Amount:     amount,
+			HoldStatus: model.BidHoldHeld,
+		}
+		if err := tx.Create(&bid).Error; err != nil {
+			return fmt.Errorf("failed to create bid: %w", err)
+		}
+
+		if err := tx.Create(&model.Transaction{
+			UserID:       userID,
+			Type:         model.TransactionTypeAuctionHold,
+			Amount:       -amount,
+			BalanceAfter: user.Balance,
+			Description:  fmt.Sprintf("Bid on %s", auction.Item.Name),
+			CreatedAt:    now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+
+		// Anti-sniping: a late bid gives everyone else time to respond
+		extended := false
+		if auction.EndsAt.Sub(now) < AuctionSnipeWindow {
+			auction.EndsAt = now.Add(AuctionSnipeExtension)
+			auction.Extensions++
+			extended = true
+		}
+
+		auction.CurrentBid = amount
+		auction.HighBidderID = &userID
+		auction.BidCount++
+		if err := tx.Model(&auction).Updates(map[string]interface{}{
+			"current_bid":    auction.CurrentBid,
+			"high_bidder_id": userID,
+			"bid_count":      auction.BidCount,
+			"ends_at":        auction.EndsAt,
+			"extensions":     auction.Extensions,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update auction: %w", err)
+		}
+
+		response = &PlaceBidResponse{
+			Auction:    viewAuction(auction, userID),
+			Bid:        bid,
+			Extended:   extended,
+			NewBalance: user.Balance,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.feed.Publish(auctionEvent(AuctionEventBid, &response.Auction.Auction))
+	if response.Extended {
+		s.feed.Publish(auctionEvent(AuctionEventExtended, &response.Auction.Auction))
+	}
+
+	return response, nil
+}
+
+// SettleDueAuctions closes every auction whose time has run out. Winners
+// receive the item and their hold is captured; otherwise the hold is released.
+func (s *AuctionService) SettleDueAuctions() (*SettlementResult, error) {
+	var ids []uint
+	if err := s.db.Model(&model.Auction{}).
+		Where("status = ? AND ends_at <= ?", model.AuctionStatusActive, time.Now()).
+		Order("ends_at ASC").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get due auctions: %w", err)
+	}
+
+	result := &SettlementResult{}
+	for _, id := range ids {
+		auction, err := s.settleAuction(id)
+		if err != nil {
+			log.Printf("Failed to settle auction %d: %v", id, err)
+			continue
+		}
+		if auction == nil {
+			continue
+		}
+
+		switch auction.Status {
+		case model.AuctionStatusSold:
+			result.Sold++
+		case model.AuctionStatusUnsold:
+			result.Unsold++
+		}
+		s.feed.Publish(auctionEvent(AuctionEventClosed, auction))
+	}
+
+	return result, nil
+}
+
+// settleAuction closes one auction; it returns nil if the auction was already
+// settled or extended by a bid since it was selected
+func (s *AuctionService) settleAuction(auctionID uint) (*model.Auction, error) {
+	var settled *model.Auction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var auction model.Auction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Item").First(&auction, auctionID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if auction.Status != model.AuctionStatusActive || now.Before(auction.EndsAt) {
+			return nil
+		}
+
+		if auction.ReserveMet() {
+			if err := tx.Model(&model.AuctionBid{}).
+				Where("auction_id = ? AND user_id = ? AND hold_status = ?", auction.ID, *auction.HighBidderID, model.BidHoldHeld).
+				Update("hold_status", model.BidHoldCaptured).Error; err != nil {
+				return fmt.Errorf("failed to capture bid: %w", err)
+			}
+
+			if err := tx.Create(&model.UserItem{
+				UserID:       *auction.HighBidderID,
+				ItemID:       auction.ItemID,
+				PurchasedAt:  now,
+				SerialNumber: auction.SerialNumber,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to deliver item: %w", err)
+			}
+			if auction.Source == model.AuctionSourceSeizure {
+				if err := settleSeizureProceeds(tx, &auction); err != nil {
+					return err
+				}
+			}
+			auction.Status = model.AuctionStatusSold
+		} else {
+			if err := releaseLeadingHold(tx, &auction, "Reserve not met on"); err != nil {
+				return err
+			}
+			if auction.Source == model.AuctionSourceHouse {
+				if err := returnShopUnit(tx, &auction); err != nil {
+					return err
+				}
+			}
+			auction.Status = model.AuctionStatusUnsold
+		}
+
+		auction.SettledAt = &now
+		if err := tx.Model(&auction).Updates(map[string]interface{}{
+			"status":     auction.Status,
+			"settled_at": now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to close auction: %w", err)
+		}
+
+		settled = &auction
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return settled, nil
+}
+
+// OpenHouseAuctions puts every epic and legendary shop item that is not
+// already on auction up for a new house auction. Each auction takes a unit
+// from the shop's stock, and a serial number for limited editions, the way a
+// purchase does; sold out items are skipped.
+func (s *AuctionService) OpenHouseAuctions() (int, error) {
+	var items []model.Item
+	if err := s.db.Where("rarity IN ?", auctionHouseRarities).
+		Where("id NOT IN (?)", s.db.Model(&model.Auction{}).
+			Select("item_id").
+			Where("status = ? AND source = ?", model.AuctionStatusActive, model.AuctionSourceHouse)).
+		Find(&items).Error; err != nil {
+		return 0, fmt.Errorf("failed to get items: %w", err)
+	}
+
+	opened := 0
+	for i := range items {
+		var auction *model.Auction
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var item model.Item
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, items[i].ID).Error; err != nil {
+				return fmt.Errorf("failed to get item: %w", err)
+			}
+			serial, err := takeShopUnit(tx, &item)
+			if err != nil {
+				if err.Error() == "item out of stock" || err.Error() == "limited edition sold out" {
+					return nil
+				}
+				return err
+			}
+
+			now := time.Now()
+			auction = &model.Auction{
+				ItemID:        item.ID,
+				Source:        model.AuctionSourceHouse,
+				SerialNumber:  serial,
+				StartingPrice: math.Max(roundMoney(item.Price*AuctionHouseStartRate), AuctionMinIncrement),
+				ReservePrice:  roundMoney(item.Price * AuctionHouseReserveRate),
+				StartsAt:      now,
+				EndsAt:        now.Add(AuctionHouseDuration),
+				Status:        model.AuctionStatusActive,
+			}
+			if err := tx.Create(auction).Error; err != nil {
+				return fmt.Errorf("failed to open auction: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return opened, err
+		}
+		if auction == nil {
+			continue
+		}
+		opened++
+		s.feed.Publish(auctionEvent(AuctionEventOpened, auction))
+	}
+
+	return opened, nil
+}
+
+// returnShopUnit puts the unit an unsold house auction took back into stock.
+// A limited edition serial is only returned while it is still the latest one
+// issued; otherwise that copy is never sold, which can't oversell the edition.
+func returnShopUnit(tx *gorm.DB, auction *model.Auction) error {
+	if err := tx.Model(&model.Item{}).
+		Where("id = ? AND stock IS NOT NULL", auction.ItemID).
+		Update("stock", gorm.Expr("stock + 1")).Error; err != nil {
+		return fmt.Errorf("failed to return stock: %w", err)
+	}
+	if auction.SerialNumber == nil {
+		return nil
+	}
+	if err := tx.Model(&model.Item{}).
+		Where("id = ? AND editions_sold = ?", auction.ItemID, *auction.SerialNumber).
+		Update("editions_sold", gorm.Expr("editions_sold - 1")).Error; err != nil {
+		return fmt.Errorf("failed to return edition: %w", err)
+	}
+	return nil
+}
+
+// consignSeizedItem puts an item taken by collectors up for auction. The
+// collections process has already credited the debtor with the resale value;
+// the auction lets other players bid for it from that price, and anything
+// bid above it is passed on when the auction settles.
+func consignSeizedItem(tx *gorm.DB, item *model.UserItem, loanID *uint) error {
+	now := time.Now()
+	price := math.Max(auctionProceeds(item, now), AuctionMinIncrement)
+	formerOwner := item.UserID
+
+	auction := model.Auction{
+		ItemID:        item.ItemID,
+		Source:        model.AuctionSourceSeizure,
+		FormerOwnerID: &formerOwner,
+		LoanID:        loanID,
+		SerialNumber:  item.SerialNumber,
+		StartingPrice: price,
+		ReservePrice:  price,
+		StartsAt:      now,
+		EndsAt:        now.Add(AuctionSeizureDuration),
+		Status:        model.AuctionStatusActive,
+	}
+	if err := tx.Create(&auction).Error; err != nil {
+		return fmt.Errorf("failed to consign seized item: %w", err)
+	}
+	return nil
+}
+
+// settleSeizureProceeds passes on what a seized item sold for above the resale
+// value the debtor was credited with when it was seized: the difference pays
+// down the loan it was seized for, or the debtor's other loans when the
+// seizure was not for one loan, and anything left goes back to the debtor
+func settleSeizureProceeds(tx *gorm.DB, auction *model.Auction) error {
+	if auction.FormerOwnerID == nil {
+		return nil
+	}
+	extra := roundMoney(auction.CurrentBid - auction.ReservePrice)
+	if extra <= 0 {
+		return nil
+	}
+
+	var debtor model.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&debtor, *auction.FormerOwnerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // Account deleted since
+		}
+		return fmt.Errorf("failed to get debtor: %w", err)
+	}
+
+	description := fmt.Sprintf("Auction of %s above its resale value", auction.Item.Name)
+	paid := 0.0
+	if auction.LoanID != nil {
+		var loan model.Loan
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("CollateralItem").First(&loan, *auction.LoanID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get loan: %w", err)
+		}
+		if err == nil && loan.RemainingAmount > 0 {
+			alloc, paidOff, err := applyLoanPayment(tx, &loan, extra, model.LoanEntryCollection, description)
+			if err != nil {
+				return err
+			}
+			paid = alloc.Total
+			if paidOff {
+				if err := settleLoan(tx, debtor.ID, &loan); err != nil {
+					return err
+				}
+			}
+		}
+	} else {
+		var err error
+		if paid, err = payDebt(tx, debtor.ID, extra, description); err != nil {
+			return err
+		}
+	}
+	if err := logCollectionEvent(tx, debtor.ID, auction.LoanID, model.CollectionEventItemAuctioned, extra, auction.Item.Name,
+		fmt.Sprintf("%s sold at auction for $%.2f, $%.2f more than you were credited", auction.Item.Name, auction.CurrentBid, extra)); err != nil {
+		return err
+	}
+
+	if surplus := roundMoney(extra - paid); surplus > 0 {
+		debtor.Balance = roundMoney(debtor.Balance + surplus)
+		if err := tx.Model(&debtor).Update("balance", debtor.Balance).Error; err != nil {
+			return fmt.Errorf("failed to return surplus: %w", err)
+		}
+		if err := logCollectionEvent(tx, debtor.ID, auction.LoanID, model.CollectionEventSurplusReturned, surplus, auction.Item.Name,
+			fmt.Sprintf("$%.2f left over from the auction of %s was returned to your balance", surplus, auction.Item.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseLeadingHold returns the funds held for the auction's current high bid
+func releaseLeadingHold(tx *gorm.DB, auction *model.Auction, description string) error {
+	if auction.HighBidderID == nil {
+		return nil
+	}
+
+	var bid model.AuctionBid
+	err := tx.Where("auction_id = ? AND user_id = ? AND hold_status = ?", auction.ID, *auction.HighBidderID, model.BidHoldHeld).
+		Order("id DESC").
+		First(&bid).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get held bid: %w", err)
+	}
+
+	var bidder model.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bidder, bid.UserID).Error; err != nil {
+		return fmt.Errorf("failed to get bidder: %w", err)
+	}
+
+	now := time.Now()
+	bidder.Balance = roundMoney(bidder.Balance + bid.Amount)
+	if err := tx.Model(&bidder).Update("balance", bidder.Balance).Error; err != nil {
+		return fmt.Errorf("failed to release hold: %w", err)
+	}
+	if err := tx.Model(&bid).Updates(map[string]interface{}{
+		"hold_status": model.BidHoldReleased,
+		"released_at": now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to release bid: %w", err)
+	}
+
+	return tx.Create(&model.Transaction{
+		UserID:       bidder.ID,
+		Type:         model.TransactionTypeAuctionRelease,
+		Amount:       bid.Amount,
+		BalanceAfter: bidder.Balance,
+		Description:  fmt.Sprintf("%s %s", description, auction.Item.Name),
+		CreatedAt:    now,
+	}).Error
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// Auction feed event types
+const (
+	AuctionEventOpened   = "auction_opened"
+	AuctionEventBid      = "bid_placed"
+	AuctionEventExtended = "auction_extended"
+	AuctionEventClosed   = "auction_closed"
+)
+
+// auctionFeedBuffer is how many events a slow subscriber may lag behind before events are dropped
+const auctionFeedBuffer = 32
+
+// AuctionEvent is a live update about an auction
+type AuctionEvent struct {
+	Type       string    `json:"type"`
+	AuctionID  uint      `json:"auction_id"`
+	Amount     float64   `json:"amount,omitempty"`
+	BidCount   int       `json:"bid_count"`
+	EndsAt     time.Time `json:"ends_at"`
+	Status     string    `json:"status"`
+	ReserveMet bool      `json:"reserve_met"`
+}
+
+// AuctionFeed fans auction events out to live subscribers
+type AuctionFeed struct {
+	mu   sync.RWMutex
+	subs map[chan AuctionEvent]uint // Subscriber channel -> auction ID (0 means all auctions)
+}
+
+// NewAuctionFeed creates an empty auction feed
+func NewAuctionFeed() *AuctionFeed {
+	return &AuctionFeed{
+		subs: make(map[chan AuctionEvent]uint),
+	}
+}
+
+// defaultAuctionFeed is shared by every AuctionService so that bids placed over
+// HTTP and settlements made by the background job reach the same subscribers
+var defaultAuctionFeed = NewAuctionFeed()
+
+// DefaultAuctionFeed returns the process-wide auction feed
+func DefaultAuctionFeed() *AuctionFeed {
+	return defaultAuctionFeed
+}
+
+// Subscribe returns a channel of events for one auction, or for every auction
+// when auctionID is 0, and a function that cancels the subscription
+func (f *AuctionFeed) Subscribe(auctionID uint) (<-chan AuctionEvent, func()) {
+	ch := make(chan AuctionEvent, auctionFeedBuffer)
+
+	f.mu.Lock()
+	f.subs[ch] = auctionID
+	f.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs, ch)
+			f.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers an event to every matching subscriber without blocking
+func (f *AuctionFeed) Publish(event AuctionEvent) {
+	if f == nil {
+		return
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for ch, auctionID := range f.subs {
+		if auctionID != 0 && auctionID != event.AuctionID {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+			// Subscriber is too slow; drop the event rather than stall bidding
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// createTestAuction opens a house auction for a new item ending after the given duration
+func createTestAuction(t *testing.T, db *gorm.DB, startingPrice, reservePrice float64, endsIn time.Duration) *model.Auction {
+	item := createTestItem(t, db, "Golden Watch", model.ItemTypeAccessories, startingPrice*2)
+	auction := &model.Auction{
+		ItemID:        item.ID,
+		Source:        model.AuctionSourceHouse,
+		StartingPrice: startingPrice,
+		ReservePrice:  reservePrice,
+		StartsAt:      time.Now().Add(-time.Hour),
+		EndsAt:        time.Now().Add(endsIn),
+		Status:        model.AuctionStatusActive,
+	}
+	require.NoError(t, db.Create(auction).Error)
+	return auction
+}
+
+func userBalance(t *testing.T, db *gorm.DB, userID uint) float64 {
+	var balance float64
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", userID).Pluck("balance", &balance).Error)
+	return balance
+}
+
+func TestMinimumBid(t *testing.T) {
+	auction := &model.Auction{StartingPrice: 100}
+	assert.Equal(t, 100.0, minimumBid(auction))
+
+	auction.BidCount = 1
+	auction.CurrentBid = 100
+	assert.Equal(t, 105.0, minimumBid(auction))
+
+	auction.CurrentBid = 10
+	assert.Equal(t, 11.0, minimumBid(auction))
+}
+
+func TestAuctionService_BidHolds(t *testing.T) {
+	db := setupTestDB(t)
+	service := &AuctionService{db: db}
+	alice := createTestUser(t, db, 500)
+	bob := createTestUser(t, db, 500)
+	auction := createTestAuction(t, db, 100, 150, time.Hour)
+
+	_, err := service.PlaceBid(alice.ID, auction.ID, PlaceBidRequest{Amount: 90})
+	assert.EqualError(t, err, "bid_too_low")
+
+	first, err := service.PlaceBid(alice.ID, auction.ID, PlaceBidRequest{Amount: 100})
+	require.NoError(t, err)
+	assert.Equal(t, 400.0, first.NewBalance)
+	assert.False(t, first.Auction.ReserveMet)
+	assert.Equal(t, 105.0, first.Auction.MinimumBid)
+
+	_, err = service.PlaceBid(alice.ID, auction.ID, PlaceBidRequest{Amount: 120})
+	assert.EqualError(t, err, "already_high_bidder")
+
+	_, err = service.PlaceBid(bob.ID, auction.ID, PlaceBidRequest{Amount: 104})
+	assert.EqualError(t, err, "bid_too_low")
+
+	second, err := service.PlaceBid(bob.ID, auction.ID, PlaceBidRequest{Amount: 160})
+	require.NoError(t, err)
+	assert.Equal(t, 340.0, second.NewBalance)
+	assert.True(t, second.Auction.ReserveMet)
+	assert.False(t, second.Extended)
+
+	// Alice's hold was released when she was outbid
+	assert.Equal(t, 500.0, userBalance(t, db, alice.ID))
+
+	var bids []model.AuctionBid
+	require.NoError(t, db.Where("auction_id = ?", auction.ID).Order("id").Find(&bids).Error)
+	require.Len(t, bids, 2)
+	assert.Equal(t, model.BidHoldReleased, bids[0].HoldStatus)
+	assert.Equal(t, model.BidHoldHeld, bids[1].HoldStatus)
+
+	var types []model.TransactionType
+	require.NoError(t, db.Model(&model.Transaction{}).Where("user_id = ?", alice.ID).Order("id").Pluck("type", &types).Error)
+	assert.Equal(t, []model.TransactionType{model.TransactionTypeAuctionHold, model.TransactionTypeAuctionRelease}, types)
+
+	poor := createTestUser(t, db, 10)
+	_, err = service.PlaceBid(poor.ID, auction.ID, PlaceBidRequest{Amount: 200})
+	assert.EqualError(t, err, "insufficient_balance")
+}
+
+func TestAuctionService_BiddersStayAnonymous(t *testing.T) {
+	db := setupTestDB(t)
+	service := &AuctionService{db: db, feed: NewAuctionFeed()}
+	alice := createTestUser(t, db, 500)
+	bob := createTestUser(t, db, 500)
+	auction := createTestAuction(t, db, 100, 150, time.Hour)
+
+	events, cancel := service.feed.Subscribe(auction.ID)
+	defer cancel()
+
+	_, err := service.PlaceBid(alice.ID, auction.ID, PlaceBidRequest{Amount: 100})
+	require.NoError(t, err)
+	_, err = service.PlaceBid(bob.ID, auction.ID, PlaceBidRequest{Amount: 120})
+	require.NoError(t, err)
+
+	// Public views and feed events carry no user IDs
+	public, err := service.GetAuction(0, auction.ID)
+	require.NoError(t, err)
+	for _, payload := range []interface{}{public, <-events} {
+		encoded, err := json.Marshal(payload)
+		require.NoError(t, err)
+		assert.NotContains(t, string(encoded), "user_id")
+		assert.NotContains(t, string(encoded), "bidder_id")
+	}
+	assert.False(t, public.Leading)
+
+	// Signed-in viewers only learn about their own bids
+	view, err := service.GetAuction(alice.ID, auction.ID)
+	require.NoError(t, err)
+	assert.False(t, view.Leading)
+	require.Len(t, view.Bids, 2)
+	assert.False(t, view.Bids[0].Mine)
+	assert.True(t, view.Bids[1].Mine)
+
+	views, _, err := service.GetAuctions(bob.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.True(t, views[0].Leading)
+}
+
+func TestAuctionService_AntiSniping(t *testing.T) {
+	db := setupTestDB(t)
+	feed := NewAuctionFeed()
+	service := &AuctionService{db: db, feed: feed}
+	user := createTestUser(t, db, 500)
+	auction := createTestAuction(t, db, 100, 100, 30*time.Second)
+
+	events, unsubscribe := feed.Subscribe(auction.ID)
+	defer unsubscribe()
+
+	result, err := service.PlaceBid(user.ID, auction.ID, PlaceBidRequest{Amount: 100})
+	require.NoError(t, err)
+	assert.True(t, result.Extended)
+	assert.Equal(t, 1, result.Auction.Extensions)
+	assert.WithinDuration(t, time.Now().Add(AuctionSnipeExtension), result.Auction.EndsAt, 5*time.Second)
+
+	bid := <-events
+	assert.Equal(t, AuctionEventBid, bid.Type)
+	assert.Equal(t, 100.0, bid.Amount)
+	extended := <-events
+	assert.Equal(t, AuctionEventExtended, extended.Type)
+}
+
+func TestAuctionService_SettleDueAuctions(t *testing.T) {
+	db := setupTestDB(t)
+	service := &AuctionService{db: db}
+	winner := createTestUser(t, db, 500)
+	loser := createTestUser(t, db, 500)
+
+	sold := createTestAuction(t, db, 100, 150, time.Hour)
+	unsold := createTestAuction(t, db, 100, 300, time.Hour)
+	open := createTestAuction(t, db, 100, 100, time.Hour)
+
+	_, err := service.PlaceBid(winner.ID, sold.ID, PlaceBidRequest{Amount: 200})
+	require.NoError(t, err)
+	_, err = service.PlaceBid(loser.ID, unsold.ID, PlaceBidRequest{Amount: 120})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Model(&model.Auction{}).Where("id IN ?", []uint{sold.ID, unsold.ID}).
+		Update("ends_at", time.Now().Add(-time.Second)).Error)
+
+	result, err := service.SettleDueAuctions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Sold)
+	assert.Equal(t, 1, result.Unsold)
+
+	// Winner paid with the hold and received the item
+	assert.Equal(t, 300.0, userBalance(t, db, winner.ID))
+	var items []model.UserItem
+	require.NoError(t, db.Where("user_id = ?", winner.ID).Find(&items).Error)
+	require.Len(t, items, 1)
+	assert.Equal(t, sold.ItemID, items[0].ItemID)
+
+	var captured model.AuctionBid
+	require.NoError(t, db.Where("auction_id = ?", sold.ID).First(&captured).Error)
+	assert.Equal(t, model.BidHoldCaptured, captured.HoldStatus)
+
+	// Reserve not met: the bid is refunded
+	assert.Equal(t, 500.0, userBalance(t, db, loser.ID))
+
+	statuses := map[uint]model.AuctionStatus{}
+	var auctions []model.Auction
+	require.NoError(t, db.Find(&auctions).Error)
+	for _, a := range auctions {
+		statuses[a.ID] = a.Status
+	}
+	assert.Equal(t, model.AuctionStatusSold, statuses[sold.ID])
+	assert.Equal(t, model.AuctionStatusUnsold, statuses[unsold.ID])
+	assert.Equal(t, model.AuctionStatusActive, statuses[open.ID])
+
+	// Settling again is a no-op
+	result, err = service.SettleDueAuctions()
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Sold+result.Unsold)
+}
+
+func TestAuctionService_OpenHouseAuctions(t *testing.T) {
+	db := setupTestDB(t)
+	service := &AuctionService{db: db}
+
+	createTestItem(t, db, "Plain Shirt", model.ItemTypeClothing, 20)
+	crown := createTestItem(t, db, "Crown", model.ItemTypeAccessories, 10000)
+	require.NoError(t, db.Model(crown).Update("rarity", model.ItemRarityLegendary).Error)
+
+	count, err := service.OpenHouseAuctions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var auction model.Auction
+	require.NoError(t, db.Where("item_id = ?", crown.ID).First(&auction).Error)
+	assert.Equal(t, 5000.0, auction.StartingPrice)
+	assert.Equal(t, 8000.0, auction.ReservePrice)
+
+	count, err = service.OpenHouseAuctions()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "items already on auction are not listed twice")
+}
+
+func TestCollectionsConsignSeizedItems(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	car := createTestItem(t, db, "Sedan", model.ItemTypeCar, 4000)
+	pledged := &model.UserItem{UserID: user.ID, ItemID: car.ID, PurchasedAt: time.Now()}
+	require.NoError(t, db.Create(pledged).Error)
+
+	loans := &LoanService{db: db}
+	result, err := loans.TakeLoan(TakeLoanRequest{
+		UserID:           user.ID,
+		Amount:           1000,
+		Type:             model.LoanTypeBank,
+		CollateralItemID: &pledged.ID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", user.ID).Update("balance", 0).Error)
+
+	sendToCollections(t, db, loans, result.Loan.ID)
+	_, _, err = loans.ProcessCollections()
+	require.NoError(t, err)
+
+	var auction model.Auction
+	require.NoError(t, db.Where("item_id = ?", car.ID).First(&auction).Error)
+	assert.Equal(t, model.AuctionSourceSeizure, auction.Source)
+	assert.Equal(t, 1960.0, auction.StartingPrice)
+	require.NotNil(t, auction.LoanID)
+	assert.Equal(t, result.Loan.ID, *auction.LoanID)
+
+	// The debtor cannot buy back their own item
+	auctions := &AuctionService{db: db}
+	_, err = auctions.PlaceBid(user.ID, auction.ID, PlaceBidRequest{Amount: 1960})
+	assert.EqualError(t, err, "cannot_bid_on_own_item")
+}
+
+func TestAuctionService_SeizureProceedsPayTheLoan(t *testing.T) {
+	db := setupTestDB(t)
+	service := &AuctionService{db: db}
+	debtor := createTestUser(t, db, 0)
+	winner := createTestUser(t, db, 500)
+
+	loans := &LoanService{db: db}
+	result, err := loans.TakeLoan(TakeLoanRequest{UserID: debtor.ID, Amount: 100, Type: model.LoanTypeMicrocredit})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", debtor.ID).Update("balance", 0).Error)
+	var loan model.Loan
+	require.NoError(t, db.First(&loan, result.Loan.ID).Error)
+
+	auction := createTestAuction(t, db, 50, 50, time.Hour)
+	require.NoError(t, db.Model(auction).Updates(map[string]interface{}{
+		"source":          model.AuctionSourceSeizure,
+		"former_owner_id": debtor.ID,
+		"loan_id":         loan.ID,
+	}).Error)
+
+	_, err = service.PlaceBid(winner.ID, auction.ID, PlaceBidRequest{Amount: 300})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(auction).Update("ends_at", time.Now().Add(-time.Minute)).Error)
+	settled, err := service.SettleDueAuctions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, settled.Sold)
+
+	// The 250 bid above the credited 50 repays the loan, and the rest goes to the debtor
+	var open int64
+	require.NoError(t, db.Model(&model.Loan{}).Where("id = ?", loan.ID).Count(&open).Error)
+	assert.Zero(t, open)
+	assert.InDelta(t, 250-loan.RemainingAmount, userBalance(t, db, debtor.ID), 0.01)
+	assert.Equal(t, 200.0, userBalance(t, db, winner.ID))
+}
+
+func TestAuctionService_HouseAuctionsRespectStock(t *testing.T) {
+	db := setupTestDB(t)
+	service := &AuctionService{db: db}
+
+	soldOut := createTestItem(t, db, "Crown", model.ItemTypeAccessories, 10000)
+	require.NoError(t, db.Model(soldOut).Updates(map[string]interface{}{
+		"rarity": model.ItemRarityLegendary, "edition_size": 1, "editions_sold": 1,
+	}).Error)
+	noStock := createTestItem(t, db, "Sceptre", model.ItemTypeAccessories, 8000)
+	require.NoError(t, db.Model(noStock).Updates(map[string]interface{}{"rarity": model.ItemRarityEpic, "stock": 0}).Error)
+	limited := createTestItem(t, db, "Tiara", model.ItemTypeAccessories, 6000)
+	require.NoError(t, db.Model(limited).Updates(map[string]interface{}{
+		"rarity": model.ItemRarityLegendary, "edition_size": 3, "stock": 2,
+	}).Error)
+
+	count, err := service.OpenHouseAuctions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "sold out items are not auctioned")
+
+	var auction model.Auction
+	require.NoError(t, db.Where("item_id = ?", limited.ID).First(&auction).Error)
+	require.NotNil(t, auction.SerialNumber)
+	assert.Equal(t, 1, *auction.SerialNumber)
+	var item model.Item
+	require.NoError(t, db.First(&item, limited.ID).Error)
+	assert.Equal(t, 1, item.EditionsSold)
+	assert.Equal(t, 1, *item.Stock)
+
+	// The winner gets that copy
+	winner := createTestUser(t, db, 10000)
+	_, err = service.PlaceBid(winner.ID, auction.ID, PlaceBidRequest{Amount: 5000})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&auction).Update("ends_at", time.Now().Add(-time.Minute)).Error)
+	_, err = service.SettleDueAuctions()
+	require.NoError(t, err)
+	var won model.UserItem
+	require.NoError(t, db.Where("user_id = ? AND item_id = ?", winner.ID, limited.ID).First(&won).Error)
+	require.NotNil(t, won.SerialNumber)
+	assert.Equal(t, 1, *won.SerialNumber)
+
+	// An unsold copy goes back to the shop
+	count, err = service.OpenHouseAuctions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	require.NoError(t, db.Model(&model.Auction{}).Where("item_id = ? AND status = ?", limited.ID, model.AuctionStatusActive).
+		Update("ends_at", time.Now().Add(-time.Minute)).Error)
+	result, err := service.SettleDueAuctions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Unsold)
+	require.NoError(t, db.First(&item, limited.ID).Error)
+	assert.Equal(t, 1, item.EditionsSold)
+	assert.Equal(t, 1, *item.Stock)
+}
@@ -113,27 +113,43 @@ func (s *AuthService) Register(req RegisterRequest) (*model.User, error) {
 	return user, nil
 }
 
-// Login authenticates a user with username/password
-func (s *AuthService) Login(req LoginRequest) (*model.User, error) {
+// Login authenticates a user with username/password. Accounts with two-factor
+// authentication get a challenge instead, to be completed with a code
+// (TwoFactorService.CompleteChallenge) before they are signed in.
+func (s *AuthService) Login(req LoginRequest) (*model.User, *LoginChallenge, error) {
 	var user model.User
 
 	// Find user by username
 	if err := s.db.Where("username = ?", req.Username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid username or password")
+			return nil, nil, errors.New("invalid username or password")
 		}
-		return nil, fmt.Errorf("database error: %w", err)
+		return nil, nil, fmt.Errorf("database error: %w", err)
 	}
 
 	// Check if user has password (might be Google OAuth user)
 	if user.PasswordHash == "" {
-		return nil, errors.New("this account uses Google login")
+		return nil, nil, errors.New("this account uses Google login")
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid username or password")
+		return nil, nil, errors.New("invalid username or password")
 	}
 
-	return &user, nil
+	// Ask for the second factor
+	twoFactor := &TwoFactorService{db: s.db}
+	enabled, err := twoFactor.enabled(s.db, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if enabled {
+		challenge, err := twoFactor.startChallenge(user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &user, challenge, nil
+	}
+
+	return &user, nil, nil
 }
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reasons a session was revoked
+const (
+	SessionRevokedLogout        = "logout"
+	SessionRevokedLogoutAll     = "logout_all"
+	SessionRevokedByUser        = "revoked"
+	SessionRevokedTokenReused   = "token_reused"
+	SessionRevokedPasswordReset = "password_reset"
+	SessionRevokedTwoFactor     = "two_factor_enabled"
+)
+
+// Session parameters
+const (
+	refreshTokenBytes      = 32
+	sessionUserAgentLength = 255
+)
+
+// AuthSessionService keeps the server-side sessions behind refresh tokens.
+// Refresh tokens are random, stored hashed and rotated on every use; a
+// rotated token presented again revokes its whole session.
+type AuthSessionService struct {
+	db  *gorm.DB
+	ttl time.Duration // Sessions expire this long after they were last refreshed
+	now func() time.Time
+}
+
+// NewAuthSessionService creates a new session service whose sessions last ttl
+// past their last refresh
+func NewAuthSessionService(ttl time.Duration) *AuthSessionService {
+	return &AuthSessionService{
+		db:  database.GetDB(),
+		ttl: ttl,
+	}
+}
+
+// clock returns the current time
+func (s *AuthSessionService) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// hashToken returns the stored form of a refresh or email token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken stores a new refresh token for a session and returns it
+func (s *AuthSessionService) issueRefreshToken(tx *gorm.DB, sessionID uint) (string, error) {
+	b := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := tx.Create(&model.RefreshToken{FamilyID: sessionID, TokenHash: hashToken(token)}).Error; err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// StartSession signs a user in on a device, returning the session and its first refresh token
+func (s *AuthSessionService) StartSession(userID uint, userAgent, ip string) (*model.AuthSession, string, error) {
+	now := s.clock()
+	if len(userAgent) > sessionUserAgentLength {
+		userAgent = userAgent[:sessionUserAgentLength]
+	}
+	session := &model.AuthSession{
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.ttl),
+	}
+
+	var token string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		var err error
+		token, err = s.issueRefreshToken(tx, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// Refresh exchanges a refresh token for a new one in the same session, which
+// is returned with its user loaded. A
+// token that has already been exchanged revokes the session, since either the
+// user or whoever stole the token is now holding a dead one.
+func (s *AuthSessionService) Refresh(token, ip string) (*model.AuthSession, string, error) {
+	now := s.clock()
+	var session model.AuthSession
+	var next string
+	reused := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var stored model.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(token)).First(&stored).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invalid_refresh_token")
+			}
+			return fmt.Errorf("failed to get refresh token: %w", err)
+		}
+		if err := tx.Preload("User").First(&session, stored.FamilyID).Error; err != nil {
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+		if session.RevokedAt != nil || session.User.ID == 0 { // Closed accounts cannot sign in
+			return errors.New("session_revoked")
+		}
+		if !now.Before(session.ExpiresAt) {
+			return errors.New("session_expired")
+		}
+
+		// Claim the token; if another refresh got there first it has been used
+		result := tx.Model(&stored).Where("used_at IS NULL").Update("used_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("failed to rotate refresh token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			if err := tx.Model(&session).Updates(map[string]interface{}{
+				"revoked_at":     now,
+				"revoked_reason": SessionRevokedTokenReused,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to revoke session: %w", err)
+			}
+			return nil
+		}
+
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(s.ttl)
+		if ip != "" {
+			session.IP = ip
+		}
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"ip":           session.IP,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+
+		var err error
+		next, err = s.issueRefreshToken(tx, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if reused {
+		return nil, "", errors.New("refresh_token_reused")
+	}
+	return &session, next, nil
+}
+
+// SessionActive reports whether a session can still be used
+func (s *AuthSessionService) SessionActive(sessionID uint) (bool, error) {
+	var count int64
+	if err := s.db.Model(&model.AuthSession{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, s.clock().Local()).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to get session: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ListSessions returns the user's active sessions, most recently used first,
+// marking the current one
+func (s *AuthSessionService) ListSessions(userID, currentID uint) ([]model.AuthSession, error) {
+	var sessions []model.AuthSession
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, s.clock().Local()).
+		Order("last_used_at DESC").Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions
+func (s *AuthSessionService) RevokeSession(userID, sessionID uint, reason string) error {
+	result := s.db.Model(&model.AuthSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Updates(map[string]interface{}{"revoked_at": s.clock(), "revoked_reason": reason})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session_not_found")
+	}
+	return nil
+}
+
+// RevokeAllSessions logs the user out everywhere except the session keepID
+// (0 to keep none), returning how many sessions were ended
+func (s *AuthSessionService) RevokeAllSessions(userID, keepID uint) (int64, error) {
+	result := s.db.Model(&model.AuthSession{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Updates(map[string]interface{}{"revoked_at": s.clock(), "revoked_reason": SessionRevokedLogoutAll})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// PruneSessions deletes sessions that ended more than AuthSessionRetention
+// ago along with their refresh tokens, returning how many
+func (s *AuthSessionService) PruneSessions() (int64, error) {
+	cutoff := s.clock().Add(-AuthSessionRetention).Local()
+	ended := s.db.Model(&model.AuthSession{}).Select("id").
+		Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff)
+
+	var count int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("family_id IN (?)", ended).Delete(&model.RefreshToken{}).Error; err != nil {
+			return fmt.Errorf("failed to prune refresh tokens: %w", err)
+		}
+		result := tx.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&model.AuthSession{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to prune sessions: %w", result.Error)
+		}
+		count = result.RowsAffected
+		return nil
+	})
+	return count, err
+}
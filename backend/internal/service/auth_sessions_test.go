@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthSessions(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+
+	now := time.Now()
+	service := &AuthSessionService{db: db, ttl: 7 * 24 * time.Hour, now: func() time.Time { return now }}
+
+	session, token, err := service.StartSession(user.ID, "Firefox", "10.0.0.1")
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	active, err := service.SessionActive(session.ID)
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	// Every refresh rotates the token and extends the session
+	now = now.Add(time.Hour)
+	refreshed, next, err := service.Refresh(token, "10.0.0.2")
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, refreshed.ID)
+	assert.Equal(t, user.Email, refreshed.User.Email)
+	assert.Equal(t, "10.0.0.2", refreshed.IP)
+	assert.WithinDuration(t, now.Add(service.ttl), refreshed.ExpiresAt, time.Second)
+	assert.NotEqual(t, token, next)
+
+	_, _, err = service.Refresh("not-a-token", "")
+	assert.EqualError(t, err, "invalid_refresh_token")
+
+	// Presenting a rotated token again ends the session for everyone holding it
+	_, _, err = service.Refresh(token, "")
+	assert.EqualError(t, err, "refresh_token_reused")
+	_, _, err = service.Refresh(next, "")
+	assert.EqualError(t, err, "session_revoked")
+	active, err = service.SessionActive(session.ID)
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	var revoked model.AuthSession
+	require.NoError(t, db.First(&revoked, session.ID).Error)
+	assert.Equal(t, SessionRevokedTokenReused, revoked.RevokedReason)
+
+	// Devices are listed and signed out one by one or all at once
+	phone, _, err := service.StartSession(user.ID, "Phone", "10.0.0.3")
+	require.NoError(t, err)
+	laptop, laptopToken, err := service.StartSession(user.ID, "Laptop", "10.0.0.4")
+	require.NoError(t, err)
+	tablet, _, err := service.StartSession(user.ID, "Tablet", "10.0.0.5")
+	require.NoError(t, err)
+
+	sessions, err := service.ListSessions(user.ID, laptop.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	for _, s := range sessions {
+		assert.Equal(t, s.ID == laptop.ID, s.Current)
+	}
+
+	require.NoError(t, service.RevokeSession(user.ID, phone.ID, SessionRevokedByUser))
+	assert.EqualError(t, service.RevokeSession(user.ID, phone.ID, SessionRevokedByUser), "session_not_found")
+	other := createTestUser(t, db, 1000)
+	assert.EqualError(t, service.RevokeSession(other.ID, tablet.ID, SessionRevokedByUser), "session_not_found")
+
+	count, err := service.RevokeAllSessions(user.ID, laptop.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	sessions, err = service.ListSessions(user.ID, laptop.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, laptop.ID, sessions[0].ID)
+
+	// Sessions lapse when not refreshed in time, and are pruned later
+	now = now.Add(service.ttl + time.Minute)
+	_, _, err = service.Refresh(laptopToken, "")
+	assert.EqualError(t, err, "session_expired")
+
+	count, err = service.PruneSessions()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	now = now.Add(AuthSessionRetention)
+	count, err = service.PruneSessions()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	var tokens int64
+	db.Model(&model.RefreshToken{}).Count(&tokens)
+	assert.Zero(t, tokens)
+}
@@ -0,0 +1,396 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/data"
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// DefaultExperiencePerShift is awarded for a completed shift when the job doesn't set it
+const DefaultExperiencePerShift = 10
+
+// CareerLevel is a rung on the career ladder
+type CareerLevel struct {
+	Level         int     `json:"level"`
+	Title         string  `json:"title"`
+	MinExperience int     `json:"min_experience"`
+	PayMultiplier float64 `json:"pay_multiplier"`
+}
+
+// CareerPenalty scales experience gained while the user has a status
+type CareerPenalty struct {
+	Status     string  `json:"status"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// CareerLadder defines levels and how statuses affect career growth
+type CareerLadder struct {
+	Levels                    []CareerLevel   `json:"levels"`
+	PromotionBlockingStatuses []string        `json:"promotion_blocking_statuses"`
+	ExperiencePenalties       []CareerPenalty `json:"experience_penalties"`
+}
+
+// CareerProgress describes a career after a completed shift
+type CareerProgress struct {
+	JobType          model.JobType `json:"job_type"`
+	Level            int           `json:"level"`
+	Title            string        `json:"title"`
+	Experience       int           `json:"experience"`
+	ExperienceGained int           `json:"experience_gained"`
+	NextLevelAt      *int          `json:"next_level_at,omitempty"`
+	PayMultiplier    float64       `json:"pay_multiplier"`
+	Promoted         bool          `json:"promoted"`
+	PromotionBlocked bool          `json:"promotion_blocked"`
+}
+
+// CareerSummary represents a career in GET /api/work/career
+type CareerSummary struct {
+	JobType          model.JobType `json:"job_type"`
+	JobName          string        `json:"job_name"`
+	Level            int           `json:"level"`
+	Title            string        `json:"title"`
+	Experience       int           `json:"experience"`
+	NextLevelAt      *int          `json:"next_level_at,omitempty"`
+	PayMultiplier    float64       `json:"pay_multiplier"`
+	ShiftsCompleted  int           `json:"shifts_completed"`
+	TotalEarned      float64       `json:"total_earned"`
+	PromotionBlocked bool          `json:"promotion_blocked"`
+	PromotedAt       *time.Time    `json:"promoted_at,omitempty"`
+}
+
+// CareerOverviewResponse lists a user's careers and which jobs they have unlocked
+type CareerOverviewResponse struct {
+	Careers    []CareerSummary `json:"careers"`
+	LockedJobs []LockedJob     `json:"locked_jobs"`
+	Levels     []CareerLevel   `json:"levels"`
+}
+
+// LockedJob is a catalogue job the user hasn't unlocked yet
+type LockedJob struct {
+	Type         model.JobType `json:"type"`
+	Name         string        `json:"name"`
+	RequiresJob  model.JobType `json:"requires_job"`
+	RequiresLvl  int           `json:"requires_level"`
+	CurrentLevel int           `json:"current_level"`
+}
+
+var (
+	defaultLadder     *CareerLadder
+	defaultLadderOnce sync.Once
+)
+
+// ParseCareerLadder parses and validates a JSON career ladder
+func ParseCareerLadder(raw []byte) (*CareerLadder, error) {
+	var ladder CareerLadder
+	if err := json.Unmarshal(raw, &ladder); err != nil {
+		return nil, fmt.Errorf("failed to parse career ladder: %w", err)
+	}
+
+	if len(ladder.Levels) == 0 {
+		return nil, errors.New("career ladder has no levels")
+	}
+
+	sort.Slice(ladder.Levels, func(i, j int) bool {
+		return ladder.Levels[i].MinExperience < ladder.Levels[j].MinExperience
+	})
+	if ladder.Levels[0].MinExperience != 0 {
+		return nil, errors.New("first career level must start at 0 experience")
+	}
+	for i, level := range ladder.Levels {
+		if level.PayMultiplier <= 0 {
+			return nil, fmt.Errorf("career level %d has non-positive pay multiplier", level.Level)
+		}
+		if level.Level != i+1 {
+			return nil, fmt.Errorf("career levels must be numbered 1..%d in experience order", len(ladder.Levels))
+		}
+	}
+
+	return &ladder, nil
+}
+
+// LoadCareerLadder loads careers.json from disk, falling back to the built-in copy
+func LoadCareerLadder() (*CareerLadder, error) {
+	dataPath := filepath.Join("backend", "internal", "data", "careers.json")
+	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
+		dataPath = filepath.Join("internal", "data", "careers.json")
+	}
+
+	raw, err := os.ReadFile(dataPath)
+	if err != nil {
+		return DefaultCareerLadder(), nil
+	}
+
+	return ParseCareerLadder(raw)
+}
+
+// DefaultCareerLadder returns the career ladder embedded in the binary
+func DefaultCareerLadder() *CareerLadder {
+	defaultLadderOnce.Do(func() {
+		ladder, err := ParseCareerLadder(data.CareersJSON)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in career ladder: %v", err))
+		}
+		defaultLadder = ladder
+	})
+	return defaultLadder
+}
+
+// Level returns the ladder entry for a level number, clamped to the ladder
+func (l *CareerLadder) Level(level int) CareerLevel {
+	if level < 1 {
+		level = 1
+	}
+	if level > len(l.Levels) {
+		level = len(l.Levels)
+	}
+	return l.Levels[level-1]
+}
+
+// LevelFor returns the highest level reachable with the given experience
+func (l *CareerLadder) LevelFor(experience int) CareerLevel {
+	current := l.Levels[0]
+	for _, level := range l.Levels {
+		if experience >= level.MinExperience {
+			current = level
+		}
+	}
+	return current
+}
+
+// nextLevelAt returns the experience needed for the next level, or nil at the top
+func (l *CareerLadder) nextLevelAt(level int) *int {
+	if level >= len(l.Levels) {
+		return nil
+	}
+	next := l.Levels[level].MinExperience
+	return &next
+}
+
+// experienceMultiplier returns the combined experience penalty for the user's statuses
+func (l *CareerLadder) experienceMultiplier(statuses map[string]bool) float64 {
+	multiplier := 1.0
+	for _, penalty := range l.ExperiencePenalties {
+		if statuses[penalty.Status] {
+			multiplier *= penalty.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// promotionBlocked reports whether any of the user's statuses blocks promotions
+func (l *CareerLadder) promotionBlocked(statuses map[string]bool) bool {
+	for _, status := range l.PromotionBlockingStatuses {
+		if statuses[status] {
+			return true
+		}
+	}
+	return false
+}
+
+// ExperiencePerShift returns the experience awarded for one completed shift
+func (job *JobDefinition) ExperiencePerShift() int {
+	if job.Experience > 0 {
+		return job.Experience
+	}
+	return DefaultExperiencePerShift
+}
+
+// loadCareer returns the user's career for a job, creating it from work history if needed
+func loadCareer(tx *gorm.DB, ladder *CareerLadder, userID uint, job *JobDefinition) (*model.Career, error) {
+	var career model.Career
+	err := tx.Where("user_id = ? AND job_type = ?", userID, job.Type).First(&career).Error
+	if err == nil {
+		return &career, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get career: %w", err)
+	}
+
+	// Backfill from shifts recorded before careers existed
+	var history struct {
+		Shifts int
+		Earned float64
+	}
+	if err := tx.Model(&model.WorkSession{}).
+		Where("user_id = ? AND job_type = ?", userID, job.Type).
+		Select("COUNT(*) as shifts, COALESCE(SUM(earned), 0) as earned").
+		Scan(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to get work history: %w", err)
+	}
+
+	experience := history.Shifts * job.ExperiencePerShift()
+	career = model.Career{
+		UserID:          userID,
+		JobType:         job.Type,
+		Experience:      experience,
+		Level:           ladder.LevelFor(experience).Level,
+		ShiftsCompleted: history.Shifts,
+		TotalEarned:     history.Earned,
+	}
+	if err := tx.Create(&career).Error; err != nil {
+		return nil, fmt.Errorf("failed to create career: %w", err)
+	}
+
+	return &career, nil
+}
+
+// advanceCareer records a completed shift and promotes the user when allowed
+func advanceCareer(tx *gorm.DB, ladder *CareerLadder, career *model.Career, job *JobDefinition, earned float64, statuses map[string]bool) (*CareerProgress, error) {
+	gained := int(float64(job.ExperiencePerShift()) * ladder.experienceMultiplier(statuses))
+
+	career.Experience += gained
+	career.ShiftsCompleted++
+	career.TotalEarned += earned
+
+	promoted := false
+	target := ladder.LevelFor(career.Experience)
+	career.PromotionBlocked = false
+	if target.Level > career.Level {
+		if ladder.promotionBlocked(statuses) {
+			career.PromotionBlocked = true
+		} else {
+			now := time.Now()
+			career.Level = target.Level
+			career.PromotedAt = &now
+			promoted = true
+		}
+	}
+
+	if err := tx.Save(career).Error; err != nil {
+		return nil, fmt.Errorf("failed to update career: %w", err)
+	}
+
+	level := ladder.Level(career.Level)
+	return &CareerProgress{
+		JobType:          career.JobType,
+		Level:            level.Level,
+		Title:            level.Title,
+		Experience:       career.Experience,
+		ExperienceGained: gained,
+		NextLevelAt:      ladder.nextLevelAt(level.Level),
+		PayMultiplier:    level.PayMultiplier,
+		Promoted:         promoted,
+		PromotionBlocked: career.PromotionBlocked,
+	}, nil
+}
+
+// careerLevel returns the user's current level in a job without creating a career
+func careerLevel(db *gorm.DB, userID uint, jobType model.JobType) (int, error) {
+	var career model.Career
+	err := db.Where("user_id = ? AND job_type = ?", userID, jobType).First(&career).Error
+	if err == nil {
+		return career.Level, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 1, nil
+	}
+	return 0, fmt.Errorf("failed to get career: %w", err)
+}
+
+// checkUnlock verifies the user has reached the career level a job requires
+func (job *JobDefinition) checkUnlock(db *gorm.DB, userID uint) error {
+	if job.Unlock == nil {
+		return nil
+	}
+
+	level, err := careerLevel(db, userID, job.Unlock.JobType)
+	if err != nil {
+		return err
+	}
+	if level < job.Unlock.MinLevel {
+		return &JobRequirementError{Code: "career_level_too_low"}
+	}
+	return nil
+}
+
+// CareerService provides read access to career progression
+type CareerService struct {
+	db      *gorm.DB
+	ladder  *CareerLadder
+	catalog *JobCatalog
+}
+
+// NewCareerService creates a new career service instance
+func NewCareerService(catalog *JobCatalog) *CareerService {
+	ladder, err := LoadCareerLadder()
+	if err != nil {
+		log.Printf("Warning: failed to load career ladder, using built-in levels: %v", err)
+		ladder = DefaultCareerLadder()
+	}
+
+	return &CareerService{
+		db:      database.GetDB(),
+		ladder:  ladder,
+		catalog: catalog,
+	}
+}
+
+// GetCareers returns every career the user has started plus locked jobs
+func (s *CareerService) GetCareers(userID uint) (*CareerOverviewResponse, error) {
+	var careers []model.Career
+	if err := s.db.Where("user_id = ?", userID).Order("experience DESC").Find(&careers).Error; err != nil {
+		return nil, fmt.Errorf("failed to get careers: %w", err)
+	}
+
+	levels := make(map[model.JobType]int, len(careers))
+	summaries := make([]CareerSummary, len(careers))
+	for i, career := range careers {
+		level := s.ladder.Level(career.Level)
+		name := string(career.JobType)
+		if job, ok := s.catalog.Get(career.JobType); ok {
+			name = job.Name
+		}
+		levels[career.JobType] = career.Level
+
+		summaries[i] = CareerSummary{
+			JobType:          career.JobType,
+			JobName:          name,
+			Level:            level.Level,
+			Title:            level.Title,
+			Experience:       career.Experience,
+			NextLevelAt:      s.ladder.nextLevelAt(level.Level),
+			PayMultiplier:    level.PayMultiplier,
+			ShiftsCompleted:  career.ShiftsCompleted,
+			TotalEarned:      career.TotalEarned,
+			PromotionBlocked: career.PromotionBlocked,
+			PromotedAt:       career.PromotedAt,
+		}
+	}
+
+	locked := make([]LockedJob, 0)
+	for _, job := range s.catalog.All() {
+		if job.Unlock == nil {
+			continue
+		}
+		current, ok := levels[job.Unlock.JobType]
+		if !ok {
+			current = 1
+		}
+		if current < job.Unlock.MinLevel {
+			locked = append(locked, LockedJob{
+				Type:         job.Type,
+				Name:         job.Name,
+				RequiresJob:  job.Unlock.JobType,
+				RequiresLvl:  job.Unlock.MinLevel,
+				CurrentLevel: current,
+			})
+		}
+	}
+
+	return &CareerOverviewResponse{
+		Careers:    summaries,
+		LockedJobs: locked,
+		Levels:     s.ladder.Levels,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func completeShift(t *testing.T, service *WorkService, userID uint, jobType model.JobType) *CompleteWorkResponse {
+	service.activeSessions[userID] = ActiveWorkSession{
+		UserID:    userID,
+		StartedAt: time.Now().Add(-WORK_DURATION * time.Second),
+		JobType:   jobType,
+	}
+	response, err := service.CompleteWork(userID)
+	require.NoError(t, err)
+	return response
+}
+
+func TestCareerBackfillsFromWorkHistory(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 0.0)
+
+	// 10 shifts recorded before careers existed = 100 XP = Specialist
+	for i := 0; i < 10; i++ {
+		require.NoError(t, db.Create(&model.WorkSession{
+			UserID:          user.ID,
+			JobType:         model.JobTypeBottleCollector,
+			DurationSeconds: WORK_DURATION,
+			Earned:          100,
+			CompletedAt:     time.Now().Add(-time.Duration(i+1) * time.Hour),
+		}).Error)
+	}
+
+	service := &WorkService{db: db, activeSessions: make(map[uint]ActiveWorkSession)}
+	response := completeShift(t, service, user.ID, model.JobTypeBottleCollector)
+
+	require.NotNil(t, response.Career)
+	assert.Equal(t, 3, response.Career.Level)
+	assert.Equal(t, "Specialist", response.Career.Title)
+	assert.Equal(t, 110, response.Career.Experience)
+	assert.Equal(t, 125.0, response.Earned) // 100 * 1.25
+}
+
+func TestCareerPromotionAndJailBlock(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 0.0)
+	service := &WorkService{db: db, activeSessions: make(map[uint]ActiveWorkSession)}
+
+	// Two shifts keep the user an Intern
+	completeShift(t, service, user.ID, model.JobTypeBottleCollector)
+	response := completeShift(t, service, user.ID, model.JobTypeBottleCollector)
+	assert.Equal(t, 1, response.Career.Level)
+	assert.Equal(t, 100.0, response.Earned)
+
+	// A jail record halves experience and withholds the promotion
+	require.NoError(t, db.Create(&model.UserStatus{
+		UserID:    user.ID,
+		Status:    "in_jail",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}).Error)
+	for i := 0; i < 2; i++ {
+		response = completeShift(t, service, user.ID, model.JobTypeBottleCollector)
+	}
+	assert.Equal(t, 30, response.Career.Experience)
+	assert.Equal(t, 1, response.Career.Level)
+	assert.True(t, response.Career.PromotionBlocked)
+
+	// Once released, the next shift promotes
+	require.NoError(t, db.Where("user_id = ?", user.ID).Delete(&model.UserStatus{}).Error)
+	response = completeShift(t, service, user.ID, model.JobTypeBottleCollector)
+	assert.Equal(t, 2, response.Career.Level)
+	assert.True(t, response.Career.Promoted)
+	assert.False(t, response.Career.PromotionBlocked)
+}
+
+func TestLockedJobRequiresCareerLevel(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 0.0)
+	service := &WorkService{db: db, activeSessions: make(map[uint]ActiveWorkSession)}
+
+	_, err := service.StartWork(user.ID, "middle_manager")
+	var reqErr *JobRequirementError
+	require.ErrorAs(t, err, &reqErr)
+	assert.Equal(t, "career_level_too_low", reqErr.Code)
+
+	require.NoError(t, db.Create(&model.Career{
+		UserID:     user.ID,
+		JobType:    model.JobTypeOffice,
+		Experience: 250,
+		Level:      4,
+	}).Error)
+
+	// Unlocked now; clothing is still required
+	_, err = service.StartWork(user.ID, "middle_manager")
+	require.ErrorAs(t, err, &reqErr)
+	assert.Equal(t, "office_no_clothes", reqErr.Code)
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/smoreg/freezino/backend/internal/database"
 	"github.com/smoreg/freezino/backend/internal/model"
 	"gorm.io/gorm"
@@ -152,79 +155,60 @@ func (s *CasinoStatsService) GetCasinoStats() (*CasinoStatsResponse, error) {
 		}
 	}
 
-	// Get top 10 winners (highest net profit)
-	var topWinnersData []struct {
-		UserID      uint
-		Username    string
-		TotalBet    float64
-		TotalWon    float64
-		NetProfit   float64
-		GamesPlayed int
+	// Top players come from the all-time net profit leaderboard, which leaves
+	// out players who opted out and anonymises those who asked to be
+	var err error
+	if stats.TopWinners, err = s.topPlayers(false); err != nil {
+		return nil, err
+	}
+	if stats.TopLosers, err = s.topPlayers(true); err != nil {
+		return nil, err
 	}
 
-	s.db.Model(&model.GameSession{}).
-		Select(`
-			game_sessions.user_id,
-			users.username,
-			COALESCE(SUM(game_sessions.bet), 0) as total_bet,
-			COALESCE(SUM(game_sessions.win), 0) as total_won,
-			COALESCE(SUM(game_sessions.win - game_sessions.bet), 0) as net_profit,
-			COUNT(*) as games_played
-		`).
-		Joins("LEFT JOIN users ON users.id = game_sessions.user_id").
-		Group("game_sessions.user_id, users.username").
-		Order("net_profit DESC").
-		Limit(10).
-		Scan(&topWinnersData)
-
-	stats.TopWinners = make([]PlayerStats, len(topWinnersData))
-	for i, winner := range topWinnersData {
-		stats.TopWinners[i] = PlayerStats{
-			UserID:      winner.UserID,
-			Username:    winner.Username,
-			TotalBet:    winner.TotalBet,
-			TotalWon:    winner.TotalWon,
-			NetProfit:   winner.NetProfit,
-			GamesPlayed: winner.GamesPlayed,
-		}
+	return stats, nil
+}
+
+// topPlayers returns the 10 players with the highest net profit, or the lowest
+// when losers is set, with their game totals
+func (s *CasinoStatsService) topPlayers(losers bool) ([]PlayerStats, error) {
+	period, _ := leaderboardPeriod(LeaderboardWindowAllTime, time.Now())
+	rows, userIDs, err := (&LeaderboardService{db: s.db}).rows(LeaderboardNetProfit, period, losers, 10, 0)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get top 10 losers (lowest net profit)
-	var topLosersData []struct {
+	var totals []struct {
 		UserID      uint
-		Username    string
 		TotalBet    float64
 		TotalWon    float64
-		NetProfit   float64
 		GamesPlayed int
 	}
-
-	s.db.Model(&model.GameSession{}).
-		Select(`
-			game_sessions.user_id,
-			users.username,
-			COALESCE(SUM(game_sessions.bet), 0) as total_bet,
-			COALESCE(SUM(game_sessions.win), 0) as total_won,
-			COALESCE(SUM(game_sessions.win - game_sessions.bet), 0) as net_profit,
-			COUNT(*) as games_played
-		`).
-		Joins("LEFT JOIN users ON users.id = game_sessions.user_id").
-		Group("game_sessions.user_id, users.username").
-		Order("net_profit ASC").
-		Limit(10).
-		Scan(&topLosersData)
-
-	stats.TopLosers = make([]PlayerStats, len(topLosersData))
-	for i, loser := range topLosersData {
-		stats.TopLosers[i] = PlayerStats{
-			UserID:      loser.UserID,
-			Username:    loser.Username,
-			TotalBet:    loser.TotalBet,
-			TotalWon:    loser.TotalWon,
-			NetProfit:   loser.NetProfit,
-			GamesPlayed: loser.GamesPlayed,
+	if len(userIDs) > 0 {
+		if err := s.db.Model(&model.GameSession{}).
+			Select("user_id, COALESCE(SUM(bet), 0) as total_bet, COALESCE(SUM(win), 0) as total_won, COUNT(*) as games_played").
+			Where("user_id IN ?", userIDs).
+			Group("user_id").
+			Scan(&totals).Error; err != nil {
+			return nil, fmt.Errorf("failed to get player totals: %w", err)
 		}
 	}
+	byUser := make(map[uint]int, len(totals))
+	for i, total := range totals {
+		byUser[total.UserID] = i
+	}
 
-	return stats, nil
+	players := make([]PlayerStats, len(rows))
+	for i, row := range rows {
+		players[i] = PlayerStats{
+			UserID:    row.UserID,
+			Username:  row.Name,
+			NetProfit: row.Score,
+		}
+		if j, ok := byUser[userIDs[i]]; ok {
+			players[i].TotalBet = totals[j].TotalBet
+			players[i].TotalWon = totals[j].TotalWon
+			players[i].GamesPlayed = totals[j].GamesPlayed
+		}
+	}
+	return players, nil
 }
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/data"
+	"github.com/smoreg/freezino/backend/internal/model"
+)
+
+// ChallengeTier is one difficulty a challenge template can roll
+type ChallengeTier struct {
+	Target float64 `json:"target"`
+	Reward float64 `json:"reward"`
+}
+
+// ChallengeTemplate declares a challenge that can appear in the rotation
+type ChallengeTemplate struct {
+	Code        string                `json:"code"`
+	Period      model.ChallengePeriod `json:"period"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"` // {target} is replaced by the rolled target
+	Metric      string                `json:"metric"`      // Achievement metric counted within the period
+	Satirical   bool                  `json:"satirical,omitempty"`
+	Tiers       []ChallengeTier       `json:"tiers"`
+}
+
+// ChallengeResetTimes sets when challenges reset. Zero-valued fields in a
+// timezone override fall back to the schedule's defaults.
+type ChallengeResetTimes struct {
+	DailyResetHour *int   `json:"daily_reset_hour,omitempty"` // Local hour daily challenges reset at
+	WeeklyResetDay string `json:"weekly_reset_day,omitempty"` // Local weekday weekly challenges reset on
+}
+
+// ChallengeSchedule controls how many challenges rotate in and when they reset
+type ChallengeSchedule struct {
+	ChallengeResetTimes
+	DailyCount  int                            `json:"daily_count"`
+	WeeklyCount int                            `json:"weekly_count"`
+	Timezones   map[string]ChallengeResetTimes `json:"timezones,omitempty"` // Overrides by IANA timezone
+}
+
+// ChallengeCatalog holds the challenge templates and their rotation schedule
+type ChallengeCatalog struct {
+	Schedule  ChallengeSchedule   `json:"schedule"`
+	Templates []ChallengeTemplate `json:"templates"`
+
+	byCode map[string]*ChallengeTemplate
+}
+
+// ChallengeWindow is one period of a challenge rotation in a user's timezone
+type ChallengeWindow struct {
+	Period model.ChallengePeriod
+	Start  time.Time
+	End    time.Time
+}
+
+var (
+	defaultChallengeCatalog     *ChallengeCatalog
+	defaultChallengeCatalogOnce sync.Once
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// ParseChallengeCatalog parses and validates a JSON challenge catalogue
+func ParseChallengeCatalog(raw []byte) (*ChallengeCatalog, error) {
+	var catalog ChallengeCatalog
+	if err := json.Unmarshal(raw, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse challenge catalogue: %w", err)
+	}
+
+	if err := catalog.Schedule.ChallengeResetTimes.validate(); err != nil {
+		return nil, err
+	}
+	if catalog.Schedule.DailyResetHour == nil || catalog.Schedule.WeeklyResetDay == "" {
+		return nil, fmt.Errorf("challenge schedule needs a daily reset hour and a weekly reset day")
+	}
+	for name, times := range catalog.Schedule.Timezones {
+		if _, err := time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("unknown challenge timezone %q", name)
+		}
+		if err := times.validate(); err != nil {
+			return nil, fmt.Errorf("timezone %q: %w", name, err)
+		}
+	}
+
+	counts := map[model.ChallengePeriod]int{}
+	catalog.byCode = make(map[string]*ChallengeTemplate, len(catalog.Templates))
+	for i := range catalog.Templates {
+		template := &catalog.Templates[i]
+		if template.Code == "" {
+			return nil, fmt.Errorf("challenge #%d has no code", i)
+		}
+		if _, exists := catalog.byCode[template.Code]; exists {
+			return nil, fmt.Errorf("duplicate challenge %q", template.Code)
+		}
+		if template.Period != model.ChallengePeriodDaily && template.Period != model.ChallengePeriodWeekly {
+			return nil, fmt.Errorf("challenge %q has unknown period %q", template.Code, template.Period)
+		}
+		if !windowedMetric(template.Metric) {
+			return nil, fmt.Errorf("challenge %q uses metric %q, which can't be counted per period", template.Code, template.Metric)
+		}
+		if len(template.Tiers) == 0 {
+			return nil, fmt.Errorf("challenge %q has no tiers", template.Code)
+		}
+		for _, tier := range template.Tiers {
+			if tier.Target <= 0 || tier.Reward < 0 {
+				return nil, fmt.Errorf("challenge %q has a tier with a non-positive target or negative reward", template.Code)
+			}
+		}
+		catalog.byCode[template.Code] = template
+		counts[template.Period]++
+	}
+
+	if counts[model.ChallengePeriodDaily] < catalog.Schedule.DailyCount {
+		return nil, fmt.Errorf("challenge schedule wants %d daily challenges but only %d exist",
+			catalog.Schedule.DailyCount, counts[model.ChallengePeriodDaily])
+	}
+	if counts[model.ChallengePeriodWeekly] < catalog.Schedule.WeeklyCount {
+		return nil, fmt.Errorf("challenge schedule wants %d weekly challenges but only %d exist",
+			catalog.Schedule.WeeklyCount, counts[model.ChallengePeriodWeekly])
+	}
+
+	return &catalog, nil
+}
+
+// validate checks the reset times that are set
+func (t ChallengeResetTimes) validate() error {
+	if t.DailyResetHour != nil && (*t.DailyResetHour < 0 || *t.DailyResetHour > 23) {
+		return fmt.Errorf("daily reset hour must be between 0 and 23")
+	}
+	if _, ok := weekdays[t.WeeklyResetDay]; t.WeeklyResetDay != "" && !ok {
+		return fmt.Errorf("unknown weekly reset day %q", t.WeeklyResetDay)
+	}
+	return nil
+}
+
+// LoadChallengeCatalog loads challenges.json from disk, falling back to the built-in copy
+func LoadChallengeCatalog() (*ChallengeCatalog, error) {
+	dataPath := filepath.Join("backend", "internal", "data", "challenges.json")
+	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
+		dataPath = filepath.Join("internal", "data", "challenges.json")
+	}
+
+	raw, err := os.ReadFile(dataPath)
+	if err != nil {
+		return DefaultChallengeCatalog(), nil
+	}
+
+	return ParseChallengeCatalog(raw)
+}
+
+// DefaultChallengeCatalog returns the catalogue embedded in the binary
+func DefaultChallengeCatalog() *ChallengeCatalog {
+	defaultChallengeCatalogOnce.Do(func() {
+		catalog, err := ParseChallengeCatalog(data.ChallengesJSON)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in challenge catalogue: %v", err))
+		}
+		defaultChallengeCatalog = catalog
+	})
+	return defaultChallengeCatalog
+}
+
+// Get returns the challenge template with the given code
+func (c *ChallengeCatalog) Get(code string) (*ChallengeTemplate, bool) {
+	template, ok := c.byCode[code]
+	return template, ok
+}
+
+// resetTimes returns the reset hour and weekday that apply in a timezone
+func (c *ChallengeCatalog) resetTimes(loc *time.Location) (int, time.Weekday) {
+	hour, day := *c.Schedule.DailyResetHour, c.Schedule.WeeklyResetDay
+	if override, ok := c.Schedule.Timezones[loc.String()]; ok {
+		if override.DailyResetHour != nil {
+			hour = *override.DailyResetHour
+		}
+		if override.WeeklyResetDay != "" {
+			day = override.WeeklyResetDay
+		}
+	}
+	return hour, weekdays[day]
+}
+
+// Window returns the daily or weekly period containing now in a timezone
+func (c *ChallengeCatalog) Window(period model.ChallengePeriod, now time.Time, loc *time.Location) ChallengeWindow {
+	hour, weekday := c.resetTimes(loc)
+	local := now.In(loc)
+
+	start := time.Date(local.Year(), local.Month(), local.Day(), hour, 0, 0, 0, loc)
+	if local.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	end := start.AddDate(0, 0, 1)
+
+	if period == model.ChallengePeriodWeekly {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) - int(weekday) + 7) % 7))
+		end = start.AddDate(0, 0, 7)
+	}
+
+	return ChallengeWindow{Period: period, Start: start, End: end}
+}
+
+// Rotation returns the templates and tiers active in a window. Everyone whose
+// period starts on the same local date gets the same challenges.
+func (c *ChallengeCatalog) Rotation(window ChallengeWindow) []ChallengeAssignment {
+	count := c.Schedule.DailyCount
+	if window.Period == model.ChallengePeriodWeekly {
+		count = c.Schedule.WeeklyCount
+	}
+
+	var pool []*ChallengeTemplate
+	for i := range c.Templates {
+		if c.Templates[i].Period == window.Period {
+			pool = append(pool, &c.Templates[i])
+		}
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(string(window.Period) + ":" + window.Start.Format("2006-01-02")))
+	rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	assignments := make([]ChallengeAssignment, 0, count)
+	for _, template := range pool[:count] {
+		assignments = append(assignments, ChallengeAssignment{
+			Template: template,
+			Tier:     template.Tiers[rng.Intn(len(template.Tiers))],
+		})
+	}
+	return assignments
+}
+
+// ChallengeAssignment is a template rolled at one tier for a period
+type ChallengeAssignment struct {
+	Template *ChallengeTemplate
+	Tier     ChallengeTier
+}
+
+// describe fills the rolled target into the template's description
+func (t *ChallengeTemplate) describe(target float64) string {
+	return strings.ReplaceAll(t.Description, "{target}", formatChallengeAmount(target))
+}
+
+// formatChallengeAmount prints whole amounts without decimals
+func formatChallengeAmount(amount float64) string {
+	if amount == float64(int64(amount)) {
+		return fmt.Sprintf("%d", int64(amount))
+	}
+	return fmt.Sprintf("%.2f", amount)
+}
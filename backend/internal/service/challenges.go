@@ -0,0 +1,308 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // Users pick any IANA timezone, whether or not the host has zoneinfo
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// userTimezone returns the user's timezone, defaulting to UTC
+func userTimezone(user *model.User) *time.Location {
+	return loadTimezone(user.Timezone)
+}
+
+// loadTimezone returns the named IANA timezone, defaulting to UTC
+func loadTimezone(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ChallengeService rotates daily and weekly challenges and pays their rewards
+type ChallengeService struct {
+	db      *gorm.DB
+	catalog *ChallengeCatalog
+	now     func() time.Time
+}
+
+// NewChallengeService creates a new challenge service instance
+func NewChallengeService() (*ChallengeService, error) {
+	catalog, err := LoadChallengeCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChallengeService{
+		db:      database.GetDB(),
+		catalog: catalog,
+	}, nil
+}
+
+// getCatalog returns the service's catalogue, defaulting to the built-in one
+func (s *ChallengeService) getCatalog() *ChallengeCatalog {
+	if s.catalog == nil {
+		return DefaultChallengeCatalog()
+	}
+	return s.catalog
+}
+
+// clock returns the current time
+func (s *ChallengeService) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// ChallengeView is a challenge as shown to a user
+type ChallengeView struct {
+	Code        string                `json:"code"`
+	Period      model.ChallengePeriod `json:"period"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Satirical   bool                  `json:"satirical,omitempty"`
+	Target      float64               `json:"target"`
+	Progress    float64               `json:"progress"`
+	Reward      float64               `json:"reward"`
+	Completed   bool                  `json:"completed"`
+	CompletedAt *time.Time            `json:"completed_at,omitempty"`
+	ResetsAt    time.Time             `json:"resets_at"`
+}
+
+// ChallengesResponse lists the user's current challenges
+type ChallengesResponse struct {
+	Timezone       string          `json:"timezone"`
+	Daily          []ChallengeView `json:"daily"`
+	Weekly         []ChallengeView `json:"weekly"`
+	DailyResetsAt  time.Time       `json:"daily_resets_at"`
+	WeeklyResetsAt time.Time       `json:"weekly_resets_at"`
+	RewardsEarned  float64         `json:"rewards_earned"` // Paid out by this request
+}
+
+// GetChallenges returns the user's challenges for the current day and week,
+// bringing their progress up to date first
+func (s *ChallengeService) GetChallenges(userID uint) (*ChallengesResponse, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user_not_found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	loc := userTimezone(&user)
+	challenges, windows, err := s.currentChallenges(userID, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ChallengesResponse{
+		Timezone:       loc.String(),
+		Daily:          []ChallengeView{},
+		Weekly:         []ChallengeView{},
+		DailyResetsAt:  windows[model.ChallengePeriodDaily].End,
+		WeeklyResetsAt: windows[model.ChallengePeriodWeekly].End,
+	}
+	catalog := s.getCatalog()
+	for i := range challenges {
+		challenge := &challenges[i]
+		paid, err := s.refresh(challenge)
+		if err != nil {
+			return nil, err
+		}
+		response.RewardsEarned += paid
+
+		template, ok := catalog.Get(challenge.Code)
+		if !ok {
+			continue // Retired from the catalogue mid-period
+		}
+		view := ChallengeView{
+			Code:        challenge.Code,
+			Period:      challenge.Period,
+			Name:        template.Name,
+			Description: template.describe(challenge.Target),
+			Satirical:   template.Satirical,
+			Target:      challenge.Target,
+			Progress:    challenge.Progress,
+			Reward:      challenge.Reward,
+			Completed:   challenge.CompletedAt != nil,
+			CompletedAt: challenge.CompletedAt,
+			ResetsAt:    windows[challenge.Period].End,
+		}
+		if challenge.Period == model.ChallengePeriodDaily {
+			response.Daily = append(response.Daily, view)
+		} else {
+			response.Weekly = append(response.Weekly, view)
+		}
+	}
+
+	return response, nil
+}
+
+// HandleEvent brings the user's current challenges up to date after activity on the bus
+func (s *ChallengeService) HandleEvent(event events.Event) error {
+	var user model.User
+	if err := s.db.First(&user, event.EventUserID()).Error; err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	challenges, _, err := s.currentChallenges(user.ID, userTimezone(&user))
+	if err != nil {
+		return err
+	}
+	for i := range challenges {
+		if _, err := s.refresh(&challenges[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentChallenges returns the user's challenges for the running day and
+// week, creating them from the rotation the first time they are needed.
+// A period keeps the window it was assigned with, so changing timezone only
+// moves the next one, and that never starts before the previous one ended;
+// otherwise each change would open a fresh set of challenges over activity
+// already rewarded.
+func (s *ChallengeService) currentChallenges(userID uint, loc *time.Location) ([]model.UserChallenge, map[model.ChallengePeriod]ChallengeWindow, error) {
+	catalog := s.getCatalog()
+	now := s.clock()
+	windows := map[model.ChallengePeriod]ChallengeWindow{}
+	var starts []time.Time
+
+	for _, period := range []model.ChallengePeriod{model.ChallengePeriodDaily, model.ChallengePeriodWeekly} {
+		var latest model.UserChallenge
+		err := s.db.Where("user_id = ? AND period = ?", userID, period).
+			Order("period_end DESC").First(&latest).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("failed to get challenges: %w", err)
+		}
+		assigned := err == nil
+		if assigned && !now.Before(latest.PeriodStart) && now.Before(latest.PeriodEnd) {
+			windows[period] = ChallengeWindow{Period: period, Start: latest.PeriodStart, End: latest.PeriodEnd}
+			starts = append(starts, latest.PeriodStart.UTC())
+			continue
+		}
+
+		window := catalog.Window(period, now, loc)
+		start := window.Start
+		if assigned && start.Before(latest.PeriodEnd) {
+			start = latest.PeriodEnd
+		}
+		windows[period] = ChallengeWindow{Period: period, Start: start, End: window.End}
+		starts = append(starts, start.UTC())
+
+		for _, assignment := range catalog.Rotation(window) {
+			if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserChallenge{
+				UserID:      userID,
+				Code:        assignment.Template.Code,
+				Period:      period,
+				PeriodStart: start.UTC(),
+				PeriodEnd:   window.End.UTC(),
+				Target:      assignment.Tier.Target,
+				Reward:      assignment.Tier.Reward,
+			}).Error; err != nil {
+				return nil, nil, fmt.Errorf("failed to assign challenge: %w", err)
+			}
+		}
+	}
+
+	var challenges []model.UserChallenge
+	if err := s.db.Where("user_id = ? AND period_start IN ?", userID, starts).
+		Order("period, id").Find(&challenges).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get challenges: %w", err)
+	}
+	return challenges, windows, nil
+}
+
+// refresh recounts a challenge's progress within its period and pays the
+// reward the first time it is reached. Returns the reward paid, if any.
+func (s *ChallengeService) refresh(challenge *model.UserChallenge) (float64, error) {
+	if challenge.CompletedAt != nil {
+		return 0, nil
+	}
+	template, ok := s.getCatalog().Get(challenge.Code)
+	if !ok {
+		return 0, nil
+	}
+
+	value, err := newWindowedMetrics(s.db, challenge.UserID, challenge.PeriodStart).get(template.Metric)
+	if err != nil {
+		return 0, err
+	}
+	progress := roundMoney(min(value, challenge.Target))
+
+	if progress < challenge.Target {
+		if progress != challenge.Progress {
+			if err := s.db.Model(challenge).Update("progress", progress).Error; err != nil {
+				return 0, fmt.Errorf("failed to update challenge progress: %w", err)
+			}
+		}
+		return 0, nil
+	}
+
+	paid := 0.0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// Only the first refresh to see the target reached pays out
+		result := tx.Model(&model.UserChallenge{}).
+			Where("id = ? AND completed_at IS NULL", challenge.ID).
+			Updates(map[string]interface{}{"progress": progress, "completed_at": now})
+		if result.Error != nil {
+			return fmt.Errorf("failed to complete challenge: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		challenge.Progress = progress
+		challenge.CompletedAt = &now
+
+		if challenge.Reward <= 0 {
+			return nil
+		}
+
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, challenge.UserID).Error; err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		newBalance := roundMoney(user.Balance + challenge.Reward)
+		if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
+			return fmt.Errorf("failed to update balance: %w", err)
+		}
+		if err := tx.Create(&model.Transaction{
+			UserID:       challenge.UserID,
+			Type:         model.TransactionTypeChallengeReward,
+			Amount:       challenge.Reward,
+			BalanceAfter: newBalance,
+			Description:  fmt.Sprintf("Challenge completed: %s", template.Name),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		if err := events.Record(tx, events.BalanceChanged{
+			UserID:  challenge.UserID,
+			Balance: newBalance,
+			Change:  challenge.Reward,
+			Reason:  fmt.Sprintf("Challenge completed: %s", template.Name),
+		}); err != nil {
+			return err
+		}
+		paid = challenge.Reward
+		return nil
+	})
+	if paid > 0 {
+		flushEvents(s.db)
+	}
+	return paid, err
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChallengeCatalog = `{
+  "schedule": {
+    "daily_reset_hour": 0,
+    "weekly_reset_day": "monday",
+    "daily_count": 1,
+    "weekly_count": 1,
+    "timezones": { "Asia/Tokyo": { "daily_reset_hour": 5 } }
+  },
+  "templates": [
+    { "code": "play", "period": "daily", "name": "Play", "description": "Play {target} games.",
+      "metric": "games_played", "tiers": [{ "target": 2, "reward": 50 }] },
+    { "code": "lose", "period": "weekly", "name": "Lose", "description": "Lose ${target}.",
+      "metric": "gambling_losses", "tiers": [{ "target": 1000, "reward": 1 }] }
+  ]
+}`
+
+func TestDefaultChallengeCatalog(t *testing.T) {
+	catalog := DefaultChallengeCatalog()
+	window := catalog.Window(model.ChallengePeriodDaily, time.Now(), time.UTC)
+	assert.Len(t, catalog.Rotation(window), catalog.Schedule.DailyCount)
+
+	_, err := ParseChallengeCatalog([]byte(`{"schedule":{"daily_reset_hour":0,"weekly_reset_day":"monday"},
+		"templates":[{"code":"x","period":"daily","metric":"balance","tiers":[{"target":1,"reward":1}]}]}`))
+	assert.EqualError(t, err, `challenge "x" uses metric "balance", which can't be counted per period`)
+}
+
+func TestChallengeWindows(t *testing.T) {
+	catalog, err := ParseChallengeCatalog([]byte(testChallengeCatalog))
+	require.NoError(t, err)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// Sunday 03:00 in Tokyo is still Saturday's challenge day there
+	now := time.Date(2026, 10, 18, 3, 0, 0, 0, tokyo)
+	daily := catalog.Window(model.ChallengePeriodDaily, now, tokyo)
+	assert.Equal(t, time.Date(2026, 10, 17, 5, 0, 0, 0, tokyo), daily.Start)
+	assert.Equal(t, time.Date(2026, 10, 18, 5, 0, 0, 0, tokyo), daily.End)
+
+	weekly := catalog.Window(model.ChallengePeriodWeekly, now, tokyo)
+	assert.Equal(t, time.Date(2026, 10, 12, 5, 0, 0, 0, tokyo), weekly.Start)
+	assert.Equal(t, time.Date(2026, 10, 19, 5, 0, 0, 0, tokyo), weekly.End)
+
+	utc := catalog.Window(model.ChallengePeriodDaily, now, time.UTC)
+	assert.Equal(t, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), utc.Start)
+
+	// The rotation is the same for everyone in the same period
+	assert.Equal(t, catalog.Rotation(daily), catalog.Rotation(daily))
+}
+
+func TestChallengeProgressAndReward(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	catalog, err := ParseChallengeCatalog([]byte(testChallengeCatalog))
+	require.NoError(t, err)
+	service := &ChallengeService{db: db, catalog: catalog}
+
+	challenges, err := service.GetChallenges(user.ID)
+	require.NoError(t, err)
+	require.Len(t, challenges.Daily, 1)
+	require.Len(t, challenges.Weekly, 1)
+	assert.Equal(t, "UTC", challenges.Timezone)
+	assert.Equal(t, "Play 2 games.", challenges.Daily[0].Description)
+	assert.Equal(t, 0.0, challenges.Daily[0].Progress)
+
+	require.NoError(t, db.Create(&model.GameSession{UserID: user.ID, GameType: model.GameTypeSlots, Bet: 10}).Error)
+	require.NoError(t, service.HandleEvent(events.BetSettled{UserID: user.ID, GameType: model.GameTypeSlots, Bet: 10}))
+
+	challenges, err = service.GetChallenges(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, challenges.Daily[0].Progress)
+	assert.False(t, challenges.Daily[0].Completed)
+
+	require.NoError(t, db.Create(&model.GameSession{UserID: user.ID, GameType: model.GameTypeSlots, Bet: 10}).Error)
+	challenges, err = service.GetChallenges(user.ID)
+	require.NoError(t, err)
+	assert.True(t, challenges.Daily[0].Completed)
+	assert.Equal(t, 50.0, challenges.RewardsEarned)
+	assert.Equal(t, 1050.0, userBalance(t, db, user.ID))
+
+	// The reward is paid once
+	require.NoError(t, service.HandleEvent(events.BetSettled{UserID: user.ID, GameType: model.GameTypeSlots, Bet: 10}))
+	challenges, err = service.GetChallenges(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, challenges.RewardsEarned)
+	assert.Equal(t, 1050.0, userBalance(t, db, user.ID))
+
+	var reward model.Transaction
+	require.NoError(t, db.Where("user_id = ? AND type = ?", user.ID, model.TransactionTypeChallengeReward).First(&reward).Error)
+	assert.Equal(t, 50.0, reward.Amount)
+}
+
+func TestChallengesFollowUserTimezone(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	require.NoError(t, db.Model(user).Update("timezone", "Asia/Tokyo").Error)
+	catalog, err := ParseChallengeCatalog([]byte(testChallengeCatalog))
+	require.NoError(t, err)
+
+	now := time.Date(2026, 10, 17, 19, 0, 0, 0, time.UTC) // 04:00 on the 18th in Tokyo
+	service := &ChallengeService{db: db, catalog: catalog, now: func() time.Time { return now }}
+
+	challenges, err := service.GetChallenges(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", challenges.Timezone)
+	assert.Equal(t, time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC), challenges.DailyResetsAt.UTC())
+}
+
+func TestChallengeTimezoneChangeWaitsForNextPeriod(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	catalog, err := ParseChallengeCatalog([]byte(testChallengeCatalog))
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(24 * time.Hour).Add(12 * time.Hour) // Noon UTC
+	service := &ChallengeService{db: db, catalog: catalog, now: func() time.Time { return now }}
+
+	_, err = service.GetChallenges(user.ID)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, db.Create(&model.GameSession{UserID: user.ID, GameType: model.GameTypeSlots, Bet: 10, CreatedAt: now.Add(-time.Hour)}).Error)
+	}
+	challenges, err := service.GetChallenges(user.ID)
+	require.NoError(t, err)
+	require.True(t, challenges.Daily[0].Completed)
+	assert.Equal(t, 1050.0, userBalance(t, db, user.ID))
+
+	// Hopping across the date line doesn't open a new day with the same games in it
+	for _, zone := range []string{"Pacific/Kiritimati", "Pacific/Pago_Pago", "Asia/Tokyo"} {
+		require.NoError(t, db.Model(user).Update("timezone", zone).Error)
+		challenges, err = service.GetChallenges(user.ID)
+		require.NoError(t, err)
+		assert.True(t, challenges.Daily[0].Completed)
+		assert.Equal(t, now.Truncate(24*time.Hour).Add(24*time.Hour), challenges.DailyResetsAt.UTC())
+	}
+	assert.Equal(t, 1050.0, userBalance(t, db, user.ID))
+
+	var daily int64
+	require.NoError(t, db.Model(&model.UserChallenge{}).Where("user_id = ? AND period = ?", user.ID, model.ChallengePeriodDaily).Count(&daily).Error)
+	assert.Equal(t, int64(1), daily)
+
+	// The next day follows the new timezone, counting from where the last one ended
+	now = now.Add(12 * time.Hour)
+	challenges, err = service.GetChallenges(user.ID)
+	require.NoError(t, err)
+	assert.False(t, challenges.Daily[0].Completed)
+	assert.Equal(t, 0.0, challenges.Daily[0].Progress)
+	assert.Equal(t, 1050.0, userBalance(t, db, user.ID))
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Collections timing and auction pricing
+const (
+	CollectionNoticePeriod   = 3 * 24 * time.Hour // Time between the collections notice and seizure
+	CollectionAuctionHaircut = 0.30               // Forced auctions sell this far below resale value
+)
+
+// auctionProceeds returns what a seized item fetches at a collections auction
+func auctionProceeds(item *model.UserItem, now time.Time) float64 {
+	return roundMoney(ResaleValue(item, now) * (1 - CollectionAuctionHaircut))
+}
+
+// collectionRun tracks what one collections pass did to a user
+type collectionRun struct {
+	itemsSeized int
+	bankrupt    bool
+}
+
+// EscalateOverdueLoans hands loans overdue past the grace period to collectors
+// and sends the user a collections notice
+func (s *LoanService) EscalateOverdueLoans() (int, error) {
+	now := time.Now()
+	cutoff := now.Add(-CollectionsGracePeriod)
+
+	var loans []model.Loan
+	if err := s.db.Where("status = ? AND overdue_at IS NOT NULL AND overdue_at < ?", model.LoanStatusOverdue, cutoff).
+		Find(&loans).Error; err != nil {
+		return 0, fmt.Errorf("failed to find loans to escalate: %w", err)
+	}
+
+	for i := range loans {
+		loan := &loans[i]
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&model.Loan{}).
+				Where("id = ? AND status = ?", loan.ID, model.LoanStatusOverdue).
+				Updates(map[string]interface{}{
+					"status":               model.LoanStatusInCollection,
+					"collection_stage":     model.CollectionStageNotice,
+					"collection_notice_at": now,
+				})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			deadline := now.Add(CollectionNoticePeriod)
+			return logCollectionEvent(tx, loan.UserID, &loan.ID, model.CollectionEventNotice, loan.RemainingAmount, "",
+				fmt.Sprintf("Your %s loan #%d was handed to collectors. Repay $%.2f by %s or pledged collateral and then your other assets will be seized.",
+					loan.Type, loan.ID, loan.RemainingAmount, deadline.Format(time.RFC1123)))
+		})
+		if err != nil {
+			return i, fmt.Errorf("failed to escalate loan %d: %w", loan.ID, err)
+		}
+	}
+
+	flushEvents(s.db)
+	return len(loans), nil
+}
+
+// ProcessCollections seizes assets for loans whose collections notice has run out.
+// Returns the number of loans processed and how many owners went bankrupt.
+func (s *LoanService) ProcessCollections() (int, int, error) {
+	loanIDs, err := s.collectableLoanIDs(0)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	processed, bankruptcies := 0, 0
+	for _, loanID := range loanIDs {
+		run, err := s.collectLoan(loanID)
+		if err != nil {
+			return processed, bankruptcies, fmt.Errorf("failed to collect loan %d: %w", loanID, err)
+		}
+		if run == nil {
+			continue
+		}
+		processed++
+		if run.bankrupt {
+			bankruptcies++
+		}
+	}
+
+	return processed, bankruptcies, nil
+}
+
+// CheckBankruptcy runs collections for the user's loans whose notice has run out.
+// Returns true if user went bankrupt
+func (s *LoanService) CheckBankruptcy(userID uint) (bool, error) {
+	// Get user
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return false, fmt.Errorf("user not found: %w", err)
+	}
+
+	loanIDs, err := s.collectableLoanIDs(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, loanID := range loanIDs {
+		run, err := s.collectLoan(loanID)
+		if err != nil {
+			return false, fmt.Errorf("failed to handle collectors: %w", err)
+		}
+		if run != nil && run.bankrupt {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetCollectionEvents returns the user's collections log, newest first
+func (s *LoanService) GetCollectionEvents(userID uint, limit int) ([]model.CollectionEvent, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	var events []model.CollectionEvent
+	if err := s.db.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get collection events: %w", err)
+	}
+
+	return events, nil
+}
+
+// collectableLoanIDs returns loans in collection whose notice period has ended,
+// optionally for one user only
+func (s *LoanService) collectableLoanIDs(userID uint) ([]uint, error) {
+	cutoff := time.Now().Add(-CollectionNoticePeriod)
+
+	query := s.db.Model(&model.Loan{}).
+		Where("status = ? AND (collection_stage <> ? OR collection_notice_at < ?)",
+			model.LoanStatusInCollection, model.CollectionStageNotice, cutoff)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var loanIDs []uint
+	if err := query.Order("id").Pluck("id", &loanIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to find loans in collection: %w", err)
+	}
+	return loanIDs, nil
+}
+
+// collectLoan walks one loan through the collections stages: pledged
+// collateral is auctioned first, then the user's balance and other items are
+// liquidated, and only if the debt is still unpaid the user goes bankrupt.
+// Returns nil if the loan was closed by an earlier step.
+func (s *LoanService) collectLoan(loanID uint) (*collectionRun, error) {
+	var loan model.Loan
+	if err := s.db.First(&loan, loanID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Collectors add up interest to the day
+	if err := s.UpdateAllLoansInterest(loan.UserID); err != nil {
+		return nil, fmt.Errorf("failed to update interest: %w", err)
+	}
+
+	run := &collectionRun{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("CollateralItem.Item").
+			First(&loan, loanID).Error; err != nil {
+			return err
+		}
+
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, loan.UserID).Error; err != nil {
+			return err
+		}
+
+		settled, err := s.seizeCollateral(tx, &loan, &user, run)
+		if err != nil || settled {
+			return err
+		}
+
+		settled, err = s.liquidateAssets(tx, &loan, &user, run)
+		if err != nil || settled {
+			return err
+		}
+
+		run.bankrupt = true
+		return declareBankruptcy(tx, &user, run)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	flushEvents(s.db)
+	return run, nil
+}
+
+// seizeCollateral auctions the item pledged for the loan and applies the proceeds to it
+func (s *LoanService) seizeCollateral(tx *gorm.DB, loan *model.Loan, user *model.User, run *collectionRun) (bool, error) {
+	if loan.CollateralItemID == nil || loan.CollateralItem == nil {
+		return false, nil
+	}
+
+	item := loan.CollateralItem
+	loan.CollateralItemID = nil
+	loan.CollateralItem = nil
+	loan.CollectionStage = model.CollectionStageCollateral
+	if err := tx.Model(loan).Updates(map[string]interface{}{
+		"collateral_item_id": nil,
+		"collection_stage":   model.CollectionStageCollateral,
+	}).Error; err != nil {
+		return false, err
+	}
+
+	if err := logCollectionEvent(tx, user.ID, &loan.ID, model.CollectionEventCollateralSeized, 0, item.Item.Name,
+		fmt.Sprintf("Collectors seized your %s, pledged as collateral for loan #%d", item.Item.Name, loan.ID)); err != nil {
+		return false, err
+	}
+
+	return s.auctionItem(tx, loan, user, item, run)
+}
+
+// liquidateAssets takes the user's balance and then auctions their other
+// unpledged items, most valuable first, until the loan is paid
+func (s *LoanService) liquidateAssets(tx *gorm.DB, loan *model.Loan, user *model.User, run *collectionRun) (bool, error) {
+	loan.CollectionStage = model.CollectionStageLiquidation
+	if err := tx.Model(loan).Update("collection_stage", model.CollectionStageLiquidation).Error; err != nil {
+		return false, err
+	}
+
+	if user.Balance > 0 {
+		alloc, paidOff, err := applyLoanPayment(tx, loan, user.Balance, model.LoanEntryCollection, "Balance seized by collectors")
+		if err != nil {
+			return false, err
+		}
+		user.Balance = roundMoney(user.Balance - alloc.Total)
+		if err := tx.Model(user).Update("balance", user.Balance).Error; err != nil {
+			return false, err
+		}
+		if err := logCollectionEvent(tx, user.ID, &loan.ID, model.CollectionEventBalanceApplied, alloc.Total, "",
+			fmt.Sprintf("Collectors took $%.2f from your balance for loan #%d", alloc.Total, loan.ID)); err != nil {
+			return false, err
+		}
+		if paidOff {
+			return true, settleLoan(tx, user.ID, loan)
+		}
+	}
+
+	var items []model.UserItem
+	if err := tx.Preload("Item").
+		Joins("JOIN items ON items.id = user_items.item_id").
+		Where("user_items.user_id = ? AND user_items.is_collateral = ?", user.ID, false).
+		Order("items.price DESC").
+		Find(&items).Error; err != nil {
+		return false, err
+	}
+
+	for i := range items {
+		if err := logCollectionEvent(tx, user.ID, &loan.ID, model.CollectionEventItemSeized, 0, items[i].Item.Name,
+			fmt.Sprintf("Collectors seized your %s for loan #%d", items[i].Item.Name, loan.ID)); err != nil {
+			return false, err
+		}
+
+		settled, err := s.auctionItem(tx, loan, user, &items[i], run)
+		if err != nil || settled {
+			return settled, err
+		}
+	}
+
+	return false, nil
+}
+
+// auctionItem sells a seized item at a haircut, applies the proceeds to the
+// loan and returns any surplus to the user
+func (s *LoanService) auctionItem(tx *gorm.DB, loan *model.Loan, user *model.User, item *model.UserItem, run *collectionRun) (bool, error) {
+	if err := cancelItemListings(tx, item.ID); err != nil {
+		return false, err
+	}
+	if err := tx.Delete(item).Error; err != nil {
+		return false, err
+	}
+	if err := consignSeizedItem(tx, item, &loan.ID); err != nil {
+		return false, err
+	}
+	run.itemsSeized++
+
+	proceeds := auctionProceeds(item, time.Now())
+	if err := logCollectionEvent(tx, user.ID, &loan.ID, model.CollectionEventItemAuctioned, proceeds, item.Item.Name,
+		fmt.Sprintf("%s auctioned for $%.2f (%.0f%% below resale value)", item.Item.Name, proceeds, CollectionAuctionHaircut*100)); err != nil {
+		return false, err
+	}
+	if proceeds <= 0 {
+		return false, nil
+	}
+
+	alloc, paidOff, err := applyLoanPayment(tx, loan, proceeds, model.LoanEntryCollection,
+		fmt.Sprintf("Auction proceeds from %s", item.Item.Name))
+	if err != nil {
+		return false, err
+	}
+
+	if surplus := roundMoney(proceeds - alloc.Total); surplus > 0 {
+		user.Balance = roundMoney(user.Balance + surplus)
+		if err := tx.Model(user).Update("balance", user.Balance).Error; err != nil {
+			return false, err
+		}
+		if err := logCollectionEvent(tx, user.ID, &loan.ID, model.CollectionEventSurplusReturned, surplus, item.Item.Name,
+			fmt.Sprintf("$%.2f left over from the auction of %s was returned to your balance", surplus, item.Item.Name)); err != nil {
+			return false, err
+		}
+	}
+
+	if paidOff {
+		return true, settleLoan(tx, user.ID, loan)
+	}
+	return false, nil
+}
+
+// settleLoan logs that collections fully repaid a loan
+func settleLoan(tx *gorm.DB, userID uint, loan *model.Loan) error {
+	return logCollectionEvent(tx, userID, &loan.ID, model.CollectionEventLoanSettled, 0, "",
+		fmt.Sprintf("Loan #%d was repaid in full by collections", loan.ID))
+}
+
+// declareBankruptcy discharges all of the user's remaining loans once their
+// assets are gone. The recorded bankruptcy lowers their credit score.
+func declareBankruptcy(tx *gorm.DB, user *model.User, run *collectionRun) error {
+	var loans []model.Loan
+	if err := tx.Preload("CollateralItem").Where("user_id = ?", user.ID).Find(&loans).Error; err != nil {
+		return fmt.Errorf("failed to get loans: %w", err)
+	}
+
+	var debt float64
+	for i := range loans {
+		loan := &loans[i]
+		debt += loan.RemainingAmount
+
+		// Lenders of other loans keep what was pledged to them
+		if loan.CollateralItem != nil {
+			if err := tx.Delete(loan.CollateralItem).Error; err != nil {
+				return fmt.Errorf("failed to surrender collateral: %w", err)
+			}
+			run.itemsSeized++
+		}
+
+		if err := tx.Create(&model.LoanStatementEntry{
+			LoanID:      loan.ID,
+			UserID:      user.ID,
+			Type:        model.LoanEntryWriteOff,
+			Amount:      loan.RemainingAmount,
+			Description: "Debt discharged in bankruptcy",
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record write-off: %w", err)
+		}
+
+		if err := tx.Delete(loan).Error; err != nil {
+			return fmt.Errorf("failed to delete loan: %w", err)
+		}
+	}
+
+	if user.Balance < 0 {
+		user.Balance = 0
+		if err := tx.Model(user).Update("balance", 0).Error; err != nil {
+			return fmt.Errorf("failed to reset balance: %w", err)
+		}
+	}
+
+	debt = roundMoney(debt)
+	if err := tx.Create(&model.Bankruptcy{
+		UserID:         user.ID,
+		DebtWrittenOff: debt,
+		ItemsSeized:    run.itemsSeized,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record bankruptcy: %w", err)
+	}
+	if err := events.Record(tx, events.Bankruptcy{
+		UserID:         user.ID,
+		DebtWrittenOff: debt,
+		ItemsSeized:    run.itemsSeized,
+	}); err != nil {
+		return err
+	}
+
+	return logCollectionEvent(tx, user.ID, nil, model.CollectionEventBankruptcy, debt, "",
+		fmt.Sprintf("You were declared bankrupt: $%.2f of debt across %d loan(s) was written off. This stays on your credit report.", debt, len(loans)))
+}
+
+// logCollectionEvent records one collections step for the user
+func logCollectionEvent(tx *gorm.DB, userID uint, loanID *uint, eventType model.CollectionEventType, amount float64, itemName, description string) error {
+	event := model.CollectionEvent{
+		UserID:      userID,
+		LoanID:      loanID,
+		Type:        eventType,
+		Amount:      amount,
+		ItemName:    itemName,
+		Description: description,
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return err
+	}
+	return events.Record(tx, events.CollectionAction{
+		UserID:      userID,
+		LoanID:      loanID,
+		Action:      eventType,
+		Amount:      amount,
+		ItemName:    itemName,
+		Description: description,
+	})
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// sendToCollections moves a loan past the overdue grace period and the collections notice
+func sendToCollections(t *testing.T, db *gorm.DB, service *LoanService, loanID uint) {
+	overdueAt := time.Now().Add(-CollectionsGracePeriod - time.Hour)
+	require.NoError(t, db.Model(&model.Loan{}).Where("id = ?", loanID).Updates(map[string]interface{}{
+		"status":           model.LoanStatusOverdue,
+		"overdue_at":       overdueAt,
+		"last_interest_at": time.Now().Add(time.Hour), // Freeze interest accrual
+	}).Error)
+
+	count, err := service.EscalateOverdueLoans()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// Nothing is seized while the notice is running
+	processed, _, err := service.ProcessCollections()
+	require.NoError(t, err)
+	require.Equal(t, 0, processed)
+
+	require.NoError(t, db.Model(&model.Loan{}).Where("id = ?", loanID).
+		Update("collection_notice_at", time.Now().Add(-CollectionNoticePeriod-time.Hour)).Error)
+}
+
+func collectionEventTypes(t *testing.T, service *LoanService, userID uint) []model.CollectionEventType {
+	events, err := service.GetCollectionEvents(userID, 0)
+	require.NoError(t, err)
+
+	types := make([]model.CollectionEventType, len(events))
+	for i, event := range events {
+		// Events come newest first; return them in order of occurrence
+		types[len(events)-1-i] = event.Type
+	}
+	return types
+}
+
+func TestCollectionsSeizeCollateralFirst(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	car := createTestItem(t, db, "Sedan", model.ItemTypeCar, 4000)
+	shirt := createTestItem(t, db, "T-Shirt", model.ItemTypeClothing, 50)
+	pledged := &model.UserItem{UserID: user.ID, ItemID: car.ID, PurchasedAt: time.Now()}
+	require.NoError(t, db.Create(pledged).Error)
+	require.NoError(t, db.Create(&model.UserItem{UserID: user.ID, ItemID: shirt.ID, PurchasedAt: time.Now()}).Error)
+
+	service := &LoanService{db: db}
+	result, err := service.TakeLoan(TakeLoanRequest{
+		UserID:           user.ID,
+		Amount:           1000,
+		Type:             model.LoanTypeBank,
+		CollateralItemID: &pledged.ID,
+	})
+	require.NoError(t, err)
+	// Spend the loan so only the collateral can pay it
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", user.ID).Update("balance", 0).Error)
+
+	sendToCollections(t, db, service, result.Loan.ID)
+
+	processed, bankruptcies, err := service.ProcessCollections()
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Equal(t, 0, bankruptcies)
+
+	// New car auctioned at 4000 * 0.7 resale * 0.7 = 1960, loan of 1000 repaid, surplus returned
+	var balance float64
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", user.ID).Pluck("balance", &balance).Error)
+	assert.Equal(t, 960.0, balance)
+
+	var items []model.UserItem
+	require.NoError(t, db.Where("user_id = ?", user.ID).Find(&items).Error)
+	require.Len(t, items, 1)
+	assert.Equal(t, shirt.ID, items[0].ItemID)
+
+	var open int64
+	require.NoError(t, db.Model(&model.Loan{}).Where("user_id = ?", user.ID).Count(&open).Error)
+	assert.Equal(t, int64(0), open)
+
+	assert.Equal(t, []model.CollectionEventType{
+		model.CollectionEventNotice,
+		model.CollectionEventCollateralSeized,
+		model.CollectionEventItemAuctioned,
+		model.CollectionEventSurplusReturned,
+		model.CollectionEventLoanSettled,
+	}, collectionEventTypes(t, service, user.ID))
+
+	statement, err := service.GetLoanStatement(user.ID, result.Loan.ID)
+	require.NoError(t, err)
+	last := statement.Entries[len(statement.Entries)-1]
+	assert.Equal(t, model.LoanEntryCollection, last.Type)
+	assert.Equal(t, 1000.0, last.Amount)
+}
+
+func TestCollectionsLiquidationThenBankruptcy(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	shirt := createTestItem(t, db, "T-Shirt", model.ItemTypeClothing, 100)
+	require.NoError(t, db.Create(&model.UserItem{UserID: user.ID, ItemID: shirt.ID, PurchasedAt: time.Now()}).Error)
+
+	service := &LoanService{db: db}
+	result, err := service.TakeLoan(TakeLoanRequest{UserID: user.ID, Amount: 500, Type: model.LoanTypeMicrocredit})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", user.ID).Update("balance", 100).Error)
+
+	sendToCollections(t, db, service, result.Loan.ID)
+
+	bankrupt, err := service.CheckBankruptcy(user.ID)
+	require.NoError(t, err)
+	assert.True(t, bankrupt)
+
+	assert.Equal(t, []model.CollectionEventType{
+		model.CollectionEventNotice,
+		model.CollectionEventBalanceApplied,
+		model.CollectionEventItemSeized,
+		model.CollectionEventItemAuctioned,
+		model.CollectionEventBankruptcy,
+	}, collectionEventTypes(t, service, user.ID))
+
+	// 500 - 100 balance - 35 auction = 365 written off
+	var bankruptcy model.Bankruptcy
+	require.NoError(t, db.Where("user_id = ?", user.ID).First(&bankruptcy).Error)
+	assert.Equal(t, 365.0, bankruptcy.DebtWrittenOff)
+	assert.Equal(t, 1, bankruptcy.ItemsSeized)
+
+	var open int64
+	require.NoError(t, db.Model(&model.Loan{}).Where("user_id = ?", user.ID).Count(&open).Error)
+	assert.Equal(t, int64(0), open)
+
+	// The bankruptcy shows up on the credit report
+	report, err := (&CreditService{db: db}).GetCreditReport(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, -120, findFactor(t, report, "bankruptcies").Points)
+}
@@ -0,0 +1,573 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EncounterCooldown is the minimum time between two collector encounters
+const EncounterCooldown = 6 * time.Hour
+
+// CollectorService runs collector encounters for users with loans in collection
+type CollectorService struct {
+	db      *gorm.DB
+	catalog *CollectorCatalog
+}
+
+// NewCollectorService creates a new collector service instance
+func NewCollectorService() *CollectorService {
+	catalog, err := LoadCollectorCatalog()
+	if err != nil {
+		log.Printf("Warning: failed to load collector scenarios, using built-in set: %v", err)
+		catalog = DefaultCollectorCatalog()
+	}
+
+	return &CollectorService{
+		db:      database.GetDB(),
+		catalog: catalog,
+	}
+}
+
+// scenarios returns the scenario catalogue used by this service
+func (s *CollectorService) scenarios() *CollectorCatalog {
+	if s.catalog == nil {
+		return DefaultCollectorCatalog()
+	}
+	return s.catalog
+}
+
+// EncounterChoiceView is a choice as shown to the player
+type EncounterChoiceView struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// EncounterView represents an encounter in its current state
+type EncounterView struct {
+	ID         uint                  `json:"id"`
+	ScenarioID string                `json:"scenario_id"`
+	Title      string                `json:"title"`
+	State      string                `json:"state"`
+	Text       string                `json:"text"`
+	Choices    []EncounterChoiceView `json:"choices"`
+	Resolved   bool                  `json:"resolved"`
+	CreatedAt  time.Time             `json:"created_at"`
+}
+
+// EncounterEffectResult describes what an applied effect did
+type EncounterEffectResult struct {
+	Type        string  `json:"type"`
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description"`
+}
+
+// EncounterChoiceRequest represents a player's choice in an encounter
+type EncounterChoiceRequest struct {
+	Choice string `json:"choice"`
+}
+
+// EncounterChoiceResponse represents the outcome of a choice
+type EncounterChoiceResponse struct {
+	Encounter     EncounterView           `json:"encounter"`
+	OutcomeText   string                  `json:"outcome_text,omitempty"`
+	Effects       []EncounterEffectResult `json:"effects"`
+	NewBalance    float64                 `json:"new_balance"`
+	RemainingDebt float64                 `json:"remaining_debt"`
+}
+
+// GetEncounter returns the user's active encounter, starting a new one when
+// the user has loans in collection and the cooldown has passed
+func (s *CollectorService) GetEncounter(userID uint) (*EncounterView, error) {
+	var active model.CollectorEncounter
+	err := s.db.Where("user_id = ? AND status = ?", userID, model.EncounterStatusActive).
+		Order("id DESC").First(&active).Error
+	if err == nil {
+		return s.view(&active)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get encounter: %w", err)
+	}
+
+	var inCollection int64
+	if err := s.db.Model(&model.Loan{}).
+		Where("user_id = ? AND status = ?", userID, model.LoanStatusInCollection).
+		Count(&inCollection).Error; err != nil {
+		return nil, fmt.Errorf("failed to check loans: %w", err)
+	}
+	if inCollection == 0 {
+		return nil, errors.New("no_encounter")
+	}
+
+	var recent int64
+	if err := s.db.Model(&model.CollectorEncounter{}).
+		Where("user_id = ? AND created_at > ?", userID, time.Now().Add(-EncounterCooldown)).
+		Count(&recent).Error; err != nil {
+		return nil, fmt.Errorf("failed to check encounter cooldown: %w", err)
+	}
+	if recent > 0 {
+		return nil, errors.New("no_encounter")
+	}
+
+	profile, err := s.profile(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scenario := s.scenarios().pick(profile)
+	if scenario == nil {
+		return nil, errors.New("no_encounter")
+	}
+
+	encounter := model.CollectorEncounter{
+		UserID:     userID,
+		ScenarioID: scenario.ID,
+		State:      scenario.Start,
+		Status:     model.EncounterStatusActive,
+	}
+	if err := s.db.Create(&encounter).Error; err != nil {
+		return nil, fmt.Errorf("failed to start encounter: %w", err)
+	}
+
+	return s.view(&encounter)
+}
+
+// Choose applies the player's choice, rolls its outcome and moves the encounter on
+func (s *CollectorService) Choose(userID, encounterID uint, req EncounterChoiceRequest) (*EncounterChoiceResponse, error) {
+	// Debts are settled with interest up to date
+	if err := (&LoanService{db: s.db}).UpdateAllLoansInterest(userID); err != nil {
+		return nil, fmt.Errorf("failed to update interest: %w", err)
+	}
+
+	var response *EncounterChoiceResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var encounter model.CollectorEncounter
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&encounter, encounterID).Error; err != nil {
+			return errors.New("encounter_not_found")
+		}
+		if encounter.UserID != userID {
+			return errors.New("encounter_not_found")
+		}
+		if encounter.Status != model.EncounterStatusActive {
+			return errors.New("encounter_resolved")
+		}
+
+		scenario, ok := s.scenarios().Get(encounter.ScenarioID)
+		if !ok {
+			return errors.New("scenario_unavailable")
+		}
+		state, ok := scenario.States[encounter.State]
+		if !ok {
+			return errors.New("scenario_unavailable")
+		}
+		choice, ok := state.Choice(req.Choice)
+		if !ok {
+			return errors.New("invalid_choice")
+		}
+
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return errors.New("user_not_found")
+		}
+		balanceBefore := user.Balance
+		debtBefore, err := totalDebt(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		outcome := rollEncounterOutcome(choice.Outcomes)
+		next := scenario.States[outcome.Next]
+
+		// Outcome effects first, then whatever happens on entering the next state
+		effects := append(append([]EncounterEffect{}, outcome.Effects...), next.Effects...)
+		results := make([]EncounterEffectResult, 0, len(effects))
+		itemsLost := 0
+		for _, effect := range effects {
+			result, lost, err := applyEncounterEffect(tx, &user, effect)
+			if err != nil {
+				return err
+			}
+			itemsLost += lost
+			results = append(results, result)
+		}
+
+		if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			return err
+		}
+		debtAfter, err := totalDebt(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		step := model.CollectorEncounterStep{
+			EncounterID:   encounter.ID,
+			FromState:     encounter.State,
+			Choice:        choice.ID,
+			ToState:       outcome.Next,
+			OutcomeText:   outcome.Text,
+			BalanceChange: roundMoney(user.Balance - balanceBefore),
+			DebtChange:    roundMoney(debtAfter - debtBefore),
+			ItemsLost:     itemsLost,
+		}
+		if err := tx.Create(&step).Error; err != nil {
+			return fmt.Errorf("failed to record encounter step: %w", err)
+		}
+		if step.BalanceChange != 0 {
+			if err := events.Record(tx, events.BalanceChanged{
+				UserID:  userID,
+				Balance: user.Balance,
+				Change:  step.BalanceChange,
+				Reason:  outcome.Text,
+			}); err != nil {
+				return err
+			}
+		}
+
+		encounter.State = outcome.Next
+		if next.Terminal() {
+			now := time.Now()
+			encounter.Status = model.EncounterStatusResolved
+			encounter.ResolvedAt = &now
+		}
+		if err := tx.Save(&encounter).Error; err != nil {
+			return fmt.Errorf("failed to update encounter: %w", err)
+		}
+
+		view, err := s.view(&encounter)
+		if err != nil {
+			return err
+		}
+		response = &EncounterChoiceResponse{
+			Encounter:     *view,
+			OutcomeText:   outcome.Text,
+			Effects:       results,
+			NewBalance:    user.Balance,
+			RemainingDebt: debtAfter,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	flushEvents(s.db)
+	return response, nil
+}
+
+// GetEncounterHistory returns the user's past encounters with their steps, newest first
+func (s *CollectorService) GetEncounterHistory(userID uint, limit int) ([]model.CollectorEncounter, error) {
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+
+	var encounters []model.CollectorEncounter
+	if err := s.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&encounters).Error; err != nil {
+		return nil, fmt.Errorf("failed to get encounters: %w", err)
+	}
+
+	return encounters, nil
+}
+
+// view renders an encounter in its current state
+func (s *CollectorService) view(encounter *model.CollectorEncounter) (*EncounterView, error) {
+	scenario, ok := s.scenarios().Get(encounter.ScenarioID)
+	if !ok {
+		return nil, errors.New("scenario_unavailable")
+	}
+	state, ok := scenario.States[encounter.State]
+	if !ok {
+		return nil, errors.New("scenario_unavailable")
+	}
+
+	choices := make([]EncounterChoiceView, 0, len(state.Choices))
+	if encounter.Status == model.EncounterStatusActive {
+		for _, choice := range state.Choices {
+			choices = append(choices, EncounterChoiceView{ID: choice.ID, Label: choice.Label})
+		}
+	}
+
+	return &EncounterView{
+		ID:         encounter.ID,
+		ScenarioID: scenario.ID,
+		Title:      scenario.Title,
+		State:      encounter.State,
+		Text:       state.Text,
+		Choices:    choices,
+		Resolved:   encounter.Status == model.EncounterStatusResolved,
+		CreatedAt:  encounter.CreatedAt,
+	}, nil
+}
+
+// profile gathers what scenario conditions are checked against
+func (s *CollectorService) profile(db *gorm.DB, userID uint) (encounterProfile, error) {
+	profile := encounterProfile{itemTypes: make(map[model.ItemType]bool)}
+
+	debt, err := totalDebt(db, userID)
+	if err != nil {
+		return profile, err
+	}
+	profile.debt = debt
+
+	var types []model.ItemType
+	if err := db.Model(&model.UserItem{}).
+		Joins("JOIN items ON items.id = user_items.item_id").
+		Where("user_items.user_id = ? AND user_items.is_collateral = ?", userID, false).
+		Pluck("items.type", &types).Error; err != nil {
+		return profile, fmt.Errorf("failed to get items: %w", err)
+	}
+	profile.items = len(types)
+	for _, t := range types {
+		profile.itemTypes[t] = true
+	}
+
+	statuses, err := activeStatuses(db, userID)
+	if err != nil {
+		return profile, err
+	}
+	profile.statuses = statuses
+
+	return profile, nil
+}
+
+// totalDebt sums everything the user owes
+func totalDebt(db *gorm.DB, userID uint) (float64, error) {
+	var debt float64
+	if err := db.Model(&model.Loan{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(SUM(remaining_amount), 0)").
+		Scan(&debt).Error; err != nil {
+		return 0, fmt.Errorf("failed to sum debt: %w", err)
+	}
+	return roundMoney(debt), nil
+}
+
+// collectorLoans returns the user's loans, those in collection first
+func collectorLoans(tx *gorm.DB, userID uint) ([]model.Loan, error) {
+	var loans []model.Loan
+	if err := tx.Preload("CollateralItem").
+		Where("user_id = ?", userID).
+		Order(clause.Expr{SQL: "CASE WHEN status = ? THEN 0 ELSE 1 END, id", Vars: []interface{}{model.LoanStatusInCollection}}).
+		Find(&loans).Error; err != nil {
+		return nil, fmt.Errorf("failed to get loans: %w", err)
+	}
+	return loans, nil
+}
+
+// payDebt applies amount to the user's loans and returns how much was used
+func payDebt(tx *gorm.DB, userID uint, amount float64, description string) (float64, error) {
+	loans, err := collectorLoans(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	paid := 0.0
+	for i := range loans {
+		remaining := roundMoney(amount - paid)
+		if remaining <= 0 {
+			break
+		}
+		if loans[i].RemainingAmount <= 0 {
+			continue
+		}
+		alloc, _, err := applyLoanPayment(tx, &loans[i], remaining, model.LoanEntryCollection, description)
+		if err != nil {
+			return paid, err
+		}
+		paid = roundMoney(paid + alloc.Total)
+	}
+	return paid, nil
+}
+
+// applyEncounterEffect applies one effect to the locked user and their loans.
+// Balance changes are made on user; the caller saves it.
+func applyEncounterEffect(tx *gorm.DB, user *model.User, effect EncounterEffect) (EncounterEffectResult, int, error) {
+	result := EncounterEffectResult{Type: effect.Type}
+
+	switch effect.Type {
+	case EncounterEffectAdjustBalance:
+		amount := math.Max(effect.Amount, -user.Balance)
+		user.Balance = roundMoney(user.Balance + amount)
+		result.Amount = amount
+		result.Description = fmt.Sprintf("Balance changed by $%.2f", amount)
+
+	case EncounterEffectPayFromBalance:
+		amount := roundMoney(math.Max(user.Balance, 0) * effect.Fraction)
+		paid := 0.0
+		if amount > 0 {
+			var err error
+			paid, err = payDebt(tx, user.ID, amount, "Paid to collectors")
+			if err != nil {
+				return result, 0, err
+			}
+		}
+		user.Balance = roundMoney(user.Balance - paid)
+		result.Amount = paid
+		result.Description = fmt.Sprintf("Paid $%.2f of your balance to collectors", paid)
+
+	case EncounterEffectSeizeItems:
+		query := tx.Preload("Item").
+			Joins("JOIN items ON items.id = user_items.item_id").
+			Where("user_items.user_id = ? AND user_items.is_collateral = ?", user.ID, false)
+		if effect.ItemType != "" {
+			query = query.Where("items.type = ?", effect.ItemType)
+		}
+		var items []model.UserItem
+		if err := query.Find(&items).Error; err != nil {
+			return result, 0, fmt.Errorf("failed to get items: %w", err)
+		}
+		if len(items) == 0 {
+			result.Description = "Collectors found nothing worth taking"
+			return result, 0, nil
+		}
+
+		proceeds := 0.0
+		names := make([]string, len(items))
+		for i := range items {
+			names[i] = items[i].Item.Name
+			proceeds += auctionProceeds(&items[i], time.Now())
+			if err := cancelItemListings(tx, items[i].ID); err != nil {
+				return result, 0, err
+			}
+			if err := tx.Delete(&items[i]).Error; err != nil {
+				return result, 0, fmt.Errorf("failed to seize item: %w", err)
+			}
+			if err := consignSeizedItem(tx, &items[i], nil); err != nil {
+				return result, 0, err
+			}
+		}
+		proceeds = roundMoney(proceeds)
+
+		paid, err := payDebt(tx, user.ID, proceeds, "Auction of items taken by collectors")
+		if err != nil {
+			return result, 0, err
+		}
+		if surplus := roundMoney(proceeds - paid); surplus > 0 {
+			user.Balance = roundMoney(user.Balance + surplus)
+		}
+
+		result.Amount = proceeds
+		result.Description = fmt.Sprintf("Seized %s and auctioned them for $%.2f", strings.Join(names, ", "), proceeds)
+		return result, len(items), nil
+
+	case EncounterEffectForgiveDebt:
+		loans, err := collectorLoans(tx, user.ID)
+		if err != nil {
+			return result, 0, err
+		}
+		forgiven := 0.0
+		for i := range loans {
+			amount, err := forgiveLoan(tx, &loans[i], effect.Fraction)
+			if err != nil {
+				return result, 0, err
+			}
+			forgiven += amount
+		}
+		result.Amount = roundMoney(forgiven)
+		result.Description = fmt.Sprintf("Collectors forgave $%.2f of debt", result.Amount)
+
+	case EncounterEffectAddFee:
+		loans, err := collectorLoans(tx, user.ID)
+		if err != nil {
+			return result, 0, err
+		}
+		if len(loans) == 0 {
+			result.Description = "No loan to charge"
+			return result, 0, nil
+		}
+		loan := &loans[0]
+		normalizeLoanBalance(loan)
+		loan.OutstandingFees = roundMoney(loan.OutstandingFees + effect.Amount)
+		loan.RemainingAmount = loanBalance(loan)
+		if err := tx.Omit("CollateralItem", "Installments").Save(loan).Error; err != nil {
+			return result, 0, err
+		}
+		if err := tx.Create(&model.LoanStatementEntry{
+			LoanID:       loan.ID,
+			UserID:       user.ID,
+			Type:         model.LoanEntryLateFee,
+			Amount:       effect.Amount,
+			BalanceAfter: loan.RemainingAmount,
+			Description:  "Collector fee",
+		}).Error; err != nil {
+			return result, 0, err
+		}
+		result.Amount = effect.Amount
+		result.Description = fmt.Sprintf("A $%.2f collector fee was added to loan #%d", effect.Amount, loan.ID)
+
+	case EncounterEffectAddStatus:
+		if err := grantStatus(tx, user.ID, effect.Status, time.Duration(effect.DurationHours*float64(time.Hour))); err != nil {
+			return result, 0, err
+		}
+		result.Description = fmt.Sprintf("You are now %s", strings.ReplaceAll(effect.Status, "_", " "))
+	}
+
+	return result, 0, nil
+}
+
+// forgiveLoan writes off a fraction of a loan's balance and returns the amount forgiven.
+// A fully forgiven loan is closed and its collateral released.
+func forgiveLoan(tx *gorm.DB, loan *model.Loan, fraction float64) (float64, error) {
+	normalizeLoanBalance(loan)
+	before := loan.RemainingAmount
+	if before <= 0 {
+		return 0, nil
+	}
+
+	if fraction >= 1 {
+		if loan.CollateralItem != nil {
+			loan.CollateralItem.IsCollateral = false
+			if err := tx.Save(loan.CollateralItem).Error; err != nil {
+				return 0, err
+			}
+		}
+		// Close the schedule too, so no unpaid installments outlive the loan
+		if err := tx.Model(&model.LoanInstallment{}).
+			Where("loan_id = ? AND status IN ?", loan.ID, []model.InstallmentStatus{model.InstallmentStatusPending, model.InstallmentStatusMissed}).
+			Update("status", model.InstallmentStatusWrittenOff).Error; err != nil {
+			return 0, err
+		}
+		if err := tx.Delete(loan).Error; err != nil {
+			return 0, err
+		}
+		loan.RemainingAmount = 0
+	} else {
+		keep := 1 - fraction
+		loan.OutstandingFees = roundMoney(loan.OutstandingFees * keep)
+		loan.AccruedInterest *= keep
+		loan.OutstandingPrincipal = roundMoney(loan.OutstandingPrincipal * keep)
+		loan.RemainingAmount = loanBalance(loan)
+		loan.InterestPerSecond = loan.OutstandingPrincipal * (currentRate(loan) / secondsPerYear)
+		if err := tx.Omit("CollateralItem", "Installments").Save(loan).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	forgiven := roundMoney(before - loan.RemainingAmount)
+	if err := tx.Create(&model.LoanStatementEntry{
+		LoanID:       loan.ID,
+		UserID:       loan.UserID,
+		Type:         model.LoanEntryWriteOff,
+		Amount:       forgiven,
+		BalanceAfter: loan.RemainingAmount,
+		Description:  "Forgiven by collectors",
+	}).Error; err != nil {
+		return 0, err
+	}
+
+	return forgiven, nil
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/smoreg/freezino/backend/internal/data"
+	"github.com/smoreg/freezino/backend/internal/model"
+)
+
+// Encounter effect types understood by the collector engine
+const (
+	EncounterEffectAdjustBalance  = "adjust_balance"   // Add Amount (may be negative) to the balance
+	EncounterEffectPayFromBalance = "pay_from_balance" // Pay Fraction of the balance towards debt
+	EncounterEffectSeizeItems     = "seize_items"      // Auction unpledged items (of ItemType, if set) towards debt
+	EncounterEffectForgiveDebt    = "forgive_debt"     // Write off Fraction of every loan
+	EncounterEffectAddFee         = "add_fee"          // Charge Amount as a fee on the loan in collection
+	EncounterEffectAddStatus      = "add_status"       // Give the user a UserStatus for DurationHours
+)
+
+// EncounterEffect changes the user's balance, items, statuses or loans
+type EncounterEffect struct {
+	Type          string         `json:"type"`
+	Amount        float64        `json:"amount,omitempty"`
+	Fraction      float64        `json:"fraction,omitempty"`
+	ItemType      model.ItemType `json:"item_type,omitempty"`
+	Status        string         `json:"status,omitempty"`
+	DurationHours float64        `json:"duration_hours,omitempty"`
+}
+
+// EncounterOutcome is one weighted result of a choice
+type EncounterOutcome struct {
+	Weight  float64           `json:"weight"`
+	Next    string            `json:"next"`
+	Text    string            `json:"text,omitempty"`
+	Effects []EncounterEffect `json:"effects,omitempty"`
+}
+
+// EncounterChoice is an option offered to the player in a state
+type EncounterChoice struct {
+	ID       string             `json:"id"`
+	Label    string             `json:"label"`
+	Outcomes []EncounterOutcome `json:"outcomes"`
+}
+
+// EncounterState is a node of a scenario. A state without choices ends the encounter.
+type EncounterState struct {
+	Text    string            `json:"text"`
+	Effects []EncounterEffect `json:"effects,omitempty"` // Applied when a choice leads into the state
+	Choices []EncounterChoice `json:"choices,omitempty"`
+}
+
+// Terminal reports whether the state ends the encounter
+func (s *EncounterState) Terminal() bool {
+	return len(s.Choices) == 0
+}
+
+// Choice returns the choice with the given ID
+func (s *EncounterState) Choice(id string) (*EncounterChoice, bool) {
+	for i := range s.Choices {
+		if s.Choices[i].ID == id {
+			return &s.Choices[i], true
+		}
+	}
+	return nil, false
+}
+
+// EncounterConditions restrict which users a scenario can happen to
+type EncounterConditions struct {
+	MinDebt          float64        `json:"min_debt,omitempty"`
+	MinItems         *int           `json:"min_items,omitempty"` // Unpledged items owned
+	MaxItems         *int           `json:"max_items,omitempty"`
+	RequiresItemType model.ItemType `json:"requires_item_type,omitempty"`
+	RequiresStatus   string         `json:"requires_status,omitempty"`
+	ExcludesStatus   string         `json:"excludes_status,omitempty"`
+}
+
+// EncounterScenario declares a collector encounter as a state machine
+type EncounterScenario struct {
+	ID         string                     `json:"id"`
+	Title      string                     `json:"title"`
+	Weight     float64                    `json:"weight"`
+	Conditions EncounterConditions        `json:"conditions"`
+	Start      string                     `json:"start"`
+	States     map[string]*EncounterState `json:"states"`
+}
+
+// CollectorCatalog holds all collector scenarios in declaration order
+type CollectorCatalog struct {
+	scenarios []EncounterScenario
+	byID      map[string]*EncounterScenario
+}
+
+var (
+	defaultCollectorCatalog     *CollectorCatalog
+	defaultCollectorCatalogOnce sync.Once
+)
+
+// ParseCollectorCatalog parses and validates JSON collector scenarios
+func ParseCollectorCatalog(raw []byte) (*CollectorCatalog, error) {
+	var scenarios []EncounterScenario
+	if err := json.Unmarshal(raw, &scenarios); err != nil {
+		return nil, fmt.Errorf("failed to parse collector scenarios: %w", err)
+	}
+
+	catalog := &CollectorCatalog{
+		scenarios: scenarios,
+		byID:      make(map[string]*EncounterScenario, len(scenarios)),
+	}
+
+	for i := range catalog.scenarios {
+		scenario := &catalog.scenarios[i]
+		if scenario.ID == "" {
+			return nil, fmt.Errorf("scenario #%d has no id", i)
+		}
+		if _, exists := catalog.byID[scenario.ID]; exists {
+			return nil, fmt.Errorf("duplicate scenario %q", scenario.ID)
+		}
+		if scenario.Weight <= 0 {
+			return nil, fmt.Errorf("scenario %q must have a positive weight", scenario.ID)
+		}
+		if _, ok := scenario.States[scenario.Start]; !ok {
+			return nil, fmt.Errorf("scenario %q starts in unknown state %q", scenario.ID, scenario.Start)
+		}
+
+		for name, state := range scenario.States {
+			if state == nil {
+				return nil, fmt.Errorf("scenario %q state %q is empty", scenario.ID, name)
+			}
+			if err := validateEncounterEffects(scenario.ID, state.Effects); err != nil {
+				return nil, err
+			}
+			for _, choice := range state.Choices {
+				if choice.ID == "" || len(choice.Outcomes) == 0 {
+					return nil, fmt.Errorf("scenario %q state %q has a choice without id or outcomes", scenario.ID, name)
+				}
+				for _, outcome := range choice.Outcomes {
+					if outcome.Weight <= 0 {
+						return nil, fmt.Errorf("scenario %q choice %q has an outcome with non-positive weight", scenario.ID, choice.ID)
+					}
+					if _, ok := scenario.States[outcome.Next]; !ok {
+						return nil, fmt.Errorf("scenario %q choice %q leads to unknown state %q", scenario.ID, choice.ID, outcome.Next)
+					}
+					if err := validateEncounterEffects(scenario.ID, outcome.Effects); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+
+		catalog.byID[scenario.ID] = scenario
+	}
+
+	return catalog, nil
+}
+
+// validateEncounterEffects checks that every effect has a known type and its parameters
+func validateEncounterEffects(scenarioID string, effects []EncounterEffect) error {
+	for _, effect := range effects {
+		switch effect.Type {
+		case EncounterEffectAdjustBalance:
+			if effect.Amount == 0 {
+				return fmt.Errorf("scenario %q: adjust_balance needs amount", scenarioID)
+			}
+		case EncounterEffectAddFee:
+			if effect.Amount <= 0 {
+				return fmt.Errorf("scenario %q: add_fee needs a positive amount", scenarioID)
+			}
+		case EncounterEffectPayFromBalance, EncounterEffectForgiveDebt:
+			if effect.Fraction <= 0 || effect.Fraction > 1 {
+				return fmt.Errorf("scenario %q: %s needs a fraction in (0, 1]", scenarioID, effect.Type)
+			}
+		case EncounterEffectAddStatus:
+			if effect.Status == "" || effect.DurationHours <= 0 {
+				return fmt.Errorf("scenario %q: add_status needs status and duration_hours", scenarioID)
+			}
+		case EncounterEffectSeizeItems:
+		default:
+			return fmt.Errorf("scenario %q: unknown effect type %q", scenarioID, effect.Type)
+		}
+	}
+	return nil
+}
+
+// LoadCollectorCatalog loads collectors.json from disk, falling back to the built-in copy
+func LoadCollectorCatalog() (*CollectorCatalog, error) {
+	dataPath := filepath.Join("backend", "internal", "data", "collectors.json")
+	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
+		dataPath = filepath.Join("internal", "data", "collectors.json")
+	}
+
+	raw, err := os.ReadFile(dataPath)
+	if err != nil {
+		return DefaultCollectorCatalog(), nil
+	}
+
+	return ParseCollectorCatalog(raw)
+}
+
+// DefaultCollectorCatalog returns the scenarios embedded in the binary
+func DefaultCollectorCatalog() *CollectorCatalog {
+	defaultCollectorCatalogOnce.Do(func() {
+		catalog, err := ParseCollectorCatalog(data.CollectorsJSON)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in collector scenarios: %v", err))
+		}
+		defaultCollectorCatalog = catalog
+	})
+	return defaultCollectorCatalog
+}
+
+// Get returns the scenario with the given ID
+func (c *CollectorCatalog) Get(id string) (*EncounterScenario, bool) {
+	scenario, ok := c.byID[id]
+	return scenario, ok
+}
+
+// All returns every scenario in catalogue order
+func (c *CollectorCatalog) All() []EncounterScenario {
+	return c.scenarios
+}
+
+// encounterProfile is what scenario conditions are checked against
+type encounterProfile struct {
+	debt      float64
+	items     int
+	itemTypes map[model.ItemType]bool
+	statuses  map[string]bool
+}
+
+// matches reports whether the scenario can happen to a user with this profile
+func (c EncounterConditions) matches(p encounterProfile) bool {
+	if p.debt < c.MinDebt {
+		return false
+	}
+	if c.MinItems != nil && p.items < *c.MinItems {
+		return false
+	}
+	if c.MaxItems != nil && p.items > *c.MaxItems {
+		return false
+	}
+	if c.RequiresItemType != "" && !p.itemTypes[c.RequiresItemType] {
+		return false
+	}
+	if c.RequiresStatus != "" && !p.statuses[c.RequiresStatus] {
+		return false
+	}
+	if c.ExcludesStatus != "" && p.statuses[c.ExcludesStatus] {
+		return false
+	}
+	return true
+}
+
+// pick chooses a weighted random scenario among those matching the profile
+func (c *CollectorCatalog) pick(p encounterProfile) *EncounterScenario {
+	eligible := make([]*EncounterScenario, 0, len(c.scenarios))
+	total := 0.0
+	for i := range c.scenarios {
+		if c.scenarios[i].Conditions.matches(p) {
+			eligible = append(eligible, &c.scenarios[i])
+			total += c.scenarios[i].Weight
+		}
+	}
+	if len(eligible) == 0 {
+		return nil
+	}
+
+	roll := rand.Float64() * total
+	for _, scenario := range eligible {
+		if roll < scenario.Weight {
+			return scenario
+		}
+		roll -= scenario.Weight
+	}
+	return eligible[len(eligible)-1]
+}
+
+// rollEncounterOutcome picks a weighted outcome of a choice
+func rollEncounterOutcome(outcomes []EncounterOutcome) *EncounterOutcome {
+	total := 0.0
+	for _, outcome := range outcomes {
+		total += outcome.Weight
+	}
+
+	roll := rand.Float64() * total
+	for i := range outcomes {
+		if roll < outcomes[i].Weight {
+			return &outcomes[i]
+		}
+		roll -= outcomes[i].Weight
+	}
+	return &outcomes[len(outcomes)-1]
+}
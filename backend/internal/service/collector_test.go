@@ -0,0 +1,205 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCollectorScenarios = `[
+  {
+    "id": "wardrobe",
+    "title": "Wardrobe",
+    "weight": 1,
+    "conditions": { "requires_item_type": "clothing" },
+    "start": "door",
+    "states": {
+      "door": {
+        "text": "Knock knock",
+        "choices": [
+          {
+            "id": "hand_over",
+            "label": "Hand over",
+            "outcomes": [
+              {
+                "weight": 1,
+                "next": "done",
+                "text": "They take your shirt",
+                "effects": [
+                  { "type": "seize_items", "item_type": "clothing" },
+                  { "type": "add_status", "status": "in_underwear", "duration_hours": 24 }
+                ]
+              }
+            ]
+          },
+          {
+            "id": "stall",
+            "label": "Stall",
+            "outcomes": [ { "weight": 1, "next": "picnic", "effects": [ { "type": "add_fee", "amount": 20 } ] } ]
+          }
+        ]
+      },
+      "picnic": {
+        "text": "A picnic",
+        "effects": [ { "type": "forgive_debt", "fraction": 0.5 } ]
+      },
+      "done": { "text": "In your underwear" }
+    }
+  }
+]`
+
+func TestParseCollectorCatalog(t *testing.T) {
+	catalog := DefaultCollectorCatalog()
+	require.NotEmpty(t, catalog.All())
+	_, ok := catalog.Get("forest_picnic")
+	assert.True(t, ok)
+
+	_, err := ParseCollectorCatalog([]byte(`[{"id":"x","weight":1,"start":"a","states":{"a":{"choices":[{"id":"c","outcomes":[{"weight":1,"next":"b"}]}]}}}]`))
+	assert.ErrorContains(t, err, "unknown state")
+
+	_, err = ParseCollectorCatalog([]byte(`[{"id":"x","weight":1,"start":"a","states":{"a":{"effects":[{"type":"explode"}]}}}]`))
+	assert.ErrorContains(t, err, "unknown effect type")
+}
+
+// setupCollectorTest creates a user with a shirt and a microcredit loan in collection
+func setupCollectorTest(t *testing.T) (*CollectorService, *model.User, *model.Loan) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	shirt := createTestItem(t, db, "Shirt", model.ItemTypeClothing, 200)
+	require.NoError(t, db.Create(&model.UserItem{UserID: user.ID, ItemID: shirt.ID, PurchasedAt: time.Now()}).Error)
+
+	result, err := (&LoanService{db: db}).TakeLoan(TakeLoanRequest{UserID: user.ID, Amount: 100, Type: model.LoanTypeMicrocredit})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&model.Loan{}).Where("id = ?", result.Loan.ID).
+		Update("status", model.LoanStatusInCollection).Error)
+
+	catalog, err := ParseCollectorCatalog([]byte(testCollectorScenarios))
+	require.NoError(t, err)
+
+	return &CollectorService{db: db, catalog: catalog}, user, &result.Loan
+}
+
+func TestCollectorEncounterSeizeItems(t *testing.T) {
+	service, user, loan := setupCollectorTest(t)
+
+	encounter, err := service.GetEncounter(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "wardrobe", encounter.ScenarioID)
+	assert.Equal(t, "door", encounter.State)
+	assert.Len(t, encounter.Choices, 2)
+
+	// The same encounter is returned until it is resolved
+	again, err := service.GetEncounter(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, encounter.ID, again.ID)
+
+	_, err = service.Choose(user.ID, encounter.ID, EncounterChoiceRequest{Choice: "dance"})
+	assert.EqualError(t, err, "invalid_choice")
+
+	result, err := service.Choose(user.ID, encounter.ID, EncounterChoiceRequest{Choice: "hand_over"})
+	require.NoError(t, err)
+	assert.True(t, result.Encounter.Resolved)
+	assert.Equal(t, "done", result.Encounter.State)
+	assert.Empty(t, result.Encounter.Choices)
+	require.Len(t, result.Effects, 2)
+	// New shirt auctioned for 200 * 0.5 resale * 0.7 = 70 towards the 100 loan
+	assert.Equal(t, 70.0, result.Effects[0].Amount)
+	assert.InDelta(t, 30, result.RemainingDebt, 0.01)
+
+	var items int64
+	require.NoError(t, service.db.Model(&model.UserItem{}).Where("user_id = ?", user.ID).Count(&items).Error)
+	assert.Equal(t, int64(0), items)
+
+	statuses, err := activeStatuses(service.db, user.ID)
+	require.NoError(t, err)
+	assert.True(t, statuses["in_underwear"])
+
+	_, err = service.Choose(user.ID, encounter.ID, EncounterChoiceRequest{Choice: "hand_over"})
+	assert.EqualError(t, err, "encounter_resolved")
+
+	// Outcome is recorded and a new encounter waits for the cooldown
+	history, err := service.GetEncounterHistory(user.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Len(t, history[0].Steps, 1)
+	assert.Equal(t, "hand_over", history[0].Steps[0].Choice)
+	assert.Equal(t, 1, history[0].Steps[0].ItemsLost)
+	assert.InDelta(t, -70, history[0].Steps[0].DebtChange, 0.01)
+
+	_, err = service.GetEncounter(user.ID)
+	assert.EqualError(t, err, "no_encounter")
+
+	var entries int64
+	require.NoError(t, service.db.Model(&model.LoanStatementEntry{}).
+		Where("loan_id = ? AND type = ?", loan.ID, model.LoanEntryCollection).Count(&entries).Error)
+	assert.Equal(t, int64(1), entries)
+}
+
+func TestCollectorEncounterStateEffects(t *testing.T) {
+	service, user, _ := setupCollectorTest(t)
+
+	encounter, err := service.GetEncounter(user.ID)
+	require.NoError(t, err)
+
+	// Fee of 20 then half of the 120 forgiven on entering the picnic state
+	result, err := service.Choose(user.ID, encounter.ID, EncounterChoiceRequest{Choice: "stall"})
+	require.NoError(t, err)
+	assert.True(t, result.Encounter.Resolved)
+	assert.InDelta(t, 60, result.RemainingDebt, 0.01)
+
+	// Other users can't touch the encounter
+	other := createTestUser(t, service.db, 10)
+	_, err = service.Choose(other.ID, encounter.ID, EncounterChoiceRequest{Choice: "stall"})
+	assert.EqualError(t, err, "encounter_not_found")
+}
+
+func TestCollectorEncounterRequiresCollection(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	service := &CollectorService{db: db}
+
+	_, err := service.GetEncounter(user.ID)
+	assert.EqualError(t, err, "no_encounter")
+}
+
+func TestForgiveLoanClosesInstallments(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	loans := &LoanService{db: db}
+
+	result, err := loans.TakeLoan(TakeLoanRequest{
+		UserID:        user.ID,
+		Amount:        400,
+		Type:          model.LoanTypeMicrocredit,
+		RepaymentPlan: model.LoanPlanAnnuity,
+		Installments:  4,
+	})
+	require.NoError(t, err)
+	loan := result.Loan
+	require.NoError(t, db.Model(&model.LoanInstallment{}).Where("loan_id = ? AND number = 1", loan.ID).
+		Update("status", model.InstallmentStatusMissed).Error)
+
+	forgiven, err := forgiveLoan(db, &loan, 1)
+	require.NoError(t, err)
+	assert.Greater(t, forgiven, 0.0)
+
+	var open int64
+	require.NoError(t, db.Model(&model.LoanInstallment{}).
+		Where("loan_id = ? AND status IN ?", loan.ID, []model.InstallmentStatus{model.InstallmentStatusPending, model.InstallmentStatusMissed}).
+		Count(&open).Error)
+	assert.Zero(t, open)
+
+	// The forgiven installments still weigh on the credit history
+	report, err := (&CreditService{db: db}).GetCreditReport(user.ID)
+	require.NoError(t, err)
+	var history string
+	for _, factor := range report.Factors {
+		if factor.Key == "repayment_history" {
+			history = factor.Explanation
+		}
+	}
+	assert.Contains(t, history, "4 paid late, missed or written off")
+}
@@ -0,0 +1,396 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// CreditService computes credit scores and the loan offers they unlock
+type CreditService struct {
+	db *gorm.DB
+}
+
+// NewCreditService creates a new credit service instance
+func NewCreditService() *CreditService {
+	return &CreditService{
+		db: database.GetDB(),
+	}
+}
+
+// Credit score range
+const (
+	MinCreditScore  = 300
+	MaxCreditScore  = 850
+	BaseCreditScore = 650
+
+	// creditHistoryWindow is how far back gambling and work income are compared
+	creditHistoryWindow = 30 * 24 * time.Hour
+	// recentBankruptcyWindow is how long a bankruptcy weighs at full penalty
+	recentBankruptcyWindow = 365 * 24 * time.Hour
+)
+
+// Credit score bands
+const (
+	CreditBandPoor      = "poor"
+	CreditBandFair      = "fair"
+	CreditBandGood      = "good"
+	CreditBandVeryGood  = "very_good"
+	CreditBandExcellent = "excellent"
+)
+
+// CreditFactor is one input of the credit score and how many points it contributed
+type CreditFactor struct {
+	Key         string  `json:"key"`
+	Points      int     `json:"points"`
+	Value       float64 `json:"value"`
+	Explanation string  `json:"explanation"`
+}
+
+// LoanOffer is what a user can borrow of one loan type at their score
+type LoanOffer struct {
+	Type       model.LoanType `json:"type"`
+	Eligible   bool           `json:"eligible"`
+	MinScore   int            `json:"min_score"` // Lowest score that qualifies at all
+	MaxAmount  float64        `json:"max_amount"`
+	APR        float64        `json:"apr"`
+	PenaltyAPR float64        `json:"penalty_apr"`
+}
+
+// CreditReport is a user's score with its contributing factors and loan offers
+type CreditReport struct {
+	UserID      uint           `json:"user_id"`
+	Score       int            `json:"score"`
+	Band        string         `json:"band"`
+	Factors     []CreditFactor `json:"factors"`
+	Offers      []LoanOffer    `json:"offers"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// Offer returns the offer for a loan type
+func (r *CreditReport) Offer(loanType model.LoanType) (LoanOffer, bool) {
+	for _, offer := range r.Offers {
+		if offer.Type == loanType {
+			return offer, true
+		}
+	}
+	return LoanOffer{}, false
+}
+
+// creditTier is the limit and rate granted from MinScore upwards
+type creditTier struct {
+	MinScore  int
+	MaxAmount float64
+	APR       float64
+}
+
+// creditTiers lists tiers per loan type, best first
+var creditTiers = map[model.LoanType][]creditTier{
+	model.LoanTypeFriends: {
+		{MinScore: 580, MaxAmount: FriendsMaxTotal, APR: FriendsInterestRate},
+		{MinScore: 450, MaxAmount: FriendsMaxTotal / 2, APR: FriendsInterestRate},
+	},
+	model.LoanTypeBank: {
+		{MinScore: 800, MaxAmount: 100000, APR: 0.07},
+		{MinScore: 740, MaxAmount: 50000, APR: 0.08},
+		{MinScore: 670, MaxAmount: 20000, APR: BankInterestRate},
+		{MinScore: 580, MaxAmount: 5000, APR: 0.15},
+	},
+	model.LoanTypeMicrocredit: {
+		{MinScore: 670, MaxAmount: 5000, APR: 1.0},
+		{MinScore: 580, MaxAmount: 2500, APR: 1.5},
+		{MinScore: MinCreditScore, MaxAmount: 1000, APR: MicrocreditInterestRate},
+	},
+}
+
+// creditLoanTypes fixes the order offers are listed in
+var creditLoanTypes = []model.LoanType{model.LoanTypeFriends, model.LoanTypeBank, model.LoanTypeMicrocredit}
+
+// creditBand returns the band name for a score
+func creditBand(score int) string {
+	switch {
+	case score >= 800:
+		return CreditBandExcellent
+	case score >= 740:
+		return CreditBandVeryGood
+	case score >= 670:
+		return CreditBandGood
+	case score >= 580:
+		return CreditBandFair
+	default:
+		return CreditBandPoor
+	}
+}
+
+// loanOffer picks the best tier of a loan type the score qualifies for
+func loanOffer(loanType model.LoanType, score int) LoanOffer {
+	tiers := creditTiers[loanType]
+	offer := LoanOffer{Type: loanType}
+	if len(tiers) > 0 {
+		offer.MinScore = tiers[len(tiers)-1].MinScore
+	}
+
+	for _, tier := range tiers {
+		if score >= tier.MinScore {
+			offer.Eligible = true
+			offer.MaxAmount = tier.MaxAmount
+			offer.APR = tier.APR
+			break
+		}
+	}
+
+	if terms, ok := GetLoanTerms(loanType); ok && offer.Eligible {
+		offer.PenaltyAPR = math.Max(terms.PenaltyAPR, offer.APR)
+	}
+	return offer
+}
+
+// GetCreditReport computes the user's current credit score
+func (s *CreditService) GetCreditReport(userID uint) (*CreditReport, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	now := time.Now()
+	factors := make([]CreditFactor, 0, 5)
+	for _, compute := range []func(*model.User, time.Time) (CreditFactor, error){
+		s.repaymentHistoryFactor,
+		s.utilisationFactor,
+		s.bankruptcyFactor,
+		s.gamblingFactor,
+		s.jailFactor,
+	} {
+		factor, err := compute(&user, now)
+		if err != nil {
+			return nil, err
+		}
+		factors = append(factors, factor)
+	}
+
+	score := BaseCreditScore
+	for _, factor := range factors {
+		score += factor.Points
+	}
+	if score < MinCreditScore {
+		score = MinCreditScore
+	}
+	if score > MaxCreditScore {
+		score = MaxCreditScore
+	}
+
+	report := &CreditReport{
+		UserID:      userID,
+		Score:       score,
+		Band:        creditBand(score),
+		Factors:     factors,
+		GeneratedAt: now,
+	}
+	for _, loanType := range creditLoanTypes {
+		report.Offers = append(report.Offers, loanOffer(loanType, score))
+	}
+
+	return report, nil
+}
+
+// repaymentHistoryFactor rewards installments paid on time and punishes late ones
+func (s *CreditService) repaymentHistoryFactor(user *model.User, now time.Time) (CreditFactor, error) {
+	var counts struct {
+		OnTime int
+		Late   int
+	}
+	err := s.db.Table("loan_installments").
+		Select(`COALESCE(SUM(CASE WHEN loan_installments.status = ? AND loan_installments.paid_at <= loan_installments.due_at THEN 1 ELSE 0 END), 0) AS on_time,
+			COALESCE(SUM(CASE WHEN loan_installments.status IN ? OR (loan_installments.status = ? AND loan_installments.paid_at > loan_installments.due_at) THEN 1 ELSE 0 END), 0) AS late`,
+			model.InstallmentStatusPaid, []model.InstallmentStatus{model.InstallmentStatusMissed, model.InstallmentStatusWrittenOff}, model.InstallmentStatusPaid).
+		Joins("JOIN loans ON loans.id = loan_installments.loan_id").
+		Where("loans.user_id = ?", user.ID).
+		Scan(&counts).Error
+	if err != nil {
+		return CreditFactor{}, fmt.Errorf("failed to read repayment history: %w", err)
+	}
+
+	points := min(counts.OnTime*3, 60) - min(counts.Late*25, 150)
+
+	explanation := "No repayment history yet"
+	if counts.OnTime+counts.Late > 0 {
+		explanation = fmt.Sprintf("%d installment(s) paid on time, %d paid late, missed or written off", counts.OnTime, counts.Late)
+	}
+
+	return CreditFactor{
+		Key:         "repayment_history",
+		Points:      points,
+		Value:       float64(counts.Late),
+		Explanation: explanation,
+	}, nil
+}
+
+// utilisationFactor compares current debt with everything the user owns
+func (s *CreditService) utilisationFactor(user *model.User, now time.Time) (CreditFactor, error) {
+	var debt float64
+	if err := s.db.Model(&model.Loan{}).
+		Where("user_id = ?", user.ID).
+		Select("COALESCE(SUM(remaining_amount), 0)").
+		Scan(&debt).Error; err != nil {
+		return CreditFactor{}, fmt.Errorf("failed to read debt: %w", err)
+	}
+
+	if debt <= 0 {
+		return CreditFactor{
+			Key:         "utilisation",
+			Points:      10,
+			Explanation: "No outstanding debt",
+		}, nil
+	}
+
+	// Items count at their resale value, like bank collateral
+	var itemValue float64
+	if err := s.db.Table("user_items").
+		Joins("JOIN items ON items.id = user_items.item_id").
+		Where("user_items.user_id = ?", user.ID).
+		Select("COALESCE(SUM(items.price), 0) * 0.5").
+		Scan(&itemValue).Error; err != nil {
+		return CreditFactor{}, fmt.Errorf("failed to read item value: %w", err)
+	}
+
+	assets := math.Max(user.Balance, 0) + itemValue
+	utilisation := debt / (debt + assets)
+
+	var points int
+	switch {
+	case utilisation < 0.3:
+		points = 20
+	case utilisation < 0.6:
+		points = 0
+	case utilisation < 0.9:
+		points = -40
+	default:
+		points = -80
+	}
+
+	return CreditFactor{
+		Key:         "utilisation",
+		Points:      points,
+		Value:       math.Round(utilisation*1000) / 1000,
+		Explanation: fmt.Sprintf("Debt of $%.2f is %.0f%% of your debt plus assets", debt, utilisation*100),
+	}, nil
+}
+
+// bankruptcyFactor punishes past bankruptcies, recent ones hardest
+func (s *CreditService) bankruptcyFactor(user *model.User, now time.Time) (CreditFactor, error) {
+	var bankruptcies []model.Bankruptcy
+	if err := s.db.Where("user_id = ?", user.ID).Find(&bankruptcies).Error; err != nil {
+		return CreditFactor{}, fmt.Errorf("failed to read bankruptcies: %w", err)
+	}
+
+	recent := 0
+	for _, b := range bankruptcies {
+		if now.Sub(b.CreatedAt) < recentBankruptcyWindow {
+			recent++
+		}
+	}
+	older := len(bankruptcies) - recent
+
+	points := -min(recent*120+older*40, 250)
+
+	explanation := "No bankruptcies on record"
+	if len(bankruptcies) > 0 {
+		explanation = fmt.Sprintf("%d bankruptcy(ies) in the last year, %d older", recent, older)
+	}
+
+	return CreditFactor{
+		Key:         "bankruptcies",
+		Points:      points,
+		Value:       float64(len(bankruptcies)),
+		Explanation: explanation,
+	}, nil
+}
+
+// gamblingFactor compares net gambling losses with work income over the last 30 days
+func (s *CreditService) gamblingFactor(user *model.User, now time.Time) (CreditFactor, error) {
+	since := now.Add(-creditHistoryWindow)
+
+	var losses float64
+	if err := s.db.Model(&model.GameSession{}).
+		Where("user_id = ? AND created_at >= ?", user.ID, since).
+		Select("COALESCE(SUM(bet - win), 0)").
+		Scan(&losses).Error; err != nil {
+		return CreditFactor{}, fmt.Errorf("failed to read gambling losses: %w", err)
+	}
+
+	var income float64
+	if err := s.db.Model(&model.WorkSession{}).
+		Where("user_id = ? AND completed_at >= ?", user.ID, since).
+		Select("COALESCE(SUM(earned), 0)").
+		Scan(&income).Error; err != nil {
+		return CreditFactor{}, fmt.Errorf("failed to read work income: %w", err)
+	}
+
+	if losses <= 0 {
+		return CreditFactor{
+			Key:         "gambling_vs_income",
+			Points:      0,
+			Explanation: "No net gambling losses in the last 30 days",
+		}, nil
+	}
+
+	ratio := math.Inf(1)
+	if income > 0 {
+		ratio = losses / income
+	}
+
+	var points int
+	switch {
+	case ratio < 0.25:
+		points = 10
+	case ratio < 1:
+		points = -10
+	case ratio < 3:
+		points = -40
+	default:
+		points = -80
+	}
+
+	explanation := fmt.Sprintf("Lost $%.2f gambling with no work income in the last 30 days", losses)
+	value := -1.0 // No income to compare against
+	if income > 0 {
+		explanation = fmt.Sprintf("Lost $%.2f gambling against $%.2f earned from work in the last 30 days", losses, income)
+		value = math.Round(ratio*100) / 100
+	}
+
+	return CreditFactor{
+		Key:         "gambling_vs_income",
+		Points:      points,
+		Value:       value,
+		Explanation: explanation,
+	}, nil
+}
+
+// jailFactor punishes users currently serving a sentence
+func (s *CreditService) jailFactor(user *model.User, now time.Time) (CreditFactor, error) {
+	var count int64
+	if err := s.db.Model(&model.UserStatus{}).
+		Where("user_id = ? AND status = ? AND expires_at > ?", user.ID, "in_jail", now).
+		Count(&count).Error; err != nil {
+		return CreditFactor{}, fmt.Errorf("failed to read jail status: %w", err)
+	}
+
+	if count == 0 {
+		return CreditFactor{
+			Key:         "jail",
+			Points:      0,
+			Explanation: "Not in jail",
+		}, nil
+	}
+
+	return CreditFactor{
+		Key:         "jail",
+		Points:      -100,
+		Value:       1,
+		Explanation: "Currently serving a jail sentence",
+	}, nil
+}
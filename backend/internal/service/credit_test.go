@@ -0,0 +1,127 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findFactor(t *testing.T, report *CreditReport, key string) CreditFactor {
+	for _, factor := range report.Factors {
+		if factor.Key == key {
+			return factor
+		}
+	}
+	t.Fatalf("factor %s not in report", key)
+	return CreditFactor{}
+}
+
+func TestCreditReportNewUser(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &CreditService{db: db}
+
+	report, err := service.GetCreditReport(user.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, 660, report.Score)
+	assert.Equal(t, CreditBandFair, report.Band)
+	assert.Len(t, report.Factors, 5)
+	assert.Equal(t, 10, findFactor(t, report, "utilisation").Points)
+
+	bank, ok := report.Offer(model.LoanTypeBank)
+	require.True(t, ok)
+	assert.True(t, bank.Eligible)
+	assert.Equal(t, 5000.0, bank.MaxAmount)
+	assert.Equal(t, 0.15, bank.APR)
+	assert.Equal(t, 0.25, bank.PenaltyAPR)
+
+	micro, _ := report.Offer(model.LoanTypeMicrocredit)
+	assert.Equal(t, 2500.0, micro.MaxAmount)
+	assert.Equal(t, 1.5, micro.APR)
+}
+
+func TestCreditReportNegativeFactors(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &CreditService{db: db}
+
+	require.NoError(t, db.Create(&model.UserStatus{UserID: user.ID, Status: "in_jail", ExpiresAt: time.Now().Add(time.Hour)}).Error)
+	require.NoError(t, db.Create(&model.Bankruptcy{UserID: user.ID, DebtWrittenOff: 500}).Error)
+	require.NoError(t, db.Create(&model.GameSession{UserID: user.ID, GameType: model.GameTypeSlots, Bet: 300, Win: 0}).Error)
+
+	report, err := service.GetCreditReport(user.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, -100, findFactor(t, report, "jail").Points)
+	assert.Equal(t, -120, findFactor(t, report, "bankruptcies").Points)
+	assert.Equal(t, -80, findFactor(t, report, "gambling_vs_income").Points)
+	assert.Equal(t, 360, report.Score)
+	assert.Equal(t, CreditBandPoor, report.Band)
+
+	friends, _ := report.Offer(model.LoanTypeFriends)
+	assert.False(t, friends.Eligible)
+	bank, _ := report.Offer(model.LoanTypeBank)
+	assert.False(t, bank.Eligible)
+	micro, _ := report.Offer(model.LoanTypeMicrocredit)
+	assert.True(t, micro.Eligible)
+	assert.Equal(t, 1000.0, micro.MaxAmount)
+	assert.Equal(t, MicrocreditInterestRate, micro.APR)
+
+	// TakeLoan follows the report
+	loans := &LoanService{db: db}
+	_, err = loans.TakeLoan(TakeLoanRequest{UserID: user.ID, Amount: 100, Type: model.LoanTypeFriends})
+	assert.EqualError(t, err, "friends_refused")
+	_, err = loans.TakeLoan(TakeLoanRequest{UserID: user.ID, Amount: 1500, Type: model.LoanTypeMicrocredit})
+	assert.EqualError(t, err, "credit_limit_exceeded")
+}
+
+func TestCreditReportRepaymentHistory(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &CreditService{db: db}
+
+	loan := model.Loan{UserID: user.ID, Type: model.LoanTypeBank, PrincipalAmount: 100, RemainingAmount: 0, LastInterestAt: time.Now()}
+	require.NoError(t, db.Create(&loan).Error)
+
+	due := time.Now().Add(-48 * time.Hour)
+	early := due.Add(-time.Hour)
+	late := due.Add(time.Hour)
+	require.NoError(t, db.Create(&[]model.LoanInstallment{
+		{LoanID: loan.ID, Number: 1, DueAt: due, AmountDue: 10, Status: model.InstallmentStatusPaid, PaidAt: &early},
+		{LoanID: loan.ID, Number: 2, DueAt: due, AmountDue: 10, Status: model.InstallmentStatusPaid, PaidAt: &late},
+		{LoanID: loan.ID, Number: 3, DueAt: due, AmountDue: 10, Status: model.InstallmentStatusMissed},
+	}).Error)
+
+	report, err := service.GetCreditReport(user.ID)
+	require.NoError(t, err)
+
+	factor := findFactor(t, report, "repayment_history")
+	assert.Equal(t, 3-50, factor.Points)
+	assert.Equal(t, 2.0, factor.Value)
+}
+
+func TestLoanLimitCoversOutstandingLoansOfType(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 0)
+	loans := &LoanService{db: db}
+
+	_, err := loans.TakeLoan(TakeLoanRequest{UserID: user.ID, Amount: 100, Type: model.LoanTypeMicrocredit})
+	require.NoError(t, err)
+
+	report, err := (&CreditService{db: db}).GetCreditReport(user.ID)
+	require.NoError(t, err)
+	micro, _ := report.Offer(model.LoanTypeMicrocredit)
+	require.True(t, micro.Eligible)
+
+	// A second loan of the full limit would stack past it
+	_, err = loans.TakeLoan(TakeLoanRequest{UserID: user.ID, Amount: micro.MaxAmount, Type: model.LoanTypeMicrocredit})
+	assert.EqualError(t, err, "credit_limit_exceeded")
+
+	// Up to what is left of the limit is fine
+	_, err = loans.TakeLoan(TakeLoanRequest{UserID: user.ID, Amount: micro.MaxAmount - 100, Type: model.LoanTypeMicrocredit})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,498 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// Data export parameters
+const (
+	DataExportDir        = "./data/exports" // Where archives are written, next to the database
+	DataExportExpiry     = 24 * time.Hour   // How long a ready archive can be downloaded
+	DataExportStaleAfter = 10 * time.Minute // A build running this long is assumed dead and retried
+	dataExportListLimit  = 10               // Past exports listed to the user
+	dataExportURLPrefix  = "/api/exports/"  // Download links are this plus the token
+	dataExportTimeLayout = time.RFC3339Nano // Times in CSV files
+)
+
+// dataExportSecretColumns are never exported, whichever table they are in
+var dataExportSecretColumns = map[string]bool{
+	"password_hash":  true,
+	"high_bidder_id": true, // Another user, and bidders are anonymous
+}
+
+// dataExportTable is one table of a user's data in the archive
+type dataExportTable struct {
+	name  string // Key in data.json and name of the CSV file
+	query func(db *gorm.DB, user *model.User) *gorm.DB
+
+	// Columns holding the other party's user ID on rows the user shares with
+	// someone, left empty unless they hold the user's own ID
+	counterparties []string
+}
+
+// byUserID selects a table's rows that belong to the user
+func byUserID(table string) func(db *gorm.DB, user *model.User) *gorm.DB {
+	return func(db *gorm.DB, user *model.User) *gorm.DB {
+		return db.Table(table).Where("user_id = ?", user.ID)
+	}
+}
+
+// dataExportTables lists everything held about a user. Soft-deleted rows are
+// included: they are still held. Left out on purpose are the tables that only
+// hold credentials or their hashes (auth_sessions, refresh_tokens,
+// email_tokens, two_factor_settings, recovery_codes, two_factor_challenges), the
+// outbox, whose events repeat the rows below and are pruned within a week,
+// and data_exports and account_deletions, which are the requests themselves.
+var dataExportTables = []dataExportTable{
+	{name: "profile", query: func(db *gorm.DB, user *model.User) *gorm.DB {
+		return db.Table("users").Where("id = ?", user.ID)
+	}},
+	{name: "transactions", query: byUserID("transactions")},
+	{name: "game_sessions", query: byUserID("game_sessions")},
+	{name: "roulette_results", query: byUserID("roulette_results")},
+	{name: "work_sessions", query: byUserID("work_sessions")},
+	{name: "items", query: byUserID("user_items")},
+	{name: "loans", query: byUserID("loans")},
+	{name: "loan_installments", query: func(db *gorm.DB, user *model.User) *gorm.DB {
+		return db.Table("loan_installments").Where("loan_id IN (?)", db.Table("loans").Select("id").Where("user_id = ?", user.ID))
+	}},
+	{name: "loan_statement_entries", query: byUserID("loan_statement_entries")},
+	{name: "bankruptcies", query: byUserID("bankruptcies")},
+	{name: "collection_events", query: byUserID("collection_events")},
+	{name: "collector_encounters", query: byUserID("collector_encounters")},
+	{name: "collector_encounter_steps", query: func(db *gorm.DB, user *model.User) *gorm.DB {
+		return db.Table("collector_encounter_steps").Where("encounter_id IN (?)", db.Table("collector_encounters").Select("id").Where("user_id = ?", user.ID))
+	}},
+	{name: "careers", query: byUserID("careers")},
+	{name: "market_listings", query: func(db *gorm.DB, user *model.User) *gorm.DB {
+		return db.Table("market_listings").Where("seller_id = ? OR buyer_id = ?", user.ID, user.ID)
+	}, counterparties: []string{"seller_id", "buyer_id"}},
+	{name: "auction_bids", query: byUserID("auction_bids")},
+	{name: "seized_auctions", query: func(db *gorm.DB, user *model.User) *gorm.DB {
+		return db.Table("auctions").Where("former_owner_id = ?", user.ID)
+	}},
+	{name: "statuses", query: byUserID("user_statuses")},
+	{name: "achievements", query: byUserID("user_achievements")},
+	{name: "challenges", query: byUserID("user_challenges")},
+	{name: "leaderboard_entries", query: byUserID("leaderboard_entries")},
+	{name: "notifications", query: byUserID("notifications")},
+	{name: "gambling_limits", query: byUserID("gambling_limits")},
+	{name: "gambling_sessions", query: byUserID("gambling_sessions")},
+	{name: "open_stakes", query: byUserID("open_stakes")},
+	{name: "reality_checks", query: byUserID("reality_checks")},
+	{name: "contact_messages", query: func(db *gorm.DB, user *model.User) *gorm.DB {
+		// The contact form isn't tied to an account, so messages are matched by email
+		return db.Table("contact_messages").Where("email = ?", user.Email)
+	}},
+}
+
+// DataExportService builds downloadable archives of a user's data
+type DataExportService struct {
+	db  *gorm.DB
+	dir string
+	now func() time.Time
+}
+
+// NewDataExportService creates a new data export service instance
+func NewDataExportService() *DataExportService {
+	return &DataExportService{
+		db:  database.GetDB(),
+		dir: DataExportDir,
+	}
+}
+
+// clock returns the current time
+func (s *DataExportService) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// DataExportResponse is a data export, with its download link when it has
+// just been issued. Only the token's hash is stored, so the link is shown once.
+type DataExportResponse struct {
+	model.DataExport
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// newExportToken returns a random download token
+func newExportToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate export token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// RequestExport queues an export of the user's data. If one is already
+// queued or being built, that one is returned instead; created reports which.
+func (s *DataExportService) RequestExport(userID uint) (*DataExportResponse, bool, error) {
+	var user model.User
+	if err := s.db.Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, errors.New("user_not_found")
+		}
+		return nil, false, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	token, err := newExportToken()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Asking again while an export is queued replaces its link, which can't be
+	// shown again, rather than building another
+	var export model.DataExport
+	err = s.db.Where("user_id = ? AND status IN ?", userID,
+		[]model.DataExportStatus{model.DataExportStatusPending, model.DataExportStatusBuilding}).
+		First(&export).Error
+	if err == nil {
+		if err := s.db.Model(&export).Update("token_hash", hashToken(token)).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to replace download link: %w", err)
+		}
+		return &DataExportResponse{DataExport: export, DownloadURL: dataExportURLPrefix + token}, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to get data exports: %w", err)
+	}
+
+	export = model.DataExport{
+		UserID:    userID,
+		Status:    model.DataExportStatusPending,
+		TokenHash: hashToken(token),
+		CreatedAt: s.clock(),
+	}
+	if err := s.db.Create(&export).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	return &DataExportResponse{DataExport: export, DownloadURL: dataExportURLPrefix + token}, true, nil
+}
+
+// GetExports returns the user's most recent data exports, newest first
+func (s *DataExportService) GetExports(userID uint) ([]DataExportResponse, error) {
+	var exports []model.DataExport
+	if err := s.db.Where("user_id = ?", userID).Order("id DESC").Limit(dataExportListLimit).
+		Find(&exports).Error; err != nil {
+		return nil, fmt.Errorf("failed to get data exports: %w", err)
+	}
+
+	responses := make([]DataExportResponse, len(exports))
+	for i := range exports {
+		responses[i] = DataExportResponse{DataExport: exports[i]}
+	}
+	return responses, nil
+}
+
+// OpenExport returns a ready export by its download token
+func (s *DataExportService) OpenExport(token string) (*model.DataExport, error) {
+	var export model.DataExport
+	if err := s.db.Where("token_hash = ?", hashToken(token)).First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("export_not_found")
+		}
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+
+	switch export.Status {
+	case model.DataExportStatusReady:
+		if export.ExpiresAt != nil && !s.clock().Before(*export.ExpiresAt) {
+			return nil, errors.New("export_expired")
+		}
+		return &export, nil
+	case model.DataExportStatusExpired:
+		return nil, errors.New("export_expired")
+	case model.DataExportStatusFailed:
+		return nil, errors.New("export_failed")
+	default:
+		return nil, errors.New("export_not_ready")
+	}
+}
+
+// BuildPendingExports builds every queued export, and retries builds that
+// have stalled, returning how many archives were written
+func (s *DataExportService) BuildPendingExports() (int, error) {
+	now := s.clock()
+
+	var exports []model.DataExport
+	if err := s.db.Where("status = ? OR (status = ? AND started_at < ?)",
+		model.DataExportStatusPending, model.DataExportStatusBuilding, now.Add(-DataExportStaleAfter).Local()).
+		Order("id").Find(&exports).Error; err != nil {
+		return 0, fmt.Errorf("failed to find pending data exports: %w", err)
+	}
+
+	count := 0
+	for i := range exports {
+		export := &exports[i]
+
+		// Claim the export so a build started elsewhere isn't repeated
+		result := s.db.Model(&model.DataExport{}).
+			Where("id = ? AND status = ? AND (started_at IS NULL OR started_at < ?)",
+				export.ID, export.Status, now.Add(-DataExportStaleAfter).Local()).
+			Updates(map[string]interface{}{"status": model.DataExportStatusBuilding, "started_at": now})
+		if result.Error != nil {
+			return count, fmt.Errorf("failed to claim data export: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		if err := s.buildExport(export); err != nil {
+			if err := s.db.Model(export).Updates(map[string]interface{}{
+				"status": model.DataExportStatusFailed,
+				"error":  err.Error(),
+			}).Error; err != nil {
+				return count, fmt.Errorf("failed to mark data export failed: %w", err)
+			}
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// buildExport writes an export's archive and marks it ready
+func (s *DataExportService) buildExport(export *model.DataExport) error {
+	var user model.User
+	if err := s.db.Unscoped().First(&user, export.UserID).Error; err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("export-%d.zip", export.ID))
+	size, err := s.writeArchive(path, &user)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	now := s.clock()
+	expiresAt := now.Add(DataExportExpiry)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(export).Updates(map[string]interface{}{
+			"status":       model.DataExportStatusReady,
+			"file_path":    path,
+			"size":         size,
+			"completed_at": now,
+			"expires_at":   expiresAt,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to mark data export ready: %w", err)
+		}
+		return events.Record(tx, events.DataExportReady{
+			UserID:    export.UserID,
+			ExportID:  export.ID,
+			Size:      size,
+			ExpiresAt: expiresAt,
+		})
+	})
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	flushEvents(s.db)
+	return nil
+}
+
+// dataExportDocument is the layout of data.json
+type dataExportDocument struct {
+	ExportedAt time.Time                           `json:"exported_at"`
+	UserID     uint                                `json:"user_id"`
+	Tables     map[string][]map[string]interface{} `json:"tables"`
+}
+
+// writeArchive writes a zip of the user's data as data.json plus a CSV file
+// per table to path, returning its size
+func (s *DataExportService) writeArchive(path string, user *model.User) (int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export archive: %w", err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	document := dataExportDocument{
+		ExportedAt: s.clock(),
+		UserID:     user.ID,
+		Tables:     make(map[string][]map[string]interface{}, len(dataExportTables)),
+	}
+
+	for _, table := range dataExportTables {
+		columns, rows, err := s.readTable(table, user)
+		if err != nil {
+			return 0, err
+		}
+
+		records := make([]map[string]interface{}, len(rows))
+		for i, row := range rows {
+			records[i] = make(map[string]interface{}, len(columns))
+			for j, column := range columns {
+				records[i][column] = row[j]
+			}
+		}
+		document.Tables[table.name] = records
+
+		w, err := archive.Create(table.name + ".csv")
+		if err != nil {
+			return 0, fmt.Errorf("failed to add %s to export archive: %w", table.name, err)
+		}
+		if err := writeExportCSV(w, columns, rows); err != nil {
+			return 0, fmt.Errorf("failed to write %s to export archive: %w", table.name, err)
+		}
+	}
+
+	w, err := archive.Create("data.json")
+	if err != nil {
+		return 0, fmt.Errorf("failed to add data.json to export archive: %w", err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return 0, fmt.Errorf("failed to write data.json to export archive: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write export archive: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to write export archive: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// readTable reads the user's rows of a table in column order, leaving out
+// secrets and other users' IDs
+func (s *DataExportService) readTable(table dataExportTable, user *model.User) ([]string, [][]interface{}, error) {
+	rows, err := table.query(s.db, user).Order("id").Rows()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	defer rows.Close()
+
+	all, err := rows.Columns()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	var columns []string
+	var keep []int
+	counterparty := make(map[int]bool)
+	for i, column := range all {
+		if !dataExportSecretColumns[column] {
+			columns = append(columns, column)
+			keep = append(keep, i)
+		}
+		for _, name := range table.counterparties {
+			if column == name {
+				counterparty[i] = true
+			}
+		}
+	}
+	userID := strconv.FormatUint(uint64(user.ID), 10)
+
+	var result [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(all))
+		pointers := make([]interface{}, len(all))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+		}
+
+		row := make([]interface{}, len(keep))
+		for i, j := range keep {
+			row[i] = exportValue(values[j])
+			if counterparty[j] && fmt.Sprint(row[i]) != userID {
+				row[i] = nil
+			}
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	return columns, result, nil
+}
+
+// exportValue turns a scanned column into a JSON-friendly value
+func exportValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case sql.RawBytes:
+		return string(v)
+	}
+	return value
+}
+
+// writeExportCSV writes rows as CSV with a header line
+func writeExportCSV(w io.Writer, columns []string, rows [][]interface{}) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, value := range row {
+			switch v := value.(type) {
+			case nil:
+				record[i] = ""
+			case time.Time:
+				record[i] = v.UTC().Format(dataExportTimeLayout)
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// ExpireExports deletes archives whose download link has expired, returning how many
+func (s *DataExportService) ExpireExports() (int, error) {
+	var exports []model.DataExport
+	if err := s.db.Where("status = ? AND expires_at <= ?", model.DataExportStatusReady, s.clock().Local()).
+		Find(&exports).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired data exports: %w", err)
+	}
+
+	for i := range exports {
+		if err := os.Remove(exports[i].FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return i, fmt.Errorf("failed to delete export archive: %w", err)
+		}
+		if err := s.db.Model(&exports[i]).Updates(map[string]interface{}{
+			"status":    model.DataExportStatusExpired,
+			"file_path": "",
+		}).Error; err != nil {
+			return i, fmt.Errorf("failed to expire data export: %w", err)
+		}
+	}
+	return len(exports), nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataExport(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	other := createTestUser(t, db, 1000)
+	require.NoError(t, db.Model(user).Update("password_hash", "secret-hash").Error)
+	addTransactions(t, db, user.ID, model.TransactionTypeGameLoss, 10, 20)
+	addTransactions(t, db, other.ID, model.TransactionTypeGameLoss, 30)
+	require.NoError(t, db.Create(&model.ContactMessage{Name: "Me", Email: user.Email, Message: "Hello, 'world'"}).Error)
+	encounter := model.CollectorEncounter{UserID: user.ID, ScenarioID: "knock", State: "door"}
+	require.NoError(t, db.Create(&encounter).Error)
+	require.NoError(t, db.Create(&model.CollectorEncounterStep{EncounterID: encounter.ID, FromState: "door", Choice: "pay", ToState: "paid"}).Error)
+	require.NoError(t, db.Create(&model.MarketListing{SellerID: other.ID, BuyerID: &user.ID, UserItemID: 1, ItemID: 1, Price: 50, Status: model.ListingStatusSold}).Error)
+	require.NoError(t, db.Create(&model.MarketListing{SellerID: user.ID, BuyerID: &other.ID, UserItemID: 2, ItemID: 1, Price: 60, Status: model.ListingStatusSold}).Error)
+
+	now := time.Now()
+	service := &DataExportService{db: db, dir: t.TempDir(), now: func() time.Time { return now }}
+
+	export, created, err := service.RequestExport(user.ID)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, model.DataExportStatusPending, export.Status)
+	require.True(t, strings.HasPrefix(export.DownloadURL, "/api/exports/"))
+	token := strings.TrimPrefix(export.DownloadURL, "/api/exports/")
+
+	// Only the token's hash is stored
+	var stored model.DataExport
+	require.NoError(t, db.First(&stored, export.ID).Error)
+	assert.Equal(t, hashToken(token), stored.TokenHash)
+
+	// Asking again while it is queued returns the same export with a new link
+	again, created, err := service.RequestExport(user.ID)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, export.ID, again.ID)
+	_, err = service.OpenExport(token)
+	assert.EqualError(t, err, "export_not_found")
+	token = strings.TrimPrefix(again.DownloadURL, "/api/exports/")
+	_, err = service.OpenExport(token)
+	assert.EqualError(t, err, "export_not_ready")
+
+	count, err := service.BuildPendingExports()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	exports, err := service.GetExports(user.ID)
+	require.NoError(t, err)
+	require.Len(t, exports, 1)
+	assert.Equal(t, model.DataExportStatusReady, exports[0].Status)
+	assert.Empty(t, exports[0].DownloadURL, "the link is only shown when issued")
+	assert.WithinDuration(t, now.Add(DataExportExpiry), *exports[0].ExpiresAt, time.Second)
+
+	ready, err := service.OpenExport(token)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("export-%d.zip", export.ID), filepath.Base(ready.FilePath), "named without the token")
+	archive, err := zip.OpenReader(ready.FilePath)
+	require.NoError(t, err)
+	defer archive.Close()
+
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	for _, table := range dataExportTables {
+		assert.Contains(t, files, table.name+".csv")
+	}
+
+	f, err := files["data.json"].Open()
+	require.NoError(t, err)
+	var document struct {
+		UserID uint                                `json:"user_id"`
+		Tables map[string][]map[string]interface{} `json:"tables"`
+	}
+	require.NoError(t, json.NewDecoder(f).Decode(&document))
+	f.Close()
+	assert.Equal(t, user.ID, document.UserID)
+	assert.Len(t, document.Tables["transactions"], 2, "only the user's own rows")
+	assert.Len(t, document.Tables["contact_messages"], 1)
+	assert.Len(t, document.Tables["collector_encounter_steps"], 1)
+	listings := document.Tables["market_listings"]
+	require.Len(t, listings, 2, "purchases as well as sales")
+	assert.Nil(t, listings[0]["seller_id"], "the other party isn't named")
+	assert.EqualValues(t, user.ID, listings[0]["buyer_id"])
+	assert.EqualValues(t, user.ID, listings[1]["seller_id"])
+	assert.Nil(t, listings[1]["buyer_id"])
+	require.Len(t, document.Tables["profile"], 1)
+	assert.Equal(t, user.Email, document.Tables["profile"][0]["email"])
+	assert.NotContains(t, document.Tables["profile"][0], "password_hash")
+
+	f, err = files["profile.csv"].Open()
+	require.NoError(t, err)
+	records, err := csv.NewReader(f).ReadAll()
+	f.Close()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Contains(t, records[0], "email")
+	assert.NotContains(t, records[0], "password_hash")
+
+	// Once the link expires the archive is deleted
+	now = now.Add(DataExportExpiry)
+	_, err = service.OpenExport(token)
+	assert.EqualError(t, err, "export_expired")
+	count, err = service.ExpireExports()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	_, err = os.Stat(ready.FilePath)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = service.OpenExport("nope")
+	assert.EqualError(t, err, "export_not_found")
+}
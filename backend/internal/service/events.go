@@ -0,0 +1,25 @@
+package service
+
+import (
+	"log"
+
+	"github.com/smoreg/freezino/backend/internal/events"
+	"gorm.io/gorm"
+)
+
+// flushEvents hands committed outbox events to the relay. A failure is left
+// to the outbox relay job rather than failing the request that raised them.
+func flushEvents(db *gorm.DB) {
+	events.Signal(db)
+}
+
+// PublishEvent records an event for a change made outside a transaction and
+// hands it to the relay. Failures are logged; the change itself has already
+// happened.
+func PublishEvent(db *gorm.DB, event events.Event) {
+	if err := events.Record(db, event); err != nil {
+		log.Printf("Failed to publish %s event: %v", event.EventName(), err)
+		return
+	}
+	flushEvents(db)
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OwnershipCosts describes how an item type loses value and what it costs to keep
+type OwnershipCosts struct {
+	ResaleRate        float64 // Share of the price a brand new item resells for
+	DailyDepreciation float64 // Share of the remaining value lost per day of ownership
+	FloorRate         float64 // Share of the price the resale value never drops below
+	DailyUpkeep       float64 // Share of the price charged per day of ownership
+}
+
+// Ownership costs by item type. Unlisted types resell at a flat 50%.
+var ownershipCosts = map[model.ItemType]OwnershipCosts{
+	model.ItemTypeClothing:    {ResaleRate: 0.5, DailyDepreciation: 0.03, FloorRate: 0.05},
+	model.ItemTypeAccessories: {ResaleRate: 0.6, DailyDepreciation: 0.005, FloorRate: 0.3},
+	model.ItemTypeCar:         {ResaleRate: 0.7, DailyDepreciation: 0.01, FloorRate: 0.1, DailyUpkeep: 0.003},
+	model.ItemTypeHouse:       {ResaleRate: 0.9, DailyDepreciation: 0.001, FloorRate: 0.6, DailyUpkeep: 0.001},
+}
+
+// Condition and maintenance parameters
+const (
+	BrokenItemResaleRate = 0.5            // A broken item resells for half of what a mint one does
+	RepairCostRate       = 0.3            // Fully repairing an item costs 30% of its price
+	UpkeepPeriod         = 24 * time.Hour // Upkeep is charged per whole day of ownership
+	UpkeepNeglectWear    = 10.0           // Durability lost per day of upkeep the owner could not pay
+)
+
+// costsFor returns the ownership costs of an item type
+func costsFor(itemType model.ItemType) OwnershipCosts {
+	if costs, ok := ownershipCosts[itemType]; ok {
+		return costs
+	}
+	return OwnershipCosts{ResaleRate: 0.5, FloorRate: 0.5}
+}
+
+// ResaleValue returns what the shop pays for a user's item: its price
+// depreciated by age along the type's curve, then reduced for wear.
+func ResaleValue(userItem *model.UserItem, now time.Time) float64 {
+	costs := costsFor(userItem.Item.Type)
+	days := max(now.Sub(userItem.PurchasedAt).Hours()/24, 0)
+	rate := max(costs.ResaleRate*math.Pow(1-costs.DailyDepreciation, days), costs.FloorRate)
+
+	condition := userItem.Durability / model.ItemDurabilityMax
+	conditionRate := BrokenItemResaleRate + (1-BrokenItemResaleRate)*condition
+
+	return roundMoney(userItem.Item.Price * rate * conditionRate)
+}
+
+// RepairCost returns what it costs to restore a user's item to mint condition
+func RepairCost(userItem *model.UserItem) float64 {
+	damage := (model.ItemDurabilityMax - userItem.Durability) / model.ItemDurabilityMax
+	return roundMoney(userItem.Item.Price * RepairCostRate * damage)
+}
+
+// wearEquippedItems takes durability off the user's equipped items of a type.
+// Items that break are unequipped.
+func wearEquippedItems(tx *gorm.DB, userID uint, itemType model.ItemType, wear float64) error {
+	equipped := tx.Model(&model.UserItem{}).
+		Where("user_id = ? AND is_equipped = ? AND item_id IN (SELECT id FROM items WHERE type = ?)", userID, true, itemType)
+
+	if err := equipped.Session(&gorm.Session{}).
+		Update("durability", gorm.Expr("MAX(durability - ?, 0)", wear)).Error; err != nil {
+		return fmt.Errorf("failed to wear %s: %w", itemType, err)
+	}
+	if err := equipped.Session(&gorm.Session{}).
+		Where("durability <= 0").
+		Update("is_equipped", false).Error; err != nil {
+		return fmt.Errorf("failed to unequip broken %s: %w", itemType, err)
+	}
+	return nil
+}
+
+// RepairItemResponse represents the response after repairing an item
+type RepairItemResponse struct {
+	UserItem      UserItemResponse `json:"user_item"`
+	Cost          float64          `json:"cost"`
+	NewBalance    float64          `json:"new_balance"`
+	TransactionID uint             `json:"transaction_id"`
+}
+
+// RepairItem restores a user's item to mint condition for its repair cost
+func (s *ShopService) RepairItem(userID uint, userItemID uint) (*RepairItemResponse, error) {
+	var response *RepairItemResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("user not found")
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		var userItem model.UserItem
+		if err := tx.Preload("Item").Where("id = ? AND user_id = ?", userItemID, userID).First(&userItem).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("user item not found or does not belong to user")
+			}
+			return fmt.Errorf("failed to get user item: %w", err)
+		}
+
+		if userItem.IsListed {
+			return fmt.Errorf("item is listed on the market")
+		}
+		if userItem.Durability >= model.ItemDurabilityMax {
+			return fmt.Errorf("item is not damaged")
+		}
+
+		cost := RepairCost(&userItem)
+		if user.Balance < cost {
+			return fmt.Errorf("insufficient balance")
+		}
+
+		newBalance := roundMoney(user.Balance - cost)
+		if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
+			return fmt.Errorf("failed to update balance: %w", err)
+		}
+		if err := tx.Model(&userItem).Update("durability", model.ItemDurabilityMax).Error; err != nil {
+			return fmt.Errorf("failed to repair item: %w", err)
+		}
+
+		transaction := model.Transaction{
+			UserID:       userID,
+			Type:         model.TransactionTypeRepair,
+			Amount:       -cost,
+			BalanceAfter: newBalance,
+			Description:  fmt.Sprintf("Repaired %s", userItem.Item.Name),
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+
+		response = &RepairItemResponse{
+			UserItem:      newUserItemResponse(&userItem, time.Now()),
+			Cost:          cost,
+			NewBalance:    newBalance,
+			TransactionID: transaction.ID,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ChargeUpkeep bills every owner of items with upkeep for each whole day since
+// they were last billed. Owners who cannot pay in full are charged what they
+// have and the unpaid days wear their items down instead.
+func (s *ShopService) ChargeUpkeep() (int, error) {
+	var types []model.ItemType
+	for itemType, costs := range ownershipCosts {
+		if costs.DailyUpkeep > 0 {
+			types = append(types, itemType)
+		}
+	}
+
+	// Deleted accounts keep their items for the records but no longer pay for them
+	var userIDs []uint
+	if err := s.db.Model(&model.UserItem{}).
+		Joins("JOIN items ON items.id = user_items.item_id").
+		Joins("JOIN users ON users.id = user_items.user_id AND users.deleted_at IS NULL").
+		Where("items.type IN ? AND items.price > 0", types).
+		Distinct().Pluck("user_items.user_id", &userIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to get item owners: %w", err)
+	}
+
+	now := time.Now()
+	charged := 0
+	for _, userID := range userIDs {
+		ok, err := s.chargeUserUpkeep(userID, types, now)
+		if err != nil {
+			// One owner's failure shouldn't hold up everyone else's bill
+			log.Printf("Failed to charge upkeep to user %d: %v", userID, err)
+			continue
+		}
+		if ok {
+			charged++
+		}
+	}
+	return charged, nil
+}
+
+// chargeUserUpkeep bills one user's due upkeep and reports whether anything was due
+func (s *ShopService) chargeUserUpkeep(userID uint, types []model.ItemType, now time.Time) (bool, error) {
+	due := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		var items []model.UserItem
+		if err := tx.Preload("Item").
+			Joins("JOIN items ON items.id = user_items.item_id").
+			Where("user_items.user_id = ? AND items.type IN ? AND items.price > 0", userID, types).
+			Find(&items).Error; err != nil {
+			return fmt.Errorf("failed to get user items: %w", err)
+		}
+
+		type dueItem struct {
+			userItem model.UserItem
+			since    time.Time
+			days     int
+		}
+		var dueItems []dueItem
+		total := 0.0
+		for _, userItem := range items {
+			since := userItem.PurchasedAt
+			if userItem.LastUpkeepAt != nil {
+				since = *userItem.LastUpkeepAt
+			}
+			days := int(now.Sub(since) / UpkeepPeriod)
+			if days <= 0 {
+				continue
+			}
+			dueItems = append(dueItems, dueItem{userItem: userItem, since: since, days: days})
+			total += roundMoney(userItem.Item.Price * costsFor(userItem.Item.Type).DailyUpkeep * float64(days))
+		}
+		if len(dueItems) == 0 {
+			return nil
+		}
+		due = true
+
+		paid := roundMoney(min(total, max(user.Balance, 0)))
+		paidShare := 1.0
+		if total > 0 {
+			paidShare = paid / total
+		}
+
+		names := make([]string, 0, len(dueItems))
+		for _, item := range dueItems {
+			// Advance by whole periods so partial days carry over to the next run
+			updates := map[string]interface{}{
+				"last_upkeep_at": item.since.Add(time.Duration(item.days) * UpkeepPeriod),
+			}
+			if paidShare < 1 {
+				neglect := UpkeepNeglectWear * float64(item.days) * (1 - paidShare)
+				updates["durability"] = gorm.Expr("MAX(durability - ?, 0)", neglect)
+			}
+			if err := tx.Model(&model.UserItem{}).Where("id = ?", item.userItem.ID).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update upkeep: %w", err)
+			}
+			names = append(names, item.userItem.Item.Name)
+		}
+
+		// Items neglected into ruin can't stay equipped
+		if paidShare < 1 {
+			if err := tx.Model(&model.UserItem{}).
+				Where("user_id = ? AND is_equipped = ? AND durability <= 0", userID, true).
+				Update("is_equipped", false).Error; err != nil {
+				return fmt.Errorf("failed to unequip broken items: %w", err)
+			}
+		}
+
+		if paid <= 0 {
+			return nil
+		}
+
+		newBalance := roundMoney(user.Balance - paid)
+		if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
+			return fmt.Errorf("failed to update balance: %w", err)
+		}
+
+		description := fmt.Sprintf("Upkeep: %s", strings.Join(names, ", "))
+		if paid < total {
+			description += fmt.Sprintf(" (paid %.2f of %.2f)", paid, total)
+		}
+		if err := tx.Create(&model.Transaction{
+			UserID:       userID,
+			Type:         model.TransactionTypeUpkeep,
+			Amount:       -paid,
+			BalanceAfter: newBalance,
+			Description:  description,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		return nil
+	})
+	return due, err
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResaleValue(t *testing.T) {
+	now := time.Now()
+	shirt := &model.UserItem{
+		PurchasedAt: now,
+		Durability:  model.ItemDurabilityMax,
+		Item:        model.Item{Type: model.ItemTypeClothing, Price: 100},
+	}
+	assert.Equal(t, 50.0, ResaleValue(shirt, now))
+
+	car := &model.UserItem{
+		PurchasedAt: now.Add(-10 * 24 * time.Hour),
+		Durability:  model.ItemDurabilityMax,
+		Item:        model.Item{Type: model.ItemTypeCar, Price: 10000},
+	}
+	assert.Equal(t, 6330.67, ResaleValue(car, now))
+
+	// Broken items sell for half, old ones never drop below the floor
+	car.Durability = 0
+	assert.Equal(t, 3165.34, ResaleValue(car, now))
+	car.Durability = model.ItemDurabilityMax
+	car.PurchasedAt = now.Add(-5 * 365 * 24 * time.Hour)
+	assert.Equal(t, 1000.0, ResaleValue(car, now))
+
+	mutation := &model.UserItem{PurchasedAt: now, Durability: 50, Item: model.Item{Type: model.ItemTypeMutation, Price: 0}}
+	assert.Equal(t, 0.0, ResaleValue(mutation, now))
+}
+
+func TestStuntDriverBreaksCar(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 5000)
+	car := createTestItem(t, db, "Old Sedan", model.ItemTypeCar, 5000)
+	owned := &model.UserItem{UserID: user.ID, ItemID: car.ID, PurchasedAt: time.Now(), IsEquipped: true}
+	require.NoError(t, db.Create(owned).Error)
+
+	stunt, _ := DefaultJobCatalog().Get(model.JobTypeStuntDriver)
+	_, err := stunt.evaluate(db, user.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, db.First(owned, owned.ID).Error)
+	assert.Equal(t, 0.0, owned.Durability)
+	assert.False(t, owned.IsEquipped)
+
+	shop := &ShopService{db: db}
+	_, err = shop.EquipItem(user.ID, owned.ID)
+	assert.EqualError(t, err, "item is broken")
+
+	repaired, err := shop.RepairItem(user.ID, owned.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1500.0, repaired.Cost)
+	assert.Equal(t, 3500.0, repaired.NewBalance)
+	assert.Equal(t, model.ItemDurabilityMax, repaired.UserItem.Durability)
+
+	_, err = shop.RepairItem(user.ID, owned.ID)
+	assert.EqualError(t, err, "item is not damaged")
+
+	_, err = shop.EquipItem(user.ID, owned.ID)
+	require.NoError(t, err)
+}
+
+func TestWearOnlyTouchesEquippedItems(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 100)
+	shirt := createTestItem(t, db, "Shirt", model.ItemTypeClothing, 100)
+	worn := &model.UserItem{UserID: user.ID, ItemID: shirt.ID, PurchasedAt: time.Now(), IsEquipped: true}
+	spare := &model.UserItem{UserID: user.ID, ItemID: shirt.ID, PurchasedAt: time.Now()}
+	require.NoError(t, db.Create(worn).Error)
+	require.NoError(t, db.Create(spare).Error)
+
+	require.NoError(t, wearEquippedItems(db, user.ID, model.ItemTypeClothing, 30))
+
+	require.NoError(t, db.First(worn, worn.ID).Error)
+	require.NoError(t, db.First(spare, spare.ID).Error)
+	assert.Equal(t, 70.0, worn.Durability)
+	assert.True(t, worn.IsEquipped)
+	assert.Equal(t, model.ItemDurabilityMax, spare.Durability)
+}
+
+func TestChargeUpkeep(t *testing.T) {
+	db := setupTestDB(t)
+	owner := createTestUser(t, db, 1000)
+	broke := createTestUser(t, db, 1)
+	car := createTestItem(t, db, "Hatchback", model.ItemTypeCar, 1000)
+	shirt := createTestItem(t, db, "Shirt", model.ItemTypeClothing, 100)
+
+	purchased := time.Now().Add(-2*UpkeepPeriod - time.Hour)
+	for _, userItem := range []*model.UserItem{
+		{UserID: owner.ID, ItemID: car.ID, PurchasedAt: purchased},
+		{UserID: owner.ID, ItemID: shirt.ID, PurchasedAt: purchased},
+		{UserID: broke.ID, ItemID: car.ID, PurchasedAt: purchased},
+	} {
+		require.NoError(t, db.Create(userItem).Error)
+	}
+
+	shop := &ShopService{db: db}
+	count, err := shop.ChargeUpkeep()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Two days of car upkeep at 0.3% a day; clothing has none
+	assert.Equal(t, 994.0, userBalance(t, db, owner.ID))
+	var upkeep model.Transaction
+	require.NoError(t, db.Where("user_id = ? AND type = ?", owner.ID, model.TransactionTypeUpkeep).First(&upkeep).Error)
+	assert.Equal(t, -6.0, upkeep.Amount)
+
+	// The broke owner pays what they have and the car wears for the rest
+	assert.Equal(t, 0.0, userBalance(t, db, broke.ID))
+	var neglected model.UserItem
+	require.NoError(t, db.Where("user_id = ?", broke.ID).First(&neglected).Error)
+	assert.InDelta(t, 100-UpkeepNeglectWear*2*5.0/6.0, neglected.Durability, 0.01)
+
+	// Nothing is due again until another day has passed
+	count, err = shop.ChargeUpkeep()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// ItemModifiers holds the effects of a user's equipped items. Work, loans and
+// games all ask it how to adjust their outcomes so that item rules live in one place.
+type ItemModifiers struct {
+	effects []model.ItemEffect
+}
+
+// LoadItemModifiers collects the effects of every item the user has equipped.
+// Invalid effects are logged and skipped rather than failing the caller.
+func LoadItemModifiers(db *gorm.DB, userID uint) (*ItemModifiers, error) {
+	var items []model.Item
+	if err := db.Model(&model.Item{}).
+		Joins("JOIN user_items ON user_items.item_id = items.id").
+		Where("user_items.user_id = ? AND user_items.is_equipped = ?", userID, true).
+		Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to get equipped items: %w", err)
+	}
+
+	modifiers := &ItemModifiers{}
+	for _, item := range items {
+		for _, effect := range item.Effects {
+			if !effect.Valid() {
+				log.Printf("Skipping invalid %q effect on item %d", effect.Type, item.ID)
+				continue
+			}
+			modifiers.effects = append(modifiers.effects, effect)
+		}
+	}
+	return modifiers, nil
+}
+
+// sum adds up the values of effects of the given type that match the filter
+func (m *ItemModifiers) sum(effectType model.ItemEffectType, matches func(model.ItemEffect) bool) float64 {
+	if m == nil {
+		return 0
+	}
+	total := 0.0
+	for _, effect := range m.effects {
+		if effect.Type == effectType && matches(effect) {
+			total += effect.Value
+		}
+	}
+	return total
+}
+
+// forJob, forLoan and forGame match effects scoped to one target or to all of them
+func forJob(jobType model.JobType) func(model.ItemEffect) bool {
+	return func(e model.ItemEffect) bool { return e.JobType == "" || e.JobType == jobType }
+}
+
+func forLoan(loanType model.LoanType) func(model.ItemEffect) bool {
+	return func(e model.ItemEffect) bool { return e.LoanType == "" || e.LoanType == loanType }
+}
+
+func forGame(gameType model.GameType) func(model.ItemEffect) bool {
+	return func(e model.ItemEffect) bool { return e.Game == "" || e.Game == gameType }
+}
+
+// WorkPay applies pay multipliers and flat bonuses to a shift's pay. Shifts
+// that paid nothing stay at nothing so items cannot turn a failure into pay.
+func (m *ItemModifiers) WorkPay(jobType model.JobType, pay float64) float64 {
+	if pay <= 0 {
+		return pay
+	}
+	multiplier := 1 + m.sum(model.ItemEffectWorkPayMultiplier, forJob(jobType))
+	return roundMoney(pay*multiplier + m.sum(model.ItemEffectWorkPayFlat, forJob(jobType)))
+}
+
+// JobLuck returns the factor applied to the weight of outcomes that pay above base pay
+func (m *ItemModifiers) JobLuck(jobType model.JobType) float64 {
+	return 1 + m.sum(model.ItemEffectJobLuck, forJob(jobType))
+}
+
+// LoanAPR applies rate discounts to a loan's APR, never going below zero
+func (m *ItemModifiers) LoanAPR(loanType model.LoanType, apr float64) float64 {
+	return max(apr-m.sum(model.ItemEffectLoanRateDiscount, forLoan(loanType)), 0)
+}
+
+// GamePayout adjusts the gross return of a round: winnings above the stake are
+// multiplied, and a rebate returns part of whatever was lost.
+func (m *ItemModifiers) GamePayout(gameType model.GameType, bet, payout float64) float64 {
+	if payout > bet {
+		multiplier := 1 + m.sum(model.ItemEffectGamePayoutMultiplier, forGame(gameType))
+		return roundMoney(bet + (payout-bet)*multiplier)
+	}
+	if payout < bet {
+		rebate := min(m.sum(model.ItemEffectGameLossRebate, forGame(gameType)), 1)
+		return roundMoney(payout + (bet-payout)*rebate)
+	}
+	return payout
+}
+
+// GamePayout loads the user's modifiers and adjusts a game's gross return. It
+// falls back to the unmodified payout if the items cannot be read so a round
+// that has already been played is never lost.
+func GamePayout(db *gorm.DB, userID uint, gameType model.GameType, bet, payout float64) float64 {
+	modifiers, err := LoadItemModifiers(db, userID)
+	if err != nil {
+		log.Printf("Failed to load item modifiers for user %d: %v", userID, err)
+		return payout
+	}
+	return modifiers.GamePayout(gameType, bet, payout)
+}
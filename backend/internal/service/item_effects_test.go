@@ -0,0 +1,124 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/game"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// giveItemWithEffects creates an item carrying effects and gives it to the user
+func giveItemWithEffects(t *testing.T, db *gorm.DB, userID uint, equipped bool, effects ...model.ItemEffect) *model.UserItem {
+	item := &model.Item{Name: "Charm", Type: model.ItemTypeAccessories, Price: 100, Effects: effects}
+	require.NoError(t, db.Create(item).Error)
+	userItem := &model.UserItem{UserID: userID, ItemID: item.ID, PurchasedAt: time.Now()}
+	require.NoError(t, db.Create(userItem).Error)
+	// Create skips false booleans in favour of the column default, so set it explicitly
+	require.NoError(t, db.Model(userItem).Update("is_equipped", equipped).Error)
+	return userItem
+}
+
+func TestLoadItemModifiersOnlyEquipped(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 100)
+	giveItemWithEffects(t, db, user.ID, true, model.ItemEffect{Type: model.ItemEffectWorkPayMultiplier, Value: 0.1})
+	giveItemWithEffects(t, db, user.ID, false, model.ItemEffect{Type: model.ItemEffectWorkPayMultiplier, Value: 0.5})
+	giveItemWithEffects(t, db, user.ID, true, model.ItemEffect{Type: model.ItemEffectGameLossRebate, Value: 2})
+
+	modifiers, err := LoadItemModifiers(db, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 110.0, modifiers.WorkPay(model.JobTypeOffice, 100))
+	assert.Equal(t, 0.0, modifiers.GamePayout(model.GameTypeSlots, 10, 0), "invalid rebate is ignored")
+}
+
+func TestItemModifiers(t *testing.T) {
+	modifiers := &ItemModifiers{effects: []model.ItemEffect{
+		{Type: model.ItemEffectWorkPayMultiplier, Value: 0.1},
+		{Type: model.ItemEffectWorkPayFlat, Value: 5, JobType: model.JobTypeCourier},
+		{Type: model.ItemEffectJobLuck, Value: 1, JobType: model.JobTypeStreamer},
+		{Type: model.ItemEffectLoanRateDiscount, Value: 0.05, LoanType: model.LoanTypeBank},
+		{Type: model.ItemEffectGamePayoutMultiplier, Value: 0.5, Game: model.GameTypeSlots},
+		{Type: model.ItemEffectGameLossRebate, Value: 0.1},
+	}}
+
+	assert.Equal(t, 110.0, modifiers.WorkPay(model.JobTypeOffice, 100))
+	assert.Equal(t, 115.0, modifiers.WorkPay(model.JobTypeCourier, 100))
+	assert.Equal(t, 0.0, modifiers.WorkPay(model.JobTypeCourier, 0))
+	assert.Equal(t, 2.0, modifiers.JobLuck(model.JobTypeStreamer))
+	assert.Equal(t, 1.0, modifiers.JobLuck(model.JobTypeOffice))
+
+	assert.InDelta(t, 0.15, modifiers.LoanAPR(model.LoanTypeBank, 0.2), 1e-9)
+	assert.Equal(t, 0.0, modifiers.LoanAPR(model.LoanTypeBank, 0.01))
+	assert.Equal(t, 0.2, modifiers.LoanAPR(model.LoanTypeMicrocredit, 0.2))
+
+	assert.Equal(t, 25.0, modifiers.GamePayout(model.GameTypeSlots, 10, 20))
+	assert.Equal(t, 20.0, modifiers.GamePayout(model.GameTypeRoulette, 10, 20))
+	assert.Equal(t, 1.0, modifiers.GamePayout(model.GameTypeRoulette, 10, 0))
+	assert.Equal(t, 10.0, modifiers.GamePayout(model.GameTypeHiLo, 10, 10), "pushes are untouched")
+
+	var none *ItemModifiers
+	assert.Equal(t, 100.0, none.WorkPay(model.JobTypeOffice, 100))
+}
+
+func TestRollOutcomeLuck(t *testing.T) {
+	jackpot := 100.0
+	outcomes := []JobOutcome{
+		{Weight: 1, Description: "nothing"},
+		{Weight: 1, Pay: &jackpot, Description: "jackpot"},
+	}
+
+	for i := 0; i < 20; i++ {
+		outcome := rollOutcome(outcomes, nil, 0, 1e9)
+		require.NotNil(t, outcome)
+		assert.Equal(t, "jackpot", outcome.Description)
+	}
+}
+
+func TestJobEvaluateItemBonus(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 100)
+	giveItemWithEffects(t, db, user.ID, true, model.ItemEffect{Type: model.ItemEffectWorkPayMultiplier, Value: 0.1})
+
+	office, _ := DefaultJobCatalog().Get(model.JobTypeOffice)
+	result, err := office.evaluate(db, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 550.0, result.Earned)
+	assert.Contains(t, result.Description, "item bonus")
+}
+
+func TestTakeLoanItemRateDiscount(t *testing.T) {
+	db := setupTestDB(t)
+	plainUser := createTestUser(t, db, 100)
+	homeowner := createTestUser(t, db, 100)
+	giveItemWithEffects(t, db, homeowner.ID, true, model.ItemEffect{Type: model.ItemEffectLoanRateDiscount, Value: 0.05})
+	service := &LoanService{db: db}
+
+	plain, err := service.TakeLoan(TakeLoanRequest{UserID: plainUser.ID, Amount: 100, Type: model.LoanTypeMicrocredit})
+	require.NoError(t, err)
+	discounted, err := service.TakeLoan(TakeLoanRequest{UserID: homeowner.ID, Amount: 100, Type: model.LoanTypeMicrocredit})
+	require.NoError(t, err)
+
+	assert.InDelta(t, plain.Loan.InterestRate-0.05, discounted.Loan.InterestRate, 1e-9)
+}
+
+func TestSlotsSpinLossRebate(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 100)
+	giveItemWithEffects(t, db, user.ID, true, model.ItemEffect{Type: model.ItemEffectGameLossRebate, Value: 0.5, Game: model.GameTypeSlots})
+	service := &SlotsService{db: db, engine: game.NewSlotsEngine()}
+
+	// Keep spinning until a losing spin shows the rebate
+	for i := 0; i < 200; i++ {
+		result, err := service.Spin(&SpinRequest{UserID: user.ID, Bet: 1})
+		require.NoError(t, err)
+		if result.Result.Multiplier == 0 {
+			assert.Equal(t, 0.5, result.Win)
+			return
+		}
+	}
+	t.Fatal("no losing spin in 200 attempts")
+}
@@ -0,0 +1,498 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/data"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// Job effect types understood by the rules evaluator
+const (
+	JobEffectAddStatus       = "add_status"        // Give the user a UserStatus for DurationHours
+	JobEffectUnequipItemType = "unequip_item_type" // Unequip every owned item of ItemType ("car broken")
+	JobEffectGrantRandomItem = "grant_random_item" // Give a random catalogue item of ItemType
+	JobEffectWearItemType    = "wear_item_type"    // Take Wear durability off every equipped item of ItemType
+)
+
+// JobRequirement is an equipped item the user must have to start a job
+type JobRequirement struct {
+	ItemType model.ItemType `json:"item_type,omitempty"`
+	ItemName string         `json:"item_name,omitempty"`
+	Error    string         `json:"error"` // Error code returned when the requirement is not met
+	Label    string         `json:"label"` // Short identifier shown to the client
+}
+
+// JobBonus adds pay when the user has a matching equipped item
+type JobBonus struct {
+	ItemType    model.ItemType `json:"item_type,omitempty"`
+	ItemName    string         `json:"item_name,omitempty"`
+	Amount      float64        `json:"amount"`
+	Label       string         `json:"label"`
+	Description string         `json:"description"`
+}
+
+// JobEffect is a side effect applied when a job completes
+type JobEffect struct {
+	Type          string         `json:"type"`
+	Status        string         `json:"status,omitempty"`
+	DurationHours float64        `json:"duration_hours,omitempty"`
+	ItemType      model.ItemType `json:"item_type,omitempty"`
+	Equip         bool           `json:"equip,omitempty"`
+	Wear          float64        `json:"wear,omitempty"`
+}
+
+// JobOutcome is one weighted result of a job. Only outcomes whose status
+// conditions match the user are eligible for the roll.
+type JobOutcome struct {
+	Weight         float64     `json:"weight"`
+	RequiresStatus string      `json:"requires_status,omitempty"`
+	ExcludesStatus string      `json:"excludes_status,omitempty"`
+	Pay            *float64    `json:"pay,omitempty"` // Overrides the job's base pay when set
+	Description    string      `json:"description,omitempty"`
+	Effects        []JobEffect `json:"effects,omitempty"`
+}
+
+// JobUnlock gates a job behind a career level in another job
+type JobUnlock struct {
+	JobType  model.JobType `json:"job_type"`
+	MinLevel int           `json:"min_level"`
+}
+
+// JobDefinition declares a job in the catalogue
+type JobDefinition struct {
+	Type            model.JobType    `json:"type"`
+	Name            string           `json:"name"`
+	Description     string           `json:"description"`
+	DurationSeconds int              `json:"duration_seconds"`
+	BasePay         float64          `json:"base_pay"`
+	CompletionText  string           `json:"completion_text,omitempty"`
+	Experience      int              `json:"experience,omitempty"` // Career experience per shift
+	Unlock          *JobUnlock       `json:"unlock,omitempty"`
+	Requirements    []JobRequirement `json:"requirements,omitempty"`
+	Bonuses         []JobBonus       `json:"bonuses,omitempty"`
+	Outcomes        []JobOutcome     `json:"outcomes,omitempty"`
+	Effects         []JobEffect      `json:"effects,omitempty"`
+	PenaltyLabel    string           `json:"penalty_label,omitempty"`
+	RewardLabel     string           `json:"reward_label,omitempty"`
+	VariableLabel   string           `json:"variable_label,omitempty"`
+}
+
+// JobCatalog holds all job definitions in declaration order
+type JobCatalog struct {
+	jobs   []JobDefinition
+	byType map[model.JobType]*JobDefinition
+}
+
+// JobRequirementError is returned when a user does not meet a job requirement
+type JobRequirementError struct {
+	Code string
+}
+
+func (e *JobRequirementError) Error() string {
+	return e.Code
+}
+
+// JobListing represents a job in the GET /api/work/jobs response
+type JobListing struct {
+	Type            model.JobType    `json:"type"`
+	Name            string           `json:"name"`
+	BaseReward      float64          `json:"base_reward"`
+	DurationSeconds int              `json:"duration_seconds"`
+	Requires        string           `json:"requires,omitempty"`
+	Bonus           string           `json:"bonus,omitempty"`
+	Penalty         string           `json:"penalty,omitempty"`
+	Reward          string           `json:"reward,omitempty"`
+	Variable        string           `json:"variable,omitempty"`
+	Description     string           `json:"description"`
+	Requirements    []JobRequirement `json:"requirements,omitempty"`
+	Bonuses         []JobBonus       `json:"bonuses,omitempty"`
+	Unlock          *JobUnlock       `json:"unlock,omitempty"`
+}
+
+var (
+	defaultCatalog     *JobCatalog
+	defaultCatalogOnce sync.Once
+)
+
+// ParseJobCatalog parses and validates a JSON job catalogue
+func ParseJobCatalog(raw []byte) (*JobCatalog, error) {
+	var jobs []JobDefinition
+	if err := json.Unmarshal(raw, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse job catalogue: %w", err)
+	}
+
+	catalog := &JobCatalog{
+		jobs:   jobs,
+		byType: make(map[model.JobType]*JobDefinition, len(jobs)),
+	}
+
+	for i := range catalog.jobs {
+		job := &catalog.jobs[i]
+		if job.Type == "" {
+			return nil, fmt.Errorf("job #%d has no type", i)
+		}
+		if _, exists := catalog.byType[job.Type]; exists {
+			return nil, fmt.Errorf("duplicate job type %q", job.Type)
+		}
+		if job.DurationSeconds <= 0 {
+			return nil, fmt.Errorf("job %q must have a positive duration", job.Type)
+		}
+		if job.BasePay < 0 {
+			return nil, fmt.Errorf("job %q has negative base pay", job.Type)
+		}
+		for _, req := range job.Requirements {
+			if req.ItemType == "" && req.ItemName == "" {
+				return nil, fmt.Errorf("job %q has a requirement without item_type or item_name", job.Type)
+			}
+		}
+		if job.Unlock != nil && job.Unlock.MinLevel < 1 {
+			return nil, fmt.Errorf("job %q has an unlock without min_level", job.Type)
+		}
+		if err := validateJobEffects(job.Type, job.Effects); err != nil {
+			return nil, err
+		}
+		for _, outcome := range job.Outcomes {
+			if outcome.Weight <= 0 {
+				return nil, fmt.Errorf("job %q has an outcome with non-positive weight", job.Type)
+			}
+			if err := validateJobEffects(job.Type, outcome.Effects); err != nil {
+				return nil, err
+			}
+		}
+		catalog.byType[job.Type] = job
+	}
+
+	for _, job := range catalog.jobs {
+		if job.Unlock != nil {
+			if _, ok := catalog.byType[job.Unlock.JobType]; !ok {
+				return nil, fmt.Errorf("job %q is unlocked by unknown job %q", job.Type, job.Unlock.JobType)
+			}
+		}
+	}
+
+	return catalog, nil
+}
+
+// validateJobEffects checks that every effect has a known type and its parameters
+func validateJobEffects(jobType model.JobType, effects []JobEffect) error {
+	for _, effect := range effects {
+		switch effect.Type {
+		case JobEffectAddStatus:
+			if effect.Status == "" || effect.DurationHours <= 0 {
+				return fmt.Errorf("job %q: add_status needs status and duration_hours", jobType)
+			}
+		case JobEffectUnequipItemType, JobEffectGrantRandomItem:
+			if effect.ItemType == "" {
+				return fmt.Errorf("job %q: %s needs item_type", jobType, effect.Type)
+			}
+		case JobEffectWearItemType:
+			if effect.ItemType == "" || effect.Wear <= 0 {
+				return fmt.Errorf("job %q: wear_item_type needs item_type and wear", jobType)
+			}
+		default:
+			return fmt.Errorf("job %q: unknown effect type %q", jobType, effect.Type)
+		}
+	}
+	return nil
+}
+
+// LoadJobCatalog loads jobs.json from disk, falling back to the built-in copy
+func LoadJobCatalog() (*JobCatalog, error) {
+	// Same lookup as countries.json so the file can be edited without a rebuild
+	dataPath := filepath.Join("backend", "internal", "data", "jobs.json")
+	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
+		dataPath = filepath.Join("internal", "data", "jobs.json")
+	}
+
+	raw, err := os.ReadFile(dataPath)
+	if err != nil {
+		return DefaultJobCatalog(), nil
+	}
+
+	return ParseJobCatalog(raw)
+}
+
+// DefaultJobCatalog returns the catalogue embedded in the binary
+func DefaultJobCatalog() *JobCatalog {
+	defaultCatalogOnce.Do(func() {
+		catalog, err := ParseJobCatalog(data.JobsJSON)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in job catalogue: %v", err))
+		}
+		defaultCatalog = catalog
+	})
+	return defaultCatalog
+}
+
+// Get returns the definition for a job type
+func (c *JobCatalog) Get(jobType model.JobType) (*JobDefinition, bool) {
+	job, ok := c.byType[jobType]
+	return job, ok
+}
+
+// All returns every job definition in catalogue order
+func (c *JobCatalog) All() []JobDefinition {
+	return c.jobs
+}
+
+// Listings returns the catalogue in the format served by GET /api/work/jobs
+func (c *JobCatalog) Listings() []JobListing {
+	listings := make([]JobListing, len(c.jobs))
+	for i, job := range c.jobs {
+		labels := make([]string, 0, len(job.Requirements))
+		for _, req := range job.Requirements {
+			labels = append(labels, req.Label)
+		}
+		bonusLabels := make([]string, 0, len(job.Bonuses))
+		for _, bonus := range job.Bonuses {
+			bonusLabels = append(bonusLabels, bonus.Label)
+		}
+
+		listings[i] = JobListing{
+			Type:            job.Type,
+			Name:            job.Name,
+			BaseReward:      job.BasePay,
+			DurationSeconds: job.DurationSeconds,
+			Requires:        strings.Join(labels, ","),
+			Bonus:           strings.Join(bonusLabels, ","),
+			Penalty:         job.PenaltyLabel,
+			Reward:          job.RewardLabel,
+			Variable:        job.VariableLabel,
+			Description:     job.Description,
+			Requirements:    job.Requirements,
+			Bonuses:         job.Bonuses,
+			Unlock:          job.Unlock,
+		}
+	}
+	return listings
+}
+
+// jobResult is the evaluated outcome of a completed job
+type jobResult struct {
+	Earned      float64
+	Description string
+}
+
+// countEquipped counts equipped user items matching an item type or name
+func countEquipped(db *gorm.DB, userID uint, itemType model.ItemType, itemName string) (int64, error) {
+	query := db.Model(&model.UserItem{}).
+		Joins("JOIN items ON items.id = user_items.item_id").
+		Where("user_items.user_id = ? AND user_items.is_equipped = ?", userID, true)
+	if itemType != "" {
+		query = query.Where("items.type = ?", itemType)
+	}
+	if itemName != "" {
+		query = query.Where("items.name = ?", itemName)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// checkRequirements verifies that the user has every required equipped item
+func (job *JobDefinition) checkRequirements(db *gorm.DB, userID uint) error {
+	for _, req := range job.Requirements {
+		count, err := countEquipped(db, userID, req.ItemType, req.ItemName)
+		if err != nil {
+			return fmt.Errorf("failed to check job requirements: %w", err)
+		}
+		if count == 0 {
+			return &JobRequirementError{Code: req.Error}
+		}
+	}
+	return nil
+}
+
+// evaluate rolls the job's outcome and applies its effects inside tx
+func (job *JobDefinition) evaluate(tx *gorm.DB, userID uint) (*jobResult, error) {
+	result := &jobResult{
+		Earned:      job.BasePay,
+		Description: job.CompletionText,
+	}
+	if result.Description == "" {
+		result.Description = "Work completed"
+	}
+
+	effects := append([]JobEffect{}, job.Effects...)
+
+	modifiers, err := LoadItemModifiers(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(job.Outcomes) > 0 {
+		statuses, err := activeStatuses(tx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		luck := modifiers.JobLuck(job.Type)
+		if outcome := rollOutcome(job.Outcomes, statuses, job.BasePay, luck); outcome != nil {
+			if outcome.Pay != nil {
+				result.Earned = *outcome.Pay
+			}
+			if outcome.Description != "" {
+				result.Description = outcome.Description
+			}
+			effects = append(effects, outcome.Effects...)
+		}
+	}
+
+	for _, bonus := range job.Bonuses {
+		count, err := countEquipped(tx, userID, bonus.ItemType, bonus.ItemName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check job bonus: %w", err)
+		}
+		if count > 0 {
+			result.Earned += bonus.Amount
+			result.Description += fmt.Sprintf(" (%s)", bonus.Description)
+		}
+	}
+
+	if pay := modifiers.WorkPay(job.Type, result.Earned); pay != result.Earned {
+		result.Earned = pay
+		result.Description += " (item bonus)"
+	}
+
+	grantedItem := ""
+	for _, effect := range effects {
+		name, err := applyJobEffect(tx, userID, effect)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			grantedItem = name
+		}
+	}
+
+	if strings.Contains(result.Description, "{item}") {
+		if grantedItem == "" {
+			result.Description = job.CompletionText
+		} else {
+			result.Description = strings.ReplaceAll(result.Description, "{item}", grantedItem)
+		}
+	}
+
+	return result, nil
+}
+
+// activeStatuses returns the set of non-expired statuses for a user
+func activeStatuses(tx *gorm.DB, userID uint) (map[string]bool, error) {
+	var names []string
+	if err := tx.Model(&model.UserStatus{}).
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Pluck("status", &names).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user statuses: %w", err)
+	}
+
+	statuses := make(map[string]bool, len(names))
+	for _, name := range names {
+		statuses[name] = true
+	}
+	return statuses, nil
+}
+
+// rollOutcome picks a weighted outcome among those eligible for the user's statuses.
+// Outcomes paying more than basePay have their weight scaled by luck.
+func rollOutcome(outcomes []JobOutcome, statuses map[string]bool, basePay, luck float64) *JobOutcome {
+	weight := func(outcome *JobOutcome) float64 {
+		if outcome.Pay != nil && *outcome.Pay > basePay {
+			return outcome.Weight * luck
+		}
+		return outcome.Weight
+	}
+
+	eligible := make([]*JobOutcome, 0, len(outcomes))
+	total := 0.0
+	for i := range outcomes {
+		outcome := &outcomes[i]
+		if outcome.RequiresStatus != "" && !statuses[outcome.RequiresStatus] {
+			continue
+		}
+		if outcome.ExcludesStatus != "" && statuses[outcome.ExcludesStatus] {
+			continue
+		}
+		eligible = append(eligible, outcome)
+		total += weight(outcome)
+	}
+
+	if len(eligible) == 0 {
+		return nil
+	}
+
+	roll := rand.Float64() * total
+	for _, outcome := range eligible {
+		if roll < weight(outcome) {
+			return outcome
+		}
+		roll -= weight(outcome)
+	}
+	return eligible[len(eligible)-1]
+}
+
+// applyJobEffect applies a single effect and returns the name of any granted item
+func applyJobEffect(tx *gorm.DB, userID uint, effect JobEffect) (string, error) {
+	switch effect.Type {
+	case JobEffectAddStatus:
+		if err := grantStatus(tx, userID, effect.Status, time.Duration(effect.DurationHours*float64(time.Hour))); err != nil {
+			return "", err
+		}
+
+	case JobEffectUnequipItemType:
+		if err := tx.Model(&model.UserItem{}).
+			Where("user_id = ? AND item_id IN (SELECT id FROM items WHERE type = ?)", userID, effect.ItemType).
+			Update("is_equipped", false).Error; err != nil {
+			return "", fmt.Errorf("failed to unequip %s: %w", effect.ItemType, err)
+		}
+
+	case JobEffectWearItemType:
+		if err := wearEquippedItems(tx, userID, effect.ItemType, effect.Wear); err != nil {
+			return "", err
+		}
+
+	case JobEffectGrantRandomItem:
+		var items []model.Item
+		if err := tx.Where("type = ?", effect.ItemType).Find(&items).Error; err != nil {
+			return "", fmt.Errorf("failed to get %s items: %w", effect.ItemType, err)
+		}
+		if len(items) == 0 {
+			return "", nil
+		}
+
+		item := items[rand.Intn(len(items))]
+
+		// Users keep a single copy of each granted item
+		var existingCount int64
+		if err := tx.Model(&model.UserItem{}).
+			Where("user_id = ? AND item_id = ?", userID, item.ID).
+			Count(&existingCount).Error; err != nil {
+			return "", fmt.Errorf("failed to check owned items: %w", err)
+		}
+		if existingCount == 0 {
+			userItem := model.UserItem{
+				UserID:      userID,
+				ItemID:      item.ID,
+				PurchasedAt: time.Now(),
+				IsEquipped:  effect.Equip,
+			}
+			if err := tx.Create(&userItem).Error; err != nil {
+				return "", fmt.Errorf("failed to give %s: %w", item.Name, err)
+			}
+		}
+		return item.Name, nil
+	}
+
+	return "", nil
+}
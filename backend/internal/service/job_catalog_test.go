@@ -0,0 +1,108 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultJobCatalogCoversAllJobTypes(t *testing.T) {
+	catalog := DefaultJobCatalog()
+
+	for _, jobType := range []model.JobType{
+		model.JobTypeOffice,
+		model.JobTypeCourier,
+		model.JobTypeLabRat,
+		model.JobTypeStuntDriver,
+		model.JobTypeDrugDealer,
+		model.JobTypeStreamer,
+		model.JobTypeBottleCollector,
+	} {
+		job, ok := catalog.Get(jobType)
+		require.True(t, ok, "missing job %s", jobType)
+		assert.Equal(t, WORK_DURATION, job.DurationSeconds)
+	}
+
+	assert.Len(t, catalog.Listings(), len(catalog.All()))
+}
+
+func TestParseJobCatalogValidation(t *testing.T) {
+	_, err := ParseJobCatalog([]byte(`[{"type": "x", "duration_seconds": 0}]`))
+	assert.Error(t, err)
+
+	_, err = ParseJobCatalog([]byte(`[{"type": "x", "duration_seconds": 10}, {"type": "x", "duration_seconds": 10}]`))
+	assert.Error(t, err)
+
+	_, err = ParseJobCatalog([]byte(`[{"type": "x", "duration_seconds": 10, "effects": [{"type": "teleport"}]}]`))
+	assert.Error(t, err)
+
+	catalog, err := ParseJobCatalog([]byte(`[{
+		"type": "crypto_influencer",
+		"name": "Crypto Influencer",
+		"duration_seconds": 60,
+		"base_pay": 0,
+		"outcomes": [{"weight": 1, "pay": 42, "description": "Shilled a coin"}]
+	}]`))
+	require.NoError(t, err)
+	job, ok := catalog.Get("crypto_influencer")
+	require.True(t, ok)
+	assert.Equal(t, 60, job.DurationSeconds)
+}
+
+func TestJobRequirementsAndBonuses(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 0.0)
+	catalog := DefaultJobCatalog()
+
+	courier, _ := catalog.Get(model.JobTypeCourier)
+	err := courier.checkRequirements(db, user.ID)
+	var reqErr *JobRequirementError
+	require.ErrorAs(t, err, &reqErr)
+	assert.Equal(t, "courier_no_uniform", reqErr.Code)
+
+	uniform := model.Item{Name: "Courier Uniform", Type: model.ItemTypeClothing, Price: 50}
+	car := model.Item{Name: "Old Sedan", Type: model.ItemTypeCar, Price: 5000}
+	require.NoError(t, db.Create(&uniform).Error)
+	require.NoError(t, db.Create(&car).Error)
+	require.NoError(t, db.Create(&model.UserItem{UserID: user.ID, ItemID: uniform.ID, PurchasedAt: time.Now(), IsEquipped: true}).Error)
+	require.NoError(t, db.Create(&model.UserItem{UserID: user.ID, ItemID: car.ID, PurchasedAt: time.Now(), IsEquipped: true}).Error)
+
+	require.NoError(t, courier.checkRequirements(db, user.ID))
+
+	result, err := courier.evaluate(db, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 750.0, result.Earned)
+	assert.Contains(t, result.Description, "own car bonus")
+}
+
+func TestJobStatusEffects(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 0.0)
+	catalog := DefaultJobCatalog()
+
+	dealer, _ := catalog.Get(model.JobTypeDrugDealer)
+	result, err := dealer.evaluate(db, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2000.0, result.Earned)
+
+	var jail model.UserStatus
+	require.NoError(t, db.Where("user_id = ? AND status = ?", user.ID, "in_jail").First(&jail).Error)
+	assert.True(t, jail.ExpiresAt.After(time.Now().Add(7*365*24*time.Hour)))
+
+	// Popular streamers always hit the status-gated outcome
+	require.NoError(t, db.Create(&model.UserStatus{
+		UserID:    user.ID,
+		Status:    "popular_streamer",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}).Error)
+
+	streamer, _ := catalog.Get(model.JobTypeStreamer)
+	for i := 0; i < 5; i++ {
+		result, err := streamer.evaluate(db, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 10000.0, result.Earned)
+	}
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/scheduler"
+)
+
+// Background job intervals
+const (
+	InterestAccrualInterval = time.Minute
+	OverdueCheckInterval    = 5 * time.Minute
+	CollectionsInterval     = 15 * time.Minute
+	StatusExpiryInterval    = time.Minute
+	AuctionSettleInterval   = 15 * time.Second
+	HouseAuctionInterval    = time.Hour
+	ShopRestockInterval     = 5 * time.Minute
+	ItemUpkeepInterval      = time.Hour
+	OutboxRelayInterval     = 10 * time.Second
+	OutboxPruneInterval     = time.Hour
+	RealityCheckJobInterval = time.Minute
+	DataExportBuildInterval = 15 * time.Second
+	AccountDeletionInterval = 15 * time.Minute
+)
+
+// Retention periods for pruned tables
+const (
+	OutboxRetention       = 7 * 24 * time.Hour  // Published events kept in the outbox
+	NotificationRetention = 24 * time.Hour      // Notifications a reconnecting client can resume from
+	LeaderboardRetention  = 90 * 24 * time.Hour // Past daily and weekly leaderboards
+	AuthSessionRetention  = 30 * 24 * time.Hour // Ended sessions, kept so reused refresh tokens still trip
+	ChallengeRetention    = 24 * time.Hour      // Expired two-factor login challenges
+)
+
+// RegisterBackgroundJobs registers every periodic job with the scheduler
+func RegisterBackgroundJobs(s *scheduler.Scheduler) error {
+	loans := NewLoanService()
+	statuses := NewUserStatusService()
+	auctions := NewAuctionService()
+	shop := NewShopService()
+	notifications := NewNotificationService()
+	leaderboards := NewLeaderboardService()
+	responsibleGaming := NewResponsibleGamingService()
+	dataExports := NewDataExportService()
+	accountDeletions := NewAccountDeletionService()
+	sessions := NewAuthSessionService(0) // Only prunes, so the session lifetime is not needed
+	twoFactor := NewTwoFactorService()
+
+	jobs := []scheduler.Job{
+		{
+			Name:     "loans.accrue_interest",
+			Interval: InterestAccrualInterval,
+			Jitter:   10 * time.Second,
+			Run: func(ctx context.Context) error {
+				_, err := loans.AccrueInterestForAllLoans()
+				return err
+			},
+		},
+		{
+			Name:     "loans.mark_overdue",
+			Interval: OverdueCheckInterval,
+			Jitter:   30 * time.Second,
+			Run: func(ctx context.Context) error {
+				count, err := loans.MarkOverdueLoans()
+				if count > 0 {
+					log.Printf("Marked %d loans overdue", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "loans.escalate_collections",
+			Interval: CollectionsInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := loans.EscalateOverdueLoans()
+				if count > 0 {
+					log.Printf("Escalated %d overdue loans to collectors", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "loans.process_collections",
+			Interval: CollectionsInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, bankruptcies, err := loans.ProcessCollections()
+				if count > 0 {
+					log.Printf("Collections processed %d loans, %d users went bankrupt", count, bankruptcies)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "statuses.expire",
+			Interval: StatusExpiryInterval,
+			Jitter:   10 * time.Second,
+			Run: func(ctx context.Context) error {
+				_, err := statuses.ExpireStatuses()
+				return err
+			},
+		},
+		{
+			Name:     "auctions.settle",
+			Interval: AuctionSettleInterval,
+			Jitter:   2 * time.Second,
+			Run: func(ctx context.Context) error {
+				result, err := auctions.SettleDueAuctions()
+				if result != nil && result.Sold+result.Unsold > 0 {
+					log.Printf("Settled auctions: %d sold, %d unsold", result.Sold, result.Unsold)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "auctions.open_house",
+			Interval: HouseAuctionInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := auctions.OpenHouseAuctions()
+				if count > 0 {
+					log.Printf("Opened %d house auctions", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "shop.restock",
+			Interval: ShopRestockInterval,
+			Jitter:   30 * time.Second,
+			Run: func(ctx context.Context) error {
+				count, err := shop.RestockItems()
+				if count > 0 {
+					log.Printf("Restocked %d shop items", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "items.charge_upkeep",
+			Interval: ItemUpkeepInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := shop.ChargeUpkeep()
+				if count > 0 {
+					log.Printf("Charged upkeep to %d users", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "events.relay_outbox",
+			Interval: OutboxRelayInterval,
+			Jitter:   2 * time.Second,
+			Run: func(ctx context.Context) error {
+				count, err := events.Flush(database.GetDB())
+				if count > 0 {
+					log.Printf("Relayed %d outbox events", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "events.prune_outbox",
+			Interval: OutboxPruneInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := events.Prune(database.GetDB(), OutboxRetention)
+				if count > 0 {
+					log.Printf("Pruned %d published outbox events", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "notifications.prune",
+			Interval: OutboxPruneInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := notifications.PruneNotifications(NotificationRetention)
+				if count > 0 {
+					log.Printf("Pruned %d notifications", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "leaderboards.prune",
+			Interval: OutboxPruneInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := leaderboards.PruneLeaderboards(LeaderboardRetention)
+				if count > 0 {
+					log.Printf("Pruned %d past leaderboard entries", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "responsible_gaming.reality_checks",
+			Interval: RealityCheckJobInterval,
+			Jitter:   10 * time.Second,
+			Run: func(ctx context.Context) error {
+				_, err := responsibleGaming.IssueDueRealityChecks()
+				return err
+			},
+		},
+		{
+			Name:     "exports.build",
+			Interval: DataExportBuildInterval,
+			Jitter:   2 * time.Second,
+			Run: func(ctx context.Context) error {
+				count, err := dataExports.BuildPendingExports()
+				if count > 0 {
+					log.Printf("Built %d data exports", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "exports.expire",
+			Interval: OutboxPruneInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := dataExports.ExpireExports()
+				if count > 0 {
+					log.Printf("Deleted %d expired data exports", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "accounts.delete_due",
+			Interval: AccountDeletionInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := accountDeletions.DeleteDueAccounts()
+				if count > 0 {
+					log.Printf("Anonymised %d deleted accounts", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "auth.prune_sessions",
+			Interval: OutboxPruneInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := sessions.PruneSessions()
+				if count > 0 {
+					log.Printf("Pruned %d ended sessions", count)
+				}
+				return err
+			},
+		},
+		{
+			Name:     "auth.prune_two_factor_challenges",
+			Interval: OutboxPruneInterval,
+			Jitter:   time.Minute,
+			Run: func(ctx context.Context) error {
+				count, err := twoFactor.PruneChallenges()
+				if count > 0 {
+					log.Printf("Pruned %d two-factor challenges", count)
+				}
+				return err
+			},
+		},
+	}
+
+	for _, job := range jobs {
+		if err := s.Register(job); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
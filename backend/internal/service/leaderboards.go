@@ -0,0 +1,464 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Leaderboards
+const (
+	LeaderboardNetProfit   = "net_profit"   // Net winnings across all games
+	LeaderboardBiggestWin  = "biggest_win"  // Largest net win on a single round
+	LeaderboardHoursWorked = "hours_worked" // Hours of completed work
+	LeaderboardMostDebt    = "most_debt"    // Highest total debt reached
+)
+
+// Leaderboard time windows
+const (
+	LeaderboardWindowDaily   = "daily"
+	LeaderboardWindowWeekly  = "weekly"
+	LeaderboardWindowAllTime = "all_time"
+)
+
+// Leaderboard parameters
+const (
+	leaderboardDefaultLimit = 50
+	leaderboardMaxLimit     = 100
+	leaderboardAnonymous    = "Anonymous player"
+)
+
+// LeaderboardBoards lists every leaderboard
+var LeaderboardBoards = []string{LeaderboardNetProfit, LeaderboardBiggestWin, LeaderboardHoursWorked, LeaderboardMostDebt}
+
+// leaderboardWindows lists every time window
+var leaderboardWindows = []string{LeaderboardWindowDaily, LeaderboardWindowWeekly, LeaderboardWindowAllTime}
+
+// validLeaderboard reports whether a board exists
+func validLeaderboard(board string) bool {
+	for _, b := range LeaderboardBoards {
+		if b == board {
+			return true
+		}
+	}
+	return false
+}
+
+// leaderboardPeriod returns the period key of a window at a time. Leaderboards
+// are shared by everyone, so days and weeks run in UTC with weeks starting on Monday.
+func leaderboardPeriod(window string, now time.Time) (string, error) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch window {
+	case LeaderboardWindowDaily:
+		return "d" + day.Format("2006-01-02"), nil
+	case LeaderboardWindowWeekly:
+		monday := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return "w" + monday.Format("2006-01-02"), nil
+	case LeaderboardWindowAllTime:
+		return "all", nil
+	}
+	return "", errors.New("unknown_window")
+}
+
+// LeaderboardService keeps leaderboards up to date from events and ranks users on them
+type LeaderboardService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewLeaderboardService creates a new leaderboard service instance
+func NewLeaderboardService() *LeaderboardService {
+	return &LeaderboardService{
+		db: database.GetDB(),
+	}
+}
+
+// clock returns the current time
+func (s *LeaderboardService) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// leaderboardUpdate is a change to one user's score on one board
+type leaderboardUpdate struct {
+	board string
+	value float64
+	max   bool // Keep the higher of the current score and value instead of adding value
+}
+
+// HandleEvent updates the user's scores in every window after activity on the bus
+func (s *LeaderboardService) HandleEvent(event events.Event) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.ApplyEvent(tx, event)
+	})
+}
+
+// ApplyEvent is HandleEvent inside tx. The bus commits it together with the
+// leaderboards' outbox cursor, so a redelivered event is not counted twice.
+func (s *LeaderboardService) ApplyEvent(tx *gorm.DB, event events.Event) error {
+	var updates []leaderboardUpdate
+
+	switch e := event.(type) {
+	case events.BetSettled:
+		net := roundMoney(e.Net())
+		updates = append(updates, leaderboardUpdate{board: LeaderboardNetProfit, value: net})
+		if net > 0 {
+			updates = append(updates, leaderboardUpdate{board: LeaderboardBiggestWin, value: net, max: true})
+		}
+	case events.WorkCompleted:
+		updates = append(updates, leaderboardUpdate{board: LeaderboardHoursWorked, value: float64(e.DurationSeconds) / 3600})
+	case events.LoanTaken, events.LoanAccrued, events.CollectionAction:
+		debt, err := totalDebt(tx, event.EventUserID())
+		if err != nil {
+			return err
+		}
+		updates = append(updates, leaderboardUpdate{board: LeaderboardMostDebt, value: debt, max: true})
+	default:
+		return nil
+	}
+
+	var user model.User
+	if err := tx.Select("id", "leaderboard_visibility").First(&user, event.EventUserID()).Error; err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	now := s.clock()
+	for _, update := range updates {
+		for _, window := range leaderboardWindows {
+			period, _ := leaderboardPeriod(window, now)
+			if err := applyLeaderboardUpdate(tx, &user, period, update); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyLeaderboardUpdate adds to or raises one score, creating the entry if needed
+func applyLeaderboardUpdate(tx *gorm.DB, user *model.User, period string, update leaderboardUpdate) error {
+	score := clause.Expr{SQL: "leaderboard_entries.score + excluded.score"}
+	if update.max {
+		score = clause.Expr{SQL: "MAX(leaderboard_entries.score, excluded.score)"}
+	}
+
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "board"}, {Name: "period"}, {Name: "user_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "score"}, Value: score},
+			{Column: clause.Column{Name: "hidden"}, Value: clause.Expr{SQL: "excluded.hidden"}},
+			{Column: clause.Column{Name: "updated_at"}, Value: clause.Expr{SQL: "excluded.updated_at"}},
+		},
+	}).Create(&model.LeaderboardEntry{
+		Board:  update.board,
+		Period: period,
+		UserID: user.ID,
+		Hidden: user.LeaderboardVisibility == model.LeaderboardVisibilityHidden,
+		Score:  update.value,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update %s leaderboard: %w", update.board, err)
+	}
+	return nil
+}
+
+// setLeaderboardVisibility applies a user's new visibility to their existing entries
+func setLeaderboardVisibility(tx *gorm.DB, userID uint, visibility model.LeaderboardVisibility) error {
+	if err := tx.Model(&model.LeaderboardEntry{}).
+		Where("user_id = ?", userID).
+		Update("hidden", visibility == model.LeaderboardVisibilityHidden).Error; err != nil {
+		return fmt.Errorf("failed to update leaderboard entries: %w", err)
+	}
+	return nil
+}
+
+// LeaderboardRow is one ranked user on a leaderboard
+type LeaderboardRow struct {
+	Rank      int     `json:"rank"`
+	UserID    uint    `json:"user_id,omitempty"` // Left out for anonymous users
+	Name      string  `json:"name"`
+	Avatar    string  `json:"avatar,omitempty"`
+	Anonymous bool    `json:"anonymous,omitempty"`
+	Score     float64 `json:"score"`
+}
+
+// LeaderboardResponse is one page of a leaderboard
+type LeaderboardResponse struct {
+	Board   string           `json:"board"`
+	Window  string           `json:"window"`
+	Period  string           `json:"period"`
+	Total   int64            `json:"total"`
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+	Entries []LeaderboardRow `json:"entries"`
+}
+
+// LeaderboardRankResponse is a user's own place on a leaderboard
+type LeaderboardRankResponse struct {
+	Board      string                      `json:"board"`
+	Window     string                      `json:"window"`
+	Period     string                      `json:"period"`
+	Ranked     bool                        `json:"ranked"` // False until the user has a score in this period
+	Rank       int                         `json:"rank,omitempty"`
+	Score      float64                     `json:"score"`
+	Total      int64                       `json:"total"`
+	Visibility model.LeaderboardVisibility `json:"visibility"` // Hidden users see where they would rank
+}
+
+// leaderboardScope scopes a query to the visible entries of one board and period
+func (s *LeaderboardService) leaderboardScope(board, period string) *gorm.DB {
+	return s.db.Model(&model.LeaderboardEntry{}).
+		Where("leaderboard_entries.board = ? AND leaderboard_entries.period = ? AND leaderboard_entries.hidden = ?", board, period, false)
+}
+
+// resolve validates a board and window and returns the current period
+func (s *LeaderboardService) resolve(board, window string) (string, error) {
+	if !validLeaderboard(board) {
+		return "", errors.New("unknown_board")
+	}
+	return leaderboardPeriod(window, s.clock())
+}
+
+// GetLeaderboard returns one page of a leaderboard, highest score first
+func (s *LeaderboardService) GetLeaderboard(board, window string, limit, offset int) (*LeaderboardResponse, error) {
+	if window == "" {
+		window = LeaderboardWindowAllTime
+	}
+	period, err := s.resolve(board, window)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > leaderboardMaxLimit {
+		limit = leaderboardDefaultLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	response := &LeaderboardResponse{
+		Board:   board,
+		Window:  window,
+		Period:  period,
+		Limit:   limit,
+		Offset:  offset,
+		Entries: []LeaderboardRow{},
+	}
+	if err := s.leaderboardScope(board, period).Count(&response.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count leaderboard: %w", err)
+	}
+
+	rows, _, err := s.rows(board, period, false, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return response, nil
+	}
+
+	// Tied scores share a rank, so the first row's rank has to be counted
+	rank, err := s.rankOf(board, period, rows[0].Score)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		if i > 0 && rows[i].Score != rows[i-1].Score {
+			rank = offset + i + 1
+		}
+		rows[i].Rank = rank
+	}
+	response.Entries = rows
+	return response, nil
+}
+
+// rows returns visible entries with their display names, highest score first
+// or lowest first when ascending, along with the user ID behind each row
+func (s *LeaderboardService) rows(board, period string, ascending bool, limit, offset int) ([]LeaderboardRow, []uint, error) {
+	order := "leaderboard_entries.score DESC, leaderboard_entries.user_id"
+	if ascending {
+		order = "leaderboard_entries.score ASC, leaderboard_entries.user_id"
+	}
+
+	var entries []struct {
+		UserID     uint
+		Score      float64
+		Username   string
+		Name       string
+		Avatar     string
+		Visibility model.LeaderboardVisibility
+	}
+	if err := s.leaderboardScope(board, period).
+		Select("leaderboard_entries.user_id, leaderboard_entries.score, users.username, users.name, users.avatar, users.leaderboard_visibility AS visibility").
+		Joins("JOIN users ON users.id = leaderboard_entries.user_id").
+		Order(order).Limit(limit).Offset(offset).
+		Scan(&entries).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+
+	rows := make([]LeaderboardRow, len(entries))
+	userIDs := make([]uint, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+		if entry.Visibility == model.LeaderboardVisibilityAnonymous {
+			rows[i] = LeaderboardRow{Name: leaderboardAnonymous, Anonymous: true, Score: roundMoney(entry.Score)}
+			continue
+		}
+		name := entry.Username
+		if name == "" {
+			name = entry.Name
+		}
+		rows[i] = LeaderboardRow{UserID: entry.UserID, Name: name, Avatar: entry.Avatar, Score: roundMoney(entry.Score)}
+	}
+	return rows, userIDs, nil
+}
+
+// rankOf returns the rank a score has among visible entries
+func (s *LeaderboardService) rankOf(board, period string, score float64) (int, error) {
+	var higher int64
+	if err := s.leaderboardScope(board, period).Where("leaderboard_entries.score > ?", score).Count(&higher).Error; err != nil {
+		return 0, fmt.Errorf("failed to rank: %w", err)
+	}
+	return int(higher) + 1, nil
+}
+
+// GetUserRank returns the user's rank and score on a leaderboard
+func (s *LeaderboardService) GetUserRank(userID uint, board, window string) (*LeaderboardRankResponse, error) {
+	if window == "" {
+		window = LeaderboardWindowAllTime
+	}
+	period, err := s.resolve(board, window)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.User
+	if err := s.db.Select("id", "leaderboard_visibility").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user_not_found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	response := &LeaderboardRankResponse{
+		Board:      board,
+		Window:     window,
+		Period:     period,
+		Visibility: user.LeaderboardVisibility,
+	}
+	if err := s.leaderboardScope(board, period).Count(&response.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count leaderboard: %w", err)
+	}
+
+	var entry model.LeaderboardEntry
+	err = s.db.Where("board = ? AND period = ? AND user_id = ?", board, period, userID).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return response, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard entry: %w", err)
+	}
+
+	response.Ranked = true
+	response.Score = roundMoney(entry.Score)
+	response.Rank, err = s.rankOf(board, period, entry.Score)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Rebuild recomputes every leaderboard for the current periods from history.
+// Use it to backfill; afterwards events keep the scores current. The highest
+// debt ever reached isn't recorded anywhere, so most_debt starts from what
+// users owe now.
+func (s *LeaderboardService) Rebuild() error {
+	now := s.clock()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&model.LeaderboardEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to clear leaderboards: %w", err)
+		}
+
+		debts := tx.Model(&model.Loan{}).
+			Select("user_id, COALESCE(SUM(remaining_amount), 0) AS score").Group("user_id")
+
+		for _, window := range leaderboardWindows {
+			period, _ := leaderboardPeriod(window, now)
+			since := time.Time{}
+			if window != LeaderboardWindowAllTime {
+				start, _ := time.Parse("2006-01-02", period[1:])
+				since = start.Local()
+			}
+
+			games := tx.Model(&model.Transaction{}).Where("type IN ? AND created_at >= ?", gameTransactionTypes, since)
+			queries := map[string]*gorm.DB{
+				LeaderboardNetProfit: games.Session(&gorm.Session{}).
+					Select("user_id, SUM(" + gameNetAmountSQL + ") AS score").Group("user_id"),
+				LeaderboardBiggestWin: games.Session(&gorm.Session{}).
+					Select("user_id, MAX(" + gameNetAmountSQL + ") AS score").Group("user_id").
+					Having("MAX(" + gameNetAmountSQL + ") > 0"),
+				LeaderboardHoursWorked: tx.Model(&model.WorkSession{}).Where("completed_at >= ?", since).
+					Select("user_id, SUM(duration_seconds) / 3600.0 AS score").Group("user_id"),
+				LeaderboardMostDebt: debts,
+			}
+
+			for _, board := range LeaderboardBoards {
+				var scores []struct {
+					UserID uint
+					Score  float64
+				}
+				if err := queries[board].Scan(&scores).Error; err != nil {
+					return fmt.Errorf("failed to compute %s leaderboard: %w", board, err)
+				}
+
+				entries := make([]model.LeaderboardEntry, 0, len(scores))
+				for _, score := range scores {
+					entries = append(entries, model.LeaderboardEntry{Board: board, Period: period, UserID: score.UserID, Score: roundMoney(score.Score)})
+				}
+				if len(entries) == 0 {
+					continue
+				}
+				if err := tx.CreateInBatches(entries, 500).Error; err != nil {
+					return fmt.Errorf("failed to save %s leaderboard: %w", board, err)
+				}
+			}
+		}
+
+		// Hidden users keep their scores but stay out of rankings
+		return tx.Model(&model.LeaderboardEntry{}).
+			Where("user_id IN (?)", tx.Model(&model.User{}).Select("id").
+				Where("leaderboard_visibility = ?", model.LeaderboardVisibilityHidden)).
+			Update("hidden", true).Error
+	})
+}
+
+// RebuildIfEmpty backfills the leaderboards the first time they are used
+func (s *LeaderboardService) RebuildIfEmpty() error {
+	var count int64
+	if err := s.db.Model(&model.LeaderboardEntry{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count leaderboard entries: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	return s.Rebuild()
+}
+
+// PruneLeaderboards deletes daily and weekly entries for periods that started
+// more than the retention period ago
+func (s *LeaderboardService) PruneLeaderboards(retention time.Duration) (int64, error) {
+	cutoff := s.clock().Add(-retention).UTC().Format("2006-01-02")
+	result := s.db.Where("(period LIKE 'd%' AND period < ?) OR (period LIKE 'w%' AND period < ?)", "d"+cutoff, "w"+cutoff).
+		Delete(&model.LeaderboardEntry{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune leaderboards: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderboardPeriods(t *testing.T) {
+	sunday := time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC)
+
+	daily, err := leaderboardPeriod(LeaderboardWindowDaily, sunday)
+	require.NoError(t, err)
+	assert.Equal(t, "d2026-10-18", daily)
+
+	weekly, err := leaderboardPeriod(LeaderboardWindowWeekly, sunday)
+	require.NoError(t, err)
+	assert.Equal(t, "w2026-10-12", weekly)
+
+	_, err = leaderboardPeriod("hourly", sunday)
+	assert.EqualError(t, err, "unknown_window")
+}
+
+func TestLeaderboardUpdatesAndRanks(t *testing.T) {
+	db := setupTestDB(t)
+	alice := createTestUser(t, db, 1000)
+	bob := createTestUser(t, db, 1000)
+	carol := createTestUser(t, db, 1000)
+	service := &LeaderboardService{db: db}
+
+	bet := func(userID uint, bet, payout float64) {
+		require.NoError(t, service.HandleEvent(events.BetSettled{UserID: userID, GameType: model.GameTypeSlots, Bet: bet, Payout: payout}))
+	}
+	bet(alice.ID, 10, 110) // +100
+	bet(alice.ID, 10, 0)   // -10
+	bet(bob.ID, 10, 100)   // +90
+	bet(carol.ID, 50, 0)   // -50
+
+	board, err := service.GetLeaderboard(LeaderboardNetProfit, LeaderboardWindowDaily, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), board.Total)
+	require.Len(t, board.Entries, 2)
+	assert.Equal(t, 1, board.Entries[0].Rank)
+	assert.Equal(t, 90.0, board.Entries[0].Score)
+	assert.Equal(t, 90.0, board.Entries[1].Score)
+	assert.Equal(t, 1, board.Entries[1].Rank, "tied scores share a rank")
+
+	biggest, err := service.GetLeaderboard(LeaderboardBiggestWin, LeaderboardWindowAllTime, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, biggest.Entries, 2)
+	assert.Equal(t, alice.ID, biggest.Entries[0].UserID)
+	assert.Equal(t, 100.0, biggest.Entries[0].Score)
+
+	rank, err := service.GetUserRank(carol.ID, LeaderboardNetProfit, LeaderboardWindowWeekly)
+	require.NoError(t, err)
+	assert.True(t, rank.Ranked)
+	assert.Equal(t, 3, rank.Rank)
+	assert.Equal(t, -50.0, rank.Score)
+
+	// Anonymous players keep their rank without their name; hidden ones drop out
+	users := &UserService{db: db}
+	anonymous, hidden := model.LeaderboardVisibilityAnonymous, model.LeaderboardVisibilityHidden
+	_, err = users.UpdateProfile(alice.ID, UpdateProfileRequest{LeaderboardVisibility: &anonymous})
+	require.NoError(t, err)
+	_, err = users.UpdateProfile(bob.ID, UpdateProfileRequest{LeaderboardVisibility: &hidden})
+	require.NoError(t, err)
+
+	board, err = service.GetLeaderboard(LeaderboardNetProfit, LeaderboardWindowAllTime, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), board.Total)
+	require.Len(t, board.Entries, 2)
+	assert.True(t, board.Entries[0].Anonymous)
+	assert.Zero(t, board.Entries[0].UserID)
+	assert.Equal(t, carol.ID, board.Entries[1].UserID)
+	assert.Equal(t, 2, board.Entries[1].Rank)
+
+	// Later events for a hidden user stay hidden
+	bet(bob.ID, 10, 500)
+	rank, err = service.GetUserRank(bob.ID, LeaderboardNetProfit, LeaderboardWindowAllTime)
+	require.NoError(t, err)
+	assert.Equal(t, model.LeaderboardVisibilityHidden, rank.Visibility)
+	assert.Equal(t, 1, rank.Rank, "hidden users see where they would rank")
+	assert.Equal(t, int64(2), rank.Total)
+
+	_, err = service.GetLeaderboard("luck", LeaderboardWindowDaily, 10, 0)
+	assert.EqualError(t, err, "unknown_board")
+}
+
+func TestLeaderboardRebuild(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &LeaderboardService{db: db}
+
+	addTransactions(t, db, user.ID, model.TransactionTypeGameWin, 40)
+	addTransactions(t, db, user.ID, model.TransactionTypeGameLoss, 15)
+	require.NoError(t, db.Create(&model.WorkSession{UserID: user.ID, DurationSeconds: 5400, Earned: 500, CompletedAt: time.Now()}).Error)
+
+	require.NoError(t, service.RebuildIfEmpty())
+	// Already built, so events are what keep it current from here
+	require.NoError(t, service.HandleEvent(events.WorkCompleted{UserID: user.ID, DurationSeconds: 1800}))
+	require.NoError(t, service.RebuildIfEmpty())
+
+	for board, score := range map[string]float64{
+		LeaderboardNetProfit:   25,
+		LeaderboardBiggestWin:  40,
+		LeaderboardHoursWorked: 2,
+	} {
+		rank, err := service.GetUserRank(user.ID, board, LeaderboardWindowDaily)
+		require.NoError(t, err)
+		assert.Equal(t, score, rank.Score, board)
+	}
+}
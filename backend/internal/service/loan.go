@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
 	"github.com/smoreg/freezino/backend/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // LoanService provides business logic for loan operations
@@ -32,12 +34,20 @@ const (
 	MicrocreditInterestRate = 2.0    // 200% annual rate (predatory)
 )
 
+// Collections timing
+const (
+	CollectionsGracePeriod = 7 * 24 * time.Hour // Overdue loans go to collectors after this
+	loanBatchSize          = 200
+)
+
 // TakeLoanRequest represents a loan application request
 type TakeLoanRequest struct {
-	UserID           uint           `json:"user_id"`
-	Amount           float64        `json:"amount"`
-	Type             model.LoanType `json:"type"`
-	CollateralItemID *uint          `json:"collateral_item_id,omitempty"` // Required for bank loans
+	UserID           uint                    `json:"user_id"`
+	Amount           float64                 `json:"amount"`
+	Type             model.LoanType          `json:"type"`
+	CollateralItemID *uint                   `json:"collateral_item_id,omitempty"` // Required for bank loans
+	RepaymentPlan    model.LoanRepaymentPlan `json:"repayment_plan,omitempty"`     // Defaults to the loan type's standard plan
+	Installments     int                     `json:"installments,omitempty"`       // Number of annuity installments
 }
 
 // TakeLoanResponse represents the response after taking a loan
@@ -53,6 +63,34 @@ type RepayLoanRequest struct {
 	Amount float64 `json:"amount"`
 }
 
+// RepayLoanResponse represents the result of a loan repayment
+type RepayLoanResponse struct {
+	Loan       model.Loan        `json:"loan"`
+	Allocation PaymentAllocation `json:"allocation"`
+	NewBalance float64           `json:"new_balance"`
+	PaidOff    bool              `json:"paid_off"`
+}
+
+// LoanScheduleResponse represents a loan's amortization schedule
+type LoanScheduleResponse struct {
+	Loan          model.Loan              `json:"loan"`
+	Installments  []model.LoanInstallment `json:"installments"`
+	EffectiveAPR  float64                 `json:"effective_apr"`
+	TotalInterest float64                 `json:"total_interest"` // Scheduled interest over the whole term
+	TotalPayable  float64                 `json:"total_payable"`  // Scheduled principal + interest
+}
+
+// LoanStatementResponse represents the money movements on a loan
+type LoanStatementResponse struct {
+	Loan              model.Loan                 `json:"loan"`
+	Entries           []model.LoanStatementEntry `json:"entries"`
+	TotalPaid         float64                    `json:"total_paid"`
+	TotalFeesCharged  float64                    `json:"total_fees_charged"`
+	TotalFeesPaid     float64                    `json:"total_fees_paid"`
+	TotalInterestPaid float64                    `json:"total_interest_paid"`
+	TotalPrincipal    float64                    `json:"total_principal_paid"`
+}
+
 // TakeLoan processes a new loan application
 func (s *LoanService) TakeLoan(req TakeLoanRequest) (*TakeLoanResponse, error) {
 	// Validate amount
@@ -60,6 +98,16 @@ func (s *LoanService) TakeLoan(req TakeLoanRequest) (*TakeLoanResponse, error) {
 		return nil, errors.New("loan amount must be positive")
 	}
 
+	terms, ok := GetLoanTerms(req.Type)
+	if !ok {
+		return nil, errors.New("invalid loan type")
+	}
+
+	plan, installments, err := terms.resolvePlan(req.RepaymentPlan, req.Installments)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get user
 	var user model.User
 	if err := s.db.First(&user, req.UserID).Error; err != nil {
@@ -72,50 +120,168 @@ func (s *LoanService) TakeLoan(req TakeLoanRequest) (*TakeLoanResponse, error) {
 		return nil, fmt.Errorf("failed to get loan summary: %w", err)
 	}
 
+	// The credit score decides eligibility, the limit and the rate
+	report, err := (&CreditService{db: s.db}).GetCreditReport(req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit report: %w", err)
+	}
+	offer, _ := report.Offer(req.Type)
+	if !offer.Eligible {
+		if req.Type == model.LoanTypeFriends {
+			return nil, errors.New("friends_refused")
+		}
+		return nil, errors.New("credit_score_too_low")
+	}
+	if req.Type != model.LoanTypeFriends {
+		// The limit covers everything still owed on loans of this type
+		outstanding, err := s.outstandingPrincipal(req.UserID, req.Type)
+		if err != nil {
+			return nil, err
+		}
+		if outstanding+req.Amount > offer.MaxAmount {
+			return nil, errors.New("credit_limit_exceeded")
+		}
+	}
+
+	// Equipped items such as houses can discount the rate
+	modifiers, err := LoadItemModifiers(s.db, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	terms.APR = modifiers.LoanAPR(req.Type, offer.APR)
+	terms.PenaltyAPR = offer.PenaltyAPR
+
+	loan := newLoan(req, terms, plan, installments, time.Now())
+
 	// Type-specific validation
+	var response *TakeLoanResponse
 	switch req.Type {
 	case model.LoanTypeFriends:
-		return s.takeFriendsLoan(req, user, summary)
+		response, err = s.takeFriendsLoan(req, user, summary, loan, terms, offer.MaxAmount)
 	case model.LoanTypeBank:
-		return s.takeBankLoan(req, user)
-	case model.LoanTypeMicrocredit:
-		return s.takeMicrocreditLoan(req, user)
+		response, err = s.takeBankLoan(req, user, loan, terms)
 	default:
-		return nil, errors.New("invalid loan type")
+		response, err = s.takeMicrocreditLoan(user, loan, terms)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	flushEvents(s.db)
+	return response, nil
+}
+
+// outstandingPrincipal sums the principal still owed on a user's loans of one type
+func (s *LoanService) outstandingPrincipal(userID uint, loanType model.LoanType) (float64, error) {
+	var loans []model.Loan
+	if err := s.db.Where("user_id = ? AND type = ?", userID, loanType).Find(&loans).Error; err != nil {
+		return 0, fmt.Errorf("failed to get loans: %w", err)
+	}
+
+	total := 0.0
+	for i := range loans {
+		normalizeLoanBalance(&loans[i])
+		total += loans[i].OutstandingPrincipal
+	}
+	return roundMoney(total), nil
+}
+
+// newLoan builds a loan on the given terms with its balance buckets initialised
+func newLoan(req TakeLoanRequest, terms LoanTerms, plan model.LoanRepaymentPlan, installments int, now time.Time) model.Loan {
+	termDays := terms.termDays(plan, installments)
+	dueAt := now.AddDate(0, 0, termDays)
+
+	return model.Loan{
+		UserID:               req.UserID,
+		Type:                 terms.Type,
+		PrincipalAmount:      req.Amount,
+		RemainingAmount:      req.Amount,
+		InterestRate:         terms.APR,
+		InterestPerSecond:    req.Amount * (terms.APR / secondsPerYear),
+		LastInterestAt:       now,
+		Status:               model.LoanStatusActive,
+		DueAt:                &dueAt,
+		RepaymentPlan:        plan,
+		TermDays:             termDays,
+		InstallmentCount:     installments,
+		LateFee:              terms.LateFee,
+		PenaltyRate:          terms.PenaltyAPR,
+		OutstandingPrincipal: req.Amount,
+	}
+}
+
+// originateLoan creates the loan with its installment schedule and disbursement entry
+func originateLoan(tx *gorm.DB, loan *model.Loan, terms LoanTerms) error {
+	if err := tx.Create(loan).Error; err != nil {
+		return err
+	}
+
+	schedule := BuildSchedule(loan.PrincipalAmount, loan.InterestRate, loan.RepaymentPlan,
+		loan.InstallmentCount, terms.PeriodDays, terms.BulletTermDays, loan.LastInterestAt)
+
+	loan.Installments = make([]model.LoanInstallment, len(schedule))
+	for i, row := range schedule {
+		loan.Installments[i] = model.LoanInstallment{
+			LoanID:       loan.ID,
+			Number:       row.Number,
+			DueAt:        row.DueAt,
+			PrincipalDue: row.PrincipalDue,
+			InterestDue:  row.InterestDue,
+			AmountDue:    row.AmountDue,
+			Status:       model.InstallmentStatusPending,
+		}
+	}
+	if err := tx.Create(&loan.Installments).Error; err != nil {
+		return err
+	}
+
+	if err := events.Record(tx, events.LoanTaken{
+		UserID:   loan.UserID,
+		LoanID:   loan.ID,
+		LoanType: loan.Type,
+		Amount:   loan.PrincipalAmount,
+		APR:      loan.InterestRate,
+	}); err != nil {
+		return err
+	}
+
+	entry := model.LoanStatementEntry{
+		LoanID:       loan.ID,
+		UserID:       loan.UserID,
+		Type:         model.LoanEntryDisbursement,
+		Amount:       loan.PrincipalAmount,
+		BalanceAfter: loan.RemainingAmount,
+		Description:  fmt.Sprintf("%s loan disbursed (%s, %d installment(s))", loan.Type, loan.RepaymentPlan, loan.InstallmentCount),
+	}
+	return tx.Create(&entry).Error
 }
 
 // takeFriendsLoan processes a loan from friends
-func (s *LoanService) takeFriendsLoan(req TakeLoanRequest, user model.User, summary *model.LoanSummary) (*TakeLoanResponse, error) {
+func (s *LoanService) takeFriendsLoan(req TakeLoanRequest, user model.User, summary *model.LoanSummary, loan model.Loan, terms LoanTerms, maxTotal float64) (*TakeLoanResponse, error) {
 	// Check if user exceeded friend loan limit
 	if summary.FriendsLoanCount >= FriendsMaxLoans {
 		return nil, errors.New("friends_refused")
 	}
 
 	// Check if total borrowed from friends exceeds limit
-	if summary.TotalFriendsLoaned+req.Amount > FriendsMaxTotal {
+	if summary.TotalFriendsLoaned+req.Amount > maxTotal {
 		return nil, errors.New("friends_limit_exceeded")
 	}
 
-	// Create loan
-	loan := model.Loan{
-		UserID:            req.UserID,
-		Type:              model.LoanTypeFriends,
-		PrincipalAmount:   req.Amount,
-		RemainingAmount:   req.Amount, // No interest
-		InterestRate:      FriendsInterestRate,
-		InterestPerSecond: 0, // Friends don't charge interest
-		LastInterestAt:    time.Now(),
-	}
+	// Friends don't charge interest
+	loan.InterestPerSecond = 0
 
-	if err := s.db.Create(&loan).Error; err != nil {
-		return nil, fmt.Errorf("failed to create loan: %w", err)
-	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := originateLoan(tx, &loan, terms); err != nil {
+			return err
+		}
 
-	// Add money to user balance
-	user.Balance += req.Amount
-	if err := s.db.Save(&user).Error; err != nil {
-		return nil, fmt.Errorf("failed to update user balance: %w", err)
+		// Add money to user balance
+		user.Balance += req.Amount
+		return tx.Save(&user).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create loan: %w", err)
 	}
 
 	return &TakeLoanResponse{
@@ -126,7 +292,7 @@ func (s *LoanService) takeFriendsLoan(req TakeLoanRequest, user model.User, summ
 }
 
 // takeBankLoan processes a bank loan with collateral
-func (s *LoanService) takeBankLoan(req TakeLoanRequest, user model.User) (*TakeLoanResponse, error) {
+func (s *LoanService) takeBankLoan(req TakeLoanRequest, user model.User, loan model.Loan, terms LoanTerms) (*TakeLoanResponse, error) {
 	// Require collateral
 	if req.CollateralItemID == nil {
 		return nil, errors.New("collateral_required")
@@ -148,39 +314,29 @@ func (s *LoanService) takeBankLoan(req TakeLoanRequest, user model.User) (*TakeL
 		return nil, errors.New("item_already_collateral")
 	}
 
+	// Listed items are held in escrow by the market
+	if userItem.IsListed {
+		return nil, errors.New("item_listed")
+	}
+
 	// Verify collateral value is sufficient (must be >= loan amount)
 	// Bank requires car or house as collateral
 	if userItem.Item.Type != model.ItemTypeCar && userItem.Item.Type != model.ItemTypeHouse {
 		return nil, errors.New("collateral_must_be_car_or_house")
 	}
 
-	// Calculate sell value (50% of purchase price)
-	collateralValue := userItem.Item.Price * 0.5
+	// The bank lends against what the item would fetch at resale
+	collateralValue := ResaleValue(&userItem, time.Now())
 	if collateralValue < req.Amount {
 		return nil, errors.New("collateral_insufficient")
 	}
 
-	// Calculate interest per second from annual rate
-	// Formula: principal * (rate / seconds_per_year)
-	secondsPerYear := 365.25 * 24 * 60 * 60
-	interestPerSecond := req.Amount * (BankInterestRate / secondsPerYear)
-
-	// Create loan
-	loan := model.Loan{
-		UserID:            req.UserID,
-		Type:              model.LoanTypeBank,
-		PrincipalAmount:   req.Amount,
-		RemainingAmount:   req.Amount,
-		InterestRate:      BankInterestRate,
-		InterestPerSecond: interestPerSecond,
-		CollateralItemID:  req.CollateralItemID,
-		LastInterestAt:    time.Now(),
-	}
+	loan.CollateralItemID = req.CollateralItemID
 
 	// Use transaction to ensure atomicity
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		// Create loan
-		if err := tx.Create(&loan).Error; err != nil {
+		// Create loan with its schedule
+		if err := originateLoan(tx, &loan, terms); err != nil {
 			return err
 		}
 
@@ -211,34 +367,19 @@ func (s *LoanService) takeBankLoan(req TakeLoanRequest, user model.User) (*TakeL
 }
 
 // takeMicrocreditLoan processes a microcredit loan
-func (s *LoanService) takeMicrocreditLoan(req TakeLoanRequest, user model.User) (*TakeLoanResponse, error) {
+func (s *LoanService) takeMicrocreditLoan(user model.User, loan model.Loan, terms LoanTerms) (*TakeLoanResponse, error) {
 	// Microcredit is available to everyone, no collateral required
 	// But charges very high interest
 
-	// Calculate interest per second from annual rate
-	secondsPerYear := 365.25 * 24 * 60 * 60
-	interestPerSecond := req.Amount * (MicrocreditInterestRate / secondsPerYear)
-
-	// Create loan
-	loan := model.Loan{
-		UserID:            req.UserID,
-		Type:              model.LoanTypeMicrocredit,
-		PrincipalAmount:   req.Amount,
-		RemainingAmount:   req.Amount,
-		InterestRate:      MicrocreditInterestRate,
-		InterestPerSecond: interestPerSecond,
-		LastInterestAt:    time.Now(),
-	}
-
 	// Use transaction
 	err := s.db.Transaction(func(tx *gorm.DB) error {
-		// Create loan
-		if err := tx.Create(&loan).Error; err != nil {
+		// Create loan with its schedule
+		if err := originateLoan(tx, &loan, terms); err != nil {
 			return err
 		}
 
 		// Update user balance
-		user.Balance += req.Amount
+		user.Balance += loan.PrincipalAmount
 		if err := tx.Save(&user).Error; err != nil {
 			return err
 		}
@@ -281,6 +422,10 @@ func (s *LoanService) GetLoanSummary(userID uint) (*model.LoanSummary, error) {
 		summary.TotalDebt += loan.RemainingAmount
 		summary.InterestPerSecond += loan.InterestPerSecond
 
+		if loan.Status != model.LoanStatusActive {
+			summary.OverdueLoans++
+		}
+
 		if loan.Type == model.LoanTypeFriends {
 			summary.FriendsLoanCount++
 			summary.TotalFriendsLoaned += loan.PrincipalAmount
@@ -312,21 +457,64 @@ func (s *LoanService) UpdateAllLoansInterest(userID uint) error {
 		return fmt.Errorf("failed to get loans: %w", err)
 	}
 
-	now := time.Now()
-	for i := range loans {
-		loan := &loans[i]
+	return s.accrueInterest(loans, time.Now())
+}
 
-		// Calculate elapsed time since last interest calculation
-		elapsed := now.Sub(loan.LastInterestAt).Seconds()
+// accrueInterest adds interest accrued since LastInterestAt to each loan.
+// Interest accrues on the outstanding principal at the loan's APR, or at its
+// penalty APR while the loan is overdue or in collection. Each loan is re-read
+// under a lock, since repayments and collections run alongside, and the update
+// only applies if nobody accrued or deleted it in the meantime.
+func (s *LoanService) accrueInterest(loans []model.Loan, now time.Time) error {
+	defer flushEvents(s.db)
 
-		// Calculate and add interest
-		interestAccrued := loan.InterestPerSecond * elapsed
-		loan.RemainingAmount += interestAccrued
-		loan.RemainingAmount = math.Round(loan.RemainingAmount*100) / 100 // Round to 2 decimals
-		loan.LastInterestAt = now
+	for i := range loans {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var loan model.Loan
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&loan, loans[i].ID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil // Repaid or written off since it was listed
+				}
+				return err
+			}
+			legacy := loan.OutstandingPrincipal == 0
+			normalizeLoanBalance(&loan)
 
-		// Save updated loan
-		if err := s.db.Save(loan).Error; err != nil {
+			// Calculate elapsed time since last interest calculation
+			elapsed := now.Sub(loan.LastInterestAt).Seconds()
+			if elapsed <= 0 {
+				return nil
+			}
+
+			// Calculate and add interest
+			interestPerSecond := loan.OutstandingPrincipal * (currentRate(&loan) / secondsPerYear)
+			interest := interestPerSecond * elapsed
+			updates := map[string]interface{}{
+				"accrued_interest":    gorm.Expr("accrued_interest + ?", interest),
+				"remaining_amount":    gorm.Expr("remaining_amount + ?", interest),
+				"interest_per_second": interestPerSecond,
+				"last_interest_at":    now,
+			}
+			if legacy && loan.OutstandingPrincipal > 0 {
+				updates["outstanding_principal"] = loan.OutstandingPrincipal
+			}
+			result := tx.Model(&model.Loan{}).
+				Where("id = ? AND deleted_at IS NULL AND last_interest_at = ?", loan.ID, loan.LastInterestAt).
+				Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 || interest <= 0 {
+				return nil // Accrued by someone else first
+			}
+			return events.Record(tx, events.LoanAccrued{
+				UserID:    loan.UserID,
+				LoanID:    loan.ID,
+				Interest:  interest,
+				Remaining: roundMoney(loan.RemainingAmount + interest),
+			})
+		})
+		if err != nil {
 			return fmt.Errorf("failed to update loan interest: %w", err)
 		}
 	}
@@ -334,142 +522,420 @@ func (s *LoanService) UpdateAllLoansInterest(userID uint) error {
 	return nil
 }
 
-// RepayLoan processes a loan repayment
-func (s *LoanService) RepayLoan(userID uint, req RepayLoanRequest) error {
+// currentRate returns the APR currently applied to a loan
+func currentRate(loan *model.Loan) float64 {
+	if loan.Status != model.LoanStatusActive && loan.PenaltyRate > loan.InterestRate {
+		return loan.PenaltyRate
+	}
+	return loan.InterestRate
+}
+
+// loanBalance returns the total owed across principal, interest and fees
+func loanBalance(loan *model.Loan) float64 {
+	return roundMoney(loan.OutstandingPrincipal + loan.AccruedInterest + loan.OutstandingFees)
+}
+
+// normalizeLoanBalance moves loans created before the balance breakdown existed
+// onto the principal bucket so allocation works for them too
+func normalizeLoanBalance(loan *model.Loan) {
+	if loan.OutstandingPrincipal == 0 && loan.AccruedInterest == 0 && loan.OutstandingFees == 0 && loan.RemainingAmount > 0 {
+		loan.OutstandingPrincipal = loan.RemainingAmount
+	}
+}
+
+// AccrueInterestForAllLoans accrues interest on every loan, so debts grow while players are offline
+func (s *LoanService) AccrueInterestForAllLoans() (int, error) {
+	var loans []model.Loan
+	processed := 0
+	now := time.Now()
+
+	err := s.db.Where("interest_per_second > 0").FindInBatches(&loans, loanBatchSize, func(tx *gorm.DB, batch int) error {
+		if err := s.accrueInterest(loans, now); err != nil {
+			return err
+		}
+		processed += len(loans)
+		return nil
+	}).Error
+	if err != nil {
+		return processed, fmt.Errorf("failed to accrue interest: %w", err)
+	}
+
+	return processed, nil
+}
+
+// MarkOverdueLoans marks installments past their due date as missed, charges
+// the loan's late fee for each and flags the loan overdue. Loans without an
+// installment schedule are flagged once their due date has passed.
+func (s *LoanService) MarkOverdueLoans() (int64, error) {
+	now := time.Now()
+
+	var missed []model.LoanInstallment
+	if err := s.db.Where("status = ? AND due_at < ?", model.InstallmentStatusPending, now).
+		Order("loan_id, number").
+		Find(&missed).Error; err != nil {
+		return 0, fmt.Errorf("failed to find missed installments: %w", err)
+	}
+
+	var count int64
+	for i := range missed {
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			return s.markInstallmentMissed(tx, &missed[i], now)
+		}); err != nil {
+			return count, fmt.Errorf("failed to mark installment %d missed: %w", missed[i].ID, err)
+		}
+		count++
+	}
+
+	// Legacy loans have no installments, only a due date
+	result := s.db.Model(&model.Loan{}).
+		Where("status = ? AND due_at IS NOT NULL AND due_at < ?", model.LoanStatusActive, now).
+		Where("NOT EXISTS (SELECT 1 FROM loan_installments WHERE loan_installments.loan_id = loans.id)").
+		Updates(map[string]interface{}{
+			"status":     model.LoanStatusOverdue,
+			"overdue_at": now,
+		})
+	if result.Error != nil {
+		return count, fmt.Errorf("failed to mark overdue loans: %w", result.Error)
+	}
+
+	return count + result.RowsAffected, nil
+}
+
+// markInstallmentMissed flags one installment missed and charges its late fee
+func (s *LoanService) markInstallmentMissed(tx *gorm.DB, installment *model.LoanInstallment, now time.Time) error {
+	var loan model.Loan
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&loan, installment.LoanID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Loan was repaid or collected; nothing left to charge
+			return tx.Model(installment).Update("status", model.InstallmentStatusMissed).Error
+		}
+		return err
+	}
+	normalizeLoanBalance(&loan)
+
+	if err := tx.Model(installment).Update("status", model.InstallmentStatusMissed).Error; err != nil {
+		return err
+	}
+
+	loan.OutstandingFees = roundMoney(loan.OutstandingFees + loan.LateFee)
+	loan.RemainingAmount = loanBalance(&loan)
+	if loan.Status == model.LoanStatusActive {
+		loan.Status = model.LoanStatusOverdue
+		loan.OverdueAt = &now
+	}
+	if err := tx.Save(&loan).Error; err != nil {
+		return err
+	}
+
+	if loan.LateFee <= 0 {
+		return nil
+	}
+
+	entry := model.LoanStatementEntry{
+		LoanID:       loan.ID,
+		UserID:       loan.UserID,
+		Type:         model.LoanEntryLateFee,
+		Amount:       loan.LateFee,
+		BalanceAfter: loan.RemainingAmount,
+		Description:  fmt.Sprintf("Late fee for missed installment #%d", installment.Number),
+	}
+	return tx.Create(&entry).Error
+}
+
+// RepayLoan processes a loan repayment. Payments go to outstanding fees
+// first, then accrued interest, then principal, and are credited to the
+// oldest unpaid installments.
+func (s *LoanService) RepayLoan(userID uint, req RepayLoanRequest) (*RepayLoanResponse, error) {
 	if req.Amount <= 0 {
-		return errors.New("repayment amount must be positive")
+		return nil, errors.New("repayment amount must be positive")
 	}
 
 	// Get loan
 	var loan model.Loan
-	if err := s.db.Preload("CollateralItem").First(&loan, req.LoanID).Error; err != nil {
-		return errors.New("loan_not_found")
+	if err := s.db.First(&loan, req.LoanID).Error; err != nil {
+		return nil, errors.New("loan_not_found")
 	}
 
 	// Verify ownership
 	if loan.UserID != userID {
-		return errors.New("not_your_loan")
+		return nil, errors.New("not_your_loan")
 	}
 
 	// Update interest before repayment
 	if err := s.UpdateAllLoansInterest(userID); err != nil {
-		return fmt.Errorf("failed to update interest: %w", err)
+		return nil, fmt.Errorf("failed to update interest: %w", err)
 	}
 
-	// Reload loan to get updated remaining amount
-	if err := s.db.Preload("CollateralItem").First(&loan, req.LoanID).Error; err != nil {
-		return errors.New("loan_not_found")
-	}
+	var response *RepayLoanResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Reload loan to get updated balances
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("CollateralItem").First(&loan, req.LoanID).Error; err != nil {
+			return errors.New("loan_not_found")
+		}
 
-	// Get user
-	var user model.User
-	if err := s.db.First(&user, userID).Error; err != nil {
-		return errors.New("user_not_found")
-	}
+		// Get user
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return errors.New("user_not_found")
+		}
 
-	// Check if user has enough balance
-	if user.Balance < req.Amount {
-		return errors.New("insufficient_balance")
-	}
+		alloc, paidOff, err := applyLoanPayment(tx, &loan, req.Amount, model.LoanEntryPayment, "Repayment")
+		if err != nil {
+			return err
+		}
 
-	// Cap payment at remaining amount
-	paymentAmount := req.Amount
-	if paymentAmount > loan.RemainingAmount {
-		paymentAmount = loan.RemainingAmount
-	}
+		// Only the part of the payment the loan takes has to be covered
+		if user.Balance < alloc.Total {
+			return errors.New("insufficient_balance")
+		}
 
-	// Use transaction
-	err := s.db.Transaction(func(tx *gorm.DB) error {
 		// Deduct from user balance
-		user.Balance -= paymentAmount
+		user.Balance -= alloc.Total
 		if err := tx.Save(&user).Error; err != nil {
 			return err
 		}
+		if err := events.Record(tx, events.BalanceChanged{
+			UserID:  userID,
+			Balance: user.Balance,
+			Change:  -alloc.Total,
+			Reason:  fmt.Sprintf("Repayment of loan #%d", loan.ID),
+		}); err != nil {
+			return err
+		}
 
-		// Reduce loan amount
-		loan.RemainingAmount -= paymentAmount
-		loan.RemainingAmount = math.Round(loan.RemainingAmount*100) / 100
+		response = &RepayLoanResponse{
+			Loan:       loan,
+			Allocation: alloc,
+			NewBalance: user.Balance,
+			PaidOff:    paidOff,
+		}
+		return nil
+	})
 
-		// If loan is fully repaid, release collateral if any
-		if loan.RemainingAmount <= 0.01 { // Account for floating point precision
-			if loan.CollateralItemID != nil && loan.CollateralItem != nil {
-				loan.CollateralItem.IsCollateral = false
-				if err := tx.Save(loan.CollateralItem).Error; err != nil {
-					return err
-				}
-			}
+	if err != nil {
+		switch err.Error() {
+		case "loan_not_found", "user_not_found", "insufficient_balance", "loan_already_repaid":
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to process repayment: %w", err)
+	}
 
-			// Delete loan record
-			if err := tx.Delete(&loan).Error; err != nil {
-				return err
-			}
-		} else {
-			// Save updated loan
-			if err := tx.Save(&loan).Error; err != nil {
-				return err
+	flushEvents(s.db)
+	return response, nil
+}
+
+// applyLoanPayment allocates up to amount to the loan's fees, interest and
+// principal, credits its installments and records a statement entry.
+// A paid-off loan releases its collateral and is closed. The caller moves the
+// money; the returned allocation says how much was actually taken.
+func applyLoanPayment(tx *gorm.DB, loan *model.Loan, amount float64, entryType model.LoanEntryType, description string) (PaymentAllocation, bool, error) {
+	normalizeLoanBalance(loan)
+
+	// Split the payment; anything above the balance is not taken
+	alloc := AllocatePayment(amount, loan.OutstandingFees, loan.AccruedInterest, loan.OutstandingPrincipal)
+	if alloc.Total <= 0 {
+		return alloc, false, errors.New("loan_already_repaid")
+	}
+
+	// Reduce loan buckets
+	loan.OutstandingFees = roundMoney(loan.OutstandingFees - alloc.Fees)
+	loan.AccruedInterest = math.Max(loan.AccruedInterest-alloc.Interest, 0)
+	loan.OutstandingPrincipal = roundMoney(loan.OutstandingPrincipal - alloc.Principal)
+	loan.RemainingAmount = loanBalance(loan)
+	loan.InterestPerSecond = loan.OutstandingPrincipal * (currentRate(loan) / secondsPerYear)
+
+	// Late fees are not part of the schedule, so only interest and principal
+	// count towards installments
+	now := time.Now()
+	if err := applyToInstallments(tx, loan.ID, alloc.Interest+alloc.Principal, now); err != nil {
+		return alloc, false, err
+	}
+
+	entry := model.LoanStatementEntry{
+		LoanID:        loan.ID,
+		UserID:        loan.UserID,
+		Type:          entryType,
+		Amount:        alloc.Total,
+		FeesPaid:      alloc.Fees,
+		InterestPaid:  alloc.Interest,
+		PrincipalPaid: alloc.Principal,
+		BalanceAfter:  loan.RemainingAmount,
+		Description:   description,
+	}
+
+	paidOff := loan.RemainingAmount <= 0.01 // Account for floating point precision
+	if paidOff {
+		entry.Description = description + " (loan closed)"
+
+		// Release collateral if any
+		if loan.CollateralItemID != nil && loan.CollateralItem != nil {
+			loan.CollateralItem.IsCollateral = false
+			if err := tx.Save(loan.CollateralItem).Error; err != nil {
+				return alloc, false, err
 			}
 		}
 
-		return nil
-	})
+		// Close any installments left over from rounding
+		if err := tx.Model(&model.LoanInstallment{}).
+			Where("loan_id = ? AND status <> ?", loan.ID, model.InstallmentStatusPaid).
+			Updates(map[string]interface{}{"status": model.InstallmentStatusPaid, "paid_at": now}).Error; err != nil {
+			return alloc, false, err
+		}
 
-	if err != nil {
-		return fmt.Errorf("failed to process repayment: %w", err)
+		loan.Status = model.LoanStatusActive
+		loan.OverdueAt = nil
+		loan.CollectionStage = model.CollectionStageNone
+		if err := tx.Omit("CollateralItem", "Installments").Save(loan).Error; err != nil {
+			return alloc, false, err
+		}
+
+		// Delete loan record; history stays available through the statement
+		if err := tx.Delete(loan).Error; err != nil {
+			return alloc, false, err
+		}
+	} else {
+		// A loan is back in good standing once every missed installment is paid
+		var missed int64
+		if err := tx.Model(&model.LoanInstallment{}).
+			Where("loan_id = ? AND status = ?", loan.ID, model.InstallmentStatusMissed).
+			Count(&missed).Error; err != nil {
+			return alloc, false, err
+		}
+		if missed == 0 && loan.Status != model.LoanStatusActive && (loan.DueAt == nil || loan.DueAt.After(now)) {
+			loan.Status = model.LoanStatusActive
+			loan.OverdueAt = nil
+			loan.CollectionStage = model.CollectionStageNone
+			loan.CollectionNoticeAt = nil
+		}
+
+		// Save updated loan
+		if err := tx.Omit("CollateralItem", "Installments").Save(loan).Error; err != nil {
+			return alloc, false, err
+		}
+	}
+
+	if err := tx.Create(&entry).Error; err != nil {
+		return alloc, false, err
+	}
+
+	return alloc, paidOff, nil
+}
+
+// applyToInstallments credits a payment to the oldest unpaid installments
+func applyToInstallments(tx *gorm.DB, loanID uint, amount float64, now time.Time) error {
+	var installments []model.LoanInstallment
+	if err := tx.Where("loan_id = ? AND status <> ?", loanID, model.InstallmentStatusPaid).
+		Order("number").
+		Find(&installments).Error; err != nil {
+		return err
+	}
+
+	remaining := amount
+	for i := range installments {
+		if remaining <= 0 {
+			break
+		}
+		inst := &installments[i]
+
+		due := roundMoney(inst.AmountDue - inst.AmountPaid)
+		paid := math.Min(remaining, due)
+		inst.AmountPaid = roundMoney(inst.AmountPaid + paid)
+		remaining = roundMoney(remaining - paid)
+
+		if inst.AmountPaid >= inst.AmountDue {
+			inst.Status = model.InstallmentStatusPaid
+			inst.PaidAt = &now
+		}
+		if err := tx.Save(inst).Error; err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// CheckBankruptcy checks if user is bankrupt and handles debt collection
-// Returns true if user went bankrupt
-func (s *LoanService) CheckBankruptcy(userID uint) (bool, error) {
-	// Get user
-	var user model.User
-	if err := s.db.First(&user, userID).Error; err != nil {
-		return false, fmt.Errorf("user not found: %w", err)
+// findOwnedLoan loads a loan (including repaid ones) and checks ownership
+func (s *LoanService) findOwnedLoan(userID, loanID uint) (*model.Loan, error) {
+	var loan model.Loan
+	if err := s.db.Unscoped().First(&loan, loanID).Error; err != nil {
+		return nil, errors.New("loan_not_found")
+	}
+	if loan.UserID != userID {
+		return nil, errors.New("not_your_loan")
+	}
+	return &loan, nil
+}
+
+// GetLoanSchedule returns a loan's installment schedule
+func (s *LoanService) GetLoanSchedule(userID, loanID uint) (*LoanScheduleResponse, error) {
+	if err := s.UpdateAllLoansInterest(userID); err != nil {
+		return nil, fmt.Errorf("failed to update interest: %w", err)
 	}
 
-	// Get loan summary
-	summary, err := s.GetLoanSummary(userID)
+	loan, err := s.findOwnedLoan(userID, loanID)
 	if err != nil {
-		return false, fmt.Errorf("failed to get loan summary: %w", err)
+		return nil, err
 	}
 
-	// Check bankruptcy condition: balance <= 0 and has active loans
-	if user.Balance <= 0 && summary.ActiveLoans > 0 {
-		// User is bankrupt! Collectors come
-		if err := s.handleCollectors(userID); err != nil {
-			return false, fmt.Errorf("failed to handle collectors: %w", err)
-		}
-		return true, nil
+	var installments []model.LoanInstallment
+	if err := s.db.Where("loan_id = ?", loan.ID).Order("number").Find(&installments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get installments: %w", err)
+	}
+
+	response := &LoanScheduleResponse{
+		Loan:         *loan,
+		Installments: installments,
+		EffectiveAPR: loan.InterestRate,
+	}
+	if terms, ok := GetLoanTerms(loan.Type); ok && loan.RepaymentPlan == model.LoanPlanAnnuity {
+		response.EffectiveAPR = EffectiveAPR(loan.InterestRate, terms.PeriodDays)
+	}
+	for _, inst := range installments {
+		response.TotalInterest += inst.InterestDue
+		response.TotalPayable += inst.AmountDue
 	}
+	response.TotalInterest = roundMoney(response.TotalInterest)
+	response.TotalPayable = roundMoney(response.TotalPayable)
 
-	return false, nil
+	return response, nil
 }
 
-// handleCollectors handles the debt collection process (removes everything)
-func (s *LoanService) handleCollectors(userID uint) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// Delete all loans
-		if err := tx.Where("user_id = ?", userID).Delete(&model.Loan{}).Error; err != nil {
-			return fmt.Errorf("failed to delete loans: %w", err)
-		}
+// GetLoanStatement returns every money movement on a loan, oldest first
+func (s *LoanService) GetLoanStatement(userID, loanID uint) (*LoanStatementResponse, error) {
+	if err := s.UpdateAllLoansInterest(userID); err != nil {
+		return nil, fmt.Errorf("failed to update interest: %w", err)
+	}
 
-		// Release all collateral items (they'll be deleted anyway)
-		if err := tx.Model(&model.UserItem{}).Where("user_id = ?", userID).Update("is_collateral", false).Error; err != nil {
-			return fmt.Errorf("failed to release collateral: %w", err)
-		}
+	loan, err := s.findOwnedLoan(userID, loanID)
+	if err != nil {
+		return nil, err
+	}
 
-		// Delete all user items
-		if err := tx.Where("user_id = ?", userID).Delete(&model.UserItem{}).Error; err != nil {
-			return fmt.Errorf("failed to delete items: %w", err)
-		}
+	var entries []model.LoanStatementEntry
+	if err := s.db.Where("loan_id = ?", loan.ID).Order("created_at, id").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get statement: %w", err)
+	}
 
-		// Reset balance to 0
-		if err := tx.Model(&model.User{}).Where("id = ?", userID).Update("balance", 0).Error; err != nil {
-			return fmt.Errorf("failed to reset balance: %w", err)
+	response := &LoanStatementResponse{
+		Loan:    *loan,
+		Entries: entries,
+	}
+	for _, entry := range entries {
+		switch entry.Type {
+		case model.LoanEntryLateFee:
+			response.TotalFeesCharged += entry.Amount
+		case model.LoanEntryPayment:
+			response.TotalPaid += entry.Amount
+			response.TotalFeesPaid += entry.FeesPaid
+			response.TotalInterestPaid += entry.InterestPaid
+			response.TotalPrincipal += entry.PrincipalPaid
 		}
+	}
+	response.TotalPaid = roundMoney(response.TotalPaid)
+	response.TotalFeesCharged = roundMoney(response.TotalFeesCharged)
+	response.TotalFeesPaid = roundMoney(response.TotalFeesPaid)
+	response.TotalInterestPaid = roundMoney(response.TotalInterestPaid)
+	response.TotalPrincipal = roundMoney(response.TotalPrincipal)
 
-		return nil
-	})
+	return response, nil
 }
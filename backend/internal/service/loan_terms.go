@@ -0,0 +1,218 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+)
+
+// secondsPerYear is used to convert annual rates to per-second accrual
+const secondsPerYear = 365.25 * 24 * 60 * 60
+
+// LoanTerms describes the contract offered for a loan type
+type LoanTerms struct {
+	Type                model.LoanType            `json:"type"`
+	DefaultPlan         model.LoanRepaymentPlan   `json:"default_plan"`
+	AllowedPlans        []model.LoanRepaymentPlan `json:"allowed_plans"`
+	APR                 float64                   `json:"apr"`
+	PenaltyAPR          float64                   `json:"penalty_apr"` // Applied while overdue
+	LateFee             float64                   `json:"late_fee"`    // Per missed installment
+	PeriodDays          int                       `json:"period_days"` // Days between annuity installments
+	DefaultInstallments int                       `json:"default_installments"`
+	MinInstallments     int                       `json:"min_installments"`
+	MaxInstallments     int                       `json:"max_installments"`
+	BulletTermDays      int                       `json:"bullet_term_days"`
+}
+
+// ScheduledInstallment is one row of an amortization schedule
+type ScheduledInstallment struct {
+	Number       int       `json:"number"`
+	DueAt        time.Time `json:"due_at"`
+	PrincipalDue float64   `json:"principal_due"`
+	InterestDue  float64   `json:"interest_due"`
+	AmountDue    float64   `json:"amount_due"`
+	BalanceAfter float64   `json:"balance_after"`
+}
+
+// loanTerms holds the standard terms per loan type
+var loanTerms = map[model.LoanType]LoanTerms{
+	model.LoanTypeFriends: {
+		Type:                model.LoanTypeFriends,
+		DefaultPlan:         model.LoanPlanBullet,
+		AllowedPlans:        []model.LoanRepaymentPlan{model.LoanPlanBullet},
+		APR:                 FriendsInterestRate,
+		PenaltyAPR:          0, // Friends don't charge interest, they just stop calling
+		LateFee:             0,
+		PeriodDays:          30,
+		DefaultInstallments: 1,
+		MinInstallments:     1,
+		MaxInstallments:     1,
+		BulletTermDays:      30,
+	},
+	model.LoanTypeBank: {
+		Type:                model.LoanTypeBank,
+		DefaultPlan:         model.LoanPlanAnnuity,
+		AllowedPlans:        []model.LoanRepaymentPlan{model.LoanPlanAnnuity, model.LoanPlanBullet},
+		APR:                 BankInterestRate,
+		PenaltyAPR:          0.25,
+		LateFee:             25,
+		PeriodDays:          30,
+		DefaultInstallments: 12,
+		MinInstallments:     2,
+		MaxInstallments:     24,
+		BulletTermDays:      365,
+	},
+	model.LoanTypeMicrocredit: {
+		Type:                model.LoanTypeMicrocredit,
+		DefaultPlan:         model.LoanPlanBullet,
+		AllowedPlans:        []model.LoanRepaymentPlan{model.LoanPlanBullet, model.LoanPlanAnnuity},
+		APR:                 MicrocreditInterestRate,
+		PenaltyAPR:          4.0,
+		LateFee:             50,
+		PeriodDays:          7,
+		DefaultInstallments: 2,
+		MinInstallments:     2,
+		MaxInstallments:     4,
+		BulletTermDays:      14,
+	},
+}
+
+// GetLoanTerms returns the standard terms for a loan type
+func GetLoanTerms(loanType model.LoanType) (LoanTerms, bool) {
+	terms, ok := loanTerms[loanType]
+	return terms, ok
+}
+
+// resolvePlan picks the repayment plan and installment count for a request
+func (t LoanTerms) resolvePlan(plan model.LoanRepaymentPlan, installments int) (model.LoanRepaymentPlan, int, error) {
+	if plan == "" {
+		plan = t.DefaultPlan
+	}
+
+	allowed := false
+	for _, p := range t.AllowedPlans {
+		if p == plan {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", 0, errors.New("repayment_plan_not_allowed")
+	}
+
+	if plan == model.LoanPlanBullet {
+		return plan, 1, nil
+	}
+
+	if installments == 0 {
+		installments = t.DefaultInstallments
+	}
+	if installments < t.MinInstallments || installments > t.MaxInstallments {
+		return "", 0, errors.New("invalid_installment_count")
+	}
+	return plan, installments, nil
+}
+
+// termDays returns the loan length in days for a plan
+func (t LoanTerms) termDays(plan model.LoanRepaymentPlan, installments int) int {
+	if plan == model.LoanPlanBullet {
+		return t.BulletTermDays
+	}
+	return t.PeriodDays * installments
+}
+
+// roundMoney rounds to cents
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// AnnuityPayment returns the fixed installment for principal at a per-period rate
+func AnnuityPayment(principal, periodRate float64, periods int) float64 {
+	if periods <= 0 {
+		return 0
+	}
+	if periodRate == 0 {
+		return principal / float64(periods)
+	}
+	return principal * periodRate / (1 - math.Pow(1+periodRate, -float64(periods)))
+}
+
+// EffectiveAPR returns the effective annual rate of a nominal APR compounded every periodDays
+func EffectiveAPR(apr float64, periodDays int) float64 {
+	if periodDays <= 0 || apr == 0 {
+		return apr
+	}
+	periodsPerYear := 365.0 / float64(periodDays)
+	return math.Pow(1+apr/periodsPerYear, periodsPerYear) - 1
+}
+
+// BuildSchedule computes the amortization schedule for a loan starting at start.
+// Interest per period is apr * periodDays / 365 on the outstanding balance; the
+// last installment absorbs rounding so principal always sums to the loan amount.
+func BuildSchedule(principal, apr float64, plan model.LoanRepaymentPlan, installments, periodDays, bulletTermDays int, start time.Time) []ScheduledInstallment {
+	if plan == model.LoanPlanBullet {
+		interest := roundMoney(principal * apr * float64(bulletTermDays) / 365)
+		return []ScheduledInstallment{{
+			Number:       1,
+			DueAt:        start.AddDate(0, 0, bulletTermDays),
+			PrincipalDue: roundMoney(principal),
+			InterestDue:  interest,
+			AmountDue:    roundMoney(principal + interest),
+			BalanceAfter: 0,
+		}}
+	}
+
+	periodRate := apr * float64(periodDays) / 365
+	payment := roundMoney(AnnuityPayment(principal, periodRate, installments))
+	balance := principal
+
+	schedule := make([]ScheduledInstallment, installments)
+	for i := 0; i < installments; i++ {
+		interest := roundMoney(balance * periodRate)
+		principalPart := roundMoney(payment - interest)
+		if i == installments-1 {
+			principalPart = roundMoney(balance)
+		}
+		balance = roundMoney(balance - principalPart)
+
+		schedule[i] = ScheduledInstallment{
+			Number:       i + 1,
+			DueAt:        start.AddDate(0, 0, periodDays*(i+1)),
+			PrincipalDue: principalPart,
+			InterestDue:  interest,
+			AmountDue:    roundMoney(principalPart + interest),
+			BalanceAfter: balance,
+		}
+	}
+
+	return schedule
+}
+
+// PaymentAllocation splits a payment across fees, interest and principal
+type PaymentAllocation struct {
+	Fees      float64 `json:"fees"`
+	Interest  float64 `json:"interest"`
+	Principal float64 `json:"principal"`
+	Total     float64 `json:"total"`
+}
+
+// AllocatePayment applies amount to fees first, then accrued interest, then
+// principal. Each part is rounded to whole cents before it is taken, so the
+// total never exceeds amount.
+func AllocatePayment(amount, fees, interest, principal float64) PaymentAllocation {
+	alloc := PaymentAllocation{}
+	remaining := math.Floor(amount*100+1e-6) / 100
+
+	alloc.Fees = math.Max(math.Min(remaining, roundMoney(fees)), 0)
+	remaining = roundMoney(remaining - alloc.Fees)
+
+	alloc.Interest = math.Max(math.Min(remaining, roundMoney(interest)), 0)
+	remaining = roundMoney(remaining - alloc.Interest)
+
+	alloc.Principal = math.Max(math.Min(remaining, roundMoney(principal)), 0)
+
+	alloc.Total = roundMoney(alloc.Fees + alloc.Interest + alloc.Principal)
+	return alloc
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildScheduleAnnuity(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedule := BuildSchedule(1200, 0.10, model.LoanPlanAnnuity, 12, 30, 365, start)
+	require.Len(t, schedule, 12)
+
+	principal := 0.0
+	for i, row := range schedule {
+		principal += row.PrincipalDue
+		assert.Equal(t, i+1, row.Number)
+		assert.Equal(t, start.AddDate(0, 0, 30*(i+1)), row.DueAt)
+		if i > 0 {
+			// Interest shrinks as the balance is paid down
+			assert.Less(t, row.InterestDue, schedule[i-1].InterestDue)
+		}
+	}
+
+	assert.InDelta(t, 1200, principal, 0.001)
+	assert.Equal(t, 0.0, schedule[11].BalanceAfter)
+	// All but the last installment are equal
+	assert.Equal(t, schedule[0].AmountDue, schedule[10].AmountDue)
+	assert.InDelta(t, 105.42, schedule[0].AmountDue, 0.01)
+}
+
+func TestBuildScheduleBullet(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedule := BuildSchedule(1000, 2.0, model.LoanPlanBullet, 1, 7, 14, start)
+	require.Len(t, schedule, 1)
+
+	assert.Equal(t, 1000.0, schedule[0].PrincipalDue)
+	assert.InDelta(t, 76.71, schedule[0].InterestDue, 0.01)
+	assert.Equal(t, start.AddDate(0, 0, 14), schedule[0].DueAt)
+}
+
+func TestBuildScheduleZeroRate(t *testing.T) {
+	schedule := BuildSchedule(100, 0, model.LoanPlanAnnuity, 3, 30, 30, time.Now())
+	require.Len(t, schedule, 3)
+	assert.Equal(t, 33.33, schedule[0].AmountDue)
+	assert.Equal(t, 33.34, schedule[2].AmountDue)
+	assert.Equal(t, 0.0, schedule[0].InterestDue)
+}
+
+func TestAllocatePaymentOrder(t *testing.T) {
+	alloc := AllocatePayment(60, 25, 10, 1000)
+	assert.Equal(t, 25.0, alloc.Fees)
+	assert.Equal(t, 10.0, alloc.Interest)
+	assert.Equal(t, 25.0, alloc.Principal)
+	assert.Equal(t, 60.0, alloc.Total)
+
+	// Overpayment is capped at the balance
+	alloc = AllocatePayment(500, 5, 1, 100)
+	assert.Equal(t, 106.0, alloc.Total)
+
+	// Rounding each part to cents never takes more than was offered
+	alloc = AllocatePayment(10.005, 3.335, 3.335, 3.335)
+	assert.LessOrEqual(t, alloc.Total, 10.005)
+	assert.Equal(t, 10.0, alloc.Total)
+}
+
+func TestRepayLoanOverpaymentOnlyNeedsRemainder(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 0)
+	service := &LoanService{db: db}
+
+	loan := model.Loan{
+		UserID:               user.ID,
+		Type:                 model.LoanTypeBank,
+		PrincipalAmount:      100,
+		OutstandingPrincipal: 5,
+		RemainingAmount:      5,
+		Status:               model.LoanStatusActive,
+		LastInterestAt:       time.Now().Add(time.Hour),
+	}
+	require.NoError(t, db.Create(&loan).Error)
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", user.ID).Update("balance", 20).Error)
+
+	// Offering more than the balance covers is fine; only the remainder is taken
+	repay, err := service.RepayLoan(user.ID, RepayLoanRequest{LoanID: loan.ID, Amount: 50})
+	require.NoError(t, err)
+	assert.True(t, repay.PaidOff)
+	assert.Equal(t, 5.0, repay.Allocation.Total)
+	assert.Equal(t, 15.0, repay.NewBalance)
+}
+
+func TestEffectiveAPR(t *testing.T) {
+	assert.Equal(t, 0.0, EffectiveAPR(0, 30))
+	assert.InDelta(t, 0.1047, EffectiveAPR(0.10, 30), 0.0001)
+}
+
+func TestLoanTermsResolvePlan(t *testing.T) {
+	bank, ok := GetLoanTerms(model.LoanTypeBank)
+	require.True(t, ok)
+
+	plan, n, err := bank.resolvePlan("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, model.LoanPlanAnnuity, plan)
+	assert.Equal(t, 12, n)
+
+	plan, n, err = bank.resolvePlan(model.LoanPlanBullet, 6)
+	require.NoError(t, err)
+	assert.Equal(t, model.LoanPlanBullet, plan)
+	assert.Equal(t, 1, n)
+
+	_, _, err = bank.resolvePlan(model.LoanPlanAnnuity, 48)
+	assert.EqualError(t, err, "invalid_installment_count")
+
+	friends, _ := GetLoanTerms(model.LoanTypeFriends)
+	_, _, err = friends.resolvePlan(model.LoanPlanAnnuity, 0)
+	assert.EqualError(t, err, "repayment_plan_not_allowed")
+}
+
+func TestTakeLoanCreatesSchedule(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	service := &LoanService{db: db}
+
+	result, err := service.TakeLoan(TakeLoanRequest{
+		UserID:        user.ID,
+		Amount:        400,
+		Type:          model.LoanTypeMicrocredit,
+		RepaymentPlan: model.LoanPlanAnnuity,
+		Installments:  4,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 410.0, result.NewBalance)
+	assert.Equal(t, 400.0, result.Loan.OutstandingPrincipal)
+	assert.Equal(t, 28, result.Loan.TermDays)
+
+	schedule, err := service.GetLoanSchedule(user.ID, result.Loan.ID)
+	require.NoError(t, err)
+	require.Len(t, schedule.Installments, 4)
+	assert.Equal(t, result.Loan.DueAt.Unix(), schedule.Installments[3].DueAt.Unix())
+	assert.Greater(t, schedule.TotalInterest, 0.0)
+
+	statement, err := service.GetLoanStatement(user.ID, result.Loan.ID)
+	require.NoError(t, err)
+	require.Len(t, statement.Entries, 1)
+	assert.Equal(t, model.LoanEntryDisbursement, statement.Entries[0].Type)
+
+	// Other users can't see the loan
+	other := createTestUser(t, db, 0)
+	_, err = service.GetLoanSchedule(other.ID, result.Loan.ID)
+	assert.EqualError(t, err, "not_your_loan")
+}
+
+func TestMissedInstallmentChargesLateFeeAndRepaymentAllocates(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	service := &LoanService{db: db}
+
+	result, err := service.TakeLoan(TakeLoanRequest{
+		UserID: user.ID,
+		Amount: 100,
+		Type:   model.LoanTypeMicrocredit,
+	})
+	require.NoError(t, err)
+	loanID := result.Loan.ID
+
+	// Move the installment into the past and freeze interest accrual
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, db.Model(&model.LoanInstallment{}).Where("loan_id = ?", loanID).Update("due_at", past).Error)
+	require.NoError(t, db.Model(&model.Loan{}).Where("id = ?", loanID).Updates(map[string]interface{}{
+		"accrued_interest": 10,
+		"last_interest_at": time.Now().Add(time.Hour),
+	}).Error)
+
+	count, err := service.MarkOverdueLoans()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	var loan model.Loan
+	require.NoError(t, db.First(&loan, loanID).Error)
+	assert.Equal(t, model.LoanStatusOverdue, loan.Status)
+	assert.Equal(t, 50.0, loan.OutstandingFees)
+	assert.Equal(t, 160.0, loan.RemainingAmount)
+
+	// Running again doesn't charge twice
+	count, err = service.MarkOverdueLoans()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// Partial payment: fees, then interest, then principal
+	repay, err := service.RepayLoan(user.ID, RepayLoanRequest{LoanID: loanID, Amount: 80})
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, repay.Allocation.Fees)
+	assert.Equal(t, 10.0, repay.Allocation.Interest)
+	assert.Equal(t, 20.0, repay.Allocation.Principal)
+	assert.Equal(t, 80.0, repay.Loan.OutstandingPrincipal)
+	assert.False(t, repay.PaidOff)
+	assert.Equal(t, model.LoanStatusOverdue, repay.Loan.Status)
+
+	// Paying the rest closes the loan
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", user.ID).Update("balance", 200).Error)
+	repay, err = service.RepayLoan(user.ID, RepayLoanRequest{LoanID: loanID, Amount: 200})
+	require.NoError(t, err)
+	assert.True(t, repay.PaidOff)
+	assert.Equal(t, 80.0, repay.Allocation.Total)
+	assert.Equal(t, 120.0, repay.NewBalance)
+
+	// The statement is still available after the loan is closed
+	statement, err := service.GetLoanStatement(user.ID, loanID)
+	require.NoError(t, err)
+	require.Len(t, statement.Entries, 4)
+	assert.Equal(t, 50.0, statement.TotalFeesCharged)
+	assert.Equal(t, 160.0, statement.TotalPaid)
+	assert.Equal(t, 100.0, statement.TotalPrincipal)
+}
+
+func TestAccrueInterestDoesNotOverwriteConcurrentChanges(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	service := &LoanService{db: db}
+
+	result, err := service.TakeLoan(TakeLoanRequest{
+		UserID: user.ID,
+		Amount: 100,
+		Type:   model.LoanTypeMicrocredit,
+	})
+	require.NoError(t, err)
+	loanID := result.Loan.ID
+
+	// The accrual job lists loans, then a repayment lands before it writes
+	var stale []model.Loan
+	require.NoError(t, db.Where("id = ?", loanID).Find(&stale).Error)
+	repay, err := service.RepayLoan(user.ID, RepayLoanRequest{LoanID: loanID, Amount: 60})
+	require.NoError(t, err)
+	require.False(t, repay.PaidOff)
+
+	require.NoError(t, service.accrueInterest(stale, time.Now().Add(time.Hour)))
+	var loan model.Loan
+	require.NoError(t, db.First(&loan, loanID).Error)
+	assert.Greater(t, loan.RemainingAmount, repay.Loan.RemainingAmount, "interest accrues on the repaid balance")
+	assert.Less(t, loan.RemainingAmount, repay.Loan.RemainingAmount+1, "the repayment must not be undone")
+
+	// A loan repaid in full in between stays closed
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", user.ID).Update("balance", 200).Error)
+	repay, err = service.RepayLoan(user.ID, RepayLoanRequest{LoanID: loanID, Amount: 200})
+	require.NoError(t, err)
+	require.True(t, repay.PaidOff)
+
+	require.NoError(t, service.accrueInterest(stale, time.Now().Add(2*time.Hour)))
+	var open int64
+	require.NoError(t, db.Model(&model.Loan{}).Where("id = ?", loanID).Count(&open).Error)
+	assert.Zero(t, open)
+}
+
+func TestFeeOnlyPaymentDoesNotPayInstallments(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10)
+	service := &LoanService{db: db}
+
+	result, err := service.TakeLoan(TakeLoanRequest{
+		UserID: user.ID,
+		Amount: 100,
+		Type:   model.LoanTypeMicrocredit,
+	})
+	require.NoError(t, err)
+	loanID := result.Loan.ID
+
+	// Miss the installment, with interest frozen at zero
+	require.NoError(t, db.Model(&model.LoanInstallment{}).Where("loan_id = ?", loanID).Update("due_at", time.Now().Add(-time.Hour)).Error)
+	require.NoError(t, db.Model(&model.Loan{}).Where("id = ?", loanID).Update("last_interest_at", time.Now().Add(time.Hour)).Error)
+	_, err = service.MarkOverdueLoans()
+	require.NoError(t, err)
+
+	var loan model.Loan
+	require.NoError(t, db.First(&loan, loanID).Error)
+	require.Greater(t, loan.OutstandingFees, 0.0)
+
+	repay, err := service.RepayLoan(user.ID, RepayLoanRequest{LoanID: loanID, Amount: loan.OutstandingFees})
+	require.NoError(t, err)
+	assert.Equal(t, loan.OutstandingFees, repay.Allocation.Fees)
+	assert.Zero(t, repay.Allocation.Interest+repay.Allocation.Principal)
+
+	var installment model.LoanInstallment
+	require.NoError(t, db.Where("loan_id = ?", loanID).First(&installment).Error)
+	assert.Equal(t, model.InstallmentStatusMissed, installment.Status)
+	assert.Zero(t, installment.AmountPaid)
+}
@@ -0,0 +1,459 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MarketService provides business logic for the player-to-player marketplace
+type MarketService struct {
+	db *gorm.DB
+}
+
+// NewMarketService creates a new market service instance
+func NewMarketService() *MarketService {
+	return &MarketService{
+		db: database.GetDB(),
+	}
+}
+
+// Marketplace fees and limits
+const (
+	MarketListingFeeRate = 0.05       // House cut charged on the asking price when listing
+	MarketMinListingFee  = 1.0        // Smallest listing fee
+	MarketMaxPrice       = 10000000.0 // Highest asking price accepted
+)
+
+// CreateListingRequest represents a request to list an item for sale
+type CreateListingRequest struct {
+	UserItemID uint    `json:"user_item_id"`
+	Price      float64 `json:"price"`
+}
+
+// ListingResponse represents a listing after it was created or cancelled
+type ListingResponse struct {
+	Listing    model.MarketListing `json:"listing"`
+	NewBalance float64             `json:"new_balance"`
+}
+
+// BuyListingResponse represents the result of buying a listing
+type BuyListingResponse struct {
+	Listing       model.MarketListing `json:"listing"`
+	UserItemID    uint                `json:"user_item_id"`
+	NewBalance    float64             `json:"new_balance"`
+	TransactionID uint                `json:"transaction_id"`
+}
+
+// MarketFilter narrows down the active listings
+type MarketFilter struct {
+	ItemType string
+	ItemID   uint
+	Limit    int
+	Offset   int
+}
+
+// PriceHistoryResponse represents the trade history of an item
+type PriceHistoryResponse struct {
+	ItemID       uint                   `json:"item_id"`
+	Points       []model.ItemPricePoint `json:"points"`
+	Sales        int64                  `json:"sales"`
+	LastPrice    float64                `json:"last_price"`
+	AveragePrice float64                `json:"average_price"`
+	MinPrice     float64                `json:"min_price"`
+	MaxPrice     float64                `json:"max_price"`
+}
+
+// listingFee returns the house cut for an asking price
+func listingFee(price float64) float64 {
+	return roundMoney(math.Max(price*MarketListingFeeRate, MarketMinListingFee))
+}
+
+// CreateListing puts an owned item up for sale and charges the listing fee.
+// The item stays in escrow until it is sold or the listing is cancelled.
+func (s *MarketService) CreateListing(userID uint, req CreateListingRequest) (*ListingResponse, error) {
+	if req.Price <= 0 || req.Price > MarketMaxPrice {
+		return nil, errors.New("invalid_price")
+	}
+	price := roundMoney(req.Price)
+	fee := listingFee(price)
+
+	var response *ListingResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return errors.New("user_not_found")
+		}
+
+		var userItem model.UserItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Item").
+			Where("id = ? AND user_id = ?", req.UserItemID, userID).
+			First(&userItem).Error; err != nil {
+			return errors.New("item_not_found")
+		}
+
+		switch {
+		case userItem.IsEquipped:
+			return errors.New("item_equipped")
+		case userItem.IsCollateral:
+			return errors.New("item_is_collateral")
+		case userItem.IsListed:
+			return errors.New("item_already_listed")
+		}
+
+		if user.Balance < fee {
+			return errors.New("insufficient_balance")
+		}
+
+		// Move the item into escrow
+		if err := tx.Model(&userItem).Update("is_listed", true).Error; err != nil {
+			return fmt.Errorf("failed to escrow item: %w", err)
+		}
+
+		user.Balance = roundMoney(user.Balance - fee)
+		if err := tx.Model(&user).Update("balance", user.Balance).Error; err != nil {
+			return fmt.Errorf("failed to charge listing fee: %w", err)
+		}
+
+		listing := model.MarketListing{
+			SellerID:   userID,
+			UserItemID: userItem.ID,
+			ItemID:     userItem.ItemID,
+			Price:      price,
+			ListingFee: fee,
+			Status:     model.ListingStatusActive,
+		}
+		if err := tx.Create(&listing).Error; err != nil {
+			return fmt.Errorf("failed to create listing: %w", err)
+		}
+		listing.Item = userItem.Item
+
+		if err := tx.Create(&model.Transaction{
+			UserID:       userID,
+			Type:         model.TransactionTypeMarketFee,
+			Amount:       -fee,
+			BalanceAfter: user.Balance,
+			Description:  fmt.Sprintf("Listing fee for %s", userItem.Item.Name),
+			CreatedAt:    time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+
+		response = &ListingResponse{Listing: listing, NewBalance: user.Balance}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// BuyListing pays the seller and hands the escrowed item to the buyer in one transaction
+func (s *MarketService) BuyListing(buyerID, listingID uint) (*BuyListingResponse, error) {
+	var response *BuyListingResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var listing model.MarketListing
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Item").First(&listing, listingID).Error; err != nil {
+			return errors.New("listing_not_found")
+		}
+		if listing.Status != model.ListingStatusActive {
+			return errors.New("listing_not_active")
+		}
+		if listing.SellerID == buyerID {
+			return errors.New("cannot_buy_own_listing")
+		}
+
+		var buyer model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&buyer, buyerID).Error; err != nil {
+			return errors.New("user_not_found")
+		}
+		if buyer.Balance < listing.Price {
+			return errors.New("insufficient_balance")
+		}
+
+		var seller model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&seller, listing.SellerID).Error; err != nil {
+			return fmt.Errorf("failed to get seller: %w", err)
+		}
+
+		// Claim the listing; only one buyer can flip it from active
+		now := time.Now()
+		result := tx.Model(&model.MarketListing{}).
+			Where("id = ? AND status = ?", listing.ID, model.ListingStatusActive).
+			Updates(map[string]interface{}{
+				"status":   model.ListingStatusSold,
+				"buyer_id": buyerID,
+				"sold_at":  now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to close listing: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("listing_not_active")
+		}
+		listing.Status = model.ListingStatusSold
+		listing.BuyerID = &buyerID
+		listing.SoldAt = &now
+
+		// Release the item from escrow to the buyer
+		result = tx.Model(&model.UserItem{}).
+			Where("id = ? AND user_id = ? AND is_listed = ?", listing.UserItemID, listing.SellerID, true).
+			Updates(map[string]interface{}{
+				"user_id":      buyerID,
+				"is_listed":    false,
+				"is_equipped":  false,
+				"purchased_at": now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to transfer item: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("listing_not_active")
+		}
+
+		buyer.Balance = roundMoney(buyer.Balance - listing.Price)
+		if err := tx.Model(&buyer).Update("balance", buyer.Balance).Error; err != nil {
+			return fmt.Errorf("failed to charge buyer: %w", err)
+		}
+		seller.Balance = roundMoney(seller.Balance + listing.Price)
+		if err := tx.Model(&seller).Update("balance", seller.Balance).Error; err != nil {
+			return fmt.Errorf("failed to pay seller: %w", err)
+		}
+
+		purchase := model.Transaction{
+			UserID:       buyerID,
+			Type:         model.TransactionTypeMarketPurchase,
+			Amount:       -listing.Price,
+			BalanceAfter: buyer.Balance,
+			Description:  fmt.Sprintf("Bought %s on the market", listing.Item.Name),
+			CreatedAt:    now,
+		}
+		if err := tx.Create(&purchase).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		if err := tx.Create(&model.Transaction{
+			UserID:       seller.ID,
+			Type:         model.TransactionTypeMarketSale,
+			Amount:       listing.Price,
+			BalanceAfter: seller.Balance,
+			Description:  fmt.Sprintf("Sold %s on the market", listing.Item.Name),
+			CreatedAt:    now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+
+		if err := tx.Create(&model.ItemPricePoint{
+			ItemID: listing.ItemID,
+			Price:  listing.Price,
+			Source: model.PriceSourceMarket,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record price: %w", err)
+		}
+
+		if err := events.Record(tx, events.ItemPurchased{
+			UserID:     buyerID,
+			ItemID:     listing.ItemID,
+			UserItemID: listing.UserItemID,
+			Price:      listing.Price,
+			Source:     events.SourceMarket,
+		}); err != nil {
+			return err
+		}
+		if err := events.Record(tx, events.ItemSold{
+			UserID: seller.ID,
+			ItemID: listing.ItemID,
+			Price:  listing.Price,
+			Source: events.SourceMarket,
+		}); err != nil {
+			return err
+		}
+
+		response = &BuyListingResponse{
+			Listing:       listing,
+			UserItemID:    listing.UserItemID,
+			NewBalance:    buyer.Balance,
+			TransactionID: purchase.ID,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	flushEvents(s.db)
+	return response, nil
+}
+
+// CancelListing takes a listing off the market and returns the item from escrow.
+// The listing fee is not refunded.
+func (s *MarketService) CancelListing(userID, listingID uint) (*ListingResponse, error) {
+	var response *ListingResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var listing model.MarketListing
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Item").First(&listing, listingID).Error; err != nil {
+			return errors.New("listing_not_found")
+		}
+		if listing.SellerID != userID {
+			return errors.New("not_your_listing")
+		}
+		if listing.Status != model.ListingStatusActive {
+			return errors.New("listing_not_active")
+		}
+
+		if err := cancelListing(tx, &listing); err != nil {
+			return err
+		}
+
+		var user model.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return errors.New("user_not_found")
+		}
+
+		response = &ListingResponse{Listing: listing, NewBalance: user.Balance}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetListings returns active listings, cheapest first
+func (s *MarketService) GetListings(filter MarketFilter) ([]model.MarketListing, int64, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	query := s.db.Model(&model.MarketListing{}).
+		Joins("JOIN items ON items.id = market_listings.item_id").
+		Where("market_listings.status = ?", model.ListingStatusActive)
+	if filter.ItemType != "" {
+		query = query.Where("items.type = ?", filter.ItemType)
+	}
+	if filter.ItemID != 0 {
+		query = query.Where("market_listings.item_id = ?", filter.ItemID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count listings: %w", err)
+	}
+
+	var listings []model.MarketListing
+	if err := query.Preload("Item").
+		Order("market_listings.price ASC, market_listings.id ASC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&listings).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get listings: %w", err)
+	}
+
+	return listings, total, nil
+}
+
+// GetMyListings returns every listing the user created, newest first
+func (s *MarketService) GetMyListings(userID uint) ([]model.MarketListing, error) {
+	var listings []model.MarketListing
+	if err := s.db.Preload("Item").
+		Where("seller_id = ?", userID).
+		Order("id DESC").
+		Find(&listings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get listings: %w", err)
+	}
+	return listings, nil
+}
+
+// GetPriceHistory returns the most recent trades of an item with summary statistics
+func (s *MarketService) GetPriceHistory(itemID uint, limit int) (*PriceHistoryResponse, error) {
+	var item model.Item
+	if err := s.db.First(&item, itemID).Error; err != nil {
+		return nil, errors.New("item_not_found")
+	}
+
+	return priceHistory(s.db, itemID, model.PriceSourceMarket, limit)
+}
+
+// priceHistory returns the most recent prices an item traded at through one source
+func priceHistory(db *gorm.DB, itemID uint, source model.PriceSource, limit int) (*PriceHistoryResponse, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	points := db.Model(&model.ItemPricePoint{}).Where("item_id = ? AND source = ?", itemID, source)
+
+	response := &PriceHistoryResponse{ItemID: itemID}
+	if err := points.Session(&gorm.Session{}).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&response.Points).Error; err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+
+	var stats struct {
+		Sales   int64
+		Average float64
+		Min     float64
+		Max     float64
+	}
+	if err := points.Session(&gorm.Session{}).
+		Select("COUNT(*) AS sales, COALESCE(AVG(price), 0) AS average, COALESCE(MIN(price), 0) AS min, COALESCE(MAX(price), 0) AS max").
+		Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to get price statistics: %w", err)
+	}
+
+	response.Sales = stats.Sales
+	response.AveragePrice = roundMoney(stats.Average)
+	response.MinPrice = stats.Min
+	response.MaxPrice = stats.Max
+	if len(response.Points) > 0 {
+		response.LastPrice = response.Points[0].Price
+	}
+
+	return response, nil
+}
+
+// cancelListing closes an active listing and returns its item from escrow
+func cancelListing(tx *gorm.DB, listing *model.MarketListing) error {
+	now := time.Now()
+	if err := tx.Model(listing).Updates(map[string]interface{}{
+		"status":       model.ListingStatusCancelled,
+		"cancelled_at": now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to cancel listing: %w", err)
+	}
+	listing.Status = model.ListingStatusCancelled
+	listing.CancelledAt = &now
+
+	if err := tx.Model(&model.UserItem{}).Where("id = ?", listing.UserItemID).
+		Update("is_listed", false).Error; err != nil {
+		return fmt.Errorf("failed to release item: %w", err)
+	}
+	return nil
+}
+
+// cancelItemListings cancels any active listing of a user item that is about to be removed
+func cancelItemListings(tx *gorm.DB, userItemID uint) error {
+	var listings []model.MarketListing
+	if err := tx.Where("user_item_id = ? AND status = ?", userItemID, model.ListingStatusActive).
+		Find(&listings).Error; err != nil {
+		return fmt.Errorf("failed to get listings: %w", err)
+	}
+	for i := range listings {
+		if err := cancelListing(tx, &listings[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
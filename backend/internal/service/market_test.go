@@ -0,0 +1,214 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListingFee(t *testing.T) {
+	assert.Equal(t, 1.0, listingFee(10))
+	assert.Equal(t, 5.0, listingFee(100))
+	assert.Equal(t, 1250.0, listingFee(25000))
+}
+
+func TestMarketService_ListAndBuy(t *testing.T) {
+	db := setupTestDB(t)
+	service := &MarketService{db: db}
+
+	seller := createTestUser(t, db, 100)
+	buyer := createTestUser(t, db, 500)
+	watch := createTestItem(t, db, "Watch", model.ItemTypeAccessories, 600)
+	userItem := &model.UserItem{UserID: seller.ID, ItemID: watch.ID}
+	require.NoError(t, db.Create(userItem).Error)
+
+	listing, err := service.CreateListing(seller.ID, CreateListingRequest{UserItemID: userItem.ID, Price: 400})
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, listing.Listing.ListingFee)
+	assert.Equal(t, 80.0, listing.NewBalance)
+
+	var escrowed model.UserItem
+	require.NoError(t, db.First(&escrowed, userItem.ID).Error)
+	assert.True(t, escrowed.IsListed)
+
+	_, err = service.CreateListing(seller.ID, CreateListingRequest{UserItemID: userItem.ID, Price: 300})
+	assert.EqualError(t, err, "item_already_listed")
+
+	_, err = service.BuyListing(seller.ID, listing.Listing.ID)
+	assert.EqualError(t, err, "cannot_buy_own_listing")
+
+	result, err := service.BuyListing(buyer.ID, listing.Listing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, result.NewBalance)
+	assert.Equal(t, model.ListingStatusSold, result.Listing.Status)
+
+	var transferred model.UserItem
+	require.NoError(t, db.First(&transferred, userItem.ID).Error)
+	assert.Equal(t, buyer.ID, transferred.UserID)
+	assert.False(t, transferred.IsListed)
+
+	var sellerAfter model.User
+	require.NoError(t, db.First(&sellerAfter, seller.ID).Error)
+	assert.Equal(t, 480.0, sellerAfter.Balance)
+
+	var types []model.TransactionType
+	require.NoError(t, db.Model(&model.Transaction{}).Where("user_id IN ?", []uint{seller.ID, buyer.ID}).
+		Order("id").Pluck("type", &types).Error)
+	assert.Equal(t, []model.TransactionType{
+		model.TransactionTypeMarketFee,
+		model.TransactionTypeMarketPurchase,
+		model.TransactionTypeMarketSale,
+	}, types)
+
+	_, err = service.BuyListing(buyer.ID, listing.Listing.ID)
+	assert.EqualError(t, err, "listing_not_active")
+}
+
+func TestMarketService_CreateListingRejectsUnavailableItems(t *testing.T) {
+	db := setupTestDB(t)
+	service := &MarketService{db: db}
+
+	user := createTestUser(t, db, 100)
+	other := createTestUser(t, db, 100)
+	item := createTestItem(t, db, "Jacket", model.ItemTypeClothing, 200)
+
+	equipped := &model.UserItem{UserID: user.ID, ItemID: item.ID, IsEquipped: true}
+	pledged := &model.UserItem{UserID: user.ID, ItemID: item.ID, IsCollateral: true}
+	foreign := &model.UserItem{UserID: other.ID, ItemID: item.ID}
+	require.NoError(t, db.Create(equipped).Error)
+	require.NoError(t, db.Create(pledged).Error)
+	require.NoError(t, db.Create(foreign).Error)
+
+	_, err := service.CreateListing(user.ID, CreateListingRequest{UserItemID: equipped.ID, Price: 100})
+	assert.EqualError(t, err, "item_equipped")
+
+	_, err = service.CreateListing(user.ID, CreateListingRequest{UserItemID: pledged.ID, Price: 100})
+	assert.EqualError(t, err, "item_is_collateral")
+
+	_, err = service.CreateListing(user.ID, CreateListingRequest{UserItemID: foreign.ID, Price: 100})
+	assert.EqualError(t, err, "item_not_found")
+
+	_, err = service.CreateListing(other.ID, CreateListingRequest{UserItemID: foreign.ID, Price: 0})
+	assert.EqualError(t, err, "invalid_price")
+}
+
+func TestMarketService_CancelListing(t *testing.T) {
+	db := setupTestDB(t)
+	service := &MarketService{db: db}
+
+	user := createTestUser(t, db, 100)
+	other := createTestUser(t, db, 100)
+	item := createTestItem(t, db, "Hat", model.ItemTypeClothing, 50)
+	userItem := &model.UserItem{UserID: user.ID, ItemID: item.ID}
+	require.NoError(t, db.Create(userItem).Error)
+
+	listing, err := service.CreateListing(user.ID, CreateListingRequest{UserItemID: userItem.ID, Price: 40})
+	require.NoError(t, err)
+
+	_, err = service.CancelListing(other.ID, listing.Listing.ID)
+	assert.EqualError(t, err, "not_your_listing")
+
+	result, err := service.CancelListing(user.ID, listing.Listing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ListingStatusCancelled, result.Listing.Status)
+	assert.Equal(t, 98.0, result.NewBalance, "listing fee is not refunded")
+
+	var released model.UserItem
+	require.NoError(t, db.First(&released, userItem.ID).Error)
+	assert.False(t, released.IsListed)
+
+	_, err = service.BuyListing(other.ID, listing.Listing.ID)
+	assert.EqualError(t, err, "listing_not_active")
+}
+
+func TestMarketService_GetListingsAndPriceHistory(t *testing.T) {
+	db := setupTestDB(t)
+	service := &MarketService{db: db}
+
+	seller := createTestUser(t, db, 1000)
+	buyer := createTestUser(t, db, 1000)
+	car := createTestItem(t, db, "Car", model.ItemTypeCar, 500)
+	shirt := createTestItem(t, db, "Shirt", model.ItemTypeClothing, 20)
+
+	var listingIDs []uint
+	for _, price := range []float64{300, 200, 250} {
+		userItem := &model.UserItem{UserID: seller.ID, ItemID: car.ID}
+		require.NoError(t, db.Create(userItem).Error)
+		listing, err := service.CreateListing(seller.ID, CreateListingRequest{UserItemID: userItem.ID, Price: price})
+		require.NoError(t, err)
+		listingIDs = append(listingIDs, listing.Listing.ID)
+	}
+	shirtItem := &model.UserItem{UserID: seller.ID, ItemID: shirt.ID}
+	require.NoError(t, db.Create(shirtItem).Error)
+	_, err := service.CreateListing(seller.ID, CreateListingRequest{UserItemID: shirtItem.ID, Price: 15})
+	require.NoError(t, err)
+
+	listings, total, err := service.GetListings(MarketFilter{ItemType: string(model.ItemTypeCar)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, listings, 3)
+	assert.Equal(t, 200.0, listings[0].Price)
+
+	_, err = service.BuyListing(buyer.ID, listingIDs[0])
+	require.NoError(t, err)
+	_, err = service.BuyListing(buyer.ID, listingIDs[1])
+	require.NoError(t, err)
+
+	history, err := service.GetPriceHistory(car.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), history.Sales)
+	assert.Equal(t, 250.0, history.AveragePrice)
+	assert.Equal(t, 200.0, history.MinPrice)
+	assert.Equal(t, 300.0, history.MaxPrice)
+	assert.Equal(t, 200.0, history.LastPrice)
+
+	mine, err := service.GetMyListings(seller.ID)
+	require.NoError(t, err)
+	assert.Len(t, mine, 4)
+}
+
+func TestShopService_SellItemRejectsListedItem(t *testing.T) {
+	db := setupTestDB(t)
+	market := &MarketService{db: db}
+	shop := &ShopService{db: db}
+
+	user := createTestUser(t, db, 100)
+	item := createTestItem(t, db, "Ring", model.ItemTypeAccessories, 100)
+	userItem := &model.UserItem{UserID: user.ID, ItemID: item.ID}
+	require.NoError(t, db.Create(userItem).Error)
+
+	_, err := market.CreateListing(user.ID, CreateListingRequest{UserItemID: userItem.ID, Price: 80})
+	require.NoError(t, err)
+
+	_, err = shop.SellItem(user.ID, userItem.ID)
+	assert.EqualError(t, err, "item is listed on the market")
+}
+
+func TestShopService_SellItemRejectsCollateral(t *testing.T) {
+	db := setupTestDB(t)
+	shop := &ShopService{db: db}
+
+	user := createTestUser(t, db, 100)
+	car := createTestItem(t, db, "Sedan", model.ItemTypeCar, 4000)
+	pledged := &model.UserItem{UserID: user.ID, ItemID: car.ID, PurchasedAt: time.Now()}
+	require.NoError(t, db.Create(pledged).Error)
+
+	loan, err := (&LoanService{db: db}).TakeLoan(TakeLoanRequest{
+		UserID:           user.ID,
+		Amount:           1000,
+		Type:             model.LoanTypeBank,
+		CollateralItemID: &pledged.ID,
+	})
+	require.NoError(t, err)
+
+	_, err = shop.SellItem(user.ID, pledged.ID)
+	assert.EqualError(t, err, "item is pledged as collateral")
+
+	// The loan still points at the item
+	var stored model.UserItem
+	require.NoError(t, db.First(&stored, *loan.Loan.CollateralItemID).Error)
+	assert.True(t, stored.IsCollateral)
+}
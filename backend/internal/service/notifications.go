@@ -0,0 +1,279 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// Notification types pushed over the event stream
+const (
+	NotificationBalanceUpdate       = "balance_update"
+	NotificationLoanUpdate          = "loan_update"
+	NotificationCollection          = "collection"
+	NotificationStatusChanged       = "status_changed"
+	NotificationAchievementUnlocked = "achievement_unlocked"
+	NotificationRealityCheck        = "reality_check"
+	NotificationDataExportReady     = "data_export_ready"
+	NotificationResync              = "resync" // Not stored; tells a resuming client it missed too much and must refetch
+)
+
+// Notification stream parameters
+const (
+	notificationFeedBuffer  = 64  // Notifications a slow stream may lag behind before it is closed
+	notificationReplayLimit = 500 // Most notifications replayed on resume before asking for a resync
+)
+
+// NotificationMessage is a notification as sent to clients
+type NotificationMessage struct {
+	ID        uint            `json:"id,omitempty"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// BalanceUpdate is the data of a balance_update notification
+type BalanceUpdate struct {
+	Balance float64 `json:"balance"`
+	Reason  string  `json:"reason"` // Event that changed it
+}
+
+// notificationMessage converts a stored notification for sending
+func notificationMessage(n *model.Notification) NotificationMessage {
+	return NotificationMessage{
+		ID:        n.ID,
+		Type:      n.Type,
+		Data:      json.RawMessage(n.Payload),
+		CreatedAt: n.CreatedAt,
+	}
+}
+
+// NotificationFeed fans notifications out to each user's live streams
+type NotificationFeed struct {
+	mu   sync.RWMutex
+	subs map[chan NotificationMessage]*feedSubscription
+}
+
+// feedSubscription is one live stream of a user's notifications
+type feedSubscription struct {
+	userID uint
+	once   sync.Once // Closes the channel once, whether cancelled or overrun
+}
+
+// NewNotificationFeed creates an empty notification feed
+func NewNotificationFeed() *NotificationFeed {
+	return &NotificationFeed{
+		subs: make(map[chan NotificationMessage]*feedSubscription),
+	}
+}
+
+// defaultNotificationFeed is shared by the bus subscriber that creates
+// notifications and the handlers streaming them
+var defaultNotificationFeed = NewNotificationFeed()
+
+// DefaultNotificationFeed returns the process-wide notification feed
+func DefaultNotificationFeed() *NotificationFeed {
+	return defaultNotificationFeed
+}
+
+// Subscribe returns a channel of a user's notifications and a function that
+// cancels the subscription. The channel is closed when the subscription is
+// cancelled or falls too far behind.
+func (f *NotificationFeed) Subscribe(userID uint) (<-chan NotificationMessage, func()) {
+	ch := make(chan NotificationMessage, notificationFeedBuffer)
+	sub := &feedSubscription{userID: userID}
+
+	f.mu.Lock()
+	f.subs[ch] = sub
+	f.mu.Unlock()
+
+	return ch, func() { f.close(ch, sub) }
+}
+
+// close removes a subscription and closes its channel
+func (f *NotificationFeed) close(ch chan NotificationMessage, sub *feedSubscription) {
+	sub.once.Do(func() {
+		f.mu.Lock()
+		delete(f.subs, ch)
+		f.mu.Unlock()
+		close(ch)
+	})
+}
+
+// Publish delivers a notification to the user's streams without blocking.
+// A stream whose buffer is full is closed rather than skipping the
+// notification, so its client reconnects and replays from its last ID.
+func (f *NotificationFeed) Publish(userID uint, message NotificationMessage) {
+	overrun := map[chan NotificationMessage]*feedSubscription{}
+
+	f.mu.RLock()
+	for ch, sub := range f.subs {
+		if sub.userID != userID {
+			continue
+		}
+		select {
+		case ch <- message:
+		default:
+			overrun[ch] = sub
+		}
+	}
+	f.mu.RUnlock()
+
+	for ch, sub := range overrun {
+		f.close(ch, sub)
+	}
+}
+
+// NotificationService turns domain events into per-user notifications
+type NotificationService struct {
+	db   *gorm.DB
+	feed *NotificationFeed
+
+	mu          sync.Mutex
+	lastBalance map[uint]float64 // Last balance pushed to each user, to skip repeats
+}
+
+// NewNotificationService creates a new notification service instance
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		db:   database.GetDB(),
+		feed: DefaultNotificationFeed(),
+	}
+}
+
+// getFeed returns the service's feed, defaulting to the process-wide one
+func (s *NotificationService) getFeed() *NotificationFeed {
+	if s.feed == nil {
+		return DefaultNotificationFeed()
+	}
+	return s.feed
+}
+
+// Subscribe returns a channel of the user's live notifications
+func (s *NotificationService) Subscribe(userID uint) (<-chan NotificationMessage, func()) {
+	return s.getFeed().Subscribe(userID)
+}
+
+// Notify stores a notification for the user and pushes it to their live streams
+func (s *NotificationService) Notify(userID uint, notificationType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s notification: %w", notificationType, err)
+	}
+
+	notification := model.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Payload: string(payload),
+	}
+	if err := s.db.Create(&notification).Error; err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	s.getFeed().Publish(userID, notificationMessage(&notification))
+	return nil
+}
+
+// HandleEvent notifies the user about an event from the bus, followed by
+// their new balance if the event changed it
+func (s *NotificationService) HandleEvent(event events.Event) error {
+	userID := event.EventUserID()
+
+	switch e := event.(type) {
+	case events.BalanceChanged:
+		return s.notifyBalance(userID, e.Balance, e.Reason)
+	case events.LoanAccrued:
+		// Interest grows the debt, not the balance
+		return s.Notify(userID, NotificationLoanUpdate, e)
+	case events.CollectionAction:
+		if err := s.Notify(userID, NotificationCollection, e); err != nil {
+			return err
+		}
+	case events.StatusChanged:
+		if err := s.Notify(userID, NotificationStatusChanged, e); err != nil {
+			return err
+		}
+	case events.AchievementUnlocked:
+		if err := s.Notify(userID, NotificationAchievementUnlocked, e); err != nil {
+			return err
+		}
+	case events.RealityCheckDue:
+		return s.Notify(userID, NotificationRealityCheck, e)
+	case events.DataExportReady:
+		return s.Notify(userID, NotificationDataExportReady, e)
+	}
+
+	var user model.User
+	if err := s.db.Select("id", "balance").First(&user, userID).Error; err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.notifyBalance(userID, user.Balance, event.EventName())
+}
+
+// notifyBalance pushes the user's balance unless it is the one they were last sent
+func (s *NotificationService) notifyBalance(userID uint, balance float64, reason string) error {
+	balance = roundMoney(balance)
+
+	s.mu.Lock()
+	if s.lastBalance == nil {
+		s.lastBalance = make(map[uint]float64)
+	}
+	last, ok := s.lastBalance[userID]
+	s.lastBalance[userID] = balance
+	s.mu.Unlock()
+
+	if ok && last == balance {
+		return nil
+	}
+	return s.Notify(userID, NotificationBalanceUpdate, BalanceUpdate{Balance: balance, Reason: reason})
+}
+
+// Replay returns the user's notifications after lastID, oldest first. It
+// reports resync instead when some of them may already have been pruned or
+// there are too many to replay.
+func (s *NotificationService) Replay(userID, lastID uint) ([]NotificationMessage, bool, error) {
+	if lastID == 0 {
+		return nil, false, nil
+	}
+
+	// IDs are shared by every user and pruned oldest first, so anything after
+	// lastID is still there as long as no older row has been pruned past it
+	var oldest *uint
+	if err := s.db.Model(&model.Notification{}).Select("MIN(id)").Scan(&oldest).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to check notifications: %w", err)
+	}
+	if oldest == nil || *oldest > lastID+1 {
+		return nil, true, nil
+	}
+
+	var notifications []model.Notification
+	if err := s.db.Where("user_id = ? AND id > ?", userID, lastID).
+		Order("id").Limit(notificationReplayLimit + 1).
+		Find(&notifications).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	if len(notifications) > notificationReplayLimit {
+		return nil, true, nil
+	}
+
+	messages := make([]NotificationMessage, len(notifications))
+	for i := range notifications {
+		messages[i] = notificationMessage(&notifications[i])
+	}
+	return messages, false, nil
+}
+
+// PruneNotifications deletes notifications older than the retention period
+func (s *NotificationService) PruneNotifications(retention time.Duration) (int64, error) {
+	result := s.db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&model.Notification{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune notifications: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
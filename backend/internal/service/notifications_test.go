@@ -0,0 +1,124 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationsFromEvents(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &NotificationService{db: db, feed: NewNotificationFeed()}
+
+	live, unsubscribe := service.Subscribe(user.ID)
+	defer unsubscribe()
+
+	require.NoError(t, service.HandleEvent(events.BetSettled{UserID: user.ID, Bet: 10}))
+	message := <-live
+	assert.Equal(t, NotificationBalanceUpdate, message.Type)
+	var update BalanceUpdate
+	require.NoError(t, json.Unmarshal(message.Data, &update))
+	assert.Equal(t, BalanceUpdate{Balance: 1000, Reason: events.NameBetSettled}, update)
+
+	// An unchanged balance is not pushed again
+	require.NoError(t, service.HandleEvent(events.WorkCompleted{UserID: user.ID}))
+	require.NoError(t, service.HandleEvent(events.LoanAccrued{UserID: user.ID, LoanID: 1, Interest: 0.5, Remaining: 100.5}))
+	message = <-live
+	assert.Equal(t, NotificationLoanUpdate, message.Type)
+	assert.Empty(t, live)
+
+	require.NoError(t, service.HandleEvent(events.StatusChanged{UserID: user.ID, Status: "in_jail", Active: true}))
+	message = <-live
+	assert.Equal(t, NotificationStatusChanged, message.Type)
+	assert.JSONEq(t, `{"user_id":1,"status":"in_jail","active":true}`, string(message.Data))
+
+	var count int64
+	require.NoError(t, db.Model(&model.Notification{}).Where("user_id = ?", user.ID).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestNotificationReplay(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	other := createTestUser(t, db, 1000)
+	service := &NotificationService{db: db, feed: NewNotificationFeed()}
+
+	for _, balance := range []float64{10, 20, 30} {
+		require.NoError(t, service.Notify(user.ID, NotificationBalanceUpdate, BalanceUpdate{Balance: balance}))
+		require.NoError(t, service.Notify(other.ID, NotificationBalanceUpdate, BalanceUpdate{Balance: balance}))
+	}
+
+	var first model.Notification
+	require.NoError(t, db.Where("user_id = ?", user.ID).Order("id").First(&first).Error)
+
+	// A fresh connection has nothing to replay
+	missed, resync, err := service.Replay(user.ID, 0)
+	require.NoError(t, err)
+	assert.False(t, resync)
+	assert.Empty(t, missed)
+
+	missed, resync, err = service.Replay(user.ID, first.ID)
+	require.NoError(t, err)
+	assert.False(t, resync)
+	require.Len(t, missed, 2)
+	assert.Greater(t, missed[0].ID, first.ID)
+	assert.Less(t, missed[0].ID, missed[1].ID)
+	assert.JSONEq(t, `{"balance":30,"reason":""}`, string(missed[1].Data))
+
+	// Once what the client missed has been pruned it has to refetch
+	require.NoError(t, db.Model(&model.Notification{}).Where("id <= ?", first.ID+1).
+		Update("created_at", time.Now().Add(-48*time.Hour)).Error)
+	pruned, err := service.PruneNotifications(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pruned)
+
+	missed, resync, err = service.Replay(user.ID, first.ID)
+	require.NoError(t, err)
+	assert.True(t, resync)
+	assert.Empty(t, missed)
+}
+
+func TestNotificationFeedClosesOverrunStream(t *testing.T) {
+	feed := NewNotificationFeed()
+	slow, unsubscribe := feed.Subscribe(1)
+	defer unsubscribe()
+	other, unsubscribeOther := feed.Subscribe(2)
+	defer unsubscribeOther()
+
+	for id := uint(1); id <= notificationFeedBuffer+1; id++ {
+		feed.Publish(1, NotificationMessage{ID: id, Type: NotificationBalanceUpdate})
+	}
+	feed.Publish(2, NotificationMessage{ID: 100, Type: NotificationBalanceUpdate})
+
+	// The stream ends after what it buffered, so its client resumes from there
+	var last uint
+	for message := range slow {
+		last = message.ID
+	}
+	assert.Equal(t, uint(notificationFeedBuffer), last)
+
+	// Other users' streams are unaffected
+	assert.Equal(t, uint(100), (<-other).ID)
+}
+
+func TestStatusChangesAreRecorded(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+
+	require.NoError(t, grantStatus(db, user.ID, "in_jail", -time.Minute))
+	count, err := (&UserStatusService{db: db}).ExpireStatuses()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	var recorded []model.OutboxEvent
+	require.NoError(t, db.Where("name = ?", events.NameStatusChanged).Order("id").Find(&recorded).Error)
+	require.Len(t, recorded, 2)
+	assert.Contains(t, recorded[0].Payload, `"active":true`)
+	assert.Contains(t, recorded[1].Payload, `"active":false`)
+}
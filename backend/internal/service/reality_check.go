@@ -0,0 +1,189 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// realityCheckDue reports whether a session is owed a reality check at now
+func realityCheckDue(session *model.GamblingSession, now time.Time) bool {
+	due := session.StartedAt.Add(RealityCheckInterval)
+	if session.RealityCheckAt != nil {
+		due = *session.RealityCheckAt
+	}
+	return !now.Before(due)
+}
+
+// RealityCheckResponse is a reality check with its loss in work time and a
+// sentence the client can show as is
+type RealityCheckResponse struct {
+	model.RealityCheck
+	WorkTime *WorkTime `json:"work_time,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// realityCheckResponse renders a reality check for the client
+func (s *ResponsibleGamingService) realityCheckResponse(check *model.RealityCheck) *RealityCheckResponse {
+	response := &RealityCheckResponse{RealityCheck: *check}
+
+	played := fmt.Sprintf("You have been playing for %d minutes", check.PlayedMinutes)
+	if check.NetResult >= 0 {
+		response.Message = fmt.Sprintf("%s and are $%.2f up.", played, check.NetResult)
+		return response
+	}
+
+	loss := -check.NetResult
+	response.Message = fmt.Sprintf("%s and have lost $%.2f.", played, loss)
+	if workTime, err := s.getStats().GetWorkTime(check.Country, loss); err == nil {
+		response.WorkTime = workTime
+		response.Message = fmt.Sprintf("%s and have lost $%.2f, which takes %.1f hours of work to earn in %s.",
+			played, loss, workTime.Hours, workTime.Country)
+	}
+	return response
+}
+
+// issueRealityCheck returns the session's unacknowledged reality check,
+// recording one first if there is none. created reports whether it is new.
+func (s *ResponsibleGamingService) issueRealityCheck(session *model.GamblingSession, now time.Time) (*model.RealityCheck, bool, error) {
+	var check model.RealityCheck
+	created := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("session_id = ? AND acknowledged_at IS NULL", session.ID).First(&check).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get reality check: %w", err)
+		}
+
+		var user model.User
+		if err := tx.First(&user, session.UserID).Error; err != nil {
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+		net, err := s.gamblingNet(tx, session.UserID, session.StartedAt)
+		if err != nil {
+			return err
+		}
+
+		check = model.RealityCheck{
+			UserID:        session.UserID,
+			SessionID:     session.ID,
+			PlayedMinutes: int(now.Sub(session.StartedAt).Minutes()),
+			Wagered:       session.Wagered,
+			NetResult:     net,
+			Country:       userCountry(&user),
+		}
+		if net < 0 {
+			if workTime, err := s.getStats().GetWorkTime(check.Country, -net); err == nil {
+				check.WorkHours = workTime.Hours
+			}
+		}
+		if err := tx.Create(&check).Error; err != nil {
+			return fmt.Errorf("failed to create reality check: %w", err)
+		}
+		created = true
+
+		return events.Record(tx, events.RealityCheckDue{
+			UserID:        check.UserID,
+			CheckID:       check.ID,
+			PlayedMinutes: check.PlayedMinutes,
+			Wagered:       check.Wagered,
+			NetResult:     check.NetResult,
+			Country:       check.Country,
+			WorkHours:     check.WorkHours,
+		})
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if created {
+		flushEvents(s.db)
+	}
+	return &check, created, nil
+}
+
+// IssueDueRealityChecks records a reality check for every active session
+// that is due one, so clients are told without waiting for the next bet
+func (s *ResponsibleGamingService) IssueDueRealityChecks() (int, error) {
+	now := s.clock()
+
+	// Accounts deleted mid-session are skipped
+	var sessions []model.GamblingSession
+	if err := s.db.Where("last_bet_at > ? AND reality_check_at <= ?", now.Add(-GamblingSessionIdleGap).Local(), now.Local()).
+		Where("user_id IN (?)", s.db.Model(&model.User{}).Select("id")).
+		Find(&sessions).Error; err != nil {
+		return 0, fmt.Errorf("failed to find sessions due a reality check: %w", err)
+	}
+
+	count := 0
+	for i := range sessions {
+		_, created, err := s.issueRealityCheck(&sessions[i], now)
+		if err != nil {
+			return count, err
+		}
+		if created {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetRealityCheck returns the reality check the user must acknowledge before
+// betting again, or nil if their session doesn't need one yet
+func (s *ResponsibleGamingService) GetRealityCheck(userID uint) (*RealityCheckResponse, error) {
+	now := s.clock()
+
+	session, err := s.currentSession(s.db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || !realityCheckDue(session, now) {
+		return nil, nil
+	}
+
+	check, _, err := s.issueRealityCheck(session, now)
+	if err != nil {
+		return nil, err
+	}
+	return s.realityCheckResponse(check), nil
+}
+
+// AcknowledgeRealityCheck records that the user has seen a reality check and
+// lets their session carry on until the next one
+func (s *ResponsibleGamingService) AcknowledgeRealityCheck(userID, checkID uint) (*RealityCheckResponse, error) {
+	now := s.clock()
+	var check model.RealityCheck
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", checkID, userID).First(&check).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("reality_check_not_found")
+			}
+			return fmt.Errorf("failed to get reality check: %w", err)
+		}
+		if check.AcknowledgedAt != nil {
+			return nil
+		}
+
+		check.AcknowledgedAt = &now
+		if err := tx.Model(&check).Update("acknowledged_at", now).Error; err != nil {
+			return fmt.Errorf("failed to acknowledge reality check: %w", err)
+		}
+		if err := tx.Model(&model.GamblingSession{}).Where("id = ?", check.SessionID).
+			Update("reality_check_at", now.Add(RealityCheckInterval)).Error; err != nil {
+			return fmt.Errorf("failed to update gambling session: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.realityCheckResponse(&check), nil
+}
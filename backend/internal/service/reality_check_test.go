@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileCountry(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	users := &UserService{db: db}
+
+	profile, err := users.GetProfile(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "US", profile.Country)
+
+	country := "de"
+	profile, err = users.UpdateProfile(user.ID, UpdateProfileRequest{Country: &country})
+	require.NoError(t, err)
+	assert.Equal(t, "DE", profile.Country)
+
+	country = "XX"
+	_, err = users.UpdateProfile(user.ID, UpdateProfileRequest{Country: &country})
+	assert.EqualError(t, err, "invalid country")
+}
+
+func TestRealityChecks(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	require.NoError(t, db.Model(user).Update("country", "DE").Error)
+	start := time.Now()
+	now := start
+	service := &ResponsibleGamingService{
+		db:    db,
+		stats: &StatsService{countries: []Country{{Code: "DE", Name: "Germany", AvgHourlyWage: 20}}},
+		now:   func() time.Time { return now },
+	}
+
+	require.NoError(t, service.AuthorizeBet(user.ID, 40))
+	addTransactions(t, db, user.ID, model.TransactionTypeGameLoss, 40)
+	now = start.Add(20 * time.Minute)
+	require.NoError(t, service.AuthorizeBet(user.ID, 10))
+
+	check, err := service.GetRealityCheck(user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, check, "not due yet")
+
+	// Half an hour in, betting stops until the player has seen where they are
+	now = start.Add(31 * time.Minute)
+	var limitErr *BetLimitError
+	err = service.AuthorizeBet(user.ID, 10)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, BetBlockedRealityCheck, limitErr.Code)
+
+	issued, err := service.IssueDueRealityChecks()
+	require.NoError(t, err)
+	assert.Equal(t, 1, issued)
+	issued, err = service.IssueDueRealityChecks()
+	require.NoError(t, err)
+	assert.Zero(t, issued, "one check per interval")
+
+	var recorded int64
+	require.NoError(t, db.Model(&model.OutboxEvent{}).Where("name = ?", events.NameRealityCheckDue).Count(&recorded).Error)
+	assert.Equal(t, int64(1), recorded)
+
+	check, err = service.GetRealityCheck(user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, check)
+	assert.Equal(t, 31, check.PlayedMinutes)
+	assert.Equal(t, 50.0, check.Wagered)
+	assert.Equal(t, -40.0, check.NetResult)
+	assert.Equal(t, 2.0, check.WorkHours)
+	assert.Equal(t, "You have been playing for 31 minutes and have lost $40.00, which takes 2.0 hours of work to earn in Germany.", check.Message)
+
+	_, err = service.AcknowledgeRealityCheck(user.ID, check.ID+1)
+	assert.EqualError(t, err, "reality_check_not_found")
+
+	acknowledged, err := service.AcknowledgeRealityCheck(user.ID, check.ID)
+	require.NoError(t, err)
+	require.NotNil(t, acknowledged.AcknowledgedAt)
+	require.NoError(t, service.AuthorizeBet(user.ID, 10))
+
+	check, err = service.GetRealityCheck(user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, check)
+
+	// The next one comes another interval after the acknowledgement
+	now = start.Add(50 * time.Minute)
+	require.NoError(t, service.AuthorizeBet(user.ID, 10))
+	now = start.Add(62 * time.Minute)
+	err = service.AuthorizeBet(user.ID, 10)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, BetBlockedRealityCheck, limitErr.Code)
+}
@@ -0,0 +1,521 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Responsible-gaming parameters
+const (
+	GamblingLimitIncreaseDelay = 24 * time.Hour   // How long a looser limit waits before it applies
+	GamblingSessionIdleGap     = 30 * time.Minute // A break this long ends a gambling session
+	RealityCheckInterval       = 30 * time.Minute // Play time between reality checks
+)
+
+// Cooldowns are recorded as user statuses
+const (
+	StatusCoolingOff   = "cooling_off"
+	StatusSelfExcluded = "self_excluded"
+)
+
+// Codes of the errors returned when a bet is blocked
+const (
+	BetBlockedSelfExcluded    = "self_excluded"
+	BetBlockedCoolingOff      = "cooling_off"
+	BetBlockedDailyLoss       = "daily_loss_limit_reached"
+	BetBlockedWeeklyLoss      = "weekly_loss_limit_reached"
+	BetBlockedSessionWager    = "session_wager_limit_reached"
+	BetBlockedSessionDuration = "session_duration_limit_reached"
+	BetBlockedRealityCheck    = "reality_check_required"
+)
+
+// GamblingLimitTypes lists every limit a user can set
+var GamblingLimitTypes = []model.GamblingLimitType{
+	model.GamblingLimitDailyLoss,
+	model.GamblingLimitWeeklyLoss,
+	model.GamblingLimitSessionWager,
+	model.GamblingLimitSessionDuration,
+}
+
+// cooldownKind is a way to stop gambling for a while
+type cooldownKind struct {
+	status  string
+	minDays int
+	maxDays int
+}
+
+// cooldownKinds maps the cooldowns users can start to their status and allowed length
+var cooldownKinds = map[string]cooldownKind{
+	"cooling_off":    {status: StatusCoolingOff, minDays: 1, maxDays: 42},
+	"self_exclusion": {status: StatusSelfExcluded, minDays: 180, maxDays: 1825},
+}
+
+// validGamblingLimit reports whether a limit type exists
+func validGamblingLimit(limitType model.GamblingLimitType) bool {
+	for _, t := range GamblingLimitTypes {
+		if t == limitType {
+			return true
+		}
+	}
+	return false
+}
+
+// BetLimitError is returned when a responsible-gaming limit blocks a bet
+type BetLimitError struct {
+	Code  string
+	Until *time.Time // When betting is possible again; nil if waiting won't help
+}
+
+func (e *BetLimitError) Error() string {
+	return e.Code
+}
+
+// gamblingLossWindow returns the calendar day or week a loss limit covers, in
+// the limit's timezone with weeks starting on Monday
+func gamblingLossWindow(limitType model.GamblingLimitType, now time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := now.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	if limitType == model.GamblingLimitWeeklyLoss {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	}
+	return start, start.AddDate(0, 0, 1)
+}
+
+// ResponsibleGamingService manages the limits users put on their own gambling
+// and enforces them before every bet
+type ResponsibleGamingService struct {
+	db    *gorm.DB
+	stats *StatsService
+	now   func() time.Time
+}
+
+// NewResponsibleGamingService creates a new responsible gaming service instance
+func NewResponsibleGamingService() *ResponsibleGamingService {
+	return &ResponsibleGamingService{
+		db:    database.GetDB(),
+		stats: DefaultStatsService(),
+	}
+}
+
+// getStats returns the service's country data, defaulting to the built-in one
+func (s *ResponsibleGamingService) getStats() *StatsService {
+	if s.stats == nil {
+		return DefaultStatsService()
+	}
+	return s.stats
+}
+
+// clock returns the current time
+func (s *ResponsibleGamingService) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// AuthorizeBet checks a stake against the user's cooldowns and limits and
+// counts it towards their gambling session. Every game calls it before taking
+// a stake, inside its own transaction where it has one.
+func AuthorizeBet(db *gorm.DB, userID uint, amount float64) error {
+	return (&ResponsibleGamingService{db: db}).AuthorizeBet(userID, amount)
+}
+
+// AuthorizeBet checks a stake against the user's cooldowns and limits and
+// counts it towards their gambling session
+func (s *ResponsibleGamingService) AuthorizeBet(userID uint, amount float64) error {
+	now := s.clock()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user_not_found")
+			}
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+
+		cooldown, err := s.activeCooldown(tx, userID, now)
+		if err != nil {
+			return err
+		}
+		if cooldown != nil {
+			code := BetBlockedCoolingOff
+			if cooldown.Kind == "self_exclusion" {
+				code = BetBlockedSelfExcluded
+			}
+			return &BetLimitError{Code: code, Until: &cooldown.Until}
+		}
+
+		session, err := s.currentSession(tx, userID, now)
+		if err != nil {
+			return err
+		}
+		if session != nil && realityCheckDue(session, now) {
+			return &BetLimitError{Code: BetBlockedRealityCheck}
+		}
+
+		limits, err := s.limits(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, check := range []struct {
+			limitType model.GamblingLimitType
+			code      string
+		}{
+			{model.GamblingLimitDailyLoss, BetBlockedDailyLoss},
+			{model.GamblingLimitWeeklyLoss, BetBlockedWeeklyLoss},
+		} {
+			limit := limits[check.limitType].Effective(now)
+			if limit == nil {
+				continue
+			}
+			start, end := gamblingLossWindow(check.limitType, now, loadTimezone(limits[check.limitType].EffectiveTimezone(now)))
+			loss, err := s.gamblingLoss(tx, userID, start)
+			if err != nil {
+				return err
+			}
+			// The stake could be lost in full, so it has to fit in what is left
+			if roundMoney(loss+amount) > *limit {
+				if amount > *limit {
+					return &BetLimitError{Code: check.code}
+				}
+				return &BetLimitError{Code: check.code, Until: &end}
+			}
+		}
+
+		if limit := limits[model.GamblingLimitSessionWager].Effective(now); limit != nil {
+			wagered := 0.0
+			if session != nil {
+				wagered = session.Wagered
+			}
+			if roundMoney(wagered+amount) > *limit {
+				if amount > *limit {
+					return &BetLimitError{Code: BetBlockedSessionWager}
+				}
+				until := session.LastBetAt.Add(GamblingSessionIdleGap)
+				return &BetLimitError{Code: BetBlockedSessionWager, Until: &until}
+			}
+		}
+
+		if limit := limits[model.GamblingLimitSessionDuration].Effective(now); limit != nil && session != nil {
+			if now.Sub(session.StartedAt) >= time.Duration(*limit*float64(time.Minute)) {
+				until := session.LastBetAt.Add(GamblingSessionIdleGap)
+				return &BetLimitError{Code: BetBlockedSessionDuration, Until: &until}
+			}
+		}
+
+		if session == nil {
+			realityCheckAt := now.Add(RealityCheckInterval)
+			return tx.Create(&model.GamblingSession{
+				UserID:         userID,
+				StartedAt:      now,
+				LastBetAt:      now,
+				Wagered:        roundMoney(amount),
+				Bets:           1,
+				RealityCheckAt: &realityCheckAt,
+			}).Error
+		}
+		return tx.Model(session).Updates(map[string]interface{}{
+			"last_bet_at": now,
+			"wagered":     gorm.Expr("wagered + ?", roundMoney(amount)),
+			"bets":        gorm.Expr("bets + 1"),
+		}).Error
+	})
+}
+
+// limits loads the user's limits by type. Missing types map to nil, whose
+// Effective is nil too.
+func (s *ResponsibleGamingService) limits(db *gorm.DB, userID uint) (map[model.GamblingLimitType]*model.GamblingLimit, error) {
+	var limits []model.GamblingLimit
+	if err := db.Where("user_id = ?", userID).Find(&limits).Error; err != nil {
+		return nil, fmt.Errorf("failed to get gambling limits: %w", err)
+	}
+
+	byType := make(map[model.GamblingLimitType]*model.GamblingLimit, len(limits))
+	for i := range limits {
+		byType[limits[i].Type] = &limits[i]
+	}
+	return byType, nil
+}
+
+// gamblingNet returns the user's net game result since a time
+func (s *ResponsibleGamingService) gamblingNet(db *gorm.DB, userID uint, since time.Time) (float64, error) {
+	var net float64
+	if err := db.Model(&model.Transaction{}).
+		Where("user_id = ? AND type IN ? AND created_at >= ?", userID, gameTransactionTypes, since.Local()).
+		Select("COALESCE(SUM(" + gameNetAmountSQL + "), 0)").Scan(&net).Error; err != nil {
+		return 0, fmt.Errorf("failed to get gambling results: %w", err)
+	}
+	return roundMoney(net), nil
+}
+
+// gamblingLoss returns the user's net game loss since a time, or 0 if they are
+// ahead. Stakes on games still being played count as lost.
+func (s *ResponsibleGamingService) gamblingLoss(db *gorm.DB, userID uint, since time.Time) (float64, error) {
+	net, err := s.gamblingNet(db, userID, since)
+	if err != nil {
+		return 0, err
+	}
+	var open float64
+	if err := db.Model(&model.OpenStake{}).
+		Where("user_id = ? AND created_at >= ?", userID, since.Local()).
+		Select("COALESCE(SUM(amount), 0)").Scan(&open).Error; err != nil {
+		return 0, fmt.Errorf("failed to get open stakes: %w", err)
+	}
+	return max(roundMoney(open-net), 0), nil
+}
+
+// currentSession returns the user's gambling session if they have bet within
+// the idle gap, or nil
+func (s *ResponsibleGamingService) currentSession(db *gorm.DB, userID uint, now time.Time) (*model.GamblingSession, error) {
+	var session model.GamblingSession
+	err := db.Where("user_id = ? AND last_bet_at > ?", userID, now.Add(-GamblingSessionIdleGap).Local()).
+		Order("last_bet_at DESC").First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gambling session: %w", err)
+	}
+	return &session, nil
+}
+
+// CooldownStatus describes a cooling-off or self-exclusion period in force
+type CooldownStatus struct {
+	Kind  string    `json:"kind"` // "cooling_off" or "self_exclusion"
+	Until time.Time `json:"until"`
+}
+
+// activeCooldown returns the user's cooldown in force at now, or nil. A
+// self-exclusion outranks a cooling-off period; until is the latest end of any.
+func (s *ResponsibleGamingService) activeCooldown(db *gorm.DB, userID uint, now time.Time) (*CooldownStatus, error) {
+	var statuses []model.UserStatus
+	if err := db.Where("user_id = ? AND status IN ? AND expires_at > ?",
+		userID, []string{StatusCoolingOff, StatusSelfExcluded}, now.Local()).
+		Find(&statuses).Error; err != nil {
+		return nil, fmt.Errorf("failed to get cooldowns: %w", err)
+	}
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	cooldown := &CooldownStatus{Kind: "cooling_off"}
+	for _, status := range statuses {
+		if status.Status == StatusSelfExcluded {
+			cooldown.Kind = "self_exclusion"
+		}
+		if status.ExpiresAt.After(cooldown.Until) {
+			cooldown.Until = status.ExpiresAt
+		}
+	}
+	return cooldown, nil
+}
+
+// GamblingLimitStatus describes one of a user's limits and how much of it is used
+type GamblingLimitStatus struct {
+	Type           model.GamblingLimitType `json:"type"`
+	Amount         *float64                `json:"amount"` // Limit in force, nil if none
+	PendingAmount  *float64                `json:"pending_amount,omitempty"`
+	PendingRemoval bool                    `json:"pending_removal,omitempty"`
+	PendingAt      *time.Time              `json:"pending_at,omitempty"` // When the pending change applies
+	Used           float64                 `json:"used"`                 // Loss this day or week, stakes or minutes this session
+	Remaining      *float64                `json:"remaining,omitempty"`
+	ResetsAt       *time.Time              `json:"resets_at,omitempty"` // End of the day or week for loss limits
+}
+
+// ResponsibleGamingResponse represents a user's limits, cooldown and session
+type ResponsibleGamingResponse struct {
+	Limits   []GamblingLimitStatus  `json:"limits"`
+	Cooldown *CooldownStatus        `json:"cooldown,omitempty"`
+	Session  *model.GamblingSession `json:"session,omitempty"`
+}
+
+// GetLimits returns the user's limits with their usage, any cooldown in force
+// and the current gambling session
+func (s *ResponsibleGamingService) GetLimits(userID uint) (*ResponsibleGamingResponse, error) {
+	now := s.clock()
+
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user_not_found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	limits, err := s.limits(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	cooldown, err := s.activeCooldown(s.db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.currentSession(s.db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ResponsibleGamingResponse{
+		Limits:   make([]GamblingLimitStatus, 0, len(GamblingLimitTypes)),
+		Cooldown: cooldown,
+		Session:  session,
+	}
+	for _, limitType := range GamblingLimitTypes {
+		limit := limits[limitType]
+		status := GamblingLimitStatus{Type: limitType, Amount: limit.Effective(now)}
+		if limit != nil && limit.PendingAt != nil && now.Before(*limit.PendingAt) {
+			status.PendingAmount = limit.PendingAmount
+			status.PendingRemoval = limit.PendingAmount == nil
+			status.PendingAt = limit.PendingAt
+		}
+
+		switch limitType {
+		case model.GamblingLimitDailyLoss, model.GamblingLimitWeeklyLoss:
+			loc := userTimezone(&user)
+			if limit != nil {
+				loc = loadTimezone(limit.EffectiveTimezone(now))
+			}
+			start, end := gamblingLossWindow(limitType, now, loc)
+			if status.Used, err = s.gamblingLoss(s.db, userID, start); err != nil {
+				return nil, err
+			}
+			status.ResetsAt = &end
+		case model.GamblingLimitSessionWager:
+			if session != nil {
+				status.Used = session.Wagered
+			}
+		case model.GamblingLimitSessionDuration:
+			if session != nil {
+				status.Used = now.Sub(session.StartedAt).Truncate(time.Minute).Minutes()
+			}
+		}
+
+		if status.Amount != nil {
+			remaining := roundMoney(max(*status.Amount-status.Used, 0))
+			status.Remaining = &remaining
+		}
+		response.Limits = append(response.Limits, status)
+	}
+
+	return response, nil
+}
+
+// SetGamblingLimitRequest represents a request to set or remove a limit
+type SetGamblingLimitRequest struct {
+	Amount *float64 `json:"amount"` // Coins, or minutes for session_duration; null removes the limit
+}
+
+// SetLimit sets or removes (amount nil) one of the user's limits. Tightening
+// a limit applies at once; loosening or removing it only applies after
+// GamblingLimitIncreaseDelay, so it can't be done on impulse mid-session. Loss
+// limits keep counting in the timezone they were set in until a loosening
+// brings in the user's current one.
+func (s *ResponsibleGamingService) SetLimit(userID uint, limitType model.GamblingLimitType, amount *float64) (*ResponsibleGamingResponse, error) {
+	if !validGamblingLimit(limitType) {
+		return nil, errors.New("unknown_limit")
+	}
+	if amount != nil {
+		if *amount <= 0 {
+			return nil, errors.New("invalid_limit")
+		}
+		rounded := roundMoney(*amount)
+		amount = &rounded
+	}
+	now := s.clock()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user_not_found")
+			}
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+
+		limit := model.GamblingLimit{UserID: userID, Type: limitType}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND type = ?", userID, limitType).First(&limit).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get gambling limit: %w", err)
+		}
+
+		current := limit.Effective(now)
+		limit.Timezone = limit.EffectiveTimezone(now)
+		limit.PendingTimezone = ""
+		if current == nil {
+			limit.Timezone = user.Timezone
+		}
+		loosens := amount == nil || (current != nil && *amount > *current)
+		if current == nil && amount == nil {
+			loosens = false
+		}
+
+		if loosens {
+			at := now.Add(GamblingLimitIncreaseDelay)
+			limit.Amount = current
+			limit.PendingAmount = amount
+			limit.PendingAt = &at
+			// A new timezone is a loosening too, as it can restart the window
+			limit.PendingTimezone = user.Timezone
+		} else {
+			// Tightening, or setting the limit in force again, also cancels a pending change
+			limit.Amount = amount
+			limit.PendingAmount = nil
+			limit.PendingAt = nil
+		}
+
+		if err := tx.Save(&limit).Error; err != nil {
+			return fmt.Errorf("failed to save gambling limit: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetLimits(userID)
+}
+
+// StartCooldownRequest represents a request to start a cooling-off or self-exclusion period
+type StartCooldownRequest struct {
+	Kind string `json:"kind"` // "cooling_off" (1-42 days) or "self_exclusion" (180-1825 days)
+	Days int    `json:"days"`
+}
+
+// StartCooldown stops the user from gambling for a number of days. A cooldown
+// can't be cancelled or shortened; starting another one can only extend it.
+func (s *ResponsibleGamingService) StartCooldown(userID uint, kind string, days int) (*ResponsibleGamingResponse, error) {
+	cooldown, ok := cooldownKinds[kind]
+	if !ok {
+		return nil, errors.New("unknown_cooldown")
+	}
+	if days < cooldown.minDays || days > cooldown.maxDays {
+		return nil, errors.New("invalid_cooldown_length")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user_not_found")
+			}
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+		return grantStatus(tx, userID, cooldown.status, time.Duration(days)*24*time.Hour)
+	})
+	if err != nil {
+		return nil, err
+	}
+	flushEvents(s.db)
+
+	return s.GetLimits(userID)
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/game"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGamblingLimitChanges(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	now := time.Now()
+	service := &ResponsibleGamingService{db: db, now: func() time.Time { return now }}
+
+	limitOf := func(response *ResponsibleGamingResponse) GamblingLimitStatus {
+		require.Equal(t, model.GamblingLimitDailyLoss, response.Limits[0].Type)
+		return response.Limits[0]
+	}
+	amount := func(v float64) *float64 { return &v }
+
+	// A new limit is a tightening and applies at once
+	response, err := service.SetLimit(user.ID, model.GamblingLimitDailyLoss, amount(100))
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, *limitOf(response).Amount)
+	assert.Nil(t, limitOf(response).PendingAt)
+
+	// Raising it waits
+	response, err = service.SetLimit(user.ID, model.GamblingLimitDailyLoss, amount(200))
+	require.NoError(t, err)
+	limit := limitOf(response)
+	assert.Equal(t, 100.0, *limit.Amount)
+	assert.Equal(t, 200.0, *limit.PendingAmount)
+	assert.WithinDuration(t, now.Add(GamblingLimitIncreaseDelay), *limit.PendingAt, time.Second)
+
+	// Lowering it applies at once and drops the pending raise
+	response, err = service.SetLimit(user.ID, model.GamblingLimitDailyLoss, amount(50))
+	require.NoError(t, err)
+	limit = limitOf(response)
+	assert.Equal(t, 50.0, *limit.Amount)
+	assert.Nil(t, limit.PendingAmount)
+	assert.Nil(t, limit.PendingAt)
+
+	// So does removing it, once the delay has passed
+	response, err = service.SetLimit(user.ID, model.GamblingLimitDailyLoss, nil)
+	require.NoError(t, err)
+	assert.True(t, limitOf(response).PendingRemoval)
+	assert.Equal(t, 50.0, *limitOf(response).Amount)
+
+	now = now.Add(GamblingLimitIncreaseDelay + time.Minute)
+	response, err = service.GetLimits(user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, limitOf(response).Amount)
+	assert.False(t, limitOf(response).PendingRemoval)
+
+	_, err = service.SetLimit(user.ID, "lifetime_loss", amount(10))
+	assert.EqualError(t, err, "unknown_limit")
+	_, err = service.SetLimit(user.ID, model.GamblingLimitSessionWager, amount(-5))
+	assert.EqualError(t, err, "invalid_limit")
+}
+
+func TestAuthorizeBetLossLimit(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &ResponsibleGamingService{db: db}
+	limit := 100.0
+
+	_, err := service.SetLimit(user.ID, model.GamblingLimitDailyLoss, &limit)
+	require.NoError(t, err)
+	addTransactions(t, db, user.ID, model.TransactionTypeGameLoss, 60, 40)
+	addTransactions(t, db, user.ID, model.TransactionTypeGameWin, 10)
+
+	// 90 lost today, so only 10 more can be staked
+	require.NoError(t, service.AuthorizeBet(user.ID, 10))
+	err = service.AuthorizeBet(user.ID, 20)
+	var limitErr *BetLimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, BetBlockedDailyLoss, limitErr.Code)
+	require.NotNil(t, limitErr.Until)
+	_, end := gamblingLossWindow(model.GamblingLimitDailyLoss, time.Now(), time.UTC)
+	assert.Equal(t, end, *limitErr.Until)
+
+	// A stake bigger than the whole limit won't fit tomorrow either
+	err = service.AuthorizeBet(user.ID, 150)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Nil(t, limitErr.Until)
+
+	// Games refuse the bet before playing it
+	slots := &SlotsService{db: db, engine: game.NewSlotsEngine()}
+	_, err = slots.Spin(&SpinRequest{UserID: user.ID, Bet: 20})
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, 1000.0, userBalance(t, db, user.ID))
+}
+
+func TestLossLimitTimezoneIsFrozen(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	require.NoError(t, db.Model(user).Update("timezone", "Pacific/Kiritimati").Error)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC) // 02:00 on the 11th in Kiritimati
+	service := &ResponsibleGamingService{db: db, now: func() time.Time { return now }}
+	limit := 100.0
+
+	_, err := service.SetLimit(user.ID, model.GamblingLimitDailyLoss, &limit)
+	require.NoError(t, err)
+	// Lost just after midnight in Kiritimati, which is still the 9th in Pago Pago
+	require.NoError(t, db.Create(&model.Transaction{
+		UserID: user.ID, Type: model.TransactionTypeGameLoss, Amount: 90, CreatedAt: now.Add(-90 * time.Minute),
+	}).Error)
+
+	// Moving to Pago Pago doesn't start a new day for the limit
+	require.NoError(t, db.Model(user).Update("timezone", "Pacific/Pago_Pago").Error)
+	err = service.AuthorizeBet(user.ID, 20)
+	var limitErr *BetLimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, BetBlockedDailyLoss, limitErr.Code)
+
+	// Nor does setting the limit again, which only brings the timezone in
+	// after the delay
+	_, err = service.SetLimit(user.ID, model.GamblingLimitDailyLoss, &limit)
+	require.NoError(t, err)
+	err = service.AuthorizeBet(user.ID, 20)
+	require.True(t, errors.As(err, &limitErr))
+	raised := 150.0
+	response, err := service.SetLimit(user.ID, model.GamblingLimitDailyLoss, &raised)
+	require.NoError(t, err)
+	assert.Equal(t, 90.0, response.Limits[0].Used)
+	assert.ErrorAs(t, service.AuthorizeBet(user.ID, 20), &limitErr)
+
+	now = now.Add(GamblingLimitIncreaseDelay)
+	response, err = service.GetLimits(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 150.0, *response.Limits[0].Amount)
+	assert.Zero(t, response.Limits[0].Used)
+	require.NoError(t, service.AuthorizeBet(user.ID, 20))
+}
+
+func TestAuthorizeBetSessionLimits(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	start := time.Now()
+	now := start
+	service := &ResponsibleGamingService{db: db, now: func() time.Time { return now }}
+	wager, minutes := 50.0, 60.0
+
+	_, err := service.SetLimit(user.ID, model.GamblingLimitSessionWager, &wager)
+	require.NoError(t, err)
+	_, err = service.SetLimit(user.ID, model.GamblingLimitSessionDuration, &minutes)
+	require.NoError(t, err)
+
+	var limitErr *BetLimitError
+	require.NoError(t, service.AuthorizeBet(user.ID, 30))
+	err = service.AuthorizeBet(user.ID, 30)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, BetBlockedSessionWager, limitErr.Code)
+
+	now = start.Add(20 * time.Minute)
+	require.NoError(t, service.AuthorizeBet(user.ID, 10))
+	now = start.Add(45 * time.Minute)
+	check, err := service.GetRealityCheck(user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, check, "half an hour in, a reality check is due")
+	_, err = service.AcknowledgeRealityCheck(user.ID, check.ID)
+	require.NoError(t, err)
+	require.NoError(t, service.AuthorizeBet(user.ID, 10))
+
+	response, err := service.GetLimits(user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, response.Session)
+	assert.Equal(t, 50.0, response.Session.Wagered)
+	assert.Equal(t, 3, response.Session.Bets)
+
+	// An hour in, the session is over until the player takes a break
+	now = start.Add(61 * time.Minute)
+	err = service.AuthorizeBet(user.ID, 0)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, BetBlockedSessionDuration, limitErr.Code)
+	assert.WithinDuration(t, start.Add(75*time.Minute), *limitErr.Until, time.Second)
+
+	now = start.Add(76 * time.Minute)
+	require.NoError(t, service.AuthorizeBet(user.ID, 40))
+}
+
+func TestCooldownBlocksBets(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &ResponsibleGamingService{db: db}
+
+	_, err := service.StartCooldown(user.ID, "cooling_off", 100)
+	assert.EqualError(t, err, "invalid_cooldown_length")
+	_, err = service.StartCooldown(user.ID, "vacation", 2)
+	assert.EqualError(t, err, "unknown_cooldown")
+
+	response, err := service.StartCooldown(user.ID, "cooling_off", 2)
+	require.NoError(t, err)
+	require.NotNil(t, response.Cooldown)
+	assert.Equal(t, "cooling_off", response.Cooldown.Kind)
+
+	var limitErr *BetLimitError
+	err = service.AuthorizeBet(user.ID, 1)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, BetBlockedCoolingOff, limitErr.Code)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), *limitErr.Until, time.Minute)
+
+	// A self-exclusion on top takes over and runs longer
+	_, err = service.StartCooldown(user.ID, "self_exclusion", 180)
+	require.NoError(t, err)
+	err = service.AuthorizeBet(user.ID, 1)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, BetBlockedSelfExcluded, limitErr.Code)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 180), *limitErr.Until, time.Hour)
+}
+
+func TestOpenStakesCountTowardsLossLimits(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	service := &ResponsibleGamingService{db: db}
+	limit := 100.0
+
+	_, err := service.SetLimit(user.ID, model.GamblingLimitDailyLoss, &limit)
+	require.NoError(t, err)
+
+	// A hand in play could still be lost, so a second one has to fit beside it
+	stake, balance, err := PlaceOpenStake(db, user.ID, model.GameTypeBlackjack, 60)
+	require.NoError(t, err)
+	assert.Equal(t, 940.0, balance)
+	_, _, err = PlaceOpenStake(db, user.ID, model.GameTypeBlackjack, 60)
+	var limitErr *BetLimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, BetBlockedDailyLoss, limitErr.Code)
+	assert.Equal(t, 940.0, userBalance(t, db, user.ID))
+
+	// Doubling down raises the open stake
+	balance, err = RaiseOpenStake(db, stake, 40)
+	require.NoError(t, err)
+	assert.Equal(t, 900.0, balance)
+	assert.Equal(t, 100.0, stake.Amount)
+	err = service.AuthorizeBet(user.ID, 1)
+	require.True(t, errors.As(err, &limitErr))
+
+	// Once the hand settles as a win, only its result counts
+	require.NoError(t, db.Delete(stake).Error)
+	addTransactions(t, db, user.ID, model.TransactionTypeGame, 100)
+	require.NoError(t, service.AuthorizeBet(user.ID, 60))
+
+	_, _, err = PlaceOpenStake(db, user.ID, model.GameTypeBlackjack, 5000)
+	assert.EqualError(t, err, "insufficient_balance")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
 	"github.com/smoreg/freezino/backend/internal/game"
 	"github.com/smoreg/freezino/backend/internal/model"
 	"gorm.io/gorm"
@@ -73,12 +74,19 @@ func (s *RouletteService) PlaceBet(req PlaceBetRequest) (*PlaceBetResponse, erro
 		return nil, fmt.Errorf("insufficient balance")
 	}
 
+	// Check responsible-gaming limits
+	if err := AuthorizeBet(tx, req.UserID, totalBet); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Calculate result
 	winningNumber, _, totalWin, err := s.rouletteGame.CalculateResult(req.Bets)
 	if err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to calculate result: %w", err)
 	}
+	totalWin = GamePayout(tx, req.UserID, model.GameTypeRoulette, totalBet, totalWin)
 
 	// Update user balance
 	profit := totalWin - totalBet
@@ -146,10 +154,21 @@ func (s *RouletteService) PlaceBet(req PlaceBetRequest) (*PlaceBetResponse, erro
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
+	if err := events.Record(tx, events.BetSettled{
+		UserID:   req.UserID,
+		GameType: model.GameTypeRoulette,
+		Bet:      totalBet,
+		Payout:   totalWin,
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	flushEvents(db)
 
 	// Return response
 	return &PlaceBetResponse{
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
 	"github.com/smoreg/freezino/backend/internal/model"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -25,23 +26,66 @@ func NewShopService() *ShopService {
 
 // ItemResponse represents a shop item response
 type ItemResponse struct {
-	ID          uint    `json:"id"`
-	Name        string  `json:"name"`
-	Type        string  `json:"type"`
-	Price       float64 `json:"price"`
-	ImageURL    string  `json:"image_url"`
-	Description string  `json:"description"`
-	CreatedAt   string  `json:"created_at"`
+	ID           uint               `json:"id"`
+	Name         string             `json:"name"`
+	Type         string             `json:"type"`
+	Price        float64            `json:"price"`      // Current price including demand
+	BasePrice    float64            `json:"base_price"` // Price without demand premium
+	ImageURL     string             `json:"image_url"`
+	Description  string             `json:"description"`
+	Stock        *int               `json:"stock,omitempty"`         // Units left, omitted when unlimited
+	EditionSize  *int               `json:"edition_size,omitempty"`  // Set for limited editions
+	EditionsLeft *int               `json:"editions_left,omitempty"` // Copies of a limited edition still for sale
+	Effects      []model.ItemEffect `json:"effects,omitempty"`       // Modifiers applied while equipped
+	CreatedAt    string             `json:"created_at"`
 }
 
 // UserItemResponse represents a user's item with full details
 type UserItemResponse struct {
-	ID          uint         `json:"id"`
-	UserID      uint         `json:"user_id"`
-	ItemID      uint         `json:"item_id"`
-	PurchasedAt string       `json:"purchased_at"`
-	IsEquipped  bool         `json:"is_equipped"`
-	Item        ItemResponse `json:"item"`
+	ID           uint         `json:"id"`
+	UserID       uint         `json:"user_id"`
+	ItemID       uint         `json:"item_id"`
+	PurchasedAt  string       `json:"purchased_at"`
+	IsEquipped   bool         `json:"is_equipped"`
+	SerialNumber *int         `json:"serial_number,omitempty"`
+	Durability   float64      `json:"durability"`
+	ResaleValue  float64      `json:"resale_value"` // What the shop pays for it right now
+	RepairCost   float64      `json:"repair_cost"`  // Cost to restore it to mint condition
+	Item         ItemResponse `json:"item"`
+}
+
+// newUserItemResponse converts a user item with its preloaded item to its response format
+func newUserItemResponse(userItem *model.UserItem, now time.Time) UserItemResponse {
+	return UserItemResponse{
+		ID:           userItem.ID,
+		UserID:       userItem.UserID,
+		ItemID:       userItem.ItemID,
+		PurchasedAt:  userItem.PurchasedAt.Format("2006-01-02T15:04:05Z07:00"),
+		IsEquipped:   userItem.IsEquipped,
+		SerialNumber: userItem.SerialNumber,
+		Durability:   userItem.Durability,
+		ResaleValue:  ResaleValue(userItem, now),
+		RepairCost:   RepairCost(userItem),
+		Item:         newItemResponse(&userItem.Item, now),
+	}
+}
+
+// newItemResponse converts an item to its response format priced at now
+func newItemResponse(item *model.Item, now time.Time) ItemResponse {
+	return ItemResponse{
+		ID:           item.ID,
+		Name:         item.Name,
+		Type:         string(item.Type),
+		Price:        CurrentPrice(item, now),
+		BasePrice:    item.Price,
+		ImageURL:     item.ImageURL,
+		Description:  item.Description,
+		Stock:        item.Stock,
+		EditionSize:  item.EditionSize,
+		EditionsLeft: editionsLeft(item),
+		Effects:      item.Effects,
+		CreatedAt:    item.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
 }
 
 // BuyItemResponse represents the response after buying an item
@@ -82,24 +126,17 @@ func (s *ShopService) GetItems(itemType string, rarity string) ([]ItemResponse,
 		return nil, fmt.Errorf("failed to get items: %w", err)
 	}
 
-	// Convert to response format
+	// Convert to response format, pricing every item at the same instant
+	now := time.Now()
 	response := make([]ItemResponse, len(items))
-	for i, item := range items {
-		response[i] = ItemResponse{
-			ID:          item.ID,
-			Name:        item.Name,
-			Type:        string(item.Type),
-			Price:       item.Price,
-			ImageURL:    item.ImageURL,
-			Description: item.Description,
-			CreatedAt:   item.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		}
+	for i := range items {
+		response[i] = newItemResponse(&items[i], now)
 	}
 
 	return response, nil
 }
 
-// BuyItem handles purchasing an item
+// BuyItem handles purchasing an item at its current demand price
 func (s *ShopService) BuyItem(userID uint, itemID uint) (*BuyItemResponse, error) {
 	// Start transaction
 	tx := s.db.Begin()
@@ -119,9 +156,9 @@ func (s *ShopService) BuyItem(userID uint, itemID uint) (*BuyItemResponse, error
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	// Get item
+	// Get item with lock so the price and stock seen here are the ones we sell at
 	var item model.Item
-	if err := tx.First(&item, itemID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, itemID).Error; err != nil {
 		tx.Rollback()
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("item not found")
@@ -129,14 +166,24 @@ func (s *ShopService) BuyItem(userID uint, itemID uint) (*BuyItemResponse, error
 		return nil, fmt.Errorf("failed to get item: %w", err)
 	}
 
+	now := time.Now()
+	price := CurrentPrice(&item, now)
+
 	// Check if user has enough balance
-	if user.Balance < item.Price {
+	if user.Balance < price {
 		tx.Rollback()
-		return nil, fmt.Errorf("insufficient balance: have %.2f, need %.2f", user.Balance, item.Price)
+		return nil, fmt.Errorf("insufficient balance: have %.2f, need %.2f", user.Balance, price)
+	}
+
+	// Take a unit from stock and assign a serial number for limited editions
+	serial, err := takeShopUnit(tx, &item)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	// Deduct price from user balance
-	newBalance := user.Balance - item.Price
+	newBalance := roundMoney(user.Balance - price)
 	if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to update balance: %w", err)
@@ -144,53 +191,68 @@ func (s *ShopService) BuyItem(userID uint, itemID uint) (*BuyItemResponse, error
 
 	// Create user item
 	userItem := model.UserItem{
-		UserID:      userID,
-		ItemID:      itemID,
-		PurchasedAt: time.Now(),
-		IsEquipped:  false,
+		UserID:       userID,
+		ItemID:       itemID,
+		PurchasedAt:  now,
+		IsEquipped:   false,
+		SerialNumber: serial,
+		Durability:   model.ItemDurabilityMax,
 	}
 	if err := tx.Create(&userItem).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to create user item: %w", err)
 	}
 
+	// Purchases push the price up for the next buyer
+	if err := recordShopDemand(tx, &item, price, now); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Create transaction record
 	transaction := model.Transaction{
 		UserID:       userID,
 		Type:         model.TransactionTypePurchase,
-		Amount:       -item.Price, // Negative because it's a purchase
+		Amount:       -price, // Negative because it's a purchase
 		BalanceAfter: newBalance,
 		Description:  fmt.Sprintf("Purchased %s", item.Name),
-		CreatedAt:    time.Now(),
+		CreatedAt:    now,
 	}
 	if err := tx.Create(&transaction).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
+	if err := events.Record(tx, events.ItemPurchased{
+		UserID:     userID,
+		ItemID:     item.ID,
+		UserItemID: userItem.ID,
+		Price:      price,
+		Source:     events.SourceShop,
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	flushEvents(s.db)
+
+	// Reflect the unit taken above in the response
+	if item.Stock != nil {
+		left := *item.Stock - 1
+		item.Stock = &left
+	}
+	if serial != nil {
+		item.EditionsSold = *serial
+	}
 
 	// Build response
+	userItem.Item = item
 	response := &BuyItemResponse{
-		UserItem: UserItemResponse{
-			ID:          userItem.ID,
-			UserID:      userItem.UserID,
-			ItemID:      userItem.ItemID,
-			PurchasedAt: userItem.PurchasedAt.Format("2006-01-02T15:04:05Z07:00"),
-			IsEquipped:  userItem.IsEquipped,
-			Item: ItemResponse{
-				ID:          item.ID,
-				Name:        item.Name,
-				Type:        string(item.Type),
-				Price:       item.Price,
-				ImageURL:    item.ImageURL,
-				Description: item.Description,
-				CreatedAt:   item.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			},
-		},
+		UserItem:      newUserItemResponse(&userItem, now),
 		NewBalance:    newBalance,
 		TransactionID: transaction.ID,
 	}
@@ -228,8 +290,20 @@ func (s *ShopService) SellItem(userID uint, userItemID uint) (*SellItemResponse,
 		return nil, fmt.Errorf("failed to get user item: %w", err)
 	}
 
-	// Calculate sale price (50% of original price)
-	salePrice := userItem.Item.Price * 0.5
+	// Items held in escrow by a market listing cannot be sold to the shop
+	if userItem.IsListed {
+		tx.Rollback()
+		return nil, fmt.Errorf("item is listed on the market")
+	}
+
+	// Pledged collateral belongs to the loan until it is repaid or seized
+	if userItem.IsCollateral {
+		tx.Rollback()
+		return nil, fmt.Errorf("item is pledged as collateral")
+	}
+
+	// The sale price follows the item type's depreciation curve and the item's condition
+	salePrice := ResaleValue(&userItem, time.Now())
 	newBalance := user.Balance + salePrice
 
 	// Update user balance
@@ -258,10 +332,21 @@ func (s *ShopService) SellItem(userID uint, userItemID uint) (*SellItemResponse,
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
+	if err := events.Record(tx, events.ItemSold{
+		UserID: userID,
+		ItemID: userItem.ItemID,
+		Price:  salePrice,
+		Source: events.SourceShop,
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	flushEvents(s.db)
 
 	response := &SellItemResponse{
 		SalePrice:     salePrice,
@@ -290,24 +375,10 @@ func (s *ShopService) GetMyItems(userID uint) ([]UserItemResponse, error) {
 	}
 
 	// Convert to response format
+	now := time.Now()
 	response := make([]UserItemResponse, len(userItems))
 	for i, userItem := range userItems {
-		response[i] = UserItemResponse{
-			ID:          userItem.ID,
-			UserID:      userItem.UserID,
-			ItemID:      userItem.ItemID,
-			PurchasedAt: userItem.PurchasedAt.Format("2006-01-02T15:04:05Z07:00"),
-			IsEquipped:  userItem.IsEquipped,
-			Item: ItemResponse{
-				ID:          userItem.Item.ID,
-				Name:        userItem.Item.Name,
-				Type:        string(userItem.Item.Type),
-				Price:       userItem.Item.Price,
-				ImageURL:    userItem.Item.ImageURL,
-				Description: userItem.Item.Description,
-				CreatedAt:   userItem.Item.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			},
-		}
+		response[i] = newUserItemResponse(&userItem, now)
 	}
 
 	return response, nil
@@ -333,6 +404,16 @@ func (s *ShopService) EquipItem(userID uint, userItemID uint) (*UserItemResponse
 		return nil, fmt.Errorf("failed to get user item: %w", err)
 	}
 
+	if userItem.IsListed {
+		tx.Rollback()
+		return nil, fmt.Errorf("item is listed on the market")
+	}
+
+	if userItem.Durability <= 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("item is broken")
+	}
+
 	// Unequip all items of the same type for this user
 	// First, get all user items of the same type
 	var sameTypeUserItems []model.UserItem
@@ -367,22 +448,7 @@ func (s *ShopService) EquipItem(userID uint, userItemID uint) (*UserItemResponse
 		return nil, fmt.Errorf("failed to reload user item: %w", err)
 	}
 
-	response := &UserItemResponse{
-		ID:          userItem.ID,
-		UserID:      userItem.UserID,
-		ItemID:      userItem.ItemID,
-		PurchasedAt: userItem.PurchasedAt.Format("2006-01-02T15:04:05Z07:00"),
-		IsEquipped:  userItem.IsEquipped,
-		Item: ItemResponse{
-			ID:          userItem.Item.ID,
-			Name:        userItem.Item.Name,
-			Type:        string(userItem.Item.Type),
-			Price:       userItem.Item.Price,
-			ImageURL:    userItem.Item.ImageURL,
-			Description: userItem.Item.Description,
-			CreatedAt:   userItem.Item.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		},
-	}
+	response := newUserItemResponse(&userItem, time.Now())
 
-	return response, nil
+	return &response, nil
 }
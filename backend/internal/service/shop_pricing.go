@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// Demand pricing parameters
+const (
+	ShopDemandStep       = 0.02           // Each purchase raises the price by 2% of the base price
+	ShopDemandMaxPremium = 1.0            // Prices never exceed twice the base price
+	ShopDemandHalfLife   = 24 * time.Hour // Time for the premium to halve without purchases
+)
+
+// demandPremium returns the item's demand premium decayed to now
+func demandPremium(item *model.Item, now time.Time) float64 {
+	if item.DemandPremium <= 0 || item.DemandUpdatedAt == nil {
+		return 0
+	}
+	elapsed := now.Sub(*item.DemandUpdatedAt)
+	if elapsed <= 0 {
+		return item.DemandPremium
+	}
+	return item.DemandPremium * math.Pow(0.5, elapsed.Hours()/ShopDemandHalfLife.Hours())
+}
+
+// CurrentPrice returns what the shop charges for the item right now
+func CurrentPrice(item *model.Item, now time.Time) float64 {
+	return roundMoney(item.Price * (1 + demandPremium(item, now)))
+}
+
+// editionsLeft returns how many copies of a limited edition remain, or nil for regular items
+func editionsLeft(item *model.Item) *int {
+	if item.EditionSize == nil {
+		return nil
+	}
+	left := max(*item.EditionSize-item.EditionsSold, 0)
+	return &left
+}
+
+// takeShopUnit reserves one unit of the item for a purchase. The conditional
+// updates make sure two buyers cannot both take the last unit or the same serial number.
+func takeShopUnit(tx *gorm.DB, item *model.Item) (*int, error) {
+	if item.Stock != nil {
+		result := tx.Model(&model.Item{}).
+			Where("id = ? AND stock > 0", item.ID).
+			Update("stock", gorm.Expr("stock - 1"))
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to update stock: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil, fmt.Errorf("item out of stock")
+		}
+	}
+
+	if item.EditionSize == nil {
+		return nil, nil
+	}
+
+	result := tx.Model(&model.Item{}).
+		Where("id = ? AND editions_sold < edition_size", item.ID).
+		Update("editions_sold", gorm.Expr("editions_sold + 1"))
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update editions: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("limited edition sold out")
+	}
+
+	var serial int
+	if err := tx.Model(&model.Item{}).Where("id = ?", item.ID).Pluck("editions_sold", &serial).Error; err != nil {
+		return nil, fmt.Errorf("failed to assign serial number: %w", err)
+	}
+	return &serial, nil
+}
+
+// recordShopDemand raises the item's demand premium after a purchase at price
+func recordShopDemand(tx *gorm.DB, item *model.Item, price float64, now time.Time) error {
+	premium := math.Min(demandPremium(item, now)+ShopDemandStep, ShopDemandMaxPremium)
+	if err := tx.Model(&model.Item{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+		"demand_premium":    premium,
+		"demand_updated_at": now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update demand: %w", err)
+	}
+
+	if err := tx.Create(&model.ItemPricePoint{
+		ItemID:    item.ID,
+		Price:     price,
+		Source:    model.PriceSourceShop,
+		CreatedAt: now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record price: %w", err)
+	}
+	return nil
+}
+
+// RestockItems tops up every stocked item whose restock interval has passed
+func (s *ShopService) RestockItems() (int, error) {
+	var items []model.Item
+	if err := s.db.Where("stock IS NOT NULL AND edition_size IS NULL AND restock_quantity > 0 AND restock_interval_hours > 0").
+		Find(&items).Error; err != nil {
+		return 0, fmt.Errorf("failed to get stocked items: %w", err)
+	}
+
+	now := time.Now()
+	restocked := 0
+	for _, item := range items {
+		interval := time.Duration(item.RestockIntervalHours) * time.Hour
+		if item.LastRestockAt != nil && now.Sub(*item.LastRestockAt) < interval {
+			continue
+		}
+
+		stock := gorm.Expr("stock + ?", item.RestockQuantity)
+		if item.MaxStock > 0 {
+			// Never restock past the cap, but don't take away stock above it either
+			stock = gorm.Expr("MAX(stock, MIN(stock + ?, ?))", item.RestockQuantity, item.MaxStock)
+		}
+		if err := s.db.Model(&model.Item{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+			"stock":           stock,
+			"last_restock_at": now,
+		}).Error; err != nil {
+			return restocked, fmt.Errorf("failed to restock item %d: %w", item.ID, err)
+		}
+		restocked++
+	}
+
+	return restocked, nil
+}
+
+// ShopPriceHistoryResponse represents the shop price history of an item
+type ShopPriceHistoryResponse struct {
+	PriceHistoryResponse
+	BasePrice    float64 `json:"base_price"`
+	CurrentPrice float64 `json:"current_price"`
+}
+
+// GetPriceHistory returns recent shop purchase prices of an item and its current price
+func (s *ShopService) GetPriceHistory(itemID uint, limit int) (*ShopPriceHistoryResponse, error) {
+	var item model.Item
+	if err := s.db.First(&item, itemID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("item not found")
+		}
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	history, err := priceHistory(s.db, itemID, model.PriceSourceShop, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ShopPriceHistoryResponse{
+		PriceHistoryResponse: *history,
+		BasePrice:            item.Price,
+		CurrentPrice:         CurrentPrice(&item, time.Now()),
+	}, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrentPriceDecays(t *testing.T) {
+	now := time.Now()
+	updated := now.Add(-ShopDemandHalfLife)
+	item := &model.Item{Price: 100, DemandPremium: 0.5, DemandUpdatedAt: &updated}
+
+	assert.Equal(t, 125.0, CurrentPrice(item, now))
+	assert.Equal(t, 150.0, CurrentPrice(item, updated))
+	assert.Equal(t, 100.0, CurrentPrice(&model.Item{Price: 100}, now))
+}
+
+func TestShopServiceBuyItemRaisesPrice(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 1000)
+	item := createTestItem(t, db, "Sneakers", model.ItemTypeClothing, 100)
+	service := &ShopService{db: db}
+
+	first, err := service.BuyItem(user.ID, item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 900.0, first.NewBalance)
+
+	second, err := service.BuyItem(user.ID, item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 798.0, second.NewBalance)
+	assert.Equal(t, 102.0, second.UserItem.Item.Price)
+	assert.Equal(t, 100.0, second.UserItem.Item.BasePrice)
+
+	history, err := service.GetPriceHistory(item.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), history.Sales)
+	assert.Equal(t, 102.0, history.LastPrice)
+	assert.InDelta(t, 104.0, history.CurrentPrice, 0.01)
+
+	// Shop purchases don't show up in market history
+	market := &MarketService{db: db}
+	marketHistory, err := market.GetPriceHistory(item.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), marketHistory.Sales)
+}
+
+func TestShopServiceBuyItemStock(t *testing.T) {
+	db := setupTestDB(t)
+	alice := createTestUser(t, db, 1000)
+	bob := createTestUser(t, db, 1000)
+	item := createTestItem(t, db, "Vintage Jacket", model.ItemTypeClothing, 100)
+	require.NoError(t, db.Model(item).Update("stock", 1).Error)
+	service := &ShopService{db: db}
+
+	result, err := service.BuyItem(alice.ID, item.ID)
+	require.NoError(t, err)
+	require.NotNil(t, result.UserItem.Item.Stock)
+	assert.Equal(t, 0, *result.UserItem.Item.Stock)
+
+	_, err = service.BuyItem(bob.ID, item.ID)
+	assert.EqualError(t, err, "item out of stock")
+	assert.Equal(t, 1000.0, userBalance(t, db, bob.ID))
+}
+
+func TestShopServiceBuyItemLimitedEdition(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, 10000)
+	item := createTestItem(t, db, "Numbered Print", model.ItemTypeAccessories, 100)
+	require.NoError(t, db.Model(item).Update("edition_size", 2).Error)
+	service := &ShopService{db: db}
+
+	for serial := 1; serial <= 2; serial++ {
+		result, err := service.BuyItem(user.ID, item.ID)
+		require.NoError(t, err)
+		require.NotNil(t, result.UserItem.SerialNumber)
+		assert.Equal(t, serial, *result.UserItem.SerialNumber)
+		assert.Equal(t, 2-serial, *result.UserItem.Item.EditionsLeft)
+	}
+
+	_, err := service.BuyItem(user.ID, item.ID)
+	assert.EqualError(t, err, "limited edition sold out")
+
+	items, err := service.GetMyItems(user.ID)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.NotNil(t, items[0].SerialNumber)
+}
+
+func TestShopServiceRestockItems(t *testing.T) {
+	db := setupTestDB(t)
+	item := createTestItem(t, db, "Limited Cap", model.ItemTypeClothing, 30)
+	require.NoError(t, db.Model(item).Updates(map[string]interface{}{
+		"stock":                  0,
+		"max_stock":              3,
+		"restock_quantity":       5,
+		"restock_interval_hours": 1,
+	}).Error)
+	unlimited := createTestItem(t, db, "Plain Cap", model.ItemTypeClothing, 10)
+	service := &ShopService{db: db}
+
+	count, err := service.RestockItems()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var restocked model.Item
+	require.NoError(t, db.First(&restocked, item.ID).Error)
+	require.NotNil(t, restocked.Stock)
+	assert.Equal(t, 3, *restocked.Stock, "restock stops at max stock")
+
+	// Not due again until the interval has passed
+	count, err = service.RestockItems()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	var untouched model.Item
+	require.NoError(t, db.First(&untouched, unlimited.ID).Error)
+	assert.Nil(t, untouched.Stock)
+}
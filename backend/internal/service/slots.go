@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
 	"github.com/smoreg/freezino/backend/internal/game"
 	"github.com/smoreg/freezino/backend/internal/model"
 	"gorm.io/gorm"
@@ -65,8 +66,14 @@ func (s *SlotsService) Spin(req *SpinRequest) (*SpinResponse, error) {
 			return fmt.Errorf("insufficient balance: have %.2f, need %.2f", user.Balance, req.Bet)
 		}
 
+		// Check responsible-gaming limits
+		if err := AuthorizeBet(tx, req.UserID, req.Bet); err != nil {
+			return err
+		}
+
 		// Perform the spin
 		result := s.engine.Spin(req.Bet)
+		result.TotalWin = GamePayout(tx, req.UserID, model.GameTypeSlots, req.Bet, result.TotalWin)
 
 		// Calculate new balance
 		balanceChange := result.TotalWin - req.Bet
@@ -109,6 +116,14 @@ func (s *SlotsService) Spin(req *SpinRequest) (*SpinResponse, error) {
 		if err := tx.Create(&gameSession).Error; err != nil {
 			return fmt.Errorf("failed to create game session: %w", err)
 		}
+		if err := events.Record(tx, events.BetSettled{
+			UserID:   req.UserID,
+			GameType: model.GameTypeSlots,
+			Bet:      req.Bet,
+			Payout:   result.TotalWin,
+		}); err != nil {
+			return err
+		}
 
 		response = &SpinResponse{
 			Result:        result,
@@ -126,6 +141,7 @@ func (s *SlotsService) Spin(req *SpinRequest) (*SpinResponse, error) {
 		return nil, err
 	}
 
+	flushEvents(s.db)
 	return response, nil
 }
 
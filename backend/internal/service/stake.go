@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TakeStake locks the user and checks a stake against their balance and
+// gambling limits, counting it towards their gambling session. It is the first
+// step of a bet's transaction; the caller takes the stake off the balance.
+func TakeStake(tx *gorm.DB, userID uint, bet float64) (*model.User, error) {
+	var user model.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user_not_found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user.Balance < bet {
+		return nil, errors.New("insufficient_balance")
+	}
+
+	if err := AuthorizeBet(tx, userID, bet); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// PlaceOpenStake takes the stake for a game that settles later, such as a
+// blackjack hand played over a socket. The balance pays it at once and it
+// counts as lost towards the loss limits until the game settles and deletes
+// the open stake. It returns the user's new balance.
+func PlaceOpenStake(db *gorm.DB, userID uint, gameType model.GameType, bet float64) (*model.OpenStake, float64, error) {
+	stake := &model.OpenStake{UserID: userID, GameType: gameType, Amount: roundMoney(bet)}
+	var balance float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if balance, err = payStake(tx, userID, bet); err != nil {
+			return err
+		}
+		if err := tx.Create(stake).Error; err != nil {
+			return fmt.Errorf("failed to record open stake: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return stake, balance, nil
+}
+
+// RaiseOpenStake adds to the stake of a game still being played, as doubling
+// down does, and returns the user's new balance
+func RaiseOpenStake(db *gorm.DB, stake *model.OpenStake, amount float64) (float64, error) {
+	var balance float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if balance, err = payStake(tx, stake.UserID, amount); err != nil {
+			return err
+		}
+		if err := tx.Model(stake).Update("amount", gorm.Expr("amount + ?", roundMoney(amount))).Error; err != nil {
+			return fmt.Errorf("failed to raise open stake: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	stake.Amount = roundMoney(stake.Amount + amount)
+	return balance, nil
+}
+
+// payStake takes a checked stake off the user's balance and returns what is left
+func payStake(tx *gorm.DB, userID uint, amount float64) (float64, error) {
+	user, err := TakeStake(tx, userID, amount)
+	if err != nil {
+		return 0, err
+	}
+	balance := user.Balance - amount
+	if err := tx.Model(user).Update("balance", balance).Error; err != nil {
+		return 0, fmt.Errorf("failed to update balance: %w", err)
+	}
+	return balance, nil
+}
@@ -6,8 +6,16 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/smoreg/freezino/backend/internal/data"
+	"github.com/smoreg/freezino/backend/internal/model"
 )
 
+// defaultCountry is the country work-time figures use when a user hasn't chosen one
+const defaultCountry = "US"
+
 // Country represents a country with wage information
 type Country struct {
 	Code          string  `json:"code"`
@@ -32,6 +40,11 @@ type StatsService struct {
 	countries []Country
 }
 
+var (
+	defaultStats     *StatsService
+	defaultStatsOnce sync.Once
+)
+
 // NewStatsService creates a new stats service instance
 func NewStatsService() (*StatsService, error) {
 	service := &StatsService{}
@@ -41,7 +54,19 @@ func NewStatsService() (*StatsService, error) {
 	return service, nil
 }
 
-// loadCountries loads country data from JSON file
+// DefaultStatsService returns a stats service over the country data embedded in the binary
+func DefaultStatsService() *StatsService {
+	defaultStatsOnce.Do(func() {
+		service := &StatsService{}
+		if err := json.Unmarshal(data.CountriesJSON, &service.countries); err != nil {
+			panic(fmt.Sprintf("invalid built-in countries data: %v", err))
+		}
+		defaultStats = service
+	})
+	return defaultStats
+}
+
+// loadCountries loads country data from JSON file, falling back to the built-in copy
 func (s *StatsService) loadCountries() error {
 	// Get the path to countries.json file
 	dataPath := filepath.Join("backend", "internal", "data", "countries.json")
@@ -51,12 +76,12 @@ func (s *StatsService) loadCountries() error {
 		dataPath = filepath.Join("internal", "data", "countries.json")
 	}
 
-	data, err := os.ReadFile(dataPath)
+	raw, err := os.ReadFile(dataPath)
 	if err != nil {
-		return fmt.Errorf("failed to read countries data: %w", err)
+		raw = data.CountriesJSON
 	}
 
-	if err := json.Unmarshal(data, &s.countries); err != nil {
+	if err := json.Unmarshal(raw, &s.countries); err != nil {
 		return fmt.Errorf("failed to parse countries data: %w", err)
 	}
 
@@ -116,3 +141,36 @@ func (s *StatsService) GetCountryByCode(code string) (*CountryStatsResponse, err
 
 	return nil, fmt.Errorf("country not found")
 }
+
+// WorkTime is how long it takes to earn an amount at a country's average wage
+type WorkTime struct {
+	Country string  `json:"country"`
+	Amount  float64 `json:"amount"`
+	Hours   float64 `json:"hours"`
+	Days    float64 `json:"days"` // Eight-hour working days
+}
+
+// GetWorkTime converts an amount into hours and working days in a country
+func (s *StatsService) GetWorkTime(code string, amount float64) (*WorkTime, error) {
+	for _, country := range s.countries {
+		if country.Code == code {
+			hours := amount / country.AvgHourlyWage
+			return &WorkTime{
+				Country: country.Name,
+				Amount:  math.Round(amount*100) / 100,
+				Hours:   math.Round(hours*100) / 100,
+				Days:    math.Round(hours/8.0*100) / 100,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("country not found")
+}
+
+// userCountry returns the user's country code, defaulting to defaultCountry
+func userCountry(user *model.User) string {
+	if user.Country == "" {
+		return defaultCountry
+	}
+	return strings.ToUpper(user.Country)
+}
@@ -0,0 +1,497 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Two-factor authentication parameters
+const (
+	TwoFactorIssuer          = "Freezino"       // Shown next to the code in authenticator apps
+	TwoFactorChallengeExpiry = 5 * time.Minute  // Time to enter a code after the password
+	TwoFactorMaxAttempts     = 5                // Wrong codes before a challenge is void and the password is needed again
+	TwoFactorLockoutAttempts = 10               // Wrong login codes in a row, across challenges, before logins are locked
+	TwoFactorLockoutDuration = 15 * time.Minute // First lockout; every further wrong code doubles it
+	TwoFactorLockoutMax      = 24 * time.Hour
+	RecoveryCodeCount        = 10
+	twoFactorSkew            = 1 // Steps either side of now a code is accepted in, for clock drift
+	recoveryCodeAlphabet     = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLength       = 10
+	challengeTokenBytes      = 32
+)
+
+// TwoFactorService manages TOTP two-factor authentication for accounts that
+// sign in with a password, and the login challenges it adds
+type TwoFactorService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewTwoFactorService creates a new two-factor service instance
+func NewTwoFactorService() *TwoFactorService {
+	return &TwoFactorService{
+		db: database.GetDB(),
+	}
+}
+
+// clock returns the current time
+func (s *TwoFactorService) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// TwoFactorStatus describes a user's two-factor setup
+type TwoFactorStatus struct {
+	UserID                 uint       `json:"user_id"`
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	Pending                bool       `json:"pending"` // Enrolled, waiting for the first code
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorEnrollment is what an authenticator app needs to be set up
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// LoginChallenge is returned by a password login on an account with
+// two-factor authentication, to be exchanged with a code for session tokens
+type LoginChallenge struct {
+	Token     string    `json:"challenge_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TwoFactorOverview counts how many accounts use two-factor authentication
+type TwoFactorOverview struct {
+	PasswordAccounts int64   `json:"password_accounts"` // Accounts that can enable it
+	Enabled          int64   `json:"enabled"`
+	Pending          int64   `json:"pending"`
+	AdoptionRate     float64 `json:"adoption_rate"` // Enabled / PasswordAccounts
+}
+
+// findUser loads a user
+func (s *TwoFactorService) findUser(userID uint) (*model.User, error) {
+	var user model.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user_not_found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return &user, nil
+}
+
+// setting returns the user's two-factor row, or nil if they never enrolled
+func (s *TwoFactorService) setting(db *gorm.DB, userID uint) (*model.TwoFactor, error) {
+	var setting model.TwoFactor
+	err := db.Where("user_id = ?", userID).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	return &setting, nil
+}
+
+// enabled reports whether a user has two-factor authentication turned on
+func (s *TwoFactorService) enabled(db *gorm.DB, userID uint) (bool, error) {
+	var count int64
+	if err := db.Model(&model.TwoFactor{}).Where("user_id = ? AND enabled_at IS NOT NULL", userID).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetStatus returns the user's two-factor setup
+func (s *TwoFactorService) GetStatus(userID uint) (*TwoFactorStatus, error) {
+	if _, err := s.findUser(userID); err != nil {
+		return nil, err
+	}
+	setting, err := s.setting(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatus{UserID: userID}
+	if setting == nil {
+		return status, nil
+	}
+	status.Enabled = setting.EnabledAt != nil
+	status.EnabledAt = setting.EnabledAt
+	status.Pending = setting.EnabledAt == nil
+	if err := s.db.Model(&model.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&status.RecoveryCodesRemaining).Error; err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return status, nil
+}
+
+// Enroll gives the user a new TOTP secret to add to their authenticator app.
+// It only takes effect once Enable is called with a code from the app.
+func (s *TwoFactorService) Enroll(userID uint) (*TwoFactorEnrollment, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PasswordHash == "" {
+		return nil, errors.New("two_factor_requires_password") // Google accounts use Google's
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		setting, err := s.setting(tx, userID)
+		if err != nil {
+			return err
+		}
+		if setting == nil {
+			return tx.Create(&model.TwoFactor{UserID: userID, Secret: secret}).Error
+		}
+		if setting.EnabledAt != nil {
+			return errors.New("two_factor_already_enabled")
+		}
+		return tx.Model(setting).Update("secret", secret).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	account := user.Username
+	if account == "" {
+		account = user.Email
+	}
+	return &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.URI(secret, TwoFactorIssuer, account),
+	}, nil
+}
+
+// Enable turns two-factor authentication on with a first code from the
+// authenticator app, returning recovery codes that are never shown again.
+// Every other session is signed out, as it was signed in without a code.
+func (s *TwoFactorService) Enable(userID, sessionID uint, code string) ([]string, error) {
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		setting, err := s.setting(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+		if setting == nil {
+			return errors.New("two_factor_not_enrolled")
+		}
+		if setting.EnabledAt != nil {
+			return errors.New("two_factor_already_enabled")
+		}
+		if err := s.checkTOTP(tx, setting, code); err != nil {
+			return err
+		}
+
+		if err := tx.Model(setting).Update("enabled_at", s.clock()).Error; err != nil {
+			return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+		}
+		if err := tx.Model(&model.AuthSession{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, sessionID).
+			Updates(map[string]interface{}{"revoked_at": s.clock(), "revoked_reason": SessionRevokedTwoFactor}).Error; err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off, given the account password and
+// a code from the authenticator app or a recovery code
+func (s *TwoFactorService) Disable(userID uint, password, code string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return errors.New("invalid_password")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		setting, err := s.setting(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+		if setting == nil || setting.EnabledAt == nil {
+			return errors.New("two_factor_not_enabled")
+		}
+		if err := s.checkCode(tx, setting, code); err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		if err := tx.Delete(setting).Error; err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+		return nil
+	})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, given a code
+// from the authenticator app
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		setting, err := s.setting(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+		if setting == nil || setting.EnabledAt == nil {
+			return errors.New("two_factor_not_enabled")
+		}
+		if err := s.checkTOTP(tx, setting, code); err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns a random code, like "k7m2q-x9tbn"
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	for i := range b {
+		b[i] = recoveryCodeAlphabet[int(b[i])%len(recoveryCodeAlphabet)]
+	}
+	return string(b[:recoveryCodeLength/2]) + "-" + string(b[recoveryCodeLength/2:]), nil
+}
+
+// normalizeRecoveryCode strips the formatting users may have typed around a code
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores new ones
+func (s *TwoFactorService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	rows := make([]model.RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		rows[i] = model.RecoveryCode{UserID: userID, CodeHash: hashToken(normalizeRecoveryCode(code)), CreatedAt: s.clock()}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// checkTOTP accepts a code from the authenticator app once
+func (s *TwoFactorService) checkTOTP(tx *gorm.DB, setting *model.TwoFactor, code string) error {
+	step, ok := totp.Validate(setting.Secret, strings.ReplaceAll(code, " ", ""), s.clock(), twoFactorSkew)
+	if !ok || step <= setting.LastUsedStep {
+		return errors.New("invalid_code")
+	}
+	result := tx.Model(setting).Where("last_used_step < ?", step).Update("last_used_step", step)
+	if result.Error != nil {
+		return fmt.Errorf("failed to use code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid_code") // Used by a concurrent request
+	}
+	return nil
+}
+
+// checkCode accepts a code from the authenticator app or an unused recovery code
+func (s *TwoFactorService) checkCode(tx *gorm.DB, setting *model.TwoFactor, code string) error {
+	if len(strings.ReplaceAll(code, " ", "")) == totp.Digits {
+		return s.checkTOTP(tx, setting, code)
+	}
+
+	result := tx.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", setting.UserID, hashToken(normalizeRecoveryCode(code))).
+		Limit(1).Update("used_at", s.clock())
+	if result.Error != nil {
+		return fmt.Errorf("failed to use recovery code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid_code")
+	}
+	return nil
+}
+
+// startChallenge begins the second step of a login for a user with
+// two-factor authentication
+func (s *TwoFactorService) startChallenge(userID uint) (*LoginChallenge, error) {
+	b := make([]byte, challengeTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := s.clock()
+	challenge := &model.TwoFactorChallenge{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(TwoFactorChallengeExpiry),
+	}
+	if err := s.db.Create(challenge).Error; err != nil {
+		return nil, fmt.Errorf("failed to create challenge: %w", err)
+	}
+	return &LoginChallenge{Token: token, ExpiresAt: challenge.ExpiresAt}, nil
+}
+
+// twoFactorLockout returns how long logins are locked after failed wrong
+// codes in a row, or 0 if they are not
+func twoFactorLockout(failed int) time.Duration {
+	if failed < TwoFactorLockoutAttempts {
+		return 0
+	}
+	lockout := TwoFactorLockoutDuration
+	for i := TwoFactorLockoutAttempts; i < failed && lockout < TwoFactorLockoutMax; i++ {
+		lockout *= 2
+	}
+	return min(lockout, TwoFactorLockoutMax)
+}
+
+// CompleteChallenge finishes a login with a code from the authenticator app
+// or a recovery code, returning the user to issue session tokens for. Wrong
+// codes count against the challenge, which is void after TwoFactorMaxAttempts,
+// and against the account, whose logins are locked after
+// TwoFactorLockoutAttempts in a row however many challenges they are spread over.
+func (s *TwoFactorService) CompleteChallenge(token, code string) (*model.User, error) {
+	now := s.clock()
+	var user *model.User
+	var codeErr error
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var challenge model.TwoFactorChallenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").
+			Where("token_hash = ?", hashToken(token)).First(&challenge).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invalid_challenge")
+			}
+			return fmt.Errorf("failed to get challenge: %w", err)
+		}
+		if challenge.UsedAt != nil || challenge.User.ID == 0 {
+			return errors.New("invalid_challenge")
+		}
+		if !now.Before(challenge.ExpiresAt) {
+			return errors.New("challenge_expired")
+		}
+		if challenge.Attempts >= TwoFactorMaxAttempts {
+			return errors.New("too_many_attempts")
+		}
+
+		// No code is needed if two-factor was turned off since the password was entered
+		setting, err := s.setting(tx.Clauses(clause.Locking{Strength: "UPDATE"}), challenge.UserID)
+		if err != nil {
+			return err
+		}
+		if setting != nil && setting.EnabledAt != nil {
+			if setting.LockedUntil != nil && now.Before(*setting.LockedUntil) {
+				return errors.New("two_factor_locked")
+			}
+			if err := s.checkCode(tx, setting, code); err != nil {
+				if err.Error() != "invalid_code" {
+					return err
+				}
+				// Commit the wrong attempt, and report it once the transaction is done
+				codeErr = err
+				failed := setting.FailedAttempts + 1
+				updates := map[string]interface{}{"failed_attempts": failed}
+				if lockout := twoFactorLockout(failed); lockout > 0 {
+					updates["locked_until"] = now.Add(lockout)
+				}
+				if err := tx.Model(setting).Updates(updates).Error; err != nil {
+					return fmt.Errorf("failed to count wrong code: %w", err)
+				}
+				return tx.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1")).Error
+			}
+			if setting.FailedAttempts > 0 {
+				if err := tx.Model(setting).Updates(map[string]interface{}{"failed_attempts": 0, "locked_until": nil}).Error; err != nil {
+					return fmt.Errorf("failed to reset wrong codes: %w", err)
+				}
+			}
+		}
+		if err := tx.Model(&challenge).Update("used_at", now).Error; err != nil {
+			return fmt.Errorf("failed to complete challenge: %w", err)
+		}
+		user = &challenge.User
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if codeErr != nil {
+		return nil, codeErr
+	}
+	return user, nil
+}
+
+// PruneChallenges deletes login challenges that expired more than
+// ChallengeRetention ago, returning how many
+func (s *TwoFactorService) PruneChallenges() (int64, error) {
+	result := s.db.Where("expires_at < ?", s.clock().Add(-ChallengeRetention).Local()).Delete(&model.TwoFactorChallenge{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune challenges: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetOverview counts how many password accounts have two-factor
+// authentication, for admins watching adoption
+func (s *TwoFactorService) GetOverview() (*TwoFactorOverview, error) {
+	overview := &TwoFactorOverview{}
+	if err := s.db.Model(&model.User{}).Where("password_hash <> ''").
+		Count(&overview.PasswordAccounts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count accounts: %w", err)
+	}
+	settings := s.db.Model(&model.TwoFactor{}).
+		Joins("JOIN users ON users.id = two_factor_settings.user_id AND users.deleted_at IS NULL")
+	if err := settings.Session(&gorm.Session{}).Where("enabled_at IS NOT NULL").
+		Count(&overview.Enabled).Error; err != nil {
+		return nil, fmt.Errorf("failed to count two-factor settings: %w", err)
+	}
+	if err := settings.Session(&gorm.Session{}).Where("enabled_at IS NULL").
+		Count(&overview.Pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to count two-factor settings: %w", err)
+	}
+	if overview.PasswordAccounts > 0 {
+		overview.AdoptionRate = float64(overview.Enabled) / float64(overview.PasswordAccounts)
+	}
+	return overview, nil
+}
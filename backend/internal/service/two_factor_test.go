@@ -0,0 +1,282 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/model"
+	"github.com/smoreg/freezino/backend/internal/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// createPasswordUser creates a test user who signs in with a password
+func createPasswordUser(t *testing.T, db *gorm.DB, password string) *model.User {
+	user := createTestUser(t, db, 1000)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(user).Updates(map[string]interface{}{"password_hash": string(hash), "google_id": nil}).Error)
+	return user
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	db := setupTestDB(t)
+	user := createPasswordUser(t, db, "hunter22")
+
+	now := time.Now()
+	service := &TwoFactorService{db: db, now: func() time.Time { return now }}
+
+	// Google accounts can't enroll
+	googleUser := createTestUser(t, db, 1000)
+	_, err := service.Enroll(googleUser.ID)
+	assert.EqualError(t, err, "two_factor_requires_password")
+
+	_, err = service.Enable(user.ID, 0, "123456")
+	assert.EqualError(t, err, "two_factor_not_enrolled")
+
+	enrollment, err := service.Enroll(user.ID)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/Freezino:")
+	assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+
+	status, err := service.GetStatus(user.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Pending)
+	assert.False(t, status.Enabled)
+
+	// Enabling needs a current code from the app
+	_, err = service.Enable(user.ID, 0, "000000")
+	assert.EqualError(t, err, "invalid_code")
+	code, err := totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	codes, err := service.Enable(user.ID, 0, code)
+	require.NoError(t, err)
+	assert.Len(t, codes, RecoveryCodeCount)
+
+	status, err = service.GetStatus(user.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.False(t, status.Pending)
+	assert.Equal(t, int64(RecoveryCodeCount), status.RecoveryCodesRemaining)
+
+	_, err = service.Enroll(user.ID)
+	assert.EqualError(t, err, "two_factor_already_enabled")
+
+	// A code is only accepted once
+	_, err = service.RegenerateRecoveryCodes(user.ID, code)
+	assert.EqualError(t, err, "invalid_code")
+	now = now.Add(totp.Period)
+	code, err = totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	regenerated, err := service.RegenerateRecoveryCodes(user.ID, code)
+	require.NoError(t, err)
+	assert.NotEqual(t, codes, regenerated)
+
+	// Recovery codes are only stored hashed
+	var stored model.RecoveryCode
+	require.NoError(t, db.Where("user_id = ?", user.ID).First(&stored).Error)
+	assert.NotContains(t, regenerated, stored.CodeHash)
+	assert.Len(t, stored.CodeHash, 64)
+
+	// Turning it off takes the password as well as a code
+	now = now.Add(totp.Period)
+	err = service.Disable(user.ID, "wrong", regenerated[0])
+	assert.EqualError(t, err, "invalid_password")
+	err = service.Disable(user.ID, "hunter22", codes[0])
+	assert.EqualError(t, err, "invalid_code")
+	require.NoError(t, service.Disable(user.ID, "hunter22", regenerated[0]))
+
+	status, err = service.GetStatus(user.ID)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+	assert.False(t, status.Pending)
+	assert.Zero(t, status.RecoveryCodesRemaining)
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	db := setupTestDB(t)
+	user := createPasswordUser(t, db, "hunter22")
+	auth := &AuthService{db: db}
+
+	// Without two-factor the password is enough
+	loggedIn, challenge, err := auth.Login(LoginRequest{Username: user.Username, Password: "hunter22"})
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	assert.Nil(t, challenge)
+
+	now := time.Now()
+	service := &TwoFactorService{db: db, now: func() time.Time { return now }}
+	enrollment, err := service.Enroll(user.ID)
+	require.NoError(t, err)
+	code, err := totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	recoveryCodes, err := service.Enable(user.ID, 0, code)
+	require.NoError(t, err)
+
+	// A wrong password still fails before any challenge
+	_, _, err = auth.Login(LoginRequest{Username: user.Username, Password: "wrong"})
+	assert.EqualError(t, err, "invalid username or password")
+
+	_, challenge, err = auth.Login(LoginRequest{Username: user.Username, Password: "hunter22"})
+	require.NoError(t, err)
+	require.NotNil(t, challenge)
+	assert.NotEmpty(t, challenge.Token)
+
+	_, err = service.CompleteChallenge("not-a-challenge", code)
+	assert.EqualError(t, err, "invalid_challenge")
+
+	// The code used to enable two-factor can't be replayed
+	_, err = service.CompleteChallenge(challenge.Token, code)
+	assert.EqualError(t, err, "invalid_code")
+
+	now = now.Add(totp.Period)
+	code, err = totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	completed, err := service.CompleteChallenge(challenge.Token, code)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, completed.ID)
+
+	// A challenge is good for one login
+	_, err = service.CompleteChallenge(challenge.Token, code)
+	assert.EqualError(t, err, "invalid_challenge")
+
+	// Recovery codes work once each, however they are typed
+	_, challenge, err = auth.Login(LoginRequest{Username: user.Username, Password: "hunter22"})
+	require.NoError(t, err)
+	_, err = service.CompleteChallenge(challenge.Token, " "+recoveryCodes[0][:5]+" "+recoveryCodes[0][6:])
+	require.NoError(t, err)
+	_, challenge, err = auth.Login(LoginRequest{Username: user.Username, Password: "hunter22"})
+	require.NoError(t, err)
+	_, err = service.CompleteChallenge(challenge.Token, recoveryCodes[0])
+	assert.EqualError(t, err, "invalid_code")
+
+	status, err := service.GetStatus(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(RecoveryCodeCount-1), status.RecoveryCodesRemaining)
+
+	// Wrong codes use up the challenge, then the password is needed again
+	for i := 1; i < TwoFactorMaxAttempts; i++ {
+		_, err = service.CompleteChallenge(challenge.Token, "000000")
+		assert.EqualError(t, err, "invalid_code")
+	}
+	now = now.Add(totp.Period)
+	code, err = totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	_, err = service.CompleteChallenge(challenge.Token, code)
+	assert.EqualError(t, err, "too_many_attempts")
+
+	// Challenges expire, and are pruned a day later
+	_, challenge, err = auth.Login(LoginRequest{Username: user.Username, Password: "hunter22"})
+	require.NoError(t, err)
+	now = now.Add(TwoFactorChallengeExpiry)
+	code, err = totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	_, err = service.CompleteChallenge(challenge.Token, code)
+	assert.EqualError(t, err, "challenge_expired")
+
+	now = now.Add(ChallengeRetention + time.Minute)
+	pruned, err := service.PruneChallenges()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), pruned)
+}
+
+func TestTwoFactorEnableSignsOutOtherSessions(t *testing.T) {
+	db := setupTestDB(t)
+	user := createPasswordUser(t, db, "hunter22")
+	now := time.Now()
+	service := &TwoFactorService{db: db, now: func() time.Time { return now }}
+
+	current := model.AuthSession{UserID: user.ID, ExpiresAt: now.Add(time.Hour)}
+	other := model.AuthSession{UserID: user.ID, ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, db.Create(&current).Error)
+	require.NoError(t, db.Create(&other).Error)
+
+	enrollment, err := service.Enroll(user.ID)
+	require.NoError(t, err)
+	code, err := totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	_, err = service.Enable(user.ID, current.ID, code)
+	require.NoError(t, err)
+
+	require.NoError(t, db.First(&current, current.ID).Error)
+	require.NoError(t, db.First(&other, other.ID).Error)
+	assert.Nil(t, current.RevokedAt)
+	require.NotNil(t, other.RevokedAt)
+	assert.Equal(t, SessionRevokedTwoFactor, other.RevokedReason)
+}
+
+func TestTwoFactorLockout(t *testing.T) {
+	db := setupTestDB(t)
+	user := createPasswordUser(t, db, "hunter22")
+	now := time.Now()
+	service := &TwoFactorService{db: db, now: func() time.Time { return now }}
+
+	enrollment, err := service.Enroll(user.ID)
+	require.NoError(t, err)
+	code, err := totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	_, err = service.Enable(user.ID, 0, code)
+	require.NoError(t, err)
+
+	// Wrong codes add up across challenges, so a fresh password login doesn't
+	// give a fresh set of guesses
+	guess := func(code string) error {
+		challenge, err := service.startChallenge(user.ID)
+		require.NoError(t, err)
+		_, err = service.CompleteChallenge(challenge.Token, code)
+		return err
+	}
+	for i := 0; i < TwoFactorLockoutAttempts; i++ {
+		assert.EqualError(t, guess("000000"), "invalid_code")
+	}
+	nextCode := func() string {
+		now = now.Add(totp.Period)
+		code, err := totp.Code(enrollment.Secret, totp.Step(now))
+		require.NoError(t, err)
+		return code
+	}
+	assert.EqualError(t, guess(nextCode()), "two_factor_locked")
+
+	// The lockout ends, and doubles with the next wrong code
+	now = now.Add(TwoFactorLockoutDuration)
+	assert.EqualError(t, guess("000000"), "invalid_code")
+	now = now.Add(TwoFactorLockoutDuration)
+	assert.EqualError(t, guess(nextCode()), "two_factor_locked")
+	now = now.Add(TwoFactorLockoutDuration)
+	require.NoError(t, guess(nextCode()))
+
+	// A right code resets the count
+	assert.EqualError(t, guess("000000"), "invalid_code")
+	require.NoError(t, guess(nextCode()))
+
+	assert.Equal(t, TwoFactorLockoutMax, twoFactorLockout(TwoFactorLockoutAttempts+20))
+}
+
+func TestTwoFactorOverview(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Now()
+	service := &TwoFactorService{db: db, now: func() time.Time { return now }}
+
+	enabled := createPasswordUser(t, db, "hunter22")
+	pending := createPasswordUser(t, db, "hunter22")
+	createPasswordUser(t, db, "hunter22")
+	createTestUser(t, db, 1000) // Google accounts don't count
+
+	enrollment, err := service.Enroll(enabled.ID)
+	require.NoError(t, err)
+	code, err := totp.Code(enrollment.Secret, totp.Step(now))
+	require.NoError(t, err)
+	_, err = service.Enable(enabled.ID, 0, code)
+	require.NoError(t, err)
+	_, err = service.Enroll(pending.ID)
+	require.NoError(t, err)
+
+	overview, err := service.GetOverview()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), overview.PasswordAccounts)
+	assert.Equal(t, int64(1), overview.Enabled)
+	assert.Equal(t, int64(1), overview.Pending)
+	assert.InDelta(t, 1.0/3, overview.AdoptionRate, 1e-9)
+}
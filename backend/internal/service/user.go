@@ -3,6 +3,8 @@ package service
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/smoreg/freezino/backend/internal/database"
 	"github.com/smoreg/freezino/backend/internal/model"
@@ -29,14 +31,22 @@ type ProfileResponse struct {
 	Name      string  `json:"name"`
 	Avatar    string  `json:"avatar"`
 	Balance   float64 `json:"balance"`
+	Timezone  string  `json:"timezone"`
+	Country   string  `json:"country"`
 	CreatedAt string  `json:"created_at"`
 	UpdatedAt string  `json:"updated_at"`
+
+	LeaderboardVisibility model.LeaderboardVisibility `json:"leaderboard_visibility"`
 }
 
 // UpdateProfileRequest represents profile update request
 type UpdateProfileRequest struct {
-	Name   *string `json:"name,omitempty"`
-	Avatar *string `json:"avatar,omitempty"`
+	Name     *string `json:"name,omitempty"`
+	Avatar   *string `json:"avatar,omitempty"`
+	Timezone *string `json:"timezone,omitempty"` // IANA name such as "Europe/Berlin"
+	Country  *string `json:"country,omitempty"`  // Code from GET /api/stats/countries such as "DE"
+
+	LeaderboardVisibility *model.LeaderboardVisibility `json:"leaderboard_visibility,omitempty"` // public, anonymous or hidden
 }
 
 // BalanceResponse represents user balance data
@@ -60,6 +70,14 @@ type StatsResponse struct {
 	BiggestLoss       float64 `json:"biggest_loss"`
 }
 
+// leaderboardVisibility returns the user's leaderboard visibility, defaulting to public
+func leaderboardVisibility(user *model.User) model.LeaderboardVisibility {
+	if user.LeaderboardVisibility == "" {
+		return model.LeaderboardVisibilityPublic
+	}
+	return user.LeaderboardVisibility
+}
+
 // GetProfile retrieves user profile by ID
 func (s *UserService) GetProfile(userID uint) (*ProfileResponse, error) {
 	var user model.User
@@ -83,8 +101,12 @@ func (s *UserService) GetProfile(userID uint) (*ProfileResponse, error) {
 		Name:      user.Name,
 		Avatar:    user.Avatar,
 		Balance:   user.Balance,
+		Timezone:  userTimezone(&user).String(),
+		Country:   userCountry(&user),
 		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+
+		LeaderboardVisibility: leaderboardVisibility(&user),
 	}, nil
 }
 
@@ -107,9 +129,39 @@ func (s *UserService) UpdateProfile(userID uint, req UpdateProfileRequest) (*Pro
 	if req.Avatar != nil {
 		updates["avatar"] = *req.Avatar
 	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" {
+			return nil, fmt.Errorf("invalid timezone")
+		}
+		updates["timezone"] = *req.Timezone
+	}
+	if req.Country != nil {
+		country := strings.ToUpper(*req.Country)
+		if _, err := DefaultStatsService().GetCountryByCode(country); err != nil {
+			return nil, fmt.Errorf("invalid country")
+		}
+		updates["country"] = country
+	}
+	if req.LeaderboardVisibility != nil {
+		switch *req.LeaderboardVisibility {
+		case model.LeaderboardVisibilityPublic, model.LeaderboardVisibilityAnonymous, model.LeaderboardVisibilityHidden:
+		default:
+			return nil, fmt.Errorf("invalid leaderboard visibility")
+		}
+		updates["leaderboard_visibility"] = *req.LeaderboardVisibility
+	}
 
 	if len(updates) > 0 {
-		if err := s.db.Model(&user).Updates(updates).Error; err != nil {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return err
+			}
+			if req.LeaderboardVisibility != nil {
+				return setLeaderboardVisibility(tx, userID, *req.LeaderboardVisibility)
+			}
+			return nil
+		})
+		if err != nil {
 			return nil, fmt.Errorf("failed to update user profile: %w", err)
 		}
 	}
@@ -131,8 +183,12 @@ func (s *UserService) UpdateProfile(userID uint, req UpdateProfileRequest) (*Pro
 		Name:      user.Name,
 		Avatar:    user.Avatar,
 		Balance:   user.Balance,
+		Timezone:  userTimezone(&user).String(),
+		Country:   userCountry(&user),
 		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+
+		LeaderboardVisibility: leaderboardVisibility(&user),
 	}, nil
 }
 
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
+	"github.com/smoreg/freezino/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// UserStatusService manages timed status effects (jail, popular streamer, ...)
+type UserStatusService struct {
+	db *gorm.DB
+}
+
+// NewUserStatusService creates a new user status service instance
+func NewUserStatusService() *UserStatusService {
+	return &UserStatusService{
+		db: database.GetDB(),
+	}
+}
+
+// ExpireStatuses deletes statuses whose expiry time has passed
+func (s *UserStatusService) ExpireStatuses() (int64, error) {
+	var expired []model.UserStatus
+	if err := s.db.Where("expires_at < ?", time.Now()).Find(&expired).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired statuses: %w", err)
+	}
+
+	var count int64
+	for _, status := range expired {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Delete(&model.UserStatus{}, status.ID)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			count++
+			return events.Record(tx, events.StatusChanged{
+				UserID: status.UserID,
+				Status: status.Status,
+			})
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to expire statuses: %w", err)
+		}
+	}
+
+	flushEvents(s.db)
+	return count, nil
+}
+
+// grantStatus gives a user a timed status as part of tx
+func grantStatus(tx *gorm.DB, userID uint, name string, duration time.Duration) error {
+	status := model.UserStatus{
+		UserID:    userID,
+		Status:    name,
+		ExpiresAt: time.Now().Add(duration),
+	}
+	if err := tx.Create(&status).Error; err != nil {
+		return fmt.Errorf("failed to create %s status: %w", name, err)
+	}
+
+	return events.Record(tx, events.StatusChanged{
+		UserID:    userID,
+		Status:    name,
+		Active:    true,
+		ExpiresAt: &status.ExpiresAt,
+	})
+}
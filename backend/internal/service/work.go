@@ -3,20 +3,23 @@ package service
 import (
 	"errors"
 	"fmt"
+	"log"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/smoreg/freezino/backend/internal/database"
+	"github.com/smoreg/freezino/backend/internal/events"
 	"github.com/smoreg/freezino/backend/internal/model"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 const (
-	// WORK_DURATION is the required work duration in seconds (3 minutes)
+	// WORK_DURATION is the default work duration in seconds (3 minutes)
 	WORK_DURATION = 180
 
-	// WORK_REWARD is the amount earned for completing work
+	// WORK_REWARD is the default amount shown when no job is selected
 	WORK_REWARD = 500.0
 )
 
@@ -32,14 +35,64 @@ type WorkService struct {
 	db             *gorm.DB
 	activeSessions map[uint]ActiveWorkSession
 	mu             sync.RWMutex
+	catalog        *JobCatalog
+	ladder         *CareerLadder
 }
 
 // NewWorkService creates a new work service instance
 func NewWorkService() *WorkService {
+	catalog, err := LoadJobCatalog()
+	if err != nil {
+		log.Printf("Warning: failed to load job catalogue, using built-in jobs: %v", err)
+		catalog = DefaultJobCatalog()
+	}
+
+	ladder, err := LoadCareerLadder()
+	if err != nil {
+		log.Printf("Warning: failed to load career ladder, using built-in levels: %v", err)
+		ladder = DefaultCareerLadder()
+	}
+
 	return &WorkService{
 		db:             database.GetDB(),
 		activeSessions: make(map[uint]ActiveWorkSession),
+		catalog:        catalog,
+		ladder:         ladder,
+	}
+}
+
+// jobs returns the job catalogue used by this service
+func (s *WorkService) jobs() *JobCatalog {
+	if s.catalog == nil {
+		return DefaultJobCatalog()
+	}
+	return s.catalog
+}
+
+// careers returns the career ladder used by this service
+func (s *WorkService) careers() *CareerLadder {
+	if s.ladder == nil {
+		return DefaultCareerLadder()
+	}
+	return s.ladder
+}
+
+// Jobs returns the job catalogue, e.g. for building a CareerService
+func (s *WorkService) Jobs() *JobCatalog {
+	return s.jobs()
+}
+
+// jobDuration returns the configured duration of a job type in seconds
+func (s *WorkService) jobDuration(jobType model.JobType) int {
+	if job, ok := s.jobs().Get(jobType); ok {
+		return job.DurationSeconds
 	}
+	return WORK_DURATION
+}
+
+// GetAvailableJobs returns the job listings generated from the catalogue
+func (s *WorkService) GetAvailableJobs() []JobListing {
+	return s.jobs().Listings()
 }
 
 // StartWorkResponse represents the response for starting work
@@ -67,18 +120,19 @@ type WorkStatusResponse struct {
 
 // CompleteWorkResponse represents the response for completing work
 type CompleteWorkResponse struct {
-	UserID         uint      `json:"user_id"`
-	Earned         float64   `json:"earned"`
-	BaseReward     float64   `json:"base_reward"`
-	NewBalance     float64   `json:"new_balance"`
-	DurationSec    int       `json:"duration_seconds"`
-	CompletedAt    time.Time `json:"completed_at"`
-	TransactionID  uint      `json:"transaction_id"`
-	WorkSessionID  uint      `json:"work_session_id"`
-	HasClothing    bool      `json:"has_clothing"`
-	HasCar         bool      `json:"has_car"`
-	ClothingBonus  float64   `json:"clothing_bonus"`  // 0 or -250
-	CarBonus       float64   `json:"car_bonus"`       // 0 or +250
+	UserID        uint            `json:"user_id"`
+	Earned        float64         `json:"earned"`
+	BaseReward    float64         `json:"base_reward"`
+	NewBalance    float64         `json:"new_balance"`
+	DurationSec   int             `json:"duration_seconds"`
+	CompletedAt   time.Time       `json:"completed_at"`
+	TransactionID uint            `json:"transaction_id"`
+	WorkSessionID uint            `json:"work_session_id"`
+	HasClothing   bool            `json:"has_clothing"`
+	HasCar        bool            `json:"has_car"`
+	ClothingBonus float64         `json:"clothing_bonus"` // 0 or -250
+	CarBonus      float64         `json:"car_bonus"`      // 0 or +250
+	Career        *CareerProgress `json:"career,omitempty"`
 }
 
 // WorkHistoryItem represents a work session in history
@@ -108,8 +162,16 @@ func (s *WorkService) StartWork(userID uint, jobType model.JobType) (*StartWorkR
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
-	// Check specific job requirements
-	if err := s.checkJobRequirements(userID, jobType); err != nil {
+	job, ok := s.jobs().Get(jobType)
+	if !ok {
+		return nil, fmt.Errorf("unknown job type")
+	}
+
+	// Check career unlocks and specific job requirements
+	if err := job.checkUnlock(s.db, userID); err != nil {
+		return nil, err
+	}
+	if err := job.checkRequirements(s.db, userID); err != nil {
 		return nil, err
 	}
 
@@ -132,9 +194,9 @@ func (s *WorkService) StartWork(userID uint, jobType model.JobType) (*StartWorkR
 	return &StartWorkResponse{
 		UserID:      userID,
 		StartedAt:   now,
-		DurationSec: WORK_DURATION,
-		Reward:      WORK_REWARD,
-		CompletesAt: now.Add(time.Duration(WORK_DURATION) * time.Second),
+		DurationSec: job.DurationSeconds,
+		Reward:      job.BasePay,
+		CompletesAt: now.Add(time.Duration(job.DurationSeconds) * time.Second),
 	}, nil
 }
 
@@ -164,31 +226,37 @@ func (s *WorkService) GetStatus(userID uint) (*WorkStatusResponse, error) {
 	}
 
 	// Calculate progress
+	duration := s.jobDuration(session.JobType)
+	reward := WORK_REWARD
+	if job, ok := s.jobs().Get(session.JobType); ok {
+		reward = job.BasePay
+	}
+
 	now := time.Now()
 	elapsed := int(now.Sub(session.StartedAt).Seconds())
-	remaining := WORK_DURATION - elapsed
+	remaining := duration - elapsed
 	if remaining < 0 {
 		remaining = 0
 	}
 
-	progress := float64(elapsed) / float64(WORK_DURATION)
+	progress := float64(elapsed) / float64(duration)
 	if progress > 1.0 {
 		progress = 1.0
 	}
 
-	canComplete := elapsed >= WORK_DURATION
-	completesAt := session.StartedAt.Add(time.Duration(WORK_DURATION) * time.Second)
+	canComplete := elapsed >= duration
+	completesAt := session.StartedAt.Add(time.Duration(duration) * time.Second)
 
 	return &WorkStatusResponse{
 		IsWorking:    true,
 		UserID:       userID,
 		StartedAt:    &session.StartedAt,
-		DurationSec:  WORK_DURATION,
+		DurationSec:  duration,
 		ElapsedSec:   elapsed,
 		RemainingSec: remaining,
 		Progress:     progress,
 		CanComplete:  canComplete,
-		Reward:       WORK_REWARD,
+		Reward:       reward,
 		CompletesAt:  &completesAt,
 	}, nil
 }
@@ -203,17 +271,23 @@ func (s *WorkService) CompleteWork(userID uint) (*CompleteWorkResponse, error) {
 		return nil, fmt.Errorf("no active work session")
 	}
 
+	jobType := session.JobType
+	job, ok := s.jobs().Get(jobType)
+	if !ok {
+		delete(s.activeSessions, userID)
+		s.mu.Unlock()
+		return nil, fmt.Errorf("unknown job type")
+	}
+
 	// Check if enough time has passed
 	now := time.Now()
 	elapsed := int(now.Sub(session.StartedAt).Seconds())
-	if elapsed < WORK_DURATION {
+	if elapsed < job.DurationSeconds {
 		s.mu.Unlock()
-		remaining := WORK_DURATION - elapsed
+		remaining := job.DurationSeconds - elapsed
 		return nil, fmt.Errorf("work not completed yet, %d seconds remaining", remaining)
 	}
 
-	jobType := session.JobType
-
 	// Remove from active sessions
 	delete(s.activeSessions, userID)
 	s.mu.Unlock()
@@ -230,11 +304,29 @@ func (s *WorkService) CompleteWork(userID uint) (*CompleteWorkResponse, error) {
 			return fmt.Errorf("failed to find user: %w", err)
 		}
 
-		// Apply job-specific effects and get earnings
-		earnedAmount, description, err := s.applyJobEffects(tx, userID, jobType)
+		// Current career level sets the pay multiplier for this shift
+		ladder := s.careers()
+		career, err := loadCareer(tx, ladder, userID, job)
 		if err != nil {
 			return err
 		}
+		statuses, err := activeStatuses(tx, userID)
+		if err != nil {
+			return err
+		}
+		level := ladder.Level(career.Level)
+
+		// Evaluate the job's rules and apply its side effects
+		result, err := job.evaluate(tx, userID)
+		if err != nil {
+			return err
+		}
+		earnedAmount := result.Earned
+		description := result.Description
+		if earnedAmount > 0 && level.PayMultiplier != 1 {
+			earnedAmount = math.Round(earnedAmount*level.PayMultiplier*100) / 100
+			description += fmt.Sprintf(" (%s pay x%.2f)", level.Title, level.PayMultiplier)
+		}
 
 		// Ensure minimum earning of 0
 		if earnedAmount < 0 {
@@ -262,20 +354,33 @@ func (s *WorkService) CompleteWork(userID uint) (*CompleteWorkResponse, error) {
 		workSession := model.WorkSession{
 			UserID:          userID,
 			JobType:         jobType,
-			DurationSeconds: WORK_DURATION,
+			DurationSeconds: job.DurationSeconds,
 			Earned:          earnedAmount,
 			CompletedAt:     now,
 		}
 		if err := tx.Create(&workSession).Error; err != nil {
 			return fmt.Errorf("failed to create work session: %w", err)
 		}
+		if err := events.Record(tx, events.WorkCompleted{
+			UserID:          userID,
+			JobType:         jobType,
+			Earned:          earnedAmount,
+			DurationSeconds: job.DurationSeconds,
+		}); err != nil {
+			return err
+		}
+
+		progress, err := advanceCareer(tx, ladder, career, job, earnedAmount, statuses)
+		if err != nil {
+			return err
+		}
 
 		response = &CompleteWorkResponse{
 			UserID:        userID,
 			Earned:        earnedAmount,
-			BaseReward:    WORK_REWARD,
+			BaseReward:    job.BasePay,
 			NewBalance:    newBalance,
-			DurationSec:   WORK_DURATION,
+			DurationSec:   job.DurationSeconds,
 			CompletedAt:   now,
 			TransactionID: transaction.ID,
 			WorkSessionID: workSession.ID,
@@ -283,6 +388,7 @@ func (s *WorkService) CompleteWork(userID uint) (*CompleteWorkResponse, error) {
 			HasCar:        false,
 			ClothingBonus: 0,
 			CarBonus:      0,
+			Career:        progress,
 		}
 
 		return nil
@@ -292,6 +398,7 @@ func (s *WorkService) CompleteWork(userID uint) (*CompleteWorkResponse, error) {
 		return nil, err
 	}
 
+	flushEvents(s.db)
 	return response, nil
 }
 
@@ -347,209 +454,6 @@ func (s *WorkService) GetHistory(userID uint, limit int, offset int) (*WorkHisto
 	}, nil
 }
 
-// checkJobRequirements checks if user meets requirements for a specific job
-func (s *WorkService) checkJobRequirements(userID uint, jobType model.JobType) error {
-	switch jobType {
-	case model.JobTypeOffice:
-		// Office requires at least one clothing item
-		var clothingCount int64
-		if err := s.db.Model(&model.UserItem{}).
-			Joins("JOIN items ON items.id = user_items.item_id").
-			Where("user_items.user_id = ? AND user_items.is_equipped = ? AND items.type = ?",
-				userID, true, model.ItemTypeClothing).
-			Count(&clothingCount).Error; err != nil {
-			return fmt.Errorf("failed to check clothing: %w", err)
-		}
-		if clothingCount == 0 {
-			return fmt.Errorf("office_no_clothes")
-		}
-
-	case model.JobTypeCourier:
-		// Courier requires uniform
-		var hasUniform bool
-		err := s.db.Model(&model.UserItem{}).
-			Joins("JOIN items ON items.id = user_items.item_id").
-			Where("user_items.user_id = ? AND user_items.is_equipped = ? AND items.name = ?",
-				userID, true, "Courier Uniform").
-			Select("COUNT(*) > 0").
-			Scan(&hasUniform).Error
-		if err != nil {
-			return fmt.Errorf("failed to check uniform: %w", err)
-		}
-		if !hasUniform {
-			return fmt.Errorf("courier_no_uniform")
-		}
-
-	case model.JobTypeStuntDriver:
-		// Stunt driver requires a car
-		var carCount int64
-		if err := s.db.Model(&model.UserItem{}).
-			Joins("JOIN items ON items.id = user_items.item_id").
-			Where("user_items.user_id = ? AND user_items.is_equipped = ? AND items.type = ?",
-				userID, true, model.ItemTypeCar).
-			Count(&carCount).Error; err != nil {
-			return fmt.Errorf("failed to check car: %w", err)
-		}
-		if carCount == 0 {
-			return fmt.Errorf("stunt_driver_no_car")
-		}
-	}
-
-	return nil
-}
-
-// applyJobEffects applies job-specific effects and returns earned amount and description
-func (s *WorkService) applyJobEffects(tx *gorm.DB, userID uint, jobType model.JobType) (float64, string, error) {
-	switch jobType {
-	case model.JobTypeOffice:
-		return s.applyOfficeJob(tx, userID)
-	case model.JobTypeCourier:
-		return s.applyCourierJob(tx, userID)
-	case model.JobTypeLabRat:
-		return s.applyLabRatJob(tx, userID)
-	case model.JobTypeStuntDriver:
-		return s.applyStuntDriverJob(tx, userID)
-	case model.JobTypeDrugDealer:
-		return s.applyDrugDealerJob(tx, userID)
-	case model.JobTypeStreamer:
-		return s.applyStreamerJob(tx, userID)
-	case model.JobTypeBottleCollector:
-		return s.applyBottleCollectorJob(tx, userID)
-	default:
-		return WORK_REWARD, "Work completed", nil
-	}
-}
-
-func (s *WorkService) applyOfficeJob(tx *gorm.DB, userID uint) (float64, string, error) {
-	// Office job: standard 500, no bonuses
-	return WORK_REWARD, "Office work completed", nil
-}
-
-func (s *WorkService) applyCourierJob(tx *gorm.DB, userID uint) (float64, string, error) {
-	// Courier: base 500 + car bonus 250
-	var carCount int64
-	if err := tx.Model(&model.UserItem{}).
-		Joins("JOIN items ON items.id = user_items.item_id").
-		Where("user_items.user_id = ? AND user_items.is_equipped = ? AND items.type = ?",
-			userID, true, model.ItemTypeCar).
-		Count(&carCount).Error; err != nil {
-		return 0, "", fmt.Errorf("failed to check car: %w", err)
-	}
-
-	earned := WORK_REWARD
-	desc := "Courier work completed"
-	if carCount > 0 {
-		earned += 250.0
-		desc += " (own car bonus: +$250)"
-	}
-
-	return earned, desc, nil
-}
-
-func (s *WorkService) applyLabRatJob(tx *gorm.DB, userID uint) (float64, string, error) {
-	// Lab rat: 500 + random mutation
-	// Get all mutations
-	var mutations []model.Item
-	if err := tx.Where("type = ?", model.ItemTypeMutation).Find(&mutations).Error; err != nil {
-		return 0, "", fmt.Errorf("failed to get mutations: %w", err)
-	}
-
-	if len(mutations) > 0 {
-		// Give random mutation
-		randomMutation := mutations[time.Now().UnixNano()%int64(len(mutations))]
-
-		// Check if user already has this mutation
-		var existingCount int64
-		tx.Model(&model.UserItem{}).
-			Where("user_id = ? AND item_id = ?", userID, randomMutation.ID).
-			Count(&existingCount)
-
-		if existingCount == 0 {
-			userItem := model.UserItem{
-				UserID:     userID,
-				ItemID:     randomMutation.ID,
-				IsEquipped: true,
-			}
-			if err := tx.Create(&userItem).Error; err != nil {
-				return 0, "", fmt.Errorf("failed to give mutation: %w", err)
-			}
-		}
-
-		return WORK_REWARD, fmt.Sprintf("Lab experiment completed. You received: %s!", randomMutation.Name), nil
-	}
-
-	return WORK_REWARD, "Lab experiment completed", nil
-}
-
-func (s *WorkService) applyStuntDriverJob(tx *gorm.DB, userID uint) (float64, string, error) {
-	// Stunt driver: 1500 but car gets broken (unequipped)
-	// Unequip all cars
-	if err := tx.Model(&model.UserItem{}).
-		Where("user_id = ? AND item_id IN (SELECT id FROM items WHERE type = ?)", userID, model.ItemTypeCar).
-		Update("is_equipped", false).Error; err != nil {
-		return 0, "", fmt.Errorf("failed to break car: %w", err)
-	}
-
-	return 1500.0, "Stunt driving completed! Earned $1500 but your car is broken (unequipped)", nil
-}
-
-func (s *WorkService) applyDrugDealerJob(tx *gorm.DB, userID uint) (float64, string, error) {
-	// Drug dealer: 2000 but go to jail (8 years = future timestamp)
-	// Create user status "in_jail" that expires in 8 years
-	jailTime := time.Now().Add(8 * 365 * 24 * time.Hour)
-	status := model.UserStatus{
-		UserID:    userID,
-		Status:    "in_jail",
-		ExpiresAt: jailTime,
-	}
-	if err := tx.Create(&status).Error; err != nil {
-		return 0, "", fmt.Errorf("failed to create jail status: %w", err)
-	}
-
-	return 2000.0, "You got caught! Earned $2000 but you're in jail for 8 years (you can skip time)", nil
-}
-
-func (s *WorkService) applyStreamerJob(tx *gorm.DB, userID uint) (float64, string, error) {
-	// Check if already popular
-	var popularStatus int64
-	tx.Model(&model.UserStatus{}).
-		Where("user_id = ? AND status = ?", userID, "popular_streamer").
-		Count(&popularStatus)
-
-	if popularStatus > 0 {
-		// Already popular, always get 10000
-		return 10000.0, "Streaming as popular streamer! Earned $10,000", nil
-	}
-
-	// Not popular yet: 70% = 0, 29% = 1, 1% = 10000 + become popular
-	roll := time.Now().UnixNano() % 100
-
-	if roll < 70 {
-		// 70%: nothing
-		return 0.0, "Streaming session completed but no one watched...", nil
-	} else if roll < 99 {
-		// 29%: $1
-		return 1.0, "Streaming session completed. Someone donated $1!", nil
-	} else {
-		// 1%: $10000 + become popular
-		status := model.UserStatus{
-			UserID:    userID,
-			Status:    "popular_streamer",
-			ExpiresAt: time.Now().Add(100 * 365 * 24 * time.Hour), // Forever
-		}
-		if err := tx.Create(&status).Error; err != nil {
-			return 0, "", fmt.Errorf("failed to create popular status: %w", err)
-		}
-
-		return 10000.0, "YOU WENT VIRAL! Earned $10,000 and became a popular streamer! All future streams will earn $10,000!", nil
-	}
-}
-
-func (s *WorkService) applyBottleCollectorJob(tx *gorm.DB, userID uint) (float64, string, error) {
-	// Bottle collector: always 100, available to everyone
-	return 100.0, "Collected bottles and cans. Earned $100", nil
-}
-
 // SkipJailTime removes the jail status from user
 func (s *WorkService) SkipJailTime(userID uint) error {
 	// Check if user is in jail
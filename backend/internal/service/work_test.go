@@ -33,8 +33,40 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&model.Transaction{},
 		&model.WorkSession{},
 		&model.GameSession{},
+		&model.RouletteResult{},
+		&model.ContactMessage{},
 		&model.Item{},
 		&model.UserItem{},
+		&model.UserStatus{},
+		&model.Career{},
+		&model.Loan{},
+		&model.LoanInstallment{},
+		&model.LoanStatementEntry{},
+		&model.Bankruptcy{},
+		&model.CollectionEvent{},
+		&model.CollectorEncounter{},
+		&model.CollectorEncounterStep{},
+		&model.MarketListing{},
+		&model.ItemPricePoint{},
+		&model.Auction{},
+		&model.AuctionBid{},
+		&model.UserAchievement{},
+		&model.UserChallenge{},
+		&model.OutboxEvent{},
+		&model.Notification{},
+		&model.LeaderboardEntry{},
+		&model.GamblingLimit{},
+		&model.GamblingSession{},
+		&model.OpenStake{},
+		&model.RealityCheck{},
+		&model.DataExport{},
+		&model.AccountDeletion{},
+		&model.AuthSession{},
+		&model.RefreshToken{},
+		&model.EmailToken{},
+		&model.TwoFactor{},
+		&model.RecoveryCode{},
+		&model.TwoFactorChallenge{},
 	)
 	require.NoError(t, err, "failed to migrate test database")
 
@@ -75,7 +107,7 @@ func TestWorkServiceStartWork(t *testing.T) {
 	assert.NotNil(t, response)
 	assert.Equal(t, user.ID, response.UserID)
 	assert.Equal(t, WORK_DURATION, response.DurationSec)
-	assert.Equal(t, WORK_REWARD, response.Reward)
+	assert.Equal(t, 100.0, response.Reward) // Bottle collector base pay from the catalogue
 	assert.WithinDuration(t, time.Now(), response.StartedAt, 1*time.Second)
 	assert.WithinDuration(t, time.Now().Add(WORK_DURATION*time.Second), response.CompletesAt, 1*time.Second)
 
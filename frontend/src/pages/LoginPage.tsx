@@ -5,7 +5,7 @@ import { useNavigate, useSearchParams, useLocation } from 'react-router-dom';
 import { PageTransition, rotateVariants, scaleFadeVariants } from '../components/animations';
 import api from '../services/api';
 import { useAuthStore } from '../store/authStore';
-import type { AuthResponse } from '../types';
+import type { AuthResponse, TwoFactorChallenge } from '../types';
 
 const LoginPage = () => {
   const navigate = useNavigate();
@@ -15,6 +15,9 @@ const LoginPage = () => {
   const [error, setError] = useState<string | null>(null);
   const [isProcessing, setIsProcessing] = useState(false);
   const [mode, setMode] = useState<'login' | 'register'>('login');
+  // Set when the password was right but the account needs a two-factor code
+  const [challengeToken, setChallengeToken] = useState<string | null>(null);
+  const [twoFactorCode, setTwoFactorCode] = useState('');
 
   // Form state
   const [formData, setFormData] = useState({
@@ -79,12 +82,20 @@ const LoginPage = () => {
     setError(null);
 
     try {
-      const response = await api.post<{ success: boolean; data: AuthResponse }>('/auth/login', {
+      const response = await api.post<{ success: boolean; data: AuthResponse | TwoFactorChallenge }>('/auth/login', {
         username: formData.username,
         password: formData.password,
       });
 
-      login(response.data.data);
+      const data = response.data.data;
+      if ('two_factor_required' in data) {
+        setChallengeToken(data.challenge_token);
+        setTwoFactorCode('');
+        setIsProcessing(false);
+        return;
+      }
+
+      login(data);
       navigate(from, { replace: true });
     } catch (err: unknown) {
       console.error('Login failed:', err);
@@ -93,6 +104,33 @@ const LoginPage = () => {
     }
   };
 
+  const handleTwoFactorVerify = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setIsProcessing(true);
+    setError(null);
+
+    try {
+      const response = await api.post<{ success: boolean; data: AuthResponse }>('/auth/2fa/verify', {
+        challenge_token: challengeToken,
+        code: twoFactorCode,
+      });
+
+      login(response.data.data);
+      navigate(from, { replace: true });
+    } catch (err: unknown) {
+      console.error('Two-factor verification failed:', err);
+      const message = (err as { response?: { data?: { message?: string } } }).response?.data?.message;
+      if (message === 'challenge_expired' || message === 'too_many_attempts' || message === 'invalid_challenge') {
+        // The challenge is spent, so the password has to be entered again
+        setChallengeToken(null);
+        setError('Время входа истекло. Введите пароль снова.');
+      } else {
+        setError('Неверный код');
+      }
+      setIsProcessing(false);
+    }
+  };
+
   const handleRegister = async (e: React.FormEvent) => {
     e.preventDefault();
     setIsProcessing(true);
@@ -183,7 +221,40 @@ const LoginPage = () => {
           </div>
 
           {/* Login/Register Form */}
-          {mode === 'login' ? (
+          {mode === 'login' && challengeToken ? (
+            <form onSubmit={handleTwoFactorVerify} className="space-y-4 mb-6">
+              <div>
+                <label className="block text-gray-400 text-sm mb-2">Код подтверждения</label>
+                <input
+                  type="text"
+                  name="code"
+                  value={twoFactorCode}
+                  onChange={(e) => setTwoFactorCode(e.target.value)}
+                  required
+                  autoFocus
+                  autoComplete="one-time-code"
+                  className="w-full bg-gray-900 border border-gray-700 rounded-lg px-4 py-2 text-white focus:outline-none focus:border-primary"
+                  placeholder="Код из приложения или резервный код"
+                />
+              </div>
+              <motion.button
+                whileHover={{ scale: 1.02 }}
+                whileTap={{ scale: 0.98 }}
+                type="submit"
+                disabled={isProcessing}
+                className="w-full bg-primary text-white font-semibold py-3 px-6 rounded-lg hover:bg-red-700 transition-colors disabled:opacity-50 disabled:cursor-not-allowed"
+              >
+                {isProcessing ? 'Проверка...' : 'Подтвердить'}
+              </motion.button>
+              <button
+                type="button"
+                onClick={() => setChallengeToken(null)}
+                className="w-full text-gray-400 text-sm hover:text-white transition-colors"
+              >
+                Назад
+              </button>
+            </form>
+          ) : mode === 'login' ? (
             <form onSubmit={handleLocalLogin} className="space-y-4 mb-6">
               <div>
                 <label className="block text-gray-400 text-sm mb-2">Логин</label>
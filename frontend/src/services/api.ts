@@ -66,10 +66,11 @@ api.interceptors.response.use(
             refresh_token: refreshToken,
           });
 
-          const { access_token } = response.data;
+          const { access_token, refresh_token } = response.data;
 
-          // Save new token
+          // Save new tokens; the old refresh token no longer works
           localStorage.setItem('access_token', access_token);
+          localStorage.setItem('refresh_token', refresh_token);
 
           // Retry original request with new token
           if (originalRequest.headers) {
@@ -44,7 +44,7 @@ export const useAuthStore = create<AuthState>()(
 
       logout: async () => {
         try {
-          // Call backend logout endpoint to clear server-side cookies
+          // Call backend logout endpoint to end the session and clear cookies
           await api.post('/auth/logout');
         } catch (error) {
           console.error('Logout API call failed:', error);
@@ -124,12 +124,13 @@ export const useAuthStore = create<AuthState>()(
         }
 
         try {
-          const response = await api.post<{ access_token: string }>('/auth/refresh', {
+          const response = await api.post<{ access_token: string; refresh_token: string }>('/auth/refresh', {
             refresh_token: refreshToken,
           });
 
-          // Save new access token
+          // Save new tokens; the old refresh token no longer works
           localStorage.setItem('access_token', response.data.access_token);
+          localStorage.setItem('refresh_token', response.data.refresh_token);
 
           // Fetch user data with new token
           const userResponse = await api.get<{ user: User }>('/auth/me');
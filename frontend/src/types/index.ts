@@ -16,6 +16,13 @@ export interface AuthResponse {
   user: User;
 }
 
+// Returned by a password login when the account needs a two-factor code
+export interface TwoFactorChallenge {
+  two_factor_required: true;
+  challenge_token: string;
+  expires_at: string;
+}
+
 // Transaction types
 export interface Transaction {
   id: string;